@@ -92,6 +92,7 @@ var rootCmd = &cobra.Command{
 		// based on atomic flag, as set by installer
 		// TODO nodeagent watch server should affect this too, and drop atomic flag
 		installDaemonReady, watchServerReady := nodeagent.StartHealthServer()
+		debugHandler := nodeagent.StartDebugServer(cfg.InstallConfig.DebugPort)
 
 		installer := install.NewInstaller(&cfg.InstallConfig, installDaemonReady)
 
@@ -128,6 +129,8 @@ var rootCmd = &cobra.Command{
 					EnableIPv6:                 cfg.InstallConfig.AmbientIPv6,
 					ReconcilePodRulesOnStartup: cfg.InstallConfig.AmbientReconcilePodRulesOnStartup,
 					NativeNftables:             cfg.InstallConfig.NativeNftables,
+					EbpfRedirection:            cfg.InstallConfig.AmbientEbpfRedirection,
+					ExcludeSecondaryInterfaces: cfg.InstallConfig.ExcludeSecondaryInterfaces,
 					ForceIptablesBinary:        cfg.InstallConfig.ForceIptablesBinary,
 				})
 			if err != nil {
@@ -164,6 +167,7 @@ var rootCmd = &cobra.Command{
 			}()
 
 			ambientAgent.Start()
+			debugHandler.SetProvider(ambientAgent)
 
 			log.Info("Ambient node agent started, starting installer...")
 
@@ -239,13 +243,20 @@ func init() {
 	registerStringParameter(constants.KubeCAFile, "", "CA file for kubeconfig. Defaults to the same as install-cni pod")
 	registerBooleanParameter(constants.SkipTLSVerify, false, "Whether to use insecure TLS in kubeconfig file")
 	registerIntegerParameter(constants.MonitoringPort, 15014, "HTTP port to serve prometheus metrics")
+	registerIntegerParameter(constants.DebugPort, 15016, "Localhost HTTP port to serve the debug endpoints, 0 to disable them")
 	registerStringParameter(constants.ZtunnelUDSAddress, "/var/run/ztunnel/ztunnel.sock", "The UDS server address which ztunnel will connect to")
 	registerBooleanParameter(constants.AmbientEnabled, false, "Whether ambient controller is enabled")
 	registerBooleanParameter(constants.EnableAmbientDetectionRetry, false, "Whether or not is ambient check is retried on error in the cni plugin")
+	registerBooleanParameter(constants.ExcludeSecondaryInterfaces, false,
+		"Whether the traffic of the interfaces of secondary networks, e.g. attached by Multus, is not redirected")
 	// Repair
 	registerBooleanParameter(constants.RepairEnabled, true, "Whether to enable race condition repair or not")
 	registerBooleanParameter(constants.RepairDeletePods, false, "Controller will delete pods when detecting pod broken by race condition")
 	registerBooleanParameter(constants.RepairLabelPods, false, "Controller will label pods when detecting pod broken by race condition")
+	registerBooleanParameter(constants.RepairMigratePods, false,
+		"Controller will migrate the traffic redirection of running sidecar pods from iptables to nftables rules")
+	registerBooleanParameter(constants.RepairMigrateDryRun, false,
+		"Controller will only report the sidecar pods it would migrate from iptables to nftables rules")
 	registerStringParameter(constants.RepairLabelKey, "cni.istio.io/uninitialized",
 		"The key portion of the label which will be set by the race repair if label pods is true")
 	registerStringParameter(constants.RepairLabelValue, "true",
@@ -322,6 +333,7 @@ func constructConfig() (*config.Config, error) {
 		CNIBinSourceDir:  constants.CNIBinDir,
 		CNIBinTargetDirs: []string{constants.HostCNIBinDir},
 		MonitoringPort:   viper.GetInt(constants.MonitoringPort),
+		DebugPort:        viper.GetInt(constants.DebugPort),
 
 		ExcludeNamespaces: viper.GetString(constants.ExcludeNamespaces),
 		PodNamespace:      viper.GetString(constants.PodNamespace),
@@ -333,7 +345,9 @@ func constructConfig() (*config.Config, error) {
 		AmbientIPv6:                       viper.GetBool(constants.AmbientIPv6),
 		AmbientDisableSafeUpgrade:         viper.GetBool(constants.AmbientDisableSafeUpgrade),
 		AmbientReconcilePodRulesOnStartup: viper.GetBool(constants.AmbientReconcilePodRulesOnStartup),
+		AmbientEbpfRedirection:            viper.GetBool(constants.AmbientEbpfRedirection),
 		EnableAmbientDetectionRetry:       viper.GetBool(constants.EnableAmbientDetectionRetry),
+		ExcludeSecondaryInterfaces:        viper.GetBool(constants.ExcludeSecondaryInterfaces),
 
 		NativeNftables:      viper.GetBool(constants.NativeNftables),
 		ForceIptablesBinary: os.Getenv("FORCE_IPTABLES_BINARY"),
@@ -358,6 +372,8 @@ func constructConfig() (*config.Config, error) {
 		RepairPods:          viper.GetBool(constants.RepairRepairPods),
 		DeletePods:          viper.GetBool(constants.RepairDeletePods),
 		LabelPods:           viper.GetBool(constants.RepairLabelPods),
+		MigratePods:         viper.GetBool(constants.RepairMigratePods),
+		MigrateDryRun:       viper.GetBool(constants.RepairMigrateDryRun),
 		LabelKey:            viper.GetString(constants.RepairLabelKey),
 		LabelValue:          viper.GetString(constants.RepairLabelValue),
 		NodeName:            viper.GetString(constants.RepairNodeName),
@@ -369,6 +385,8 @@ func constructConfig() (*config.Config, error) {
 		FieldSelectors:      viper.GetString(constants.RepairFieldSelectors),
 		NativeNftables:      viper.GetBool(constants.NativeNftables),
 		ForceIptablesBinary: os.Getenv("FORCE_IPTABLES_BINARY"),
+
+		ExcludeSecondaryInterfaces: viper.GetBool(constants.ExcludeSecondaryInterfaces),
 	}
 
 	return &config.Config{InstallConfig: installCfg, RepairConfig: repairCfg}, nil
@@ -55,6 +55,8 @@ const (
 // that may need to be taken into account when injecting pod rules
 type PodLevelOverrides struct {
 	VirtualInterfaces []string
+	// ExcludeInterfaces are the interfaces of secondary networks (e.g. attached by Multus) whose traffic is not redirected.
+	ExcludeInterfaces []string
 	IngressMode       bool
 	DNSProxy          PodDNSOverride
 }
@@ -138,6 +140,8 @@ type InstallConfig struct {
 
 	// The HTTP port for monitoring
 	MonitoringPort int
+	// The localhost HTTP port serving the debug endpoints, 0 to disable them
+	DebugPort int
 
 	// The ztunnel server socket address that the ztunnel will connect to.
 	ZtunnelUDSAddress string
@@ -160,9 +164,15 @@ type InstallConfig struct {
 	// Whether reconciliation of iptables at post startup is enabled for Ambient workloads
 	AmbientReconcilePodRulesOnStartup bool
 
+	// Whether eBPF programs should be used instead of iptables or nftables rules for in-pod traffic redirection
+	AmbientEbpfRedirection bool
+
 	// Whether to retry checking if a pod is ambient in the cni plugin when there are errors
 	EnableAmbientDetectionRetry bool
 
+	// Whether the traffic of the interfaces of secondary networks (e.g. attached by Multus) is not redirected
+	ExcludeSecondaryInterfaces bool
+
 	// Whether native nftables should be used instead of iptable rules for traffic redirection
 	NativeNftables bool
 
@@ -199,6 +209,12 @@ type RepairConfig struct {
 	// Whether to label broken pods
 	LabelPods bool
 
+	// Whether to migrate the redirection of running sidecar pods from iptables to nftables
+	MigratePods bool
+
+	// Whether to only report the pods that would be migrated, without changing their rules
+	MigrateDryRun bool
+
 	// Filters for race repair, including name of sidecar annotation, name of init container,
 	// init container termination message and exit code.
 	SidecarAnnotation  string
@@ -213,6 +229,9 @@ type RepairConfig struct {
 	// Whether to repair pods by running nftables rules
 	NativeNftables bool
 
+	// Whether the traffic of the interfaces of secondary networks is not redirected when repairing pods
+	ExcludeSecondaryInterfaces bool
+
 	// Choose which iptables binary to use (legacy or nft)
 	ForceIptablesBinary string
 }
@@ -243,6 +262,7 @@ func (c InstallConfig) String() string {
 	b.WriteString("CNIBinTargetDirs: " + strings.Join(c.CNIBinTargetDirs, ",") + "\n")
 
 	b.WriteString("MonitoringPort: " + fmt.Sprint(c.MonitoringPort) + "\n")
+	b.WriteString("DebugPort: " + fmt.Sprint(c.DebugPort) + "\n")
 	b.WriteString("ZtunnelUDSAddress: " + fmt.Sprint(c.ZtunnelUDSAddress) + "\n")
 
 	b.WriteString("AmbientEnabled: " + fmt.Sprint(c.AmbientEnabled) + "\n")
@@ -251,7 +271,9 @@ func (c InstallConfig) String() string {
 	b.WriteString("AmbientIPv6: " + fmt.Sprint(c.AmbientIPv6) + "\n")
 	b.WriteString("AmbientDisableSafeUpgrade: " + fmt.Sprint(c.AmbientDisableSafeUpgrade) + "\n")
 	b.WriteString("AmbientReconcilePodRulesOnStartup: " + fmt.Sprint(c.AmbientReconcilePodRulesOnStartup) + "\n")
+	b.WriteString("AmbientEbpfRedirection: " + fmt.Sprint(c.AmbientEbpfRedirection) + "\n")
 	b.WriteString("EnableAmbientDetectionRetry: " + fmt.Sprint(c.EnableAmbientDetectionRetry) + "\n")
+	b.WriteString("ExcludeSecondaryInterfaces: " + fmt.Sprint(c.ExcludeSecondaryInterfaces) + "\n")
 
 	b.WriteString("NativeNftables: " + fmt.Sprint(c.NativeNftables) + "\n")
 	b.WriteString("ForceIptablesBinary: " + fmt.Sprint(c.ForceIptablesBinary) + "\n")
@@ -266,6 +288,8 @@ func (c RepairConfig) String() string {
 	b.WriteString("LabelValue: " + c.LabelValue + "\n")
 	b.WriteString("DeletePods: " + fmt.Sprint(c.DeletePods) + "\n")
 	b.WriteString("LabelPods: " + fmt.Sprint(c.LabelPods) + "\n")
+	b.WriteString("MigratePods: " + fmt.Sprint(c.MigratePods) + "\n")
+	b.WriteString("MigrateDryRun: " + fmt.Sprint(c.MigrateDryRun) + "\n")
 	b.WriteString("SidecarAnnotation: " + c.SidecarAnnotation + "\n")
 	b.WriteString("InitContainerName: " + c.InitContainerName + "\n")
 	b.WriteString("InitTerminationMsg: " + c.InitTerminationMsg + "\n")
@@ -273,6 +297,7 @@ func (c RepairConfig) String() string {
 	b.WriteString("LabelSelectors: " + c.LabelSelectors + "\n")
 	b.WriteString("FieldSelectors: " + c.FieldSelectors + "\n")
 	b.WriteString("NativeNftables: " + fmt.Sprint(c.NativeNftables) + "\n")
+	b.WriteString("ExcludeSecondaryInterfaces: " + fmt.Sprint(c.ExcludeSecondaryInterfaces) + "\n")
 	b.WriteString("ForceIptablesBinary: " + fmt.Sprint(c.ForceIptablesBinary) + "\n")
 	return b.String()
 }
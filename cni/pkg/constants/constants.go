@@ -35,6 +35,7 @@ const (
 	KubeCAFile                        = "kube-ca-file"
 	SkipTLSVerify                     = "skip-tls-verify"
 	MonitoringPort                    = "monitoring-port"
+	DebugPort                         = "debug-port"
 	LogUDSSocket                      = "log-uds-socket"
 	ZtunnelUDSAddress                 = "ztunnel-uds-address"
 	CNIEventSocket                    = "cni-event-address"
@@ -47,7 +48,9 @@ const (
 	AmbientIPv6                       = "ambient-ipv6"
 	AmbientDisableSafeUpgrade         = "ambient-disable-safe-upgrade"
 	AmbientReconcilePodRulesOnStartup = "ambient-reconcile-pod-rules-on-startup"
+	AmbientEbpfRedirection            = "ambient-ebpf-redirection"
 	EnableAmbientDetectionRetry       = "enable-ambient-detection-retry"
+	ExcludeSecondaryInterfaces        = "exclude-secondary-interfaces"
 
 	NativeNftables = "native-nftables"
 
@@ -65,6 +68,8 @@ const (
 	RepairInitExitCode       = "repair-init-container-exit-code"
 	RepairLabelSelectors     = "repair-label-selectors"
 	RepairFieldSelectors     = "repair-field-selectors"
+	RepairMigratePods        = "repair-migrate-pods"
+	RepairMigrateDryRun      = "repair-migrate-dry-run"
 )
 
 // Internal constants
@@ -85,6 +90,7 @@ const (
 	LivenessEndpoint                   = "/healthz"
 	ReadinessEndpoint                  = "/readyz"
 	ReadinessPort                      = "8000"
+	DebugPodsEndpoint                  = "/debug/pods"
 	ServiceAccountPath                 = "/var/run/secrets/kubernetes.io/serviceaccount"
 	SelfNetNSPath                      = "/proc/self/ns/net"
 	DefaultIstioOwnedCNIConfigFilename = "02-istio-cni.conflist"
@@ -0,0 +1,491 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/iptables"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+)
+
+const (
+	// FilterPrefix prefixes the names of the tc filters of the redirection programs.
+	// The names end with a hash of the program, so a program that differs from the expected one is detected.
+	FilterPrefix = "istio-inpod-"
+
+	filterPriority = 1
+	filterHandle   = 1
+
+	progName    = "istio_redirect"
+	progLicense = "Apache-2.0"
+)
+
+var (
+	directionIngress = direction{name: "ingress", parent: netlink.HANDLE_MIN_INGRESS}
+	directionEgress  = direction{name: "egress", parent: netlink.HANDLE_MIN_EGRESS}
+)
+
+type direction struct {
+	name   string
+	parent uint32
+}
+
+// EbpfConfigurator redirects the traffic of pods to ztunnel with eBPF programs attached with tc to the
+// interfaces of the pod network namespace, instead of iptables or nftables rules.
+type EbpfConfigurator struct {
+	cfg    *config.AmbientConfig
+	nlDeps iptables.NetlinkDependencies
+
+	mu sync.Mutex
+	// watches stop the watches of the interfaces of the pod network namespaces, by namespace.
+	watches map[string]chan struct{}
+}
+
+func NewEbpfConfigurator(cfg *config.AmbientConfig, nlDeps iptables.NetlinkDependencies) *EbpfConfigurator {
+	if cfg == nil {
+		cfg = &config.AmbientConfig{}
+	}
+	return &EbpfConfigurator{
+		cfg:     cfg,
+		nlDeps:  nlDeps,
+		watches: map[string]chan struct{}{},
+	}
+}
+
+// program is a redirection program to attach to an interface.
+type program struct {
+	link      netlink.Link
+	direction direction
+	insns     asm.Instructions
+	name      string
+}
+
+// CreateInpodRules attaches the redirection programs to the interfaces of the pod.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *EbpfConfigurator) CreateInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) error {
+	programs, err := cfg.inpodPrograms(log, podOverrides)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.nlDeps.AddLoopbackRoutes(cfg.cfg); err != nil {
+		return err
+	}
+	if err := cfg.nlDeps.AddInpodMarkIPRule(cfg.cfg); err != nil {
+		return err
+	}
+
+	log.Debug("Attaching eBPF redirection programs")
+	if err := attachPrograms(log, programs); err != nil {
+		return err
+	}
+	return cfg.watchLinks(log, podOverrides, programs)
+}
+
+func attachPrograms(log *istiolog.Scope, programs []program) error {
+	for _, p := range programs {
+		if err := attach(p); err != nil {
+			log.Errorf("failed to attach eBPF program %s: %v", p.name, err)
+			return err
+		}
+		if p.direction == directionIngress && !p.l3Device() {
+			// Packets redirected by the egress program have the pod address as source, which is only accepted
+			// on a non-loopback interface with accept_local.
+			if err := setAcceptLocal(p.link.Attrs().Name, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watchLinks attaches the programs to the interfaces added to the pod network namespace after CreateInpodRules,
+// like the interfaces of secondary networks, which are often added by CNI plugins chained after the Istio one.
+// The watch stops with DeleteInpodRules, or once the interfaces of the pod are removed on pod deletion.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *EbpfConfigurator) watchLinks(log *istiolog.Scope, podOverrides config.PodLevelOverrides, programs []program) error {
+	ns, err := netns.Get()
+	if err != nil {
+		return fmt.Errorf("failed to open the pod network namespace: %v", err)
+	}
+	key := ns.UniqueId()
+	updates := make(chan netlink.LinkUpdate)
+	done := make(chan struct{})
+	err = netlink.LinkSubscribeWithOptions(updates, done, netlink.LinkSubscribeOptions{
+		// The interfaces added before the subscription are listed
+		ListExisting: true,
+		ErrorCallback: func(err error) {
+			select {
+			case <-done:
+			default:
+				log.Warnf("failed to watch the pod interfaces: %v", err)
+			}
+		},
+	})
+	if err != nil {
+		ns.Close()
+		return fmt.Errorf("failed to watch the pod interfaces: %v", err)
+	}
+
+	cfg.mu.Lock()
+	if stop, f := cfg.watches[key]; f {
+		close(stop)
+	}
+	cfg.watches[key] = done
+	cfg.mu.Unlock()
+
+	attachLink := func(link netlink.Link) error {
+		return inNetns(ns, func() error {
+			programs, err := cfg.linkPrograms(link, podOverrides)
+			if err != nil {
+				return err
+			}
+			log.Debugf("Attaching eBPF redirection programs to new interface %s", link.Attrs().Name)
+			return attachPrograms(log, programs)
+		})
+	}
+	go func() {
+		defer ns.Close()
+		handleLinkUpdates(log, updates, programs, attachLink)
+		cfg.stopWatch(key, done)
+		// Drain the updates until the subscription is closed
+		for range updates {
+		}
+	}()
+	return nil
+}
+
+func (cfg *EbpfConfigurator) stopWatch(key string, done chan struct{}) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if cfg.watches[key] == done {
+		delete(cfg.watches, key)
+		close(done)
+	}
+}
+
+// handleLinkUpdates attaches the programs to the interfaces of the pod once they are up, as CNI plugins may rename
+// them before. It returns when the updates are closed, or when all the interfaces of the pod were removed.
+func handleLinkUpdates(log *istiolog.Scope, updates <-chan netlink.LinkUpdate, programs []program, attach func(netlink.Link) error) {
+	// The names of the interfaces which programs are attached, or were excluded, by index.
+	handled := map[int]string{}
+	for _, p := range programs {
+		handled[p.link.Attrs().Index] = p.link.Attrs().Name
+	}
+	for u := range updates {
+		attrs := u.Link.Attrs()
+		if attrs.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		switch u.Header.Type {
+		case unix.RTM_DELLINK:
+			delete(handled, attrs.Index)
+			if len(handled) == 0 {
+				log.Debug("all the pod interfaces were removed, stop watching the interfaces")
+				return
+			}
+		case unix.RTM_NEWLINK:
+			if attrs.Flags&net.FlagUp == 0 {
+				continue
+			}
+			if name, f := handled[attrs.Index]; f && name == attrs.Name {
+				continue
+			}
+			if err := attach(u.Link); err != nil {
+				log.Errorf("failed to redirect the traffic of new interface %s: %v", attrs.Name, err)
+				continue
+			}
+			handled[attrs.Index] = attrs.Name
+		}
+	}
+}
+
+// inNetns runs f in the network namespace.
+func inNetns(ns netns.NsHandle, f func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, err := netns.Get()
+	if err != nil {
+		return err
+	}
+	defer orig.Close()
+	if err := netns.Set(ns); err != nil {
+		return err
+	}
+	defer netns.Set(orig)
+	return f()
+}
+
+// DeleteInpodRules detaches the redirection programs from the interfaces of the pod.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *EbpfConfigurator) DeleteInpodRules(log *istiolog.Scope) error {
+	if ns, err := netns.Get(); err == nil {
+		key := ns.UniqueId()
+		ns.Close()
+		cfg.mu.Lock()
+		if stop, f := cfg.watches[key]; f {
+			delete(cfg.watches, key)
+			close(stop)
+		}
+		cfg.mu.Unlock()
+	}
+
+	var errs []error
+	links, err := podLinks()
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		for _, d := range []direction{directionIngress, directionEgress} {
+			filters, err := istioFilters(link, d)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, f := range filters {
+				log.Debugf("Detaching eBPF program %s from %s", f.Name, link.Attrs().Name)
+				if err := netlink.FilterDel(f); err != nil {
+					errs = append(errs, fmt.Errorf("failed to delete filter %s of %s: %v", f.Name, link.Attrs().Name, err))
+				}
+			}
+		}
+		if len(link.Attrs().HardwareAddr) > 0 {
+			if err := setAcceptLocal(link.Attrs().Name, false); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if err := cfg.nlDeps.DelInpodMarkIPRule(cfg.cfg); err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete inpod mark ip rule: %v", err))
+	}
+	if err := cfg.nlDeps.DelLoopbackRoutes(cfg.cfg); err != nil {
+		errs = append(errs, fmt.Errorf("failed to delete loopback routes: %v", err))
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyInpodRules reports whether the programs attached to the interfaces of the pod differ from the programs
+// CreateInpodRules would attach.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *EbpfConfigurator) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	programs, err := cfg.inpodPrograms(log, podOverrides)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range programs {
+		filters, err := istioFilters(p.link, p.direction)
+		if err != nil {
+			return false, err
+		}
+		if len(filters) != 1 || filters[0].Name != p.name {
+			log.Debugf("eBPF program %s is not attached to %s %s", p.name, p.link.Attrs().Name, p.direction.name)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReconcileModeEnabled returns true, as attaching the programs replaces the ones previously attached.
+func (cfg *EbpfConfigurator) ReconcileModeEnabled() bool {
+	return true
+}
+
+// ErrDNSCaptureUnsupported is returned when DNS capture is enabled with the eBPF redirection, which cannot
+// redirect the DNS queries of the pods to ztunnel.
+var ErrDNSCaptureUnsupported = errors.New("DNS capture is not supported by the eBPF redirection")
+
+func (cfg *EbpfConfigurator) inpodPrograms(_ *istiolog.Scope, podOverrides config.PodLevelOverrides) ([]program, error) {
+	redirectDNS := cfg.cfg.RedirectDNS
+	switch podOverrides.DNSProxy {
+	case config.PodDNSEnabled:
+		redirectDNS = true
+	case config.PodDNSDisabled:
+		redirectDNS = false
+	}
+	if redirectDNS {
+		return nil, ErrDNSCaptureUnsupported
+	}
+
+	links, err := podLinks()
+	if err != nil {
+		return nil, err
+	}
+	var programs []program
+	for _, link := range links {
+		p, err := cfg.linkPrograms(link, podOverrides)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, p...)
+	}
+	return programs, nil
+}
+
+// linkPrograms returns the programs to attach to the interface, if its traffic is redirected.
+func (cfg *EbpfConfigurator) linkPrograms(link netlink.Link, podOverrides config.PodLevelOverrides) ([]program, error) {
+	if slices.Contains(podOverrides.ExcludeInterfaces, link.Attrs().Name) {
+		// The traffic of the excluded interfaces of secondary networks is not redirected.
+		return nil, nil
+	}
+	pc := ProgramConfig{
+		HostProbeSNATAddress:   cfg.cfg.HostProbeSNATAddress,
+		HostProbeV6SNATAddress: cfg.cfg.HostProbeV6SNATAddress,
+		EnableIPv6:             cfg.cfg.EnableIPv6,
+		IngressMode:            podOverrides.IngressMode,
+		Outbound:               slices.Contains(podOverrides.VirtualInterfaces, link.Attrs().Name),
+		L3Device:               len(link.Attrs().HardwareAddr) == 0,
+	}
+	ingress, err := IngressProgram(pc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ingress program: %v", err)
+	}
+	egress, err := EgressProgram(pc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate egress program: %v", err)
+	}
+	ingressName, err := filterName(directionIngress, ingress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ingress program: %v", err)
+	}
+	egressName, err := filterName(directionEgress, egress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate egress program: %v", err)
+	}
+	return []program{
+		{link: link, direction: directionIngress, insns: ingress, name: ingressName},
+		{link: link, direction: directionEgress, insns: egress, name: egressName},
+	}, nil
+}
+
+func (p program) l3Device() bool {
+	return len(p.link.Attrs().HardwareAddr) == 0
+}
+
+// filterName returns the name of the filter of the program, which ends with a hash of its instructions.
+// The byte order of the hashed encoding does not matter, as long as it does not change.
+func filterName(d direction, insns asm.Instructions) (string, error) {
+	var buf bytes.Buffer
+	if err := insns.Marshal(&buf, binary.LittleEndian); err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	h.Write(buf.Bytes())
+	return fmt.Sprintf("%s%s-%08x", FilterPrefix, d.name, h.Sum32()), nil
+}
+
+// podLinks returns the interfaces of the current network namespace, except the loopback interface.
+func podLinks() ([]netlink.Link, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %v", err)
+	}
+	return slices.FilterInPlace(links, func(l netlink.Link) bool {
+		return l.Attrs().Flags&net.FlagLoopback == 0
+	}), nil
+}
+
+func istioFilters(link netlink.Link, d direction) ([]*netlink.BpfFilter, error) {
+	filters, err := netlink.FilterList(link, d.parent)
+	if err != nil {
+		if errors.Is(err, unix.EINVAL) {
+			// There is no clsact qdisc
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s filters of %s: %v", d.name, link.Attrs().Name, err)
+	}
+	var res []*netlink.BpfFilter
+	for _, f := range filters {
+		if bf, ok := f.(*netlink.BpfFilter); ok && strings.HasPrefix(bf.Name, FilterPrefix+d.name) {
+			res = append(res, bf)
+		}
+	}
+	return res, nil
+}
+
+func attach(p program) error {
+	idx := p.link.Attrs().Index
+	err := netlink.QdiscReplace(&netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: idx,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_CLSACT,
+		},
+		QdiscType: "clsact",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add clsact qdisc to %s: %v", p.link.Attrs().Name, err)
+	}
+
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         progName,
+		Type:         ebpf.SchedCLS,
+		Instructions: p.insns,
+		License:      progLicense,
+	})
+	if err != nil {
+		// %+v includes the full log of the verifier
+		return fmt.Errorf("failed to load eBPF program: %+v", err)
+	}
+	// The filter holds a reference to the program
+	defer prog.Close()
+	err = netlink.FilterReplace(&netlink.BpfFilter{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: idx,
+			Parent:    p.direction.parent,
+			Handle:    filterHandle,
+			Priority:  filterPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Fd:           prog.FD(),
+		Name:         p.name,
+		DirectAction: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s filter to %s: %v", p.direction.name, p.link.Attrs().Name, err)
+	}
+	return nil
+}
+
+func setAcceptLocal(iface string, enabled bool) error {
+	v := "0"
+	if enabled {
+		v = "1"
+	}
+	path := filepath.Join("/proc/sys/net/ipv4/conf", iface, "accept_local")
+	if err := os.WriteFile(path, []byte(v), 0o644); err != nil {
+		return fmt.Errorf("failed to set accept_local of %s: %v", iface, err)
+	}
+	return nil
+}
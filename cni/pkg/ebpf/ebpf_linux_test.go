@@ -0,0 +1,319 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/iptables"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	podIP    = "10.199.0.1"
+	clientIP = "10.199.0.2"
+)
+
+// testNetwork is a pod network namespace, connected with a veth pair to a client network namespace.
+type testNetwork struct {
+	pod, client netns.NsHandle
+}
+
+func setupNetwork(t *testing.T) testNetwork {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, err := netns.Get()
+	assert.NoError(t, err)
+	defer orig.Close()
+	defer netns.Set(orig)
+
+	newNetns := func() netns.NsHandle {
+		ns, err := netns.New()
+		if errors.Is(err, unix.EPERM) {
+			t.Skip("creating network namespaces requires CAP_SYS_ADMIN")
+		}
+		assert.NoError(t, err)
+		t.Cleanup(func() { ns.Close() })
+		lo, err := netlink.LinkByName("lo")
+		assert.NoError(t, err)
+		assert.NoError(t, netlink.LinkSetUp(lo))
+		return ns
+	}
+	n := testNetwork{client: newNetns(), pod: newNetns()}
+
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "eth0"}, PeerName: "client0", PeerNamespace: netlink.NsFd(n.client)}
+	assert.NoError(t, netlink.LinkAdd(veth))
+	configureLink(t, "eth0", podIP)
+	assert.NoError(t, netns.Set(n.client))
+	configureLink(t, "client0", clientIP)
+	return n
+}
+
+func configureLink(t *testing.T, name, ip string) {
+	link, err := netlink.LinkByName(name)
+	assert.NoError(t, err)
+	addr, err := netlink.ParseAddr(ip + "/24")
+	assert.NoError(t, err)
+	assert.NoError(t, netlink.AddrAdd(link, addr))
+	assert.NoError(t, netlink.LinkSetUp(link))
+}
+
+// run runs f in the network namespace.
+func run(t *testing.T, ns netns.NsHandle, f func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	orig, err := netns.Get()
+	assert.NoError(t, err)
+	defer orig.Close()
+	assert.NoError(t, netns.Set(ns))
+	defer netns.Set(orig)
+	f()
+}
+
+// listen listens on the port of any address, with a transparent socket as ztunnel does.
+func listen(t *testing.T, ns netns.NsHandle, port int) net.Listener {
+	var l net.Listener
+	run(t, ns, func() {
+		lc := net.ListenConfig{Control: func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_IP, unix.IP_TRANSPARENT, 1)
+				if serr == nil {
+					serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, config.InpodMark)
+				}
+			})
+			return errors.Join(err, serr)
+		}}
+		var err error
+		l, err = lc.Listen(context.Background(), "tcp4", fmt.Sprintf("0.0.0.0:%d", port))
+		assert.NoError(t, err)
+	})
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// expectConnection dials the address from the network namespace, and returns the local address of the connection
+// accepted by the listener.
+func expectConnection(t *testing.T, ns netns.NsHandle, addr string, l net.Listener) string {
+	t.Helper()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		if err == nil {
+			accepted <- c
+		}
+	}()
+	var conn net.Conn
+	run(t, ns, func() {
+		var err error
+		conn, err = net.DialTimeout("tcp4", addr, 5*time.Second)
+		assert.NoError(t, err)
+	})
+	defer conn.Close()
+	select {
+	case c := <-accepted:
+		defer c.Close()
+		// Check the connection carries data in both directions
+		_, err := conn.Write([]byte("ping"))
+		assert.NoError(t, err)
+		buf := make([]byte, 4)
+		_, err = c.Read(buf)
+		assert.NoError(t, err)
+		_, err = c.Write(buf)
+		assert.NoError(t, err)
+		_, err = conn.Read(buf)
+		assert.NoError(t, err)
+		return c.LocalAddr().String()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("connection to %s was not accepted by %s", addr, l.Addr())
+		return ""
+	}
+}
+
+func TestRedirection(t *testing.T) {
+	n := setupNetwork(t)
+	cfg := NewEbpfConfigurator(&config.AmbientConfig{
+		HostProbeSNATAddress: netip.MustParseAddr("169.254.7.127"),
+	}, iptables.RealNlDeps())
+	log := istiolog.RegisterScope("ebpf-test", "")
+	// net2 is the interface of a secondary network, whose traffic is not redirected
+	overrides := config.PodLevelOverrides{ExcludeInterfaces: []string{"net2"}}
+
+	run(t, n.pod, func() {
+		err := cfg.CreateInpodRules(log, overrides)
+		if err != nil && (errors.Is(err, unix.EPERM) || errors.Is(err, unix.ENOSYS)) {
+			t.Skipf("eBPF programs cannot be loaded: %v", err)
+		}
+		assert.NoError(t, err)
+	})
+
+	inbound := listen(t, n.pod, config.ZtunnelInboundPlaintextPort)
+	outbound := listen(t, n.pod, config.ZtunnelOutboundPort)
+	hbone := listen(t, n.pod, config.ZtunnelInboundPort)
+
+	t.Run("inbound", func(t *testing.T) {
+		got := expectConnection(t, n.client, podIP+":8080", inbound)
+		assert.Equal(t, got, podIP+":8080")
+	})
+	t.Run("hbone", func(t *testing.T) {
+		got := expectConnection(t, n.client, fmt.Sprintf("%s:%d", podIP, config.ZtunnelInboundPort), hbone)
+		assert.Equal(t, got, fmt.Sprintf("%s:%d", podIP, config.ZtunnelInboundPort))
+	})
+	t.Run("outbound", func(t *testing.T) {
+		got := expectConnection(t, n.pod, clientIP+":9090", outbound)
+		assert.Equal(t, got, clientIP+":9090")
+	})
+	t.Run("new interface", func(t *testing.T) {
+		run(t, n.pod, func() {
+			assert.NoError(t, netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "net1"}, PeerName: "net1-peer"}))
+			assert.NoError(t, netlink.LinkAdd(&netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "net2"}, PeerName: "net2-peer"}))
+			// The interfaces are handled in the order they are up, net1-peer last
+			for _, name := range []string{"net2-peer", "net2", "net1", "net1-peer"} {
+				l, err := netlink.LinkByName(name)
+				assert.NoError(t, err)
+				assert.NoError(t, netlink.LinkSetUp(l))
+			}
+			link, err := netlink.LinkByName("net1-peer")
+			assert.NoError(t, err)
+			retry.UntilSuccessOrFail(t, func() error {
+				filters, err := istioFilters(link, directionIngress)
+				if err != nil {
+					return err
+				}
+				if len(filters) != 1 {
+					return fmt.Errorf("expected the programs to be attached to the new interface, got %v filters", len(filters))
+				}
+				return nil
+			}, retry.Timeout(5*time.Second))
+			// The excluded interface of the secondary network is skipped
+			excluded, err := netlink.LinkByName("net2")
+			assert.NoError(t, err)
+			filters, err := istioFilters(excluded, directionIngress)
+			assert.NoError(t, err)
+			assert.Equal(t, len(filters), 0)
+			drifted, err := cfg.VerifyInpodRules(log, overrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drifted, false)
+		})
+	})
+	t.Run("verify", func(t *testing.T) {
+		run(t, n.pod, func() {
+			drifted, err := cfg.VerifyInpodRules(log, overrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drifted, false)
+			drifted, err = cfg.VerifyInpodRules(log, config.PodLevelOverrides{ExcludeInterfaces: overrides.ExcludeInterfaces, IngressMode: true})
+			assert.NoError(t, err)
+			assert.Equal(t, drifted, true)
+		})
+	})
+	t.Run("delete", func(t *testing.T) {
+		run(t, n.pod, func() {
+			assert.NoError(t, cfg.DeleteInpodRules(log))
+			drifted, err := cfg.VerifyInpodRules(log, overrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drifted, true)
+		})
+		cfg.mu.Lock()
+		assert.Equal(t, len(cfg.watches), 0)
+		cfg.mu.Unlock()
+		// Without redirection, the connection is refused
+		run(t, n.client, func() {
+			_, err := net.DialTimeout("tcp4", podIP+":8080", 5*time.Second)
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestDNSCaptureRejected(t *testing.T) {
+	log := istiolog.RegisterScope("ebpf-test", "")
+	_, err := NewEbpfConfigurator(&config.AmbientConfig{RedirectDNS: true}, nil).VerifyInpodRules(log, config.PodLevelOverrides{})
+	assert.Equal(t, errors.Is(err, ErrDNSCaptureUnsupported), true)
+	_, err = NewEbpfConfigurator(&config.AmbientConfig{}, nil).VerifyInpodRules(log, config.PodLevelOverrides{DNSProxy: config.PodDNSEnabled})
+	assert.Equal(t, errors.Is(err, ErrDNSCaptureUnsupported), true)
+}
+
+func linkUpdate(msgType uint16, index int, name string, flags net.Flags) netlink.LinkUpdate {
+	return netlink.LinkUpdate{
+		Header: unix.NlMsghdr{Type: msgType},
+		Link:   &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Index: index, Name: name, Flags: flags}},
+	}
+}
+
+func TestHandleLinkUpdates(t *testing.T) {
+	log := istiolog.RegisterScope("ebpf-test", "")
+	eth0 := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0"}}
+	programs := []program{{link: eth0, direction: directionIngress}, {link: eth0, direction: directionEgress}}
+
+	updates := make(chan netlink.LinkUpdate)
+	var attached []string
+	failNext := false
+	done := make(chan struct{})
+	go func() {
+		handleLinkUpdates(log, updates, programs, func(link netlink.Link) error {
+			attached = append(attached, link.Attrs().Name)
+			if failNext {
+				failNext = false
+				return errors.New("failed")
+			}
+			return nil
+		})
+		close(done)
+	}()
+
+	updates <- linkUpdate(unix.RTM_NEWLINK, 1, "lo", net.FlagUp|net.FlagLoopback)
+	updates <- linkUpdate(unix.RTM_NEWLINK, 2, "eth0", net.FlagUp)
+	// The interface is renamed before it is up
+	updates <- linkUpdate(unix.RTM_NEWLINK, 3, "tmp1234", 0)
+	updates <- linkUpdate(unix.RTM_NEWLINK, 3, "net1", 0)
+	updates <- linkUpdate(unix.RTM_NEWLINK, 3, "net1", net.FlagUp)
+	updates <- linkUpdate(unix.RTM_NEWLINK, 3, "net1", net.FlagUp|net.FlagRunning)
+	// Failures are retried on the next update
+	failNext = true
+	updates <- linkUpdate(unix.RTM_NEWLINK, 4, "net2", net.FlagUp)
+	updates <- linkUpdate(unix.RTM_NEWLINK, 4, "net2", net.FlagUp|net.FlagRunning)
+
+	// The watch stops once all the interfaces of the pod are removed
+	updates <- linkUpdate(unix.RTM_DELLINK, 3, "net1", 0)
+	// The previous updates were handled once the next one is received
+	assert.Equal(t, attached, []string{"net1", "net2", "net2"})
+	updates <- linkUpdate(unix.RTM_DELLINK, 1, "lo", net.FlagLoopback)
+	updates <- linkUpdate(unix.RTM_DELLINK, 4, "net2", 0)
+	select {
+	case <-done:
+		t.Fatal("watch stopped while the pod has interfaces")
+	default:
+	}
+	updates <- linkUpdate(unix.RTM_DELLINK, 2, "eth0", 0)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop once the interfaces of the pod were removed")
+	}
+}
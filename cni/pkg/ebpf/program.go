@@ -0,0 +1,427 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/cilium/ebpf/asm"
+
+	"istio.io/istio/cni/pkg/config"
+)
+
+// The redirection programs are attached with tc to the interfaces of the pod network namespace,
+// and mirror the in-pod iptables rules for TCP:
+//
+//   - On ingress, inbound connections are assigned to the ztunnel inbound plaintext socket, except for
+//     HBONE traffic and kubelet probes, SNATed to the host probe address on the node.
+//   - On egress, connections that are not from ztunnel, identified by its mark, are marked and redirected
+//     to the ingress of the interface. On ingress, these are assigned to the ztunnel outbound socket,
+//     and routed locally by the in-pod mark ip rule.
+//
+// Packets of established connections are matched with a socket lookup, so no map is needed.
+
+// Offsets of the fields of struct __sk_buff and struct bpf_sock we use.
+const (
+	skbMark     = 8
+	skbProtocol = 16
+	skbIfindex  = 40
+
+	sockState = 72
+)
+
+const (
+	tcActUnspec = -1
+	tcActShot   = 2
+
+	bpfFIngress      = 1
+	bpfFCurrentNetns = -1
+
+	tcpStateListen = 10
+
+	tcpFlagSYN = 0x02
+	tcpFlagACK = 0x10
+
+	ethHeaderLen = 14
+	ipProtoTCP   = 6
+)
+
+// Layout of the stack: a struct bpf_sock_tuple, preceded by scratch areas for bytes read from the packet.
+const (
+	stackMAC     = -56
+	stackScratch = -48
+	stackTuple   = -40
+)
+
+// ProgramConfig configures the redirection programs attached in a pod network namespace.
+type ProgramConfig struct {
+	HostProbeSNATAddress   netip.Addr
+	HostProbeV6SNATAddress netip.Addr
+	EnableIPv6             bool
+	// IngressMode disables the redirection of inbound connections.
+	IngressMode bool
+	// Outbound handles all the connections entering the interface as outbound, as done for virtual interfaces.
+	Outbound bool
+	// L3Device is set for interfaces without an ethernet header, like tun devices.
+	L3Device bool
+}
+
+// addressFamily describes where the fields of a packet of the family are, and where they are copied on the stack.
+type addressFamily struct {
+	name      string
+	l2Len     int32
+	ethProto  uint16
+	tupleSize int32
+	srcAddr   int16
+	dstAddr   int16
+	dstPort   int16
+	probeAddr netip.Addr
+}
+
+func (c ProgramConfig) families() []addressFamily {
+	l2Len := int32(ethHeaderLen)
+	if c.L3Device {
+		l2Len = 0
+	}
+	f := []addressFamily{{
+		name:      "v4",
+		l2Len:     l2Len,
+		ethProto:  0x0800,
+		tupleSize: 12,
+		srcAddr:   stackTuple,
+		dstAddr:   stackTuple + 4,
+		dstPort:   stackTuple + 10,
+		probeAddr: c.HostProbeSNATAddress,
+	}}
+	if c.EnableIPv6 {
+		f = append(f, addressFamily{
+			name:      "v6",
+			l2Len:     l2Len,
+			ethProto:  0x86dd,
+			tupleSize: 36,
+			srcAddr:   stackTuple,
+			dstAddr:   stackTuple + 16,
+			dstPort:   stackTuple + 34,
+			probeAddr: c.HostProbeV6SNATAddress,
+		})
+	}
+	return f
+}
+
+// IngressProgram returns the program attached to the ingress of the pod interfaces.
+func IngressProgram(c ProgramConfig) (asm.Instructions, error) {
+	b := &builder{}
+	b.emit(asm.Mov.Reg(asm.R6, asm.R1))
+	for _, f := range c.families() {
+		b.parse(f)
+		if !c.Outbound {
+			b.emit(
+				asm.LoadMem(asm.R2, asm.R6, skbMark, asm.Word),
+				asm.And.Imm(asm.R2, config.InpodTProxyMask),
+				asm.JEq.Imm(asm.R2, config.InpodTProxyMark, f.name+"_outbound"),
+			)
+			if c.IngressMode {
+				b.emit(asm.Ja.Label("pass"))
+			} else {
+				b.inbound(f)
+			}
+		}
+		b.label(f.name + "_outbound")
+		b.outbound(f)
+		b.label(f.name + "_next")
+	}
+	b.verdicts(true)
+	return b.instructions()
+}
+
+// EgressProgram returns the program attached to the egress of the pod interfaces.
+func EgressProgram(c ProgramConfig) (asm.Instructions, error) {
+	b := &builder{}
+	b.emit(
+		asm.Mov.Reg(asm.R6, asm.R1),
+		// Skip ztunnel traffic
+		asm.LoadMem(asm.R2, asm.R6, skbMark, asm.Word),
+		asm.And.Imm(asm.R2, config.InpodMask),
+		asm.JEq.Imm(asm.R2, config.InpodMark, "pass"),
+	)
+	for _, f := range c.families() {
+		b.parse(f)
+		b.skipAddress(f.dstAddr, f.probeAddr, f.name+"_not_probe")
+
+		// New connections are redirected, as well as the packets of the connections owned by the ztunnel outbound socket.
+		b.emit(
+			asm.Mov.Reg(asm.R2, asm.R7),
+			asm.Add.Imm(asm.R2, 13),
+		)
+		b.loadBytes(asm.R2, stackScratch, 1)
+		b.emit(
+			asm.LoadMem(asm.R2, asm.RFP, stackScratch, asm.Byte),
+			asm.And.Imm(asm.R2, tcpFlagSYN|tcpFlagACK),
+			asm.JEq.Imm(asm.R2, tcpFlagSYN, "redirect"),
+		)
+		b.lookup(f)
+		b.emit(
+			asm.JEq.Imm(asm.R0, 0, "pass"),
+			asm.LoadMem(asm.R7, asm.R0, sockState, asm.Word),
+			asm.Mov.Reg(asm.R1, asm.R0),
+			asm.FnSkRelease.Call(),
+			asm.JEq.Imm(asm.R7, tcpStateListen, "pass"),
+			asm.Ja.Label("redirect"),
+		)
+		b.label(f.name + "_next")
+	}
+	b.emit(asm.Ja.Label("pass"))
+
+	// Redirected packets are received by the interface, so they must be addressed to it for the stack to accept them.
+	b.label("redirect")
+	if !c.L3Device {
+		b.emit(asm.Mov.Imm(asm.R2, 6))
+		b.loadBytes(asm.R2, stackMAC, 6)
+		b.emit(
+			asm.Mov.Reg(asm.R1, asm.R6),
+			asm.Mov.Imm(asm.R2, 0),
+			asm.Mov.Reg(asm.R3, asm.RFP),
+			asm.Add.Imm(asm.R3, stackMAC),
+			asm.Mov.Imm(asm.R4, 6),
+			asm.Mov.Imm(asm.R5, 0),
+			asm.FnSkbStoreBytes.Call(),
+			asm.JNE.Imm(asm.R0, 0, "pass"),
+		)
+	}
+	b.emit(
+		asm.LoadMem(asm.R2, asm.R6, skbMark, asm.Word),
+		asm.And.Imm(asm.R2, ^config.InpodTProxyMask),
+		asm.Or.Imm(asm.R2, config.InpodTProxyMark),
+		asm.StoreMem(asm.R6, skbMark, asm.R2, asm.Word),
+		asm.LoadMem(asm.R1, asm.R6, skbIfindex, asm.Word),
+		asm.Mov.Imm(asm.R2, bpfFIngress),
+		asm.FnRedirect.Call(),
+		asm.Return(),
+	)
+	b.verdicts(false)
+	return b.instructions()
+}
+
+// builder builds a program from instructions and labels. Several labels may designate the same instruction,
+// while an instruction only has one symbol, so the other labels are aliases of the symbol.
+type builder struct {
+	insns   asm.Instructions
+	pending []string
+	aliases map[string]string
+}
+
+func (b *builder) emit(insns ...asm.Instruction) {
+	for _, ins := range insns {
+		if len(b.pending) > 0 {
+			ins = ins.WithSymbol(b.pending[0])
+			for _, l := range b.pending[1:] {
+				if b.aliases == nil {
+					b.aliases = map[string]string{}
+				}
+				b.aliases[l] = b.pending[0]
+			}
+			b.pending = nil
+		}
+		b.insns = append(b.insns, ins)
+	}
+}
+
+// label designates the next instruction.
+func (b *builder) label(name string) {
+	b.pending = append(b.pending, name)
+}
+
+// instructions returns the instructions of the program, with the references to aliases replaced by their symbol.
+// Unresolved references are reported when the program is marshaled.
+func (b *builder) instructions() (asm.Instructions, error) {
+	if len(b.pending) > 0 {
+		return nil, fmt.Errorf("labels %v do not designate an instruction", b.pending)
+	}
+	for i, ins := range b.insns {
+		if sym, f := b.aliases[ins.Reference()]; f {
+			b.insns[i] = ins.WithReference(sym)
+		}
+	}
+	return b.insns, nil
+}
+
+// parse jumps to the next family label unless the packet is of the family. TCP packets of the family have
+// their addresses and ports copied in the tuple on the stack, and r7 holds the offset of the TCP header.
+// Other packets are passed.
+func (b *builder) parse(f addressFamily) {
+	b.emit(
+		asm.LoadMem(asm.R2, asm.R6, skbProtocol, asm.Word),
+		asm.JNE.Imm32(asm.R2, int32(htons(f.ethProto)), f.name+"_next"),
+	)
+	switch f.name {
+	case "v4":
+		b.emit(asm.Mov.Imm(asm.R2, f.l2Len))
+		b.loadBytes(asm.R2, stackScratch, 1)
+		b.emit(
+			asm.LoadMem(asm.R7, asm.RFP, stackScratch, asm.Byte),
+			asm.And.Imm(asm.R7, 0xf),
+			asm.LSh.Imm(asm.R7, 2),
+			asm.Add.Imm(asm.R7, f.l2Len),
+			asm.Mov.Imm(asm.R2, f.l2Len+9),
+		)
+		b.loadBytes(asm.R2, stackScratch, 1)
+		b.emit(
+			asm.LoadMem(asm.R2, asm.RFP, stackScratch, asm.Byte),
+			asm.JNE.Imm(asm.R2, ipProtoTCP, "pass"),
+			asm.Mov.Imm(asm.R2, f.l2Len+12),
+		)
+		b.loadBytes(asm.R2, stackTuple, 8)
+	case "v6":
+		// Extension headers are not supported
+		b.emit(asm.Mov.Imm(asm.R2, f.l2Len+6))
+		b.loadBytes(asm.R2, stackScratch, 1)
+		b.emit(
+			asm.LoadMem(asm.R2, asm.RFP, stackScratch, asm.Byte),
+			asm.JNE.Imm(asm.R2, ipProtoTCP, "pass"),
+			asm.Mov.Imm(asm.R2, f.l2Len+8),
+		)
+		b.loadBytes(asm.R2, stackTuple, 32)
+		b.emit(asm.Mov.Imm(asm.R7, f.l2Len+40))
+	}
+	b.loadBytes(asm.R7, f.dstPort-2, 4)
+}
+
+// inbound assigns inbound connections to the ztunnel inbound plaintext socket.
+func (b *builder) inbound(f addressFamily) {
+	b.emit(
+		asm.LoadMem(asm.R2, asm.RFP, f.dstPort, asm.Half),
+		asm.JEq.Imm32(asm.R2, int32(htons(config.ZtunnelInboundPort)), "pass"),
+	)
+	b.skipAddress(f.srcAddr, f.probeAddr, f.name+"_inbound_not_probe")
+
+	// Packets of established connections, or connections to ztunnel, are delivered as usual.
+	b.lookup(f)
+	b.emit(
+		asm.JEq.Imm(asm.R0, 0, f.name+"_inbound_listener"),
+		asm.LoadMem(asm.R7, asm.R0, sockState, asm.Word),
+		asm.Mov.Reg(asm.R1, asm.R0),
+		asm.FnSkRelease.Call(),
+		asm.JNE.Imm(asm.R7, tcpStateListen, "pass"),
+	)
+	b.label(f.name + "_inbound_listener")
+	b.emit(asm.StoreImm(asm.RFP, f.dstPort, int64(htons(config.ZtunnelInboundPlaintextPort)), asm.Half))
+	b.lookup(f)
+	b.emit(
+		asm.JEq.Imm(asm.R0, 0, "drop"),
+		asm.Mov.Reg(asm.R8, asm.R0),
+		asm.Ja.Label(f.name+"_assign"),
+	)
+}
+
+// outbound assigns the connections redirected by the egress program to the ztunnel outbound socket.
+func (b *builder) outbound(f addressFamily) {
+	b.lookup(f)
+	b.emit(
+		asm.JEq.Imm(asm.R0, 0, f.name+"_outbound_listener"),
+		asm.Mov.Reg(asm.R8, asm.R0),
+		asm.LoadMem(asm.R2, asm.R8, sockState, asm.Word),
+		asm.JNE.Imm(asm.R2, tcpStateListen, f.name+"_assign"),
+		asm.Mov.Reg(asm.R1, asm.R8),
+		asm.FnSkRelease.Call(),
+	)
+	b.label(f.name + "_outbound_listener")
+	b.emit(asm.StoreImm(asm.RFP, f.dstPort, int64(htons(config.ZtunnelOutboundPort)), asm.Half))
+	b.lookup(f)
+	b.emit(
+		asm.JEq.Imm(asm.R0, 0, "drop"),
+		asm.Mov.Reg(asm.R8, asm.R0),
+	)
+
+	b.label(f.name + "_assign")
+	b.emit(
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Reg(asm.R2, asm.R8),
+		asm.Mov.Imm(asm.R3, 0),
+		asm.FnSkAssign.Call(),
+		asm.Mov.Reg(asm.R7, asm.R0),
+		asm.Mov.Reg(asm.R1, asm.R8),
+		asm.FnSkRelease.Call(),
+		asm.JNE.Imm(asm.R7, 0, "drop"),
+		asm.Ja.Label("pass"),
+	)
+}
+
+// skipAddress jumps to pass if the address at the stack offset is addr.
+func (b *builder) skipAddress(off int16, addr netip.Addr, next string) {
+	if !addr.IsValid() {
+		return
+	}
+	a := addr.AsSlice()
+	for i := 0; i < len(a); i += 4 {
+		b.emit(
+			asm.LoadMem(asm.R2, asm.RFP, off+int16(i), asm.Word),
+			asm.JNE.Imm32(asm.R2, int32(binary.NativeEndian.Uint32(a[i:i+4])), next),
+		)
+	}
+	b.emit(asm.Ja.Label("pass"))
+	b.label(next)
+}
+
+// loadBytes copies size bytes of the packet at the offset in the register to the stack.
+func (b *builder) loadBytes(off asm.Register, stackOff int16, size int32) {
+	b.emit(asm.Mov.Reg(asm.R1, asm.R6))
+	if off != asm.R2 {
+		b.emit(asm.Mov.Reg(asm.R2, off))
+	}
+	b.emit(
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, int32(stackOff)),
+		asm.Mov.Imm(asm.R4, size),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "pass"),
+	)
+}
+
+// lookup looks up the socket of the tuple on the stack, and returns it in r0.
+func (b *builder) lookup(f addressFamily) {
+	b.emit(
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, stackTuple),
+		asm.Mov.Imm(asm.R3, f.tupleSize),
+		asm.Mov.Imm(asm.R4, bpfFCurrentNetns),
+		asm.Mov.Imm(asm.R5, 0),
+		asm.FnSkcLookupTcp.Call(),
+	)
+}
+
+// verdicts emits the pass label, and the drop label if the program drops packets, as unreachable instructions
+// are rejected by the verifier.
+func (b *builder) verdicts(drop bool) {
+	b.label("pass")
+	b.emit(
+		asm.Mov.Imm(asm.R0, tcActUnspec),
+		asm.Return(),
+	)
+	if drop {
+		b.label("drop")
+		b.emit(
+			asm.Mov.Imm(asm.R0, tcActShot),
+			asm.Return(),
+		)
+	}
+}
+
+// htons returns the value of the 16 bits integer in network byte order, as read from memory.
+func htons(v uint16) uint16 {
+	return binary.NativeEndian.Uint16(binary.BigEndian.AppendUint16(nil, v))
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"testing"
+
+	"github.com/cilium/ebpf/asm"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	b := &builder{}
+	b.emit(asm.JEq.Imm(asm.R1, 1, "end"))
+	b.emit(asm.Mov.Imm(asm.R0, 2))
+	b.label("end")
+	b.label("alias")
+	b.emit(asm.Return())
+	b.emit(asm.Ja.Label("alias"))
+	insns, err := b.instructions()
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, insns.Marshal(&buf, binary.LittleEndian))
+	assert.Equal(t, buf.Bytes(), []byte{
+		0x15, 0x01, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, // if r1 == 1 goto +1
+		0xb7, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, // r0 = 2
+		0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // exit
+		0x05, 0x00, 0xfe, 0xff, 0x00, 0x00, 0x00, 0x00, // goto -2
+	})
+}
+
+func TestBuilderErrors(t *testing.T) {
+	b := &builder{}
+	b.emit(asm.Ja.Label("missing"))
+	insns, err := b.instructions()
+	assert.NoError(t, err)
+	assert.Error(t, insns.Marshal(io.Discard, binary.LittleEndian))
+
+	b = &builder{}
+	b.label("dangling")
+	_, err = b.instructions()
+	assert.Error(t, err)
+}
+
+func TestPrograms(t *testing.T) {
+	base := ProgramConfig{
+		HostProbeSNATAddress:   netip.MustParseAddr("169.254.7.127"),
+		HostProbeV6SNATAddress: netip.MustParseAddr("fd16:9254:7127:1337:ffff:ffff:ffff:ffff"),
+	}
+	configs := map[string]ProgramConfig{
+		"default":  base,
+		"ipv6":     {HostProbeSNATAddress: base.HostProbeSNATAddress, HostProbeV6SNATAddress: base.HostProbeV6SNATAddress, EnableIPv6: true},
+		"ingress":  {HostProbeSNATAddress: base.HostProbeSNATAddress, IngressMode: true},
+		"outbound": {HostProbeSNATAddress: base.HostProbeSNATAddress, Outbound: true},
+		"l3":       {HostProbeSNATAddress: base.HostProbeSNATAddress, L3Device: true},
+	}
+	ingress := map[string][]byte{}
+	for name, c := range configs {
+		t.Run(name, func(t *testing.T) {
+			in, err := IngressProgram(c)
+			assert.NoError(t, err)
+			eg, err := EgressProgram(c)
+			assert.NoError(t, err)
+			// All the jumps resolve, and the programs end with an exit
+			var inBuf, egBuf bytes.Buffer
+			assert.NoError(t, in.Marshal(&inBuf, binary.LittleEndian))
+			assert.NoError(t, eg.Marshal(&egBuf, binary.LittleEndian))
+			assert.Equal(t, in[len(in)-1].OpCode.JumpOp(), asm.Exit)
+			assert.Equal(t, eg[len(eg)-1].OpCode.JumpOp(), asm.Exit)
+			ingress[name] = inBuf.Bytes()
+		})
+	}
+	// Programs differ with the configuration, so a drift is detected from the hash of the program
+	for name, in := range ingress {
+		if name != "default" && bytes.Equal(in, ingress["default"]) {
+			t.Errorf("ingress program of %q is the same as the default one", name)
+		}
+	}
+}
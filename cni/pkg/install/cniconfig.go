@@ -46,6 +46,7 @@ func createCNIConfigFile(ctx context.Context, cfg *config.InstallConfig) (string
 		PodNamespace:                cfg.PodNamespace,
 		NativeNftables:              cfg.NativeNftables,
 		EnableAmbientDetectionRetry: cfg.EnableAmbientDetectionRetry,
+		ExcludeSecondaryInterfaces:  cfg.ExcludeSecondaryInterfaces,
 	}
 
 	pluginConfig.Name = "istio-cni"
@@ -114,7 +115,7 @@ func writeCNIConfig(ctx context.Context, pluginConfig []byte, cfg *config.Instal
 		return cniConfigFilepath, err
 	}
 
-	if cfg.ChainedCNIPlugin && strings.HasSuffix(cniConfigFilepath, ".conf") {
+	if cfg.ChainedCNIPlugin && strings.HasSuffix(cniConfigFilepath, ".conf") && isConfigList(pluginConfig) {
 		// If the old CNI config filename ends with .conf, rename it to .conflist, because it has to be changed to a list
 		installLog.Infof("Renaming %s extension to .conflist", cniConfigFilepath)
 		err = os.Rename(cniConfigFilepath, cniConfigFilepath+"list")
@@ -129,6 +130,17 @@ func writeCNIConfig(ctx context.Context, pluginConfig []byte, cfg *config.Instal
 	return cniConfigFilepath, nil
 }
 
+// isConfigList reports whether the CNI config is a network config list. The config of the Multus thin plugin stays
+// a single network config when Istio CNI is chained in its delegate.
+func isConfigList(cniConfig []byte) bool {
+	var cniConfigMap map[string]any
+	if err := json.Unmarshal(cniConfig, &cniConfigMap); err != nil {
+		return false
+	}
+	_, ok := cniConfigMap["plugins"]
+	return ok
+}
+
 // If configured as chained CNI plugin, waits indefinitely for a main CNI config file to exist before returning
 // Or until cancelled by parent context
 func getCNIConfigFilepath(ctx context.Context, cniConfName, mountedCNINetDir string, chained bool) (string, error) {
@@ -256,7 +268,11 @@ func insertCNIConfig(newCNIConfig, existingCNIConfig []byte) ([]byte, error) {
 
 	var newMap map[string]any
 
-	if _, ok := existingMap["type"]; ok {
+	list, err := util.GetChainedConfigList(existingMap)
+	if err != nil {
+		return nil, fmt.Errorf("existing CNI config: %v", err)
+	}
+	if _, ok := list["type"]; ok {
 		// Assume it is a regular network conf file
 		delete(existingMap, "cniVersion")
 
@@ -270,9 +286,9 @@ func insertCNIConfig(newCNIConfig, existingCNIConfig []byte) ([]byte, error) {
 			"plugins":    plugins,
 		}
 	} else {
-		// Assume it is a network list file
+		// Assume it is a network list file, or a Multus config delegating to one
 		newMap = existingMap
-		plugins, err := util.GetPlugins(newMap)
+		plugins, err := util.GetPlugins(list)
 		if err != nil {
 			return nil, fmt.Errorf("existing CNI config: %v", err)
 		}
@@ -288,7 +304,7 @@ func insertCNIConfig(newCNIConfig, existingCNIConfig []byte) ([]byte, error) {
 			}
 		}
 
-		newMap["plugins"] = append(plugins, istioMap)
+		list["plugins"] = append(plugins, istioMap)
 	}
 
 	return util.MarshalCNIConfig(newMap)
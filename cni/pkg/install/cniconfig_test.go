@@ -332,6 +332,21 @@ func TestInsertCNIConfig(t *testing.T) {
 			existingConfFilename: "list-with-istio.conflist",
 			newConfFilename:      "istio-cni.conf",
 		},
+		{
+			name:                 "multus delegating to a network file",
+			existingConfFilename: "multus.conf",
+			newConfFilename:      "istio-cni.conf",
+		},
+		{
+			name:                 "multus delegating to a list network file with existing istio",
+			existingConfFilename: "multus-list.conf",
+			newConfFilename:      "istio-cni.conf",
+		},
+		{
+			name:                 "multus with a cluster network name",
+			existingConfFilename: "multus-cluster-network.conf",
+			newConfFilename:      "istio-cni.conf",
+		},
 	}
 
 	for _, c := range cases {
@@ -141,7 +141,11 @@ func (in *Installer) Cleanup() error {
 				return fmt.Errorf("failed to read CNI config map from file %s: %w", in.cniConfigFilepath, err)
 			}
 			// Find Istio CNI and remove from plugin list
-			plugins, err := util.GetPlugins(cniConfigMap)
+			list, err := util.GetChainedConfigList(cniConfigMap)
+			if err != nil {
+				return fmt.Errorf("%s: %w", in.cniConfigFilepath, err)
+			}
+			plugins, err := util.GetPlugins(list)
 			if err != nil {
 				return fmt.Errorf("%s: %w", in.cniConfigFilepath, err)
 			}
@@ -151,7 +155,7 @@ func (in *Installer) Cleanup() error {
 					return fmt.Errorf("%s: %w", in.cniConfigFilepath, err)
 				}
 				if plugin["type"] == "istio-cni" {
-					cniConfigMap["plugins"] = append(plugins[:i], plugins[i+1:]...)
+					list["plugins"] = append(plugins[:i], plugins[i+1:]...)
 					break
 				}
 			}
@@ -347,7 +351,11 @@ func checkValidCNIConfig(ctx context.Context, cfg *config.InstallConfig, cniConf
 		if err != nil {
 			return err
 		}
-		plugins, err := util.GetPlugins(cniConfigMap)
+		list, err := util.GetChainedConfigList(cniConfigMap)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cniConfigFilepath, err)
+		}
+		plugins, err := util.GetPlugins(list)
 		if err != nil {
 			return fmt.Errorf("%s: %w", cniConfigFilepath, err)
 		}
@@ -382,7 +390,11 @@ func checkValidCNIConfig(ctx context.Context, cfg *config.InstallConfig, cniConf
 			if err != nil {
 				return err
 			}
-			primaryPlugins, err := util.GetPlugins(primaryCniConfigMap)
+			primaryList, err := util.GetChainedConfigList(primaryCniConfigMap)
+			if err != nil {
+				return fmt.Errorf("%s: %w", primaryCNIConfigFilepath, err)
+			}
+			primaryPlugins, err := util.GetPlugins(primaryList)
 			if err != nil {
 				return fmt.Errorf("%s: %w", primaryCNIConfigFilepath, err)
 			}
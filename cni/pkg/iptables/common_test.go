@@ -56,6 +56,15 @@ func GetCommonInPodTestCases() []struct {
 				VirtualInterfaces: []string{"fake1s0f0", "fake1s0f1"},
 			},
 		},
+		{
+			name: "exclude_interfaces",
+			config: func(cfg *config.AmbientConfig) {
+				cfg.RedirectDNS = true
+			},
+			podOverrides: config.PodLevelOverrides{
+				ExcludeInterfaces: []string{"net1", "net2"},
+			},
+		},
 		{
 			name: "dns_pod_enabled_and_off_globally",
 			config: func(cfg *config.AmbientConfig) {
@@ -186,6 +186,18 @@ func (cfg *IptablesConfigurator) CreateInpodRules(log *istiolog.Scope, podOverri
 	return nil
 }
 
+// VerifyInpodRules reports whether the iptables rules in the current network namespace differ from the rules
+// CreateInpodRules would create.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *IptablesConfigurator) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	var ipt6V *dep.IptablesVersion
+	if cfg.cfg.EnableIPv6 {
+		ipt6V = &cfg.ipt6V
+	}
+	_, deltaExists := iptablescapture.VerifyIptablesState(log, cfg.ext, cfg.AppendInpodRules(podOverrides), &cfg.iptV, ipt6V)
+	return deltaExists, nil
+}
+
 func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOverrides) *builder.IptablesRuleBuilder {
 	var redirectDNS bool
 
@@ -245,7 +257,17 @@ func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 	// From here on, we should be only inserting rules into our custom chains.
 
 	// To keep things manageable, the first rules in the ISTIO_PRERT chain should be short-circuits, like
-	// virtual interface exclusions/redirects:
+	// excluded interfaces and virtual interface exclusions/redirects:
+	for _, excludeInterface := range podOverrides.ExcludeInterfaces {
+		// CLI: -t nat -A ISTIO_PRERT -i net1 -j RETURN
+		//
+		// DESC: Traffic received on the excluded interfaces of secondary networks is not redirected to ztunnel.
+		iptablesBuilder.AppendRule(ChainInpodPrerouting, "nat",
+			"-i", excludeInterface,
+			"-j", "RETURN",
+		)
+	}
+
 	if len(podOverrides.VirtualInterfaces) != 0 {
 		for _, virtInterface := range podOverrides.VirtualInterfaces {
 			// CLI: -t nat -A ISTIO_PRERT -i virt0 -p tcp -j REDIRECT --to-ports 15001
@@ -303,6 +325,16 @@ func (cfg *IptablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		)
 	}
 
+	for _, excludeInterface := range podOverrides.ExcludeInterfaces {
+		// CLI: -t nat -A ISTIO_OUTPUT -o net1 -j RETURN
+		//
+		// DESC: Traffic sent through the excluded interfaces of secondary networks is not redirected to ztunnel.
+		iptablesBuilder.AppendRule(ChainInpodOutput, "nat",
+			"-o", excludeInterface,
+			"-j", "RETURN",
+		)
+	}
+
 	// CLI: -t NAT -A ISTIO_OUTPUT -d 169.254.7.127 -p tcp -m tcp -j ACCEPT
 	// CLI: -t NAT -A ISTIO_OUTPUT -d fd16:9254:7127:1337:ffff:ffff:ffff:ffff -p tcp -m tcp -j ACCEPT
 	//
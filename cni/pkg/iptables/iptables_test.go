@@ -15,6 +15,8 @@
 package iptables
 
 import (
+	"bytes"
+	"io"
 	"net/netip"
 	"path/filepath"
 	"strings"
@@ -23,7 +25,9 @@ import (
 	"istio.io/istio/cni/pkg/config"
 	"istio.io/istio/cni/pkg/scopes"
 	testutil "istio.io/istio/pilot/test/util"
+	istiolog "istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/test/util/assert"
+	iptablesconstants "istio.io/istio/tools/istio-iptables/pkg/constants"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
 )
 
@@ -116,6 +120,53 @@ func TestInvokedTwiceIsIdempotent(t *testing.T) {
 	}
 }
 
+// savedStateDeps returns the saved state from iptables-save, and runs everything else with the stub.
+type savedStateDeps struct {
+	*dep.DependenciesStub
+	saved string
+}
+
+func (s *savedStateDeps) Run(logger *istiolog.Scope, quietLogging bool, cmd iptablesconstants.IptablesCmd,
+	iptVer *dep.IptablesVersion, stdin io.ReadSeeker, args ...string,
+) (*bytes.Buffer, error) {
+	if cmd == iptablesconstants.IPTablesSave {
+		return bytes.NewBufferString(s.saved), nil
+	}
+	return s.DependenciesStub.Run(logger, quietLogging, cmd, iptVer, stdin, args...)
+}
+
+func TestVerifyInpodRules(t *testing.T) {
+	for _, tt := range GetCommonInPodTestCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			tt.config(cfg)
+			ext := &savedStateDeps{DependenciesStub: &dep.DependenciesStub{}}
+			iptConfigurator, _, _ := NewIptablesConfigurator(cfg, cfg, ext, ext, EmptyNlDeps())
+
+			drift, err := iptConfigurator.VerifyInpodRules(scopes.CNIAgent, tt.podOverrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drift, true)
+
+			ext.saved = iptConfigurator.AppendInpodRules(tt.podOverrides).BuildV4Restore()
+			drift, err = iptConfigurator.VerifyInpodRules(scopes.CNIAgent, tt.podOverrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drift, false)
+
+			// Another tool flushing one of our chains is detected.
+			var saved []string
+			for _, line := range strings.Split(ext.saved, "\n") {
+				if !strings.HasPrefix(line, "-A "+ChainInpodOutput+" ") {
+					saved = append(saved, line)
+				}
+			}
+			ext.saved = strings.Join(saved, "\n")
+			drift, err = iptConfigurator.VerifyInpodRules(scopes.CNIAgent, tt.podOverrides)
+			assert.NoError(t, err)
+			assert.Equal(t, drift, true)
+		})
+	}
+}
+
 func ipstr(ipv6 bool) string {
 	if ipv6 {
 		return "ipv6"
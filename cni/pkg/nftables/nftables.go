@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/cni/pkg/scopes"
 	"istio.io/istio/cni/pkg/util"
 	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
 	"istio.io/istio/tools/istio-nftables/pkg/builder"
 )
@@ -132,6 +133,108 @@ func (cfg *NftablesConfigurator) CreateInpodRules(log *istiolog.Scope, podOverri
 }
 
 func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOverrides) (*knftables.Transaction, error) {
+	return cfg.executeCommands(cfg.buildInpodRules(podOverrides))
+}
+
+// VerifyInpodRules reports whether the nftables rules in the current network namespace differ from the rules
+// CreateInpodRules would create. As nft does not list rules the way they were added, the rules are tagged with a
+// comment holding a fingerprint of their content, and the fingerprints of the rules of each chain are compared in order.
+// Rules created by an agent predating the fingerprints carry no comments and cannot be compared. They are not reported
+// as drifted, but re-created with fingerprints, so later checks can compare them.
+// NOTE that this expects to be run from within the pod network namespace!
+func (cfg *NftablesConfigurator) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	expected, err := cfg.expectedInpodRules(podOverrides)
+	if err != nil {
+		return false, err
+	}
+	var listed []listedChain
+	for table, expectedChains := range expected {
+		nft, err := cfg.nftProvider(knftables.InetFamily, table)
+		if err != nil {
+			return false, err
+		}
+		chains, err := nft.List(context.TODO(), "chains")
+		if knftables.IsNotFound(err) {
+			log.Debugf("nftables table %s not found", table)
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to list chains of table %s: %w", table, err)
+		}
+		for chain, want := range expectedChains {
+			if !slices.Contains(chains, chain) {
+				log.Debugf("nftables chain %s not found in table %s", chain, table)
+				return true, nil
+			}
+			rules, err := nft.ListRules(context.TODO(), chain)
+			if err != nil {
+				return false, fmt.Errorf("failed to list rules of chain %s in table %s: %w", chain, table, err)
+			}
+			listed = append(listed, listedChain{table: table, chain: chain, got: builder.RuleComments(rules), want: want})
+		}
+	}
+	if legacyInpodRules(listed) {
+		log.Infof("nftables in-pod rules have no fingerprints, re-creating them with fingerprints")
+		if _, err := cfg.AppendInpodRules(podOverrides); err != nil {
+			return false, fmt.Errorf("failed to add fingerprints to in-pod rules: %w", err)
+		}
+		return false, nil
+	}
+	for _, c := range listed {
+		if !slices.Equal(c.got, c.want) {
+			log.Debugf("nftables chain %s in table %s has rules %v, expected %v", c.chain, c.table, c.got, c.want)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// listedChain holds the comments of the rules of a chain, and the comments expected for it.
+type listedChain struct {
+	table, chain string
+	got, want    []string
+}
+
+// legacyInpodRules returns true if the chains have rules, none of which carries a comment, as created by agents
+// predating rule fingerprints.
+func legacyInpodRules(chains []listedChain) bool {
+	found := false
+	for _, c := range chains {
+		for _, comment := range c.got {
+			if comment != "" {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// expectedInpodRules returns the comments of the rules CreateInpodRules would create, by table and chain,
+// in the order they end up in once the inserts are applied.
+func (cfg *NftablesConfigurator) expectedInpodRules(podOverrides config.PodLevelOverrides) (map[string]map[string][]string, error) {
+	rb := cfg.buildInpodRules(podOverrides)
+	fake := knftables.NewFake("", "")
+	tx := fake.NewTransaction()
+	tx = cfg.addIstioNatTableRules(tx, rb)
+	tx = cfg.addIstioMangleTableRules(tx, rb)
+	tx = cfg.addIstioRawTableRules(tx, rb)
+	if err := fake.Run(context.TODO(), tx); err != nil {
+		return nil, fmt.Errorf("failed to build the expected rules: %w", err)
+	}
+
+	expected := make(map[string]map[string][]string)
+	for name, table := range fake.Tables[knftables.InetFamily] {
+		chains := make(map[string][]string, len(table.Chains))
+		for chain, c := range table.Chains {
+			chains[chain] = builder.RuleComments(c.Rules)
+		}
+		expected[name] = chains
+	}
+	return expected, nil
+}
+
+func (cfg *NftablesConfigurator) buildInpodRules(podOverrides config.PodLevelOverrides) *builder.NftablesRuleBuilder {
 	rb := builder.NewNftablesRuleBuilder(config.GetConfig(cfg.cfg))
 
 	var redirectDNS bool
@@ -177,7 +280,16 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 	)
 
 	// To keep things manageable, the first rules in the istio-prerouting chain should be short-circuits, like
-	// virtual interface exclusions/redirects:
+	// excluded interfaces and virtual interface exclusions/redirects:
+	for _, excludeInterface := range podOverrides.ExcludeInterfaces {
+		// CLI: nft add rule inet istio-ambient-nat istio-prerouting iifname <iface> counter return
+		// DESC: Traffic received on the excluded interfaces of secondary networks is not redirected to ztunnel.
+		rb.AppendRule(IstioPreroutingChain, AmbientNatTable,
+			"iifname", excludeInterface, Counter,
+			"return",
+		)
+	}
+
 	if len(podOverrides.VirtualInterfaces) != 0 {
 		for _, virtInterface := range podOverrides.VirtualInterfaces {
 			// CLI: nft add rule inet istio-ambient-nat istio-prerouting iifname <iface> meta l4proto tcp counter redirect to :15001
@@ -233,6 +345,15 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		)
 	}
 
+	for _, excludeInterface := range podOverrides.ExcludeInterfaces {
+		// CLI: nft add rule inet istio-ambient-nat istio-output oifname <iface> counter return
+		// DESC: Traffic sent through the excluded interfaces of secondary networks is not redirected to ztunnel.
+		rb.AppendRule(IstioOutputChain, AmbientNatTable,
+			"oifname", excludeInterface, Counter,
+			"return",
+		)
+	}
+
 	// CLI: nft add rule inet istio-ambient-nat istio-output meta l4proto tcp ip daddr 169.254.7.127 counter accept
 	// DESC: Anything coming BACK from the pod healthcheck port with a dest of our SNAT-ed hostside IP we also short-circuit.
 	rb.AppendRule(IstioOutputChain, AmbientNatTable,
@@ -389,7 +510,8 @@ func (cfg *NftablesConfigurator) AppendInpodRules(podOverrides config.PodLevelOv
 		"redirect to", ":"+fmt.Sprintf("%d", config.ZtunnelOutboundPort),
 	)
 
-	return cfg.executeCommands(rb)
+	// Tag the rules with a fingerprint of their content, compared by VerifyInpodRules to detect drift.
+	return rb.AddFingerprints()
 }
 
 // DeleteInpodRules removes nftables rules from a pod's network namespace
@@ -28,6 +28,7 @@ import (
 	"istio.io/istio/cni/pkg/iptables"
 	"istio.io/istio/cni/pkg/scopes"
 	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/slices"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
 	"istio.io/istio/tools/istio-nftables/pkg/builder"
 )
@@ -189,6 +190,108 @@ func TestInvokedTwiceIsIdempotent(t *testing.T) {
 	}
 }
 
+func TestVerifyInpodRules(t *testing.T) {
+	for _, tt := range GetCommonInPodTestCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			tt.config(cfg)
+			ext := &dep.DependenciesStub{}
+
+			mock := builder.NewMockNftables("", "")
+			originalProvider := nftProviderVar
+			nftProviderVar = func(family knftables.Family, table string) (builder.NftablesAPI, error) {
+				if table == "" {
+					return mock, nil
+				}
+				// Listing requires a default table, so return a view of the tables of the shared mock.
+				view := builder.NewMockNftables(family, table)
+				mock.RLock()
+				defer mock.RUnlock()
+				view.Tables = mock.Tables
+				view.Table = mock.Tables[family][table]
+				return view, nil
+			}
+			defer func() {
+				nftProviderVar = originalProvider
+			}()
+
+			_, nftConfigurator, _ := NewNftablesConfigurator(cfg, cfg, ext, ext, iptables.EmptyNlDeps())
+			assertDrift := func(want bool) {
+				t.Helper()
+				drift, err := nftConfigurator.VerifyInpodRules(scopes.CNIAgent, tt.podOverrides)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if drift != want {
+					t.Fatalf("expected drift %v, got %v", want, drift)
+				}
+			}
+
+			assertDrift(true)
+			if err := nftConfigurator.CreateInpodRules(scopes.CNIAgent, tt.podOverrides); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(false)
+
+			// Another tool flushing our rules is detected.
+			tx := mock.NewTransaction()
+			tx.Flush(&knftables.Chain{Name: IstioOutputChain, Table: AmbientNatTable, Family: knftables.InetFamily})
+			if err := mock.Run(context.Background(), tx); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(true)
+
+			if err := nftConfigurator.CreateInpodRules(scopes.CNIAgent, tt.podOverrides); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(false)
+
+			// Another tool replacing one of our rules, keeping the number of rules, is detected.
+			mock.RLock()
+			rules := mock.Tables[knftables.InetFamily][AmbientNatTable].Chains[IstioOutputChain].Rules
+			mock.RUnlock()
+			if len(rules) == 0 {
+				t.Fatalf("no rule found in chain %s", IstioOutputChain)
+			}
+			tx = mock.NewTransaction()
+			tx.Replace(&knftables.Rule{
+				Chain:  IstioOutputChain,
+				Table:  AmbientNatTable,
+				Family: knftables.InetFamily,
+				Handle: rules[0].Handle,
+				Rule:   "counter accept",
+			})
+			if err := mock.Run(context.Background(), tx); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(true)
+
+			// Rules created by an agent predating fingerprints have no comments. They are not reported as drifted,
+			// but re-created with fingerprints.
+			if err := nftConfigurator.CreateInpodRules(scopes.CNIAgent, tt.podOverrides); err != nil {
+				t.Fatal(err)
+			}
+			mock.Lock()
+			for _, table := range mock.Tables[knftables.InetFamily] {
+				for _, chain := range table.Chains {
+					for _, rule := range chain.Rules {
+						rule.Comment = nil
+					}
+				}
+			}
+			mock.Unlock()
+			assertDrift(false)
+			mock.RLock()
+			comments := builder.RuleComments(mock.Tables[knftables.InetFamily][AmbientNatTable].Chains[IstioOutputChain].Rules)
+			mock.RUnlock()
+			if len(comments) == 0 || slices.Contains(comments, "") {
+				t.Fatalf("expected legacy rules to be re-created with fingerprints, got comments %v", comments)
+			}
+			assertDrift(false)
+		})
+	}
+}
+
 // TestCreateInpodRulesConcurrent guards against the race that previously
 // existed when AppendInpodRules mutated a shared *NftablesRuleBuilder field
 // on NftablesConfigurator. Two pod-add goroutines hitting the same node
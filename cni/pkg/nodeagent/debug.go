@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DebugInfo is the node agent view of the pods it manages, served on the debug endpoint.
+type DebugInfo struct {
+	Node string `json:"node"`
+	// ConnectedZtunnels are the UUIDs of the ztunnel connections, the latest last.
+	ConnectedZtunnels []string `json:"connectedZtunnels"`
+	// HostProbeSetError is set if the host probe set could not be listed.
+	HostProbeSetError string         `json:"hostProbeSetError,omitempty"`
+	Pods              []PodDebugInfo `json:"pods"`
+}
+
+// PodDebugInfo is the node agent view of a single pod.
+type PodDebugInfo struct {
+	UID            string `json:"uid"`
+	Name           string `json:"name,omitempty"`
+	Namespace      string `json:"namespace,omitempty"`
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// Netns is the inode of the pod network namespace held by the node agent, or 0 if unknown.
+	Netns  uint64   `json:"netns,omitempty"`
+	PodIPs []string `json:"podIPs,omitempty"`
+	// InHostProbeSet is true if all the pod IPs are in the host probe set, allowing kubelet probes.
+	InHostProbeSet bool `json:"inHostProbeSet"`
+	// InZtunnelSnapshot is true if the pod is part of the state sent to connecting ztunnels.
+	InZtunnelSnapshot bool `json:"inZtunnelSnapshot"`
+	// LastError is the last failure adding the pod to or removing it from the mesh, or repairing its rules, cleared on success.
+	LastError *PodOperationError `json:"lastError,omitempty"`
+}
+
+// PodOperationError records a failed AddPodToMesh, RemovePodFromMesh or RepairPodRules.
+type PodOperationError struct {
+	Operation string    `json:"operation"`
+	Error     string    `json:"error"`
+	Time      time.Time `json:"time"`
+}
+
+const (
+	addPodOperation    = "AddPodToMesh"
+	removePodOperation = "RemovePodFromMesh"
+	repairPodOperation = "RepairPodRules"
+)
+
+// DebugInfoProvider provides the state served on the debug endpoint.
+type DebugInfoProvider interface {
+	DebugInfo() DebugInfo
+}
+
+// DebugHandler serves the DebugInfo of the provider set once the ambient node agent is started.
+type DebugHandler struct {
+	mu       sync.RWMutex
+	provider DebugInfoProvider
+}
+
+// SetProvider sets the provider of the served state.
+func (d *DebugHandler) SetProvider(p DebugInfoProvider) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.provider = p
+}
+
+func (d *DebugHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	d.mu.RLock()
+	p := d.provider
+	d.mu.RUnlock()
+	if p == nil {
+		http.Error(w, "ambient node agent is not running", http.StatusServiceUnavailable)
+		return
+	}
+	b, err := json.MarshalIndent(p.DebugInfo(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// DebugInfo returns the state of the mesh dataplane, if supported.
+func (s *Server) DebugInfo() DebugInfo {
+	if p, ok := s.dataplane.(DebugInfoProvider); ok {
+		return p.DebugInfo()
+	}
+	return DebugInfo{Node: NodeName}
+}
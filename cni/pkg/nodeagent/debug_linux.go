@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"cmp"
+	"net/netip"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
+)
+
+type podDebugState struct {
+	name      string
+	namespace string
+	podIPs    []netip.Addr
+	lastError *PodOperationError
+}
+
+var _ DebugInfoProvider = &meshDataplane{}
+
+// recordPodOperation records the IPs of the pod, and the result of the operation if any.
+// A successful removal forgets the pod.
+func (s *meshDataplane) recordPodOperation(pod *corev1.Pod, podIPs []netip.Addr, operation string, err error) {
+	uid := string(pod.UID)
+	s.podDebugMu.Lock()
+	defer s.podDebugMu.Unlock()
+	if operation == removePodOperation && err == nil {
+		delete(s.podDebug, uid)
+		return
+	}
+	if s.podDebug == nil {
+		s.podDebug = map[string]*podDebugState{}
+	}
+	st, f := s.podDebug[uid]
+	if !f {
+		st = &podDebugState{}
+		s.podDebug[uid] = st
+	}
+	st.name, st.namespace = pod.Name, pod.Namespace
+	if len(podIPs) > 0 {
+		st.podIPs = podIPs
+	}
+	switch {
+	case err != nil:
+		st.lastError = &PodOperationError{Operation: operation, Error: err.Error(), Time: time.Now()}
+	case operation != "":
+		st.lastError = nil
+	}
+}
+
+// DebugInfo returns the state of the pods handled by this node agent.
+func (s *meshDataplane) DebugInfo() DebugInfo {
+	info := DebugInfo{Node: NodeName, ConnectedZtunnels: []string{}}
+	if s.ztunnelConns != nil {
+		for _, c := range s.ztunnelConns.snapshotConns() {
+			info.ConnectedZtunnels = append(info.ConnectedZtunnels, c.UUID().String())
+		}
+	}
+
+	probeSet := sets.New[netip.Addr]()
+	if s.hostAddrSet != nil {
+		if err := util.RunAsHost(func() error {
+			ips, err := s.hostAddrSet.ListEntriesByIP()
+			probeSet.InsertAll(ips...)
+			return err
+		}); err != nil {
+			info.HostProbeSetError = err.Error()
+		}
+	}
+
+	pods := map[string]*PodDebugInfo{}
+	getOrCreate := func(uid string) *PodDebugInfo {
+		p, f := pods[uid]
+		if !f {
+			p = &PodDebugInfo{UID: uid}
+			pods[uid] = p
+		}
+		return p
+	}
+	if s.podCache != nil {
+		for uid, wl := range s.podCache.ReadCurrentPodSnapshot() {
+			p := getOrCreate(uid)
+			p.InZtunnelSnapshot = true
+			if wl.Workload != nil {
+				p.Name, p.Namespace, p.ServiceAccount = wl.Workload.Name, wl.Workload.Namespace, wl.Workload.ServiceAccount
+			}
+			if wl.Netns != nil {
+				p.Netns = wl.Netns.Inode()
+			}
+		}
+	}
+	s.podDebugMu.Lock()
+	for uid, st := range s.podDebug {
+		p := getOrCreate(uid)
+		p.Name, p.Namespace = st.name, st.namespace
+		p.PodIPs = slices.Map(st.podIPs, netip.Addr.String)
+		p.InHostProbeSet = len(st.podIPs) > 0 && probeSet.ContainsAll(sets.New(st.podIPs...))
+		if st.lastError != nil {
+			e := *st.lastError
+			p.LastError = &e
+		}
+	}
+	s.podDebugMu.Unlock()
+
+	info.Pods = make([]PodDebugInfo, 0, len(pods))
+	for _, p := range pods {
+		info.Pods = append(info.Pods, *p)
+	}
+	slices.SortFunc(info.Pods, func(a, b PodDebugInfo) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name), cmp.Compare(a.UID, b.UID))
+	})
+	return info
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	set "istio.io/istio/cni/pkg/addressset"
+	"istio.io/istio/cni/pkg/ipset"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestMeshDataplaneDebugInfo(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			UID:       types.UID("test"),
+		},
+		Status: corev1.PodStatus{PodIP: "99.9.9.1", PodIPs: []corev1.PodIP{{IP: "99.9.9.1"}}},
+	}
+	fakeCtx := context.Background()
+	fakeClientSet := fake.NewClientset(pod)
+
+	podIP := netip.MustParseAddr("99.9.9.1")
+	podIPs := []netip.Addr{podIP}
+
+	server := &fakeServer{}
+	server.On("AddPodToMesh", fakeCtx, pod, podIPs, "").Return(errors.New("some retryable failure")).Once()
+	server.On("AddPodToMesh", fakeCtx, pod, podIPs, "").Return(nil).Once()
+	server.On("RemovePodFromMesh", fakeCtx, pod, false).Return(nil).Once()
+
+	fakeIPSetDeps := ipset.FakeNLDeps()
+	ipsetInstance := ipset.IPSet{V4Name: "foo-v4", Prefix: "foo", Deps: fakeIPSetDeps}
+	setWrapper := set.NewIPSetWrapper(ipsetInstance)
+	m := getFakeDPWithAddressSet(server, fakeClientSet, setWrapper)
+
+	fakeIPSetDeps.On("listEntriesByIP", "foo-v4").Return([]netip.Addr{}, nil).Once()
+	assert.Error(t, m.AddPodToMesh(fakeCtx, pod, podIPs, ""))
+
+	info := m.DebugInfo()
+	assert.Equal(t, len(info.Pods), 1)
+	assert.Equal(t, info.Pods[0].Name, "test")
+	assert.Equal(t, info.Pods[0].PodIPs, []string{"99.9.9.1"})
+	assert.Equal(t, info.Pods[0].InHostProbeSet, false)
+	if info.Pods[0].LastError == nil || info.Pods[0].LastError.Operation != addPodOperation {
+		t.Fatalf("expected last error from %s, got %+v", addPodOperation, info.Pods[0].LastError)
+	}
+
+	expectPodAddedToIPSet(fakeIPSetDeps, podIP, pod.ObjectMeta)
+	fakeIPSetDeps.On("listEntriesByIP", "foo-v4").Return(podIPs, nil).Once()
+	assert.NoError(t, m.AddPodToMesh(fakeCtx, pod, podIPs, ""))
+
+	info = m.DebugInfo()
+	assert.Equal(t, len(info.Pods), 1)
+	assert.Equal(t, info.Pods[0].InHostProbeSet, true)
+	assert.Equal(t, info.Pods[0].LastError, nil)
+
+	expectPodRemovedFromIPSet(fakeIPSetDeps, string(pod.UID), pod.Status.PodIPs)
+	fakeIPSetDeps.On("listEntriesByIP", "foo-v4").Return([]netip.Addr{}, nil).Once()
+	assert.NoError(t, m.RemovePodFromMesh(fakeCtx, pod, false))
+
+	info = m.DebugInfo()
+	assert.Equal(t, len(info.Pods), 0)
+	fakeIPSetDeps.AssertExpectations(t)
+}
@@ -15,7 +15,9 @@
 package nodeagent
 
 import (
+	"net"
 	"net/http"
+	"strconv"
 	"sync/atomic"
 
 	"istio.io/istio/cni/pkg/constants"
@@ -45,6 +47,26 @@ func initRouter(router *http.ServeMux) (installReady *atomic.Value, watchReady *
 	return installReady, watchReady
 }
 
+// StartDebugServer starts a web server exposing the debug endpoint listing the pods managed by the ambient node agent.
+// As the node agent runs on the host network, the server only listens on localhost: it is reached through a port-forward.
+// A port of 0 disables the server, in which case the returned handler is not served.
+func StartDebugServer(port int) *DebugHandler {
+	debug := &DebugHandler{}
+	if port == 0 {
+		return debug
+	}
+	router := http.NewServeMux()
+	router.Handle(constants.DebugPodsEndpoint, debug)
+
+	go func() {
+		if err := http.ListenAndServe(net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), router); err != nil {
+			log.Errorf("debug server failed: %v", err)
+		}
+	}()
+
+	return debug
+}
+
 func healthz(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
@@ -35,6 +35,8 @@ func TestServer(t *testing.T) {
 
 	makeReq(t, server.URL, constants.LivenessEndpoint, http.StatusOK)
 	makeReq(t, server.URL, constants.ReadinessEndpoint, http.StatusServiceUnavailable)
+	// The debug endpoint is not served on the health server, reachable from the host network.
+	makeReq(t, server.URL, constants.DebugPodsEndpoint, http.StatusNotFound)
 
 	installReady.Store(true)
 	watchReady.Store(true)
@@ -63,3 +65,15 @@ func makeReq(t *testing.T, url, endpoint string, expectedStatusCode int) {
 		t.Fatalf("expected status code from %s: %d, got: %d", endpoint, expectedStatusCode, res.StatusCode)
 	}
 }
+
+func TestDebugHandler(t *testing.T) {
+	debug := &DebugHandler{}
+	router := http.NewServeMux()
+	router.Handle(constants.DebugPodsEndpoint, debug)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	makeReq(t, server.URL, constants.DebugPodsEndpoint, http.StatusServiceUnavailable)
+	debug.SetProvider(&Server{})
+	makeReq(t, server.URL, constants.DebugPodsEndpoint, http.StatusOK)
+}
@@ -61,6 +61,11 @@ func (f *fakeServer) SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr) er
 	return args.Error(0)
 }
 
+func (f *fakeServer) RepairPodRules(pod *corev1.Pod) (bool, error) {
+	args := f.Called(pod)
+	return args.Bool(0), args.Error(1)
+}
+
 func (f *fakeServer) Start(ctx context.Context) {
 }
 
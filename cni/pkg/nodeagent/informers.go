@@ -153,6 +153,7 @@ func (s *InformerHandlers) Start() {
 	// here, because we cannot successfully process the event queue until a ztunnel connects.
 	//
 	// We will always retry failed events, for as long as the agent is running.
+	go s.runPodRuleDriftChecks(PodRuleDriftCheckInterval)
 }
 
 // Gets a point-in-time snapshot of all pods that are CURRENTLY ambient enabled
@@ -212,6 +213,9 @@ func (s *InformerHandlers) isNamespaceExcluded(namespace string) bool {
 }
 
 func (s *InformerHandlers) reconcile(input any) error {
+	if check, ok := input.(podRuleDriftCheck); ok {
+		return s.reconcilePodRuleDrift(check)
+	}
 	event := input.(controllers.Event)
 
 	defer EventTotals.With(eventTypeTag.Value(event.Event.String())).Increment()
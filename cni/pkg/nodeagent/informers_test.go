@@ -1609,3 +1609,62 @@ func populateClientAndWaitForInformerWithExcludes(ctx context.Context, t *testin
 
 	return handlers, mt
 }
+
+func TestInformerPodRuleDriftCheckOnlyRepairsPodsInMesh(t *testing.T) {
+	setupLogging()
+	NodeName = "testnode"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	enrolled := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "enrolled",
+			Namespace:   "test",
+			UID:         "1234",
+			Annotations: map[string]string{annotation.AmbientRedirection.Name: constants.AmbientRedirectionEnabled},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: NodeName,
+		},
+		Status: corev1.PodStatus{
+			PodIP: "11.1.1.12",
+		},
+	}
+	notEnrolled := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "not-enrolled",
+			Namespace: "test",
+			UID:       "5678",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: NodeName,
+		},
+		Status: corev1.PodStatus{
+			PodIP: "11.1.1.13",
+		},
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient},
+		},
+	}
+
+	client := kube.NewFakeClient(ns, enrolled, notEnrolled)
+	fs := &fakeServer{}
+	fs.Start(ctx)
+	fs.On("RepairPodRules", mock.IsType(enrolled)).Once().Return(false, nil)
+
+	server := getFakeDP(fs, client.Kube())
+
+	handlers := setupHandlers(ctx, client, server, "istio-system", defaultAmbientSelector, nil)
+	client.RunAndWait(ctx.Done())
+
+	assert.NoError(t, handlers.reconcile(podRuleDriftCheck{Name: enrolled.Name, Namespace: enrolled.Namespace}))
+	// Pods that are not, or no longer, in the mesh are skipped.
+	assert.NoError(t, handlers.reconcile(podRuleDriftCheck{Name: notEnrolled.Name, Namespace: notEnrolled.Namespace}))
+	assert.NoError(t, handlers.reconcile(podRuleDriftCheck{Name: "deleted", Namespace: "test"}))
+
+	fs.AssertExpectations(t)
+}
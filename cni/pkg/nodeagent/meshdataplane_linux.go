@@ -26,6 +26,7 @@ import (
 	set "istio.io/istio/cni/pkg/addressset"
 	"istio.io/istio/cni/pkg/trafficmanager"
 	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/pkg/kube/kclient"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -34,12 +35,23 @@ type meshDataplane struct {
 	netServer          MeshDataplane
 	hostTrafficManager trafficmanager.TrafficRuleManager
 	hostAddrSet        set.AddressSetManager
+	// whether the traffic of the interfaces of secondary networks is not redirected
+	excludeSecondaryInterfaces bool
 
 	// branchENIRules tracks the branch ENI routing info we added rules for,
 	// keyed by pod IP. We cache it at add time so teardown can delete the rules
 	// even if aws-vpc-cni has already removed its iif rule (making re-detection fail).
 	branchENIMu    sync.Mutex
 	branchENIRules map[netip.Addr]*branchENIRoute
+
+	// podCache and ztunnelConns are inspected by the debug endpoint, if set.
+	podCache     PodNetnsCache
+	ztunnelConns *connMgr
+	// events records the repairs of drifted in-pod rules on the pods, if set.
+	events *kclient.EventRecorder
+	// podDebug tracks the IPs and the last failed operation of the pods we handled, keyed by pod UID.
+	podDebugMu sync.Mutex
+	podDebug   map[string]*podDebugState
 }
 
 // ConstructInitialSnapshot is always called first, before Start.
@@ -47,6 +59,9 @@ type meshDataplane struct {
 // and constructs various required "state" (adding the pods to the host-level node ipset,
 // building the state of the world snapshot send to connecting ztunnels)
 func (s *meshDataplane) ConstructInitialSnapshot(existingAmbientPods []*corev1.Pod) error {
+	for _, pod := range existingAmbientPods {
+		s.recordPodOperation(pod, util.GetPodIPsIfPresent(pod), "", nil)
+	}
 	if err := s.syncHostAddrSets(existingAmbientPods); err != nil {
 		log.Errorf("failed to sync host addressSet: %v", err)
 		return err
@@ -85,6 +100,9 @@ func (s *meshDataplane) Stop(skipCleanup bool) {
 	}
 
 	s.netServer.Stop(skipCleanup)
+	if s.events != nil {
+		s.events.Shutdown()
+	}
 }
 
 // rememberBranchENIRoute caches the branch ENI info for a pod IP so we can
@@ -134,7 +152,8 @@ func (s *meshDataplane) flushBranchENIRules() {
 // If the *last* step of sending the pod to ztunnel fails, then the pod will still be annotated
 // with a partially-captured status (indicating it has been mutated/redirected, and thus potentially
 // needs cleanup) and the error will be returned, indicating that the function call can be retried.
-func (s *meshDataplane) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIPs []netip.Addr, netNs string) error {
+func (s *meshDataplane) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIPs []netip.Addr, netNs string) (err error) {
+	defer func() { s.recordPodOperation(pod, podIPs, addPodOperation, err) }()
 	// Ordering is important in this func:
 	//
 	// - Inject rules and add to ztunnel FIRST
@@ -180,7 +199,8 @@ func (s *meshDataplane) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIP
 	// This should be the last step in all cases - once we do this, the CP (and the informer) will consider
 	// this pod "ambient" and successfully enrolled.
 	log.Debugf("annotating pod")
-	if err := util.AnnotateEnrolledPod(s.kubeClient, &pod.ObjectMeta); err != nil {
+	bypassed := getPodLevelTrafficOverrides(pod, s.excludeSecondaryInterfaces).ExcludeInterfaces
+	if err := util.AnnotateEnrolledPod(s.kubeClient, &pod.ObjectMeta, bypassed); err != nil {
 		// If we have an error annotating the full status - that is retryable.
 		// (maybe K8S is busy, etc - but we need a k8s controlplane ACK).
 		return err
@@ -196,7 +216,8 @@ func (s *meshDataplane) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIP
 // from the node ipset.
 //
 // If anything fails, an error will be returned, indicating that the function call can be retried.
-func (s *meshDataplane) RemovePodFromMesh(ctx context.Context, pod *corev1.Pod, isDelete bool) error {
+func (s *meshDataplane) RemovePodFromMesh(ctx context.Context, pod *corev1.Pod, isDelete bool) (err error) {
+	defer func() { s.recordPodOperation(pod, nil, removePodOperation, err) }()
 	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
 	log.WithLabels("deleted", isDelete).Info("removing pod from mesh")
 
@@ -240,10 +261,48 @@ func (s *meshDataplane) RemovePodFromMesh(ctx context.Context, pod *corev1.Pod,
 // SyncHostProbeIPSet re-asserts an already-enrolled pod's probe IPs in the host ipset
 // (see the MeshDataplane interface for why). addPodToHostAddrSet is an idempotent upsert.
 func (s *meshDataplane) SyncHostProbeIPSet(pod *corev1.Pod, podIPs []netip.Addr) error {
+	s.recordPodOperation(pod, podIPs, "", nil)
 	_, err := s.addPodToHostAddrSet(pod, podIPs)
 	return err
 }
 
+// RepairPodRules re-applies the in-pod rules of a pod in the mesh if they drifted.
+// A drift is reported with an event and a metric. If the rules cannot be re-applied, the pod is annotated
+// with a pending status so the informer retries adding it to the mesh.
+func (s *meshDataplane) RepairPodRules(pod *corev1.Pod) (bool, error) {
+	repairer, ok := s.netServer.(PodRuleRepairer)
+	if !ok {
+		return false, nil
+	}
+	drifted, err := repairer.RepairPodRules(pod)
+	if !drifted {
+		return false, err
+	}
+
+	log := log.WithLabels("ns", pod.Namespace, "name", pod.Name)
+	s.recordPodOperation(pod, nil, repairPodOperation, err)
+	if err != nil {
+		podRuleDriftTotal.With(repairResultTag.Value(repairResultFailed)).Increment()
+		s.writeEvent(pod, "in-pod redirection rules drifted, and could not be re-applied: %v", err)
+		log.Errorf("in-pod rules drifted and could not be re-applied, annotating with pending status: %v", err)
+		if aerr := util.AnnotatePartiallyEnrolledPod(s.kubeClient, &pod.ObjectMeta); aerr != nil {
+			return true, errors.Join(err, aerr)
+		}
+		return true, err
+	}
+	podRuleDriftTotal.With(repairResultTag.Value(repairResultRepaired)).Increment()
+	s.writeEvent(pod, "in-pod redirection rules drifted, re-applied")
+	log.Warn("in-pod rules drifted, re-applied")
+	return true, nil
+}
+
+func (s *meshDataplane) writeEvent(pod *corev1.Pod, messageFmt string, args ...any) {
+	if s.events == nil {
+		return
+	}
+	s.events.Write(pod, corev1.EventTypeWarning, ReasonPodRulesDrifted, messageFmt, args...)
+}
+
 // syncHostAddrSets is called after the host node ipset has been created (or found + flushed)
 // during initial snapshot creation, it will insert every snapshotted pod's IP into the set.
 //
@@ -24,7 +24,9 @@ import (
 	"istio.io/istio/cni/pkg/util"
 )
 
-func getPodLevelTrafficOverrides(pod *corev1.Pod) config.PodLevelOverrides {
+// getPodLevelTrafficOverrides returns the traffic overrides of the pod. If excludeSecondaryInterfaces is set, the
+// interfaces of its secondary networks that are not captured are excluded from the redirection.
+func getPodLevelTrafficOverrides(pod *corev1.Pod, excludeSecondaryInterfaces bool) config.PodLevelOverrides {
 	// If true, the pod will run in 'ingress mode'. This is intended to be used for "ingress" type workloads which handle
 	// non-mesh traffic on inbound, and send to the mesh on outbound.
 	// Basically, this just disables inbound redirection.
@@ -54,5 +56,14 @@ func getPodLevelTrafficOverrides(pod *corev1.Pod) config.PodLevelOverrides {
 		}
 	}
 
+	if excludeSecondaryInterfaces {
+		excluded, err := util.UncapturedInterfaces(pod)
+		if err != nil {
+			log.WithLabels("ns", pod.Namespace, "name", pod.Name).
+				Warnf("failed to read the secondary networks of the pod, the traffic of all its interfaces is redirected: %v", err)
+		}
+		podCfg.ExcludeInterfaces = excluded
+	}
+
 	return podCfg
 }
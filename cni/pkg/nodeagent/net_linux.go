@@ -33,6 +33,8 @@ type NetServer struct {
 	currentPodSnapshot *podNetnsCache
 	trafficManager     trafficmanager.TrafficRuleManager
 	podNs              PodNetnsFinder
+	// whether the traffic of the interfaces of secondary networks is not redirected
+	excludeSecondaryInterfaces bool
 	// allow overriding for tests
 	netnsRunner func(fdable NetnsFd, toRun func() error) error
 }
@@ -122,7 +124,7 @@ func (s *NetServer) AddPodToMesh(ctx context.Context, pod *corev1.Pod, podIPs []
 		return NewErrNonRetryableAdd(err)
 	}
 
-	podCfg := getPodLevelTrafficOverrides(pod)
+	podCfg := getPodLevelTrafficOverrides(pod, s.excludeSecondaryInterfaces)
 
 	log.Debug("calling CreateInpodRules")
 	if err := s.netnsRunner(openNetns, func() error {
@@ -210,7 +212,7 @@ func (s *NetServer) reconcileExistingPod(pod *corev1.Pod) error {
 		return err
 	}
 
-	podCfg := getPodLevelTrafficOverrides(pod)
+	podCfg := getPodLevelTrafficOverrides(pod, s.excludeSecondaryInterfaces)
 
 	if err := s.netnsRunner(openNetns, func() error {
 		return s.trafficManager.CreateInpodRules(log, podCfg)
@@ -221,6 +223,36 @@ func (s *NetServer) reconcileExistingPod(pod *corev1.Pod) error {
 	return nil
 }
 
+// RepairPodRules verifies the in-pod rules of a pod in the mesh against the rules we would program,
+// and re-applies them if they drifted, e.g. if they were flushed by another CNI or node-level tooling.
+// Pods whose netns is not cached are skipped, they are not in the mesh yet.
+func (s *NetServer) RepairPodRules(pod *corev1.Pod) (bool, error) {
+	openNetns := s.currentPodSnapshot.Get(string(pod.UID))
+	if openNetns == nil {
+		return false, nil
+	}
+
+	podCfg := getPodLevelTrafficOverrides(pod, s.excludeSecondaryInterfaces)
+
+	drifted := false
+	err := s.netnsRunner(openNetns, func() error {
+		var err error
+		drifted, err = s.trafficManager.VerifyInpodRules(log, podCfg)
+		if err != nil || !drifted {
+			return err
+		}
+		if !s.trafficManager.ReconcileModeEnabled() {
+			// Without reconciliation the rules are applied on top of the existing ones,
+			// so remove what is left first.
+			if err := s.trafficManager.DeleteInpodRules(log); err != nil {
+				log.Debugf("failed to delete drifted in-pod rules: %v", err)
+			}
+		}
+		return s.trafficManager.CreateInpodRules(log, podCfg)
+	})
+	return drifted, err
+}
+
 func (s *NetServer) rescanPod(pod *corev1.Pod) error {
 	// this can happen if the pod was dynamically added to the mesh after it was created.
 	// in that case, try finding the netns using procfs.
@@ -32,6 +32,7 @@ import (
 	"istio.io/api/annotation"
 	"istio.io/istio/cni/pkg/config"
 	"istio.io/istio/cni/pkg/iptables"
+	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/test/util/retry"
 	"istio.io/istio/tools/istio-iptables/pkg/dependencies"
@@ -503,9 +504,40 @@ func TestReconcilePodReturnsNoErrorIfPodReconciles(t *testing.T) {
 	assert.Equal(t, (len(fakeDeps.ExecutedAll) != 0), true)
 }
 
+func TestRepairPodRules(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupLogging()
+
+	fakeDeps := &dependencies.DependenciesStub{}
+	fixture := getTestFixureWithIptablesConfig(ctx, fakeDeps, nil, nil)
+	netServer := fixture.netServer
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "foo",
+		Namespace: "bar",
+		UID:       types.UID("863b91d4-4b68-4efa-917f-4b560e3e86aa"),
+	}}
+
+	// Pods that are not in the mesh are skipped
+	drifted, err := netServer.RepairPodRules(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, false)
+	assert.Equal(t, len(fakeDeps.ExecutedAll), 0)
+
+	assert.NoError(t, netServer.AddPodToMesh(ctx, pod, []netip.Addr{netip.MustParseAddr("99.9.9.9")}, "fakenetns"))
+	fakeDeps.ExecutedAll = nil
+
+	// The faked iptables-save output is empty, so the rules always drifted and are re-applied
+	drifted, err = netServer.RepairPodRules(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, true)
+	assert.Equal(t, len(fakeDeps.ExecutedAll) != 0, true)
+}
+
 var overrideTests = map[string]struct {
-	in  corev1.Pod
-	out config.PodLevelOverrides
+	in                         corev1.Pod
+	excludeSecondaryInterfaces bool
+	out                        config.PodLevelOverrides
 }{
 	"pod dns override": {
 		in: corev1.Pod{
@@ -574,6 +606,44 @@ var overrideTests = map[string]struct {
 		},
 	},
 
+	"secondary networks, one captured": {
+		in: corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+				UID:       "12345",
+				Annotations: map[string]string{
+					util.MultusNetworksAnnotation:    "macvlan, other/sriov@data0, bridge",
+					util.CaptureInterfacesAnnotation: "data0",
+				},
+			},
+		},
+		excludeSecondaryInterfaces: true,
+		out: config.PodLevelOverrides{
+			ExcludeInterfaces: []string{"net1", "net3"},
+			IngressMode:       false,
+			DNSProxy:          config.PodDNSUnset,
+		},
+	},
+
+	"secondary networks, exclusion disabled": {
+		in: corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+				UID:       "12345",
+				Annotations: map[string]string{
+					util.MultusNetworksAnnotation:    "macvlan, other/sriov@data0, bridge",
+					util.CaptureInterfacesAnnotation: "data0",
+				},
+			},
+		},
+		out: config.PodLevelOverrides{
+			IngressMode: false,
+			DNSProxy:    config.PodDNSUnset,
+		},
+	},
+
 	"various manglings": {
 		in: corev1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
@@ -598,7 +668,7 @@ var overrideTests = map[string]struct {
 func TestGetPodLevelOverrides(t *testing.T) {
 	for name, test := range overrideTests {
 		t.Run(name, func(t *testing.T) {
-			res := getPodLevelTrafficOverrides(&test.in)
+			res := getPodLevelTrafficOverrides(&test.in, test.excludeSecondaryInterfaces)
 			assert.Equal(t, res, test.out, fmt.Sprintf("test '%s' failed", name))
 		})
 	}
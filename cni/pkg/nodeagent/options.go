@@ -16,6 +16,7 @@ package nodeagent
 
 import (
 	"net/netip"
+	"time"
 
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/config/constants"
@@ -33,6 +34,15 @@ var (
 	UseScopedIptablesLegacyLocking = env.RegisterBoolVar("AMBIENT_USE_SCOPED_XTABLES_LOCKING", true, "").Get()
 	EnableAWSBranchENIProbe        = env.RegisterBoolVar("AMBIENT_ENABLE_AWS_BRANCH_ENI_PROBE", true,
 		"If true, detect AWS VPC CNI branch ENI pods and add ip rules to route probe traffic via veth").Get()
+	PodRuleDriftCheckInterval = env.RegisterDurationVar("AMBIENT_POD_RULE_DRIFT_CHECK_INTERVAL", 0,
+		"If set, the interval at which the in-pod redirection rules of the pods in the mesh are verified, and re-applied if they drifted. "+
+			"The check is disabled by default").Get()
+	ZtunnelHandoffBatchSize = env.RegisterIntVar("AMBIENT_ZTUNNEL_HANDOFF_BATCH_SIZE", 0,
+		"If greater than 0, when a ztunnel connects while another one is connected, e.g. during an upgrade, "+
+			"the pods are handed off to the new ztunnel in batches of this many pods, grouped by namespace, "+
+			"and removed from the previous ztunnel once acknowledged. If 0, the new ztunnel takes over all pods at once").Get()
+	ZtunnelHandoffBatchInterval = env.RegisterDurationVar("AMBIENT_ZTUNNEL_HANDOFF_BATCH_INTERVAL", 5*time.Second,
+		"The interval between batches of pods handed off to a new ztunnel").Get()
 )
 
 const (
@@ -58,5 +68,7 @@ type AmbientArgs struct {
 	EnableIPv6                 bool
 	ReconcilePodRulesOnStartup bool
 	NativeNftables             bool
+	EbpfRedirection            bool
+	ExcludeSecondaryInterfaces bool
 	ForceIptablesBinary        string
 }
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/monitoring"
+)
+
+const (
+	// ReasonPodRulesDrifted is the reason of the event written when the in-pod rules of a pod drifted.
+	ReasonPodRulesDrifted = "RedirectionRulesDrifted"
+
+	repairResultRepaired = "repaired"
+	repairResultFailed   = "failed"
+)
+
+var (
+	repairResultTag   = monitoring.CreateLabel("result")
+	podRuleDriftTotal = monitoring.NewSum(
+		"nodeagent_pod_rule_drift_total",
+		"The total number of pods whose in-pod redirection rules drifted from the expected state.",
+	)
+)
+
+// PodRuleRepairer verifies and repairs the in-pod redirection rules of pods in the mesh.
+type PodRuleRepairer interface {
+	// RepairPodRules re-applies the in-pod rules of the pod if they drifted, and returns whether they did.
+	RepairPodRules(pod *corev1.Pod) (bool, error)
+}
+
+// podRuleDriftCheck is the queue item verifying the in-pod rules of a pod. It is processed by the informer queue,
+// so the check never runs concurrently with adding the pod to or removing it from the mesh.
+type podRuleDriftCheck types.NamespacedName
+
+// runPodRuleDriftChecks periodically queues the verification of the in-pod rules of the pods in the mesh,
+// until the handlers are stopped.
+func (s *InformerHandlers) runPodRuleDriftChecks(interval time.Duration) {
+	if _, ok := s.dataplane.(PodRuleRepairer); !ok || interval <= 0 {
+		return
+	}
+	log.Infof("checking in-pod redirection rules for drift every %v", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, pod := range s.GetActiveAmbientPodSnapshot() {
+				s.queue.Add(podRuleDriftCheck{Name: pod.Name, Namespace: pod.Namespace})
+			}
+		}
+	}
+}
+
+// reconcilePodRuleDrift repairs the in-pod rules of a pod, if it is still in the mesh.
+func (s *InformerHandlers) reconcilePodRuleDrift(check podRuleDriftCheck) error {
+	repairer, ok := s.dataplane.(PodRuleRepairer)
+	if !ok {
+		return nil
+	}
+	log := log.WithLabels("ns", check.Namespace, "name", check.Name)
+	// The pod may have been removed from the mesh since the check was queued.
+	pod := s.pods.Get(check.Name, check.Namespace)
+	ns := s.namespaces.Get(check.Namespace, "")
+	if pod == nil || ns == nil || kube.CheckPodTerminal(pod) || !util.PodFullyEnrolled(pod) ||
+		!s.enablementSelector.Matches(pod.Labels, pod.Annotations, ns.Labels) || s.isNamespaceExcluded(pod.Namespace) {
+		log.Debugf("skipping in-pod rule drift check, pod is no longer in the mesh")
+		return nil
+	}
+	if _, err := repairer.RepairPodRules(pod); err != nil {
+		// A pod whose rules could not be re-applied is annotated as pending, and retried as a regular add.
+		log.Warnf("failed to repair in-pod rules: %v", err)
+	}
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/cni/pkg/trafficmanager"
 	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
 	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
 )
 
@@ -100,14 +101,17 @@ func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, er
 	if err != nil {
 		return nil, fmt.Errorf("error initializing the ztunnel server: %w", err)
 	}
+	events := kclient.NewEventRecorder(client, "istio-cni-node")
+	ztunnelServer.events = &events
 
 	hostTrafficManager, podTrafficManager, err := trafficmanager.NewTrafficRuleManager(&trafficmanager.TrafficRuleManagerConfig{
-		NativeNftables: useNftables,
-		HostConfig:     hostCfg,
-		PodConfig:      podCfg,
-		HostDeps:       realDependenciesHost(args.ForceIptablesBinary),
-		PodDeps:        realDependenciesInpod(UseScopedIptablesLegacyLocking, args.ForceIptablesBinary),
-		NlDeps:         iptables.RealNlDeps(),
+		NativeNftables:  useNftables,
+		EbpfRedirection: args.EbpfRedirection,
+		HostConfig:      hostCfg,
+		PodConfig:       podCfg,
+		HostDeps:        realDependenciesHost(args.ForceIptablesBinary),
+		PodDeps:         realDependenciesInpod(UseScopedIptablesLegacyLocking, args.ForceIptablesBinary),
+		NlDeps:          iptables.RealNlDeps(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating traffic managers: %w", err)
@@ -138,12 +142,17 @@ func initMeshDataplane(client kube.Client, args AmbientArgs) (*meshDataplane, er
 		return nil, err
 	}
 	netServer := newNetServer(ztunnelServer, podNsMap, podTrafficManager, podNetns)
+	netServer.excludeSecondaryInterfaces = args.ExcludeSecondaryInterfaces
 
 	return &meshDataplane{
-		kubeClient:         client.Kube(),
-		netServer:          netServer,
-		hostTrafficManager: hostTrafficManager,
-		hostAddrSet:        setManager,
+		kubeClient:                 client.Kube(),
+		netServer:                  netServer,
+		hostTrafficManager:         hostTrafficManager,
+		hostAddrSet:                setManager,
+		excludeSecondaryInterfaces: args.ExcludeSecondaryInterfaces,
+		podCache:                   podNsMap,
+		ztunnelConns:               ztunnelServer.conns,
+		events:                     &events,
 	}, nil
 }
 
@@ -32,6 +32,7 @@ import (
 	set "istio.io/istio/cni/pkg/addressset"
 	"istio.io/istio/cni/pkg/ipset"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/monitoring/monitortest"
 	"istio.io/istio/pkg/test/util/assert"
 )
 
@@ -121,6 +122,55 @@ func TestMeshDataplaneAddsAnnotationOnAddWithPartialError(t *testing.T) {
 	assert.Equal(t, pod.Annotations[annotation.AmbientRedirection.Name], constants.AmbientRedirectionPending)
 }
 
+func TestMeshDataplaneRepairPodRules(t *testing.T) {
+	mt := monitortest.New(t)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "test",
+			UID:         types.UID("test"),
+			Annotations: map[string]string{annotation.AmbientRedirection.Name: constants.AmbientRedirectionEnabled},
+		},
+	}
+	fakeCtx := context.Background()
+	fakeClientSet := fake.NewClientset(pod)
+
+	server := &fakeServer{}
+	server.On("RepairPodRules", pod).Return(false, nil).Once()
+	server.On("RepairPodRules", pod).Return(true, nil).Once()
+	server.On("RepairPodRules", pod).Return(true, errors.New("some failure")).Once()
+
+	m := getFakeDP(server, fakeClientSet)
+
+	// No drift
+	drifted, err := m.RepairPodRules(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, false)
+
+	// Drift repaired, the pod stays enrolled
+	drifted, err = m.RepairPodRules(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, drifted, true)
+	mt.Assert(podRuleDriftTotal.Name(), map[string]string{"result": repairResultRepaired}, monitortest.Exactly(1))
+	got, err := fakeClientSet.CoreV1().Pods("test").Get(fakeCtx, "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, got.Annotations[annotation.AmbientRedirection.Name], constants.AmbientRedirectionEnabled)
+
+	// Drift not repaired, the pod is annotated as pending so the informer retries adding it
+	drifted, err = m.RepairPodRules(pod)
+	assert.Error(t, err)
+	assert.Equal(t, drifted, true)
+	mt.Assert(podRuleDriftTotal.Name(), map[string]string{"result": repairResultFailed}, monitortest.Exactly(1))
+	got, err = fakeClientSet.CoreV1().Pods("test").Get(fakeCtx, "test", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, got.Annotations[annotation.AmbientRedirection.Name], constants.AmbientRedirectionPending)
+
+	if st := m.podDebug[string(pod.UID)]; st == nil || st.lastError == nil || st.lastError.Operation != repairPodOperation {
+		t.Fatalf("expected last error from %s, got %+v", repairPodOperation, st)
+	}
+	server.AssertExpectations(t)
+}
+
 func TestMeshDataplaneDoesntAnnotateOnAddWithNonretryableError(t *testing.T) {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,260 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeagent
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/monitoring"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/zdsapi"
+)
+
+const (
+	ReasonZtunnelHandoffStarted   = "ZtunnelHandoffStarted"
+	ReasonZtunnelHandoffCompleted = "ZtunnelHandoffCompleted"
+	ReasonZtunnelHandoffAborted   = "ZtunnelHandoffAborted"
+
+	handoffResultMigrated = "migrated"
+	handoffResultFailed   = "failed"
+	handoffResultComplete = "completed"
+	handoffResultAborted  = "aborted"
+)
+
+var (
+	handoffResultTag = monitoring.CreateLabel("result")
+
+	ztunnelHandoffs = monitoring.NewSum(
+		"nodeagent_ztunnel_handoffs_total",
+		"The total number of handoffs of the pods from a ztunnel to a newly connected one.",
+	)
+	ztunnelHandoffPods = monitoring.NewSum(
+		"nodeagent_ztunnel_handoff_pods_total",
+		"The total number of pods handed off to a newly connected ztunnel.",
+	)
+	ztunnelHandoffPending = monitoring.NewGauge(
+		"nodeagent_ztunnel_handoff_pods_pending",
+		"The number of pods still served by the previous ztunnel during a handoff.",
+	)
+)
+
+// handoff migrates the pods in the snapshot from the previously connected ztunnels to a newly connected one.
+//
+// The new ztunnel received an empty snapshot, so the previous ones keep serving every pod. Pods are then migrated
+// in batches of whole namespaces: each pod of a batch is added to the new ztunnel, and once acknowledged,
+// deleted from the previous ones so they drain it. The next batch is sent after ZtunnelHandoffBatchInterval.
+// Pods the new ztunnel fails to add are retried, and pods removed during the handoff are skipped.
+// If the previous ztunnels disconnect, the remaining pods are migrated at once. If the new one disconnects,
+// the handoff is aborted and the remaining pods stay with the previous ones.
+func (z *ztunnelServer) handoff(ctx context.Context, from []ZtunnelConnection, to ZtunnelConnection) {
+	log := log.WithLabels("conn_uuid", to.UUID())
+	batches := handoffBatches(z.pods.ReadCurrentPodSnapshot(), ZtunnelHandoffBatchSize)
+	pending := 0
+	for _, b := range batches {
+		pending += len(b)
+	}
+	log.Infof("handing off %d pods from %d ztunnels in %d batches", pending, len(from), len(batches))
+	z.writeNodeEvent(corev1.EventTypeNormal, ReasonZtunnelHandoffStarted,
+		"handing off %d pods to ztunnel connection %s in %d batches", pending, to.UUID(), len(batches))
+	ztunnelHandoffPending.RecordInt(int64(pending))
+	defer ztunnelHandoffPending.RecordInt(0)
+
+	abort := func(reason string) {
+		log.Warnf("ztunnel handoff aborted, %d pods stay with the previous ztunnels: %s", pending, reason)
+		ztunnelHandoffs.With(handoffResultTag.Value(handoffResultAborted)).Increment()
+		z.writeNodeEvent(corev1.EventTypeWarning, ReasonZtunnelHandoffAborted,
+			"handoff to ztunnel connection %s aborted, %d pods stay with the previous ztunnels: %s", to.UUID(), pending, reason)
+	}
+
+	// Pods that failed to be added to the new ztunnel are retried with the next batch, or on their own after the
+	// last batch, for as long as the new ztunnel is connected.
+	var retry []string
+	for i := 0; i < len(batches) || len(retry) > 0; i++ {
+		retrying := i >= len(batches)
+		if i > 0 && (retrying || slices.FindFunc(from, isConnAlive) != nil) {
+			select {
+			case <-time.After(ZtunnelHandoffBatchInterval):
+			case <-to.Done():
+				abort("new ztunnel disconnected")
+				return
+			case <-ctx.Done():
+				abort("node agent stopped")
+				return
+			}
+		}
+		batch := retry
+		retry = nil
+		if !retrying {
+			batch = append(batch, batches[i]...)
+		}
+		// Pods may have been removed since the handoff started.
+		current := z.pods.ReadCurrentPodSnapshot()
+		var migrated []string
+		for _, uid := range batch {
+			wl, ok := current[uid]
+			if !ok || wl.Netns == nil {
+				// Pods we hold no netns for cannot be added to the new ztunnel, the previous ones keep them.
+				pending--
+				continue
+			}
+			if err := z.sendHandoffAdd(ctx, to, uid, wl); err != nil {
+				if !isConnAlive(to) || ctx.Err() != nil {
+					abort(err.Error())
+					return
+				}
+				log.WithLabels("uid", uid).Warnf("failed to hand off pod, will retry: %v", err)
+				ztunnelHandoffPods.With(handoffResultTag.Value(handoffResultFailed)).Increment()
+				retry = append(retry, uid)
+				continue
+			}
+			pending--
+			if _, ok := z.pods.ReadCurrentPodSnapshot()[uid]; !ok {
+				// The pod was removed while it was handed off, and the deletion may have reached the ztunnels
+				// before the add. Delete it again from every ztunnel that was sent the pod, so none of them keeps it,
+				// even if the handoff is aborted.
+				z.drainHandedOffPods(ctx, append([]ZtunnelConnection{to}, from...), []string{uid})
+				continue
+			}
+			migrated = append(migrated, uid)
+			ztunnelHandoffPods.With(handoffResultTag.Value(handoffResultMigrated)).Increment()
+		}
+		z.drainHandedOffPods(ctx, from, migrated)
+		ztunnelHandoffPending.RecordInt(int64(pending))
+		if retrying {
+			log.Infof("handed off %d retried pods, %d pods pending", len(migrated), pending)
+		} else {
+			log.Infof("handed off batch %d/%d (%d pods), %d pods pending", i+1, len(batches), len(migrated), pending)
+		}
+	}
+
+	log.Info("ztunnel handoff completed")
+	ztunnelHandoffs.With(handoffResultTag.Value(handoffResultComplete)).Increment()
+	z.writeNodeEvent(corev1.EventTypeNormal, ReasonZtunnelHandoffCompleted,
+		"handoff to ztunnel connection %s completed", to.UUID())
+}
+
+func (z *ztunnelServer) sendHandoffAdd(ctx context.Context, to ZtunnelConnection, uid string, wl WorkloadInfo) error {
+	fd := int(wl.Netns.Fd())
+	resp, err := to.Send(ctx, &zdsapi.WorkloadRequest{
+		Payload: &zdsapi.WorkloadRequest_Add{
+			Add: &zdsapi.AddWorkload{
+				Uid:          uid,
+				WorkloadInfo: wl.Workload,
+			},
+		},
+	}, &fd)
+	if err != nil {
+		return err
+	}
+	if resp.GetAck().GetError() != "" {
+		return fmt.Errorf("got ack error: %s", resp.GetAck().GetError())
+	}
+	return nil
+}
+
+// drainHandedOffPods deletes the pods from the given ztunnels that are still connected.
+func (z *ztunnelServer) drainHandedOffPods(ctx context.Context, from []ZtunnelConnection, uids []string) {
+	for _, conn := range from {
+		if !isConnAlive(conn) {
+			continue
+		}
+		for _, uid := range uids {
+			z.sendHandoffDel(ctx, conn, uid)
+		}
+	}
+}
+
+func (z *ztunnelServer) sendHandoffDel(ctx context.Context, conn ZtunnelConnection, uid string) {
+	_, err := conn.Send(ctx, &zdsapi.WorkloadRequest{
+		Payload: &zdsapi.WorkloadRequest_Del{
+			Del: &zdsapi.DelWorkload{
+				Uid: uid,
+			},
+		},
+	}, nil)
+	if err != nil {
+		log.WithLabels("conn_uuid", conn.UUID(), "uid", uid).Warnf("failed to delete handed off pod: %v", err)
+	}
+}
+
+func (z *ztunnelServer) writeNodeEvent(eventType, reason, messageFmt string, args ...any) {
+	if z.events == nil || NodeName == "" {
+		return
+	}
+	z.events.Write(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: NodeName}}, eventType, reason, messageFmt, args...)
+}
+
+func isConnAlive(conn ZtunnelConnection) bool {
+	select {
+	case <-conn.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// handoffBatches returns the UIDs of the pods in the snapshot, in batches of at most batchSize pods.
+// Pods are grouped by namespace, and a namespace is only split over several batches if it is larger than batchSize.
+func handoffBatches(snap map[string]WorkloadInfo, batchSize int) [][]string {
+	type podRef struct {
+		uid, namespace, name string
+	}
+	pods := make([]podRef, 0, len(snap))
+	for uid, wl := range snap {
+		p := podRef{uid: uid}
+		if wl.Workload != nil {
+			p.namespace, p.name = wl.Workload.Namespace, wl.Workload.Name
+		}
+		pods = append(pods, p)
+	}
+	slices.SortFunc(pods, func(a, b podRef) int {
+		return cmp.Or(cmp.Compare(a.namespace, b.namespace), cmp.Compare(a.name, b.name), cmp.Compare(a.uid, b.uid))
+	})
+
+	if batchSize <= 0 {
+		batchSize = len(pods)
+	}
+	var batches [][]string
+	var batch []string
+	for start := 0; start < len(pods); {
+		end := start
+		for end < len(pods) && pods[end].namespace == pods[start].namespace {
+			end++
+		}
+		namespace := pods[start:end]
+		start = end
+		if len(batch) > 0 && len(batch)+len(namespace) > batchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		for _, p := range namespace {
+			if len(batch) == batchSize {
+				batches = append(batches, batch)
+				batch = nil
+			}
+			batch = append(batch, p.uid)
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -29,6 +29,7 @@ import (
 	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 
+	"istio.io/istio/pkg/kube/kclient"
 	"istio.io/istio/pkg/monitoring"
 	"istio.io/istio/pkg/zdsapi"
 )
@@ -103,6 +104,20 @@ func (c *connMgr) deleteConn(conn ZtunnelConnection) {
 	ztunnelConnected.RecordInt(int64(len(c.connectionSet)))
 }
 
+// previousConns returns the connections established before conn.
+func (c *connMgr) previousConns(conn ZtunnelConnection) []ZtunnelConnection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var conns []ZtunnelConnection
+	for _, existingConn := range c.connectionSet {
+		if existingConn == conn {
+			break
+		}
+		conns = append(conns, existingConn)
+	}
+	return conns
+}
+
 // this is used in tests
 // nolint: unused
 func (c *connMgr) len() int {
@@ -120,6 +135,8 @@ type ztunnelServer struct {
 	conns             *connMgr
 	pods              PodNetnsCache
 	keepaliveInterval time.Duration
+	// events records the progress of ztunnel handoffs on the node, if set.
+	events *kclient.EventRecorder
 }
 
 var _ ZtunnelServer = &ztunnelServer{}
@@ -182,9 +199,19 @@ func (z *ztunnelServer) handleConn(ctx context.Context, conn ZtunnelConnection)
 	}
 
 	log.WithLabels("version", m.Version).Infof("received hello from ztunnel")
-	log.Debug("sending snapshot to ztunnel")
-	if err := z.sendSnapshot(ctx, conn); err != nil {
-		return err
+	if previous := z.conns.previousConns(conn); ZtunnelHandoffBatchSize > 0 && len(previous) > 0 {
+		// Another ztunnel is serving the pods, e.g. during an upgrade. Hand them off gradually
+		// instead of sending them all in the snapshot.
+		log.Debug("sending empty snapshot to ztunnel before handoff")
+		if err := z.sendSnapshot(ctx, conn, nil); err != nil {
+			return err
+		}
+		go z.handoff(ctx, previous, conn)
+	} else {
+		log.Debug("sending snapshot to ztunnel")
+		if err := z.sendSnapshot(ctx, conn, z.pods.ReadCurrentPodSnapshot()); err != nil {
+			return err
+		}
 	}
 	for {
 		// listen for updates:
@@ -262,8 +289,7 @@ func podToWorkload(pod *v1.Pod) *zdsapi.WorkloadInfo {
 	}
 }
 
-func (z *ztunnelServer) sendSnapshot(_ context.Context, conn ZtunnelConnection) error {
-	snap := z.pods.ReadCurrentPodSnapshot()
+func (z *ztunnelServer) sendSnapshot(_ context.Context, conn ZtunnelConnection, snap map[string]WorkloadInfo) error {
 	for uid, wl := range snap {
 		var resp *zdsapi.WorkloadResponse
 		var err error
@@ -34,6 +34,7 @@ import (
 
 	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/zdsapi"
 )
@@ -898,3 +899,248 @@ type fakePodCache struct {
 func (f fakePodCache) ReadCurrentPodSnapshot() map[string]WorkloadInfo {
 	return maps.Clone(f.pods)
 }
+
+func TestZtunnelHandoff(t *testing.T) {
+	mt := monitortest.New(t)
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batchSize, batchInterval := ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval
+	ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = 1, time.Millisecond
+	t.Cleanup(func() {
+		ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = batchSize, batchInterval
+	})
+
+	cache := &fakePodCache{}
+	cacheCloser := fillCacheWithFakePods(cache, 2)
+	defer cacheCloser()
+	uids := maps.Keys(cache.pods)
+	slices.Sort(uids)
+	for i, uid := range uids {
+		cache.pods[uid].Workload.Namespace = fmt.Sprintf("ns-%d", i)
+	}
+
+	srv := startServerWithPodCache(ctx, cache)
+	defer srv.ztunServer.Close()
+
+	// The first ztunnel gets all the pods in its snapshot
+	client1 := connectZtClientToServer(srv.addr)
+	defer client1.Close()
+	sendHello(client1)
+	for i := 0; i < 2; i++ {
+		m, _ := readRequest(t, client1)
+		assert.Equal(t, m.GetAdd() != nil, true)
+		sendAck(client1)
+	}
+	m, _ := readRequest(t, client1)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client1)
+
+	// The second one gets an empty snapshot, and the pods are then handed off one namespace at a time
+	client2 := connectZtClientToServer(srv.addr)
+	defer client2.Close()
+	sendHello(client2)
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client2)
+
+	for _, uid := range uids {
+		m, fds := readRequest(t, client2)
+		assert.Equal(t, m.GetAdd().GetUid(), uid)
+		assert.Equal(t, len(fds), 1)
+		sendAck(client2)
+
+		m, fds = readRequest(t, client1)
+		assert.Equal(t, m.GetDel().GetUid(), uid)
+		assert.Equal(t, len(fds), 0)
+		sendAck(client1)
+	}
+
+	mt.Assert(ztunnelHandoffPods.Name(), map[string]string{"result": handoffResultMigrated}, monitortest.Exactly(2))
+	mt.Assert(ztunnelHandoffs.Name(), map[string]string{"result": handoffResultComplete}, monitortest.Exactly(1))
+	mt.Assert(ztunnelHandoffPending.Name(), nil, monitortest.Exactly(0))
+}
+
+// lockedPodCache is a fakePodCache whose pods can be removed while a handoff reads it.
+type lockedPodCache struct {
+	mu sync.Mutex
+	fakePodCache
+}
+
+func (l *lockedPodCache) ReadCurrentPodSnapshot() map[string]WorkloadInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fakePodCache.ReadCurrentPodSnapshot()
+}
+
+func (l *lockedPodCache) remove(uid string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pods, uid)
+}
+
+func TestZtunnelHandoffRetriesAndSkipsRemovedPods(t *testing.T) {
+	mt := monitortest.New(t)
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batchSize, batchInterval := ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval
+	ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = 1, time.Millisecond
+	t.Cleanup(func() {
+		ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = batchSize, batchInterval
+	})
+
+	cache := &lockedPodCache{}
+	cacheCloser := fillCacheWithFakePods(&cache.fakePodCache, 2)
+	defer cacheCloser()
+	uids := maps.Keys(cache.pods)
+	slices.Sort(uids)
+	for i, uid := range uids {
+		cache.pods[uid].Workload.Namespace = fmt.Sprintf("ns-%d", i)
+	}
+
+	srv := startServerWithPodCache(ctx, cache)
+	defer srv.ztunServer.Close()
+
+	client1 := connectZtClientToServer(srv.addr)
+	defer client1.Close()
+	sendHello(client1)
+	for i := 0; i < 2; i++ {
+		readRequest(t, client1)
+		sendAck(client1)
+	}
+	m, _ := readRequest(t, client1)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client1)
+
+	client2 := connectZtClientToServer(srv.addr)
+	defer client2.Close()
+	sendHello(client2)
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client2)
+
+	// The first pod is rejected, and retried with the next batch.
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetAdd().GetUid(), uids[0])
+	sendAckError(client2, "not ready")
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetAdd().GetUid(), uids[0])
+	sendAck(client2)
+
+	// The second pod is removed while it is handed off, so it is deleted again from both ztunnels.
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetAdd().GetUid(), uids[1])
+	cache.remove(uids[1])
+	sendAck(client2)
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetDel().GetUid(), uids[1])
+	sendAck(client2)
+	m, _ = readRequest(t, client1)
+	assert.Equal(t, m.GetDel().GetUid(), uids[1])
+	sendAck(client1)
+
+	// Only the migrated pod is drained from the previous ztunnel.
+	m, _ = readRequest(t, client1)
+	assert.Equal(t, m.GetDel().GetUid(), uids[0])
+	sendAck(client1)
+
+	mt.Assert(ztunnelHandoffPods.Name(), map[string]string{"result": handoffResultFailed}, monitortest.Exactly(1))
+	mt.Assert(ztunnelHandoffPods.Name(), map[string]string{"result": handoffResultMigrated}, monitortest.Exactly(1))
+	mt.Assert(ztunnelHandoffs.Name(), map[string]string{"result": handoffResultComplete}, monitortest.Exactly(1))
+}
+
+func TestZtunnelHandoffAbortedAfterRemovedPod(t *testing.T) {
+	mt := monitortest.New(t)
+	setupLogging()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	batchSize, batchInterval := ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval
+	ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = 1, time.Hour
+	t.Cleanup(func() {
+		ZtunnelHandoffBatchSize, ZtunnelHandoffBatchInterval = batchSize, batchInterval
+	})
+
+	cache := &lockedPodCache{}
+	cacheCloser := fillCacheWithFakePods(&cache.fakePodCache, 2)
+	defer cacheCloser()
+	uids := maps.Keys(cache.pods)
+	slices.Sort(uids)
+	for i, uid := range uids {
+		cache.pods[uid].Workload.Namespace = fmt.Sprintf("ns-%d", i)
+	}
+
+	srv := startServerWithPodCache(ctx, cache)
+	defer srv.ztunServer.Close()
+
+	client1 := connectZtClientToServer(srv.addr)
+	defer client1.Close()
+	sendHello(client1)
+	for i := 0; i < 2; i++ {
+		readRequest(t, client1)
+		sendAck(client1)
+	}
+	m, _ := readRequest(t, client1)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client1)
+
+	client2 := connectZtClientToServer(srv.addr)
+	sendHello(client2)
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetSnapshotSent() != nil, true)
+	sendAck(client2)
+
+	// The first pod is removed while it is handed off, so it is deleted from both ztunnels.
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetAdd().GetUid(), uids[0])
+	cache.remove(uids[0])
+	sendAck(client2)
+	m, _ = readRequest(t, client2)
+	assert.Equal(t, m.GetDel().GetUid(), uids[0])
+	sendAck(client2)
+	m, _ = readRequest(t, client1)
+	assert.Equal(t, m.GetDel().GetUid(), uids[0])
+	sendAck(client1)
+
+	// The new ztunnel disconnects before the next batch, the second pod stays with the previous one.
+	client2.Close()
+	mt.Assert(ztunnelHandoffs.Name(), map[string]string{"result": handoffResultAborted}, monitortest.Exactly(1))
+}
+
+func sendAckError(c *net.UnixConn, msg string) {
+	ack := &zdsapi.WorkloadResponse{
+		Payload: &zdsapi.WorkloadResponse_Ack{
+			Ack: &zdsapi.Ack{Error: msg},
+		},
+	}
+	data, err := proto.Marshal(ack)
+	if err != nil {
+		panic(err)
+	}
+	err = c.SetWriteDeadline(time.Now().Add(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	c.Write(data)
+}
+
+func TestHandoffBatches(t *testing.T) {
+	workload := func(namespace, name string) WorkloadInfo {
+		return WorkloadInfo{Workload: &zdsapi.WorkloadInfo{Namespace: namespace, Name: name}}
+	}
+	snap := map[string]WorkloadInfo{
+		"a1": workload("a", "1"),
+		"a2": workload("a", "2"),
+		"b1": workload("b", "1"),
+		"c1": workload("c", "1"),
+		"c2": workload("c", "2"),
+		"c3": workload("c", "3"),
+	}
+	assert.Equal(t, handoffBatches(snap, 0), [][]string{{"a1", "a2", "b1", "c1", "c2", "c3"}})
+	assert.Equal(t, handoffBatches(snap, 3), [][]string{{"a1", "a2", "b1"}, {"c1", "c2", "c3"}})
+	assert.Equal(t, handoffBatches(snap, 2), [][]string{{"a1", "a2"}, {"b1"}, {"c1", "c2"}, {"c3"}})
+	assert.Equal(t, handoffBatches(nil, 2), nil)
+}
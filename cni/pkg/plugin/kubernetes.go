@@ -25,6 +25,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"istio.io/istio/cni/pkg/constants"
+	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/util/sets"
@@ -38,6 +39,8 @@ type PodInfo struct {
 	ProxyEnvironments map[string]string
 	ProxyUID          *int64
 	ProxyGID          *int64
+	// UncapturedInterfaces are the interfaces of the secondary networks of the pod whose traffic is not redirected.
+	UncapturedInterfaces []string
 }
 
 // newK8sClient returns a Kubernetes client
@@ -58,25 +61,35 @@ func newK8sClient(conf Config) (kubernetes.Interface, error) {
 }
 
 // getK8sPodInfo returns information of a POD
-func getK8sPodInfo(client kubernetes.Interface, podName, podNamespace string) (*PodInfo, error) {
+func getK8sPodInfo(client kubernetes.Interface, podName, podNamespace string, excludeSecondaryInterfaces bool) (*PodInfo, error) {
 	pod, err := client.CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	pi := ExtractPodInfo(pod)
+	pi := ExtractPodInfo(pod, excludeSecondaryInterfaces)
 	log.Debugf("Pod %v/%v info: \n%+v", podNamespace, podName, pi)
 
 	return pi, nil
 }
 
-func ExtractPodInfo(pod *v1.Pod) *PodInfo {
+// ExtractPodInfo returns the information of the pod needed to redirect its traffic. If excludeSecondaryInterfaces is set,
+// the interfaces of its secondary networks that are not captured are excluded from the redirection.
+func ExtractPodInfo(pod *v1.Pod, excludeSecondaryInterfaces bool) *PodInfo {
 	pi := &PodInfo{
 		Containers:        sets.New[string](),
 		Labels:            pod.Labels,
 		Annotations:       pod.Annotations,
 		ProxyEnvironments: make(map[string]string),
 	}
+	if excludeSecondaryInterfaces {
+		uncaptured, err := util.UncapturedInterfaces(pod)
+		if err != nil {
+			log.Warnf("failed to read the secondary networks of pod %s/%s, the traffic of all its interfaces is redirected: %v",
+				pod.Namespace, pod.Name, err)
+		}
+		pi.UncapturedInterfaces = uncaptured
+	}
 	for _, c := range containers(pod) {
 		pi.Containers.Insert(c.Name)
 		if c.Name == ISTIOPROXY {
@@ -114,5 +127,6 @@ func (pi PodInfo) String() string {
 	b.WriteString(fmt.Sprintf("  Annotations: %+v\n", pi.Annotations))
 	b.WriteString(fmt.Sprintf("  Envs: %+v\n", pi.ProxyEnvironments))
 	b.WriteString(fmt.Sprintf("  ProxyConfig: %+v\n", pi.ProxyEnvironments))
+	b.WriteString(fmt.Sprintf("  UncapturedInterfaces: %v\n", pi.UncapturedInterfaces))
 	return b.String()
 }
@@ -74,6 +74,7 @@ type Config struct {
 	PodNamespace                string                    `json:"pod_namespace"`
 	NativeNftables              bool                      `json:"native_nftables"`
 	EnableAmbientDetectionRetry bool                      `json:"enable_ambient_detection_retry"`
+	ExcludeSecondaryInterfaces  bool                      `json:"exclude_secondary_interfaces"`
 }
 
 // K8sArgs is the valid CNI_ARGS used for Kubernetes
@@ -274,7 +275,7 @@ func doAddRun(args *skel.CmdArgs, conf *Config, kClient kubernetes.Interface, ru
 	pi := &PodInfo{}
 	var k8sErr error
 	for attempt := 1; attempt <= podRetrievalMaxRetries; attempt++ {
-		pi, k8sErr = getK8sPodInfo(kClient, podName, podNamespace)
+		pi, k8sErr = getK8sPodInfo(kClient, podName, podNamespace, conf.ExcludeSecondaryInterfaces)
 		if k8sErr == nil {
 			break
 		}
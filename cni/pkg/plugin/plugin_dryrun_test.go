@@ -71,14 +71,14 @@ func generateMockGetNsFunc(netNs string) netNsFunc {
 	}
 }
 
-func buildDryrunConf() string {
+func buildDryrunConf(t *testing.T) string {
 	return fmt.Sprintf(
 		mockConfTmpl,
 		"1.0.0",
 		"1.0.0",
 		"eth0",
 		testSandboxDirectory,
-		filepath.Dir("/tmp"),
+		t.TempDir(),
 		false,
 		false,
 		"iptables",
@@ -86,7 +86,7 @@ func buildDryrunConf() string {
 }
 
 func TestIPTablesRuleGeneration(t *testing.T) {
-	cniConf := buildDryrunConf()
+	cniConf := buildDryrunConf(t)
 
 	customUID := int64(1000670000)
 	customGID := int64(1000670001)
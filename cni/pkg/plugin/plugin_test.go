@@ -19,6 +19,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/containernetworking/cni/pkg/skel"
@@ -28,6 +29,7 @@ import (
 
 	"istio.io/api/annotation"
 	"istio.io/api/label"
+	"istio.io/istio/cni/pkg/util"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube"
@@ -119,22 +121,22 @@ type mockInterceptRuleMgr struct {
 	lastRedirect []*Redirect
 }
 
-func buildMockConfWithRetryOption(ambientEnabled bool, enableAmbientDetectionRetry bool) string {
+func buildMockConfWithRetryOption(t *testing.T, ambientEnabled bool, enableAmbientDetectionRetry bool) string {
 	return fmt.Sprintf(
 		mockConfTmpl,
 		"1.0.0",
 		"1.0.0",
 		"eth0",
 		testSandboxDirectory,
-		"", // unused here
+		t.TempDir(), // keeps the plugin logs out of the source tree
 		ambientEnabled,
 		enableAmbientDetectionRetry,
 		"mock",
 	)
 }
 
-func buildMockConf(ambientEnabled bool) string {
-	return buildMockConfWithRetryOption(ambientEnabled, false)
+func buildMockConf(t *testing.T, ambientEnabled bool) string {
+	return buildMockConfWithRetryOption(t, ambientEnabled, false)
 }
 
 func buildFakePodAndNSForClient() (*corev1.Pod, *corev1.Namespace) {
@@ -259,7 +261,7 @@ func testDoAddRun(t *testing.T, stdinData, nsName string, objects ...runtime.Obj
 }
 
 func TestIsAmbientPod(t *testing.T) {
-	cniConf := buildMockConfWithRetryOption(true, true)
+	cniConf := buildMockConfWithRetryOption(t, true, true)
 	pod, ns := buildFakePodAndNSForClient()
 	ns.ObjectMeta.Labels = map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}
 
@@ -290,7 +292,7 @@ func TestIsAmbientPod(t *testing.T) {
 func TestCmdAddAmbientEnabledOnNS(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 	ns.ObjectMeta.Labels = map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}
@@ -305,7 +307,7 @@ func TestCmdAddAmbientEnabledOnNS(t *testing.T) {
 func TestCmdAddAmbientEnabledOnNSServerFails(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(true)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 	ns.ObjectMeta.Labels = map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}
@@ -320,7 +322,7 @@ func TestCmdAddAmbientEnabledOnNSServerFails(t *testing.T) {
 func TestCmdAddPodWithProxySidecarAmbientEnabledNS(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 
@@ -341,7 +343,7 @@ func TestCmdAddPodWithProxySidecarAmbientEnabledNS(t *testing.T) {
 func TestCmdAddPodWithGenericSidecar(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 
@@ -361,7 +363,7 @@ func TestCmdAddPodWithGenericSidecar(t *testing.T) {
 func TestCmdAddPodDisabledLabel(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 
@@ -380,7 +382,7 @@ func TestCmdAddPodDisabledLabel(t *testing.T) {
 func TestCmdAddPodEnabledNamespaceDisabled(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	pod, ns := buildFakePodAndNSForClient()
 
@@ -397,7 +399,7 @@ func TestCmdAddPodEnabledNamespaceDisabled(t *testing.T) {
 func TestCmdAddPodInExcludedNamespace(t *testing.T) {
 	serverClose := setupCNIEventClientWithMockServer(false)
 
-	cniConf := buildMockConf(true)
+	cniConf := buildMockConf(t, true)
 
 	excludedNS := "testExcludeNS"
 	pod, ns := buildFakePodAndNSForClient()
@@ -419,7 +421,7 @@ func TestCmdAddPodInExcludedNamespace(t *testing.T) {
 
 func TestCmdAdd(t *testing.T) {
 	pod, ns := buildFakePodAndNSForClient()
-	testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 }
 
 func TestCmdAddTwoContainersWithAnnotation(t *testing.T) {
@@ -429,7 +431,7 @@ func TestCmdAddTwoContainersWithAnnotation(t *testing.T) {
 	pod.Spec.Containers[1].Name = "istio-proxy"
 	pod.ObjectMeta.Annotations[injectAnnotationKey] = "false"
 
-	testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 }
 
 func TestCmdAddTwoContainersWithLabel(t *testing.T) {
@@ -438,7 +440,7 @@ func TestCmdAddTwoContainersWithLabel(t *testing.T) {
 	pod.Spec.Containers[1].Name = "istio-proxy"
 	pod.ObjectMeta.Annotations[label.SidecarInject.Name] = "false"
 
-	testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 }
 
 func TestCmdAddTwoContainers(t *testing.T) {
@@ -448,7 +450,7 @@ func TestCmdAddTwoContainers(t *testing.T) {
 	pod.Spec.Containers[1].Name = "istio-proxy"
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 
-	mockIntercept := testDoAddRun(t, buildMockConf(false), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, false), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) == 0 {
 		t.Fatal("expected nsenterFunc to be called")
@@ -467,7 +469,7 @@ func TestCmdAddTwoContainersWithStarInboundPort(t *testing.T) {
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 	pod.ObjectMeta.Annotations[includeInboundPortsKey] = "*"
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 1 {
 		t.Fatal("expected nsenterFunc to be called")
@@ -486,7 +488,7 @@ func TestCmdAddTwoContainersWithEmptyInboundPort(t *testing.T) {
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 	pod.ObjectMeta.Annotations[includeInboundPortsKey] = ""
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 1 {
 		t.Fatal("expected nsenterFunc to be called")
@@ -504,7 +506,7 @@ func TestCmdAddTwoContainersWithEmptyExcludeInboundPort(t *testing.T) {
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 	pod.ObjectMeta.Annotations[excludeInboundPortsKey] = ""
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 1 {
 		t.Fatal("expected nsenterFunc to be called")
@@ -522,7 +524,7 @@ func TestCmdAddTwoContainersWithExplictExcludeInboundPort(t *testing.T) {
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 	pod.ObjectMeta.Annotations[excludeInboundPortsKey] = "3306"
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) == 0 {
 		t.Fatal("expected nsenterFunc to be called")
@@ -533,12 +535,44 @@ func TestCmdAddTwoContainersWithExplictExcludeInboundPort(t *testing.T) {
 	}
 }
 
+func TestCmdAddTwoContainersWithSecondaryNetworks(t *testing.T) {
+	pod, ns := buildFakePodAndNSForClient()
+	pod.Spec.Containers[0].Name = "mockContainer"
+	pod.Spec.Containers[1].Name = "istio-proxy"
+	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
+	pod.ObjectMeta.Annotations[excludeInterfacesKey] = "eth1"
+	pod.ObjectMeta.Annotations[util.MultusNetworksAnnotation] = "macvlan,sriov@data0"
+	pod.ObjectMeta.Annotations[util.CaptureInterfacesAnnotation] = "sriov"
+
+	// The secondary networks are only excluded when enabled in the config
+	for _, tt := range []struct {
+		conf string
+		want string
+	}{
+		{conf: buildMockConf(t, false), want: "eth1"},
+		{
+			conf: strings.Replace(buildMockConf(t, false), `"plugin_log_level"`, `"exclude_secondary_interfaces": true, "plugin_log_level"`, 1),
+			want: "eth1,net1",
+		},
+	} {
+		mockIntercept := testDoAddRun(t, tt.conf, testNSName, pod, ns)
+
+		if len(mockIntercept.lastRedirect) == 0 {
+			t.Fatal("expected nsenterFunc to be called")
+		}
+		r := mockIntercept.lastRedirect[len(mockIntercept.lastRedirect)-1]
+		if r.excludeInterfaces != tt.want {
+			t.Fatalf("expect excludeInterfaces is %q, actual %v", tt.want, r.excludeInterfaces)
+		}
+	}
+}
+
 func TestCmdAddTwoContainersWithoutSideCar(t *testing.T) {
 	pod, ns := buildFakePodAndNSForClient()
 	pod.Spec.Containers[0].Name = "mockContainer"
 	pod.Spec.Containers[1].Name = "istio-proxy"
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 0 {
 		t.Fatal("Didn't Expect nsenterFunc to be called because this pod does not contain a sidecar")
@@ -548,7 +582,7 @@ func TestCmdAddTwoContainersWithoutSideCar(t *testing.T) {
 func TestCmdAddExcludePod(t *testing.T) {
 	pod, ns := buildFakePodAndNSForClient()
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), "testExcludeNS", pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), "testExcludeNS", pod, ns)
 	if len(mockIntercept.lastRedirect) != 0 {
 		t.Fatal("failed to exclude pod")
 	}
@@ -559,7 +593,7 @@ func TestCmdAddExcludePodWithIstioInitContainer(t *testing.T) {
 	pod.ObjectMeta.Annotations[sidecarStatusKey] = "true"
 	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "istio-init"})
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 0 {
 		t.Fatal("failed to exclude pod")
@@ -574,7 +608,7 @@ func TestCmdAddExcludePodWithEnvoyDisableEnv(t *testing.T) {
 		Env:  []corev1.EnvVar{{Name: "DISABLE_ENVOY", Value: "true"}},
 	})
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) != 0 {
 		t.Fatal("failed to exclude pod")
@@ -590,6 +624,7 @@ func TestCmdAddNoPrevResult(t *testing.T) {
          "sampleconfig": []
     },
     "loglevel": "debug",
+	"cni_agent_run_dir": "%s",
 	"ambient_enabled": %t,
 	"enablement_selectors": [
 		{
@@ -626,8 +661,8 @@ func TestCmdAddNoPrevResult(t *testing.T) {
     }`
 
 	pod, ns := buildFakePodAndNSForClient()
-	testDoAddRun(t, fmt.Sprintf(confNoPrevResult, false), testNSName, pod, ns)
-	testDoAddRun(t, fmt.Sprintf(confNoPrevResult, true), testNSName, pod, ns)
+	testDoAddRun(t, fmt.Sprintf(confNoPrevResult, t.TempDir(), false), testNSName, pod, ns)
+	testDoAddRun(t, fmt.Sprintf(confNoPrevResult, t.TempDir(), true), testNSName, pod, ns)
 }
 
 func TestCmdAddEnableDualStack(t *testing.T) {
@@ -640,7 +675,7 @@ func TestCmdAddEnableDualStack(t *testing.T) {
 		}, {Name: "mockContainer"},
 	}
 
-	mockIntercept := testDoAddRun(t, buildMockConf(true), testNSName, pod, ns)
+	mockIntercept := testDoAddRun(t, buildMockConf(t, true), testNSName, pod, ns)
 
 	if len(mockIntercept.lastRedirect) == 0 {
 		t.Fatal("expected nsenterFunc to be called")
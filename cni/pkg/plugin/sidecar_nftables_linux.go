@@ -22,32 +22,13 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 
 	"istio.io/istio/pkg/log"
-	"istio.io/istio/tools/common/config"
 	"istio.io/istio/tools/istio-nftables/pkg/nft"
 )
 
 // Program defines a method which programs nftables based on the parameters
 // provided in Redirect.
 func (n *nftables) Program(podName, netns string, rdrct *Redirect) error {
-	cfg := config.DefaultConfig()
-	cfg.HostFilesystemPodNetwork = true
-	cfg.NetworkNamespace = netns
-	cfg.ProxyPort = rdrct.targetPort
-	cfg.ProxyUID = rdrct.noRedirectUID
-	cfg.ProxyGID = rdrct.noRedirectGID
-	cfg.InboundInterceptionMode = rdrct.redirectMode
-	cfg.OutboundIPRangesInclude = rdrct.includeIPCidrs
-	cfg.InboundPortsExclude = rdrct.excludeInboundPorts
-	cfg.InboundPortsInclude = rdrct.includeInboundPorts
-	cfg.ExcludeInterfaces = rdrct.excludeInterfaces
-	cfg.OutboundPortsExclude = rdrct.excludeOutboundPorts
-	cfg.OutboundPortsInclude = rdrct.includeOutboundPorts
-	cfg.OutboundIPRangesExclude = rdrct.excludeIPCidrs
-	cfg.RerouteVirtualInterfaces = rdrct.rerouteVirtualInterfaces
-	cfg.RedirectDNS = rdrct.dnsRedirect
-	cfg.CaptureAllDNS = rdrct.dnsRedirect
-	cfg.DropInvalid = rdrct.invalidDrop
-	cfg.DualStack = rdrct.dualStack
+	cfg := rdrct.SidecarConfig(netns)
 
 	netNs, err := getNs(netns)
 	if err != nil {
@@ -23,7 +23,9 @@ import (
 
 	"istio.io/api/annotation"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
 	netutil "istio.io/istio/pkg/util/net"
+	"istio.io/istio/tools/common/config"
 	"istio.io/istio/tools/istio-iptables/pkg/cmd"
 )
 
@@ -95,6 +97,31 @@ type Redirect struct {
 	invalidDrop              bool
 }
 
+// SidecarConfig returns the configuration of the sidecar redirection rules of the pod network namespace.
+// Configuration only known within the network namespace is filled with FillConfigFromEnvironment.
+func (rdrct *Redirect) SidecarConfig(netns string) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.HostFilesystemPodNetwork = true
+	cfg.NetworkNamespace = netns
+	cfg.ProxyPort = rdrct.targetPort
+	cfg.ProxyUID = rdrct.noRedirectUID
+	cfg.ProxyGID = rdrct.noRedirectGID
+	cfg.InboundInterceptionMode = rdrct.redirectMode
+	cfg.OutboundIPRangesInclude = rdrct.includeIPCidrs
+	cfg.InboundPortsExclude = rdrct.excludeInboundPorts
+	cfg.InboundPortsInclude = rdrct.includeInboundPorts
+	cfg.ExcludeInterfaces = rdrct.excludeInterfaces
+	cfg.OutboundPortsExclude = rdrct.excludeOutboundPorts
+	cfg.OutboundPortsInclude = rdrct.includeOutboundPorts
+	cfg.OutboundIPRangesExclude = rdrct.excludeIPCidrs
+	cfg.RerouteVirtualInterfaces = rdrct.rerouteVirtualInterfaces
+	cfg.RedirectDNS = rdrct.dnsRedirect
+	cfg.CaptureAllDNS = rdrct.dnsRedirect
+	cfg.DropInvalid = rdrct.invalidDrop
+	cfg.DualStack = rdrct.dualStack
+	return cfg
+}
+
 type annotationValidationFunc func(value string) error
 
 type annotationParam struct {
@@ -276,6 +303,11 @@ func NewRedirect(pi *PodInfo) (*Redirect, error) {
 		return nil, fmt.Errorf("annotation value error for value %s; annotationFound = %t: %v",
 			"excludeInterfaces", isFound, valErr)
 	}
+	// The traffic of the secondary networks that are not captured is excluded as well
+	if len(pi.UncapturedInterfaces) > 0 {
+		excludeInterfaces := append(config.Split(redir.excludeInterfaces), pi.UncapturedInterfaces...)
+		redir.excludeInterfaces = strings.Join(slices.FilterDuplicates(excludeInterfaces), ",")
+	}
 	// kubeVirtInterfaces is deprecated, so prefer`reroute-virtual-interfaces` if both are defined.
 	isFound, redir.rerouteVirtualInterfaces, valErr = getAnnotationOrDefault("reroute-virtual-interfaces", pi.Annotations)
 	if valErr != nil {
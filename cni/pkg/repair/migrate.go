@@ -0,0 +1,161 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/plugin"
+)
+
+// MigrationAnnotation records the progress of the migration of a pod from iptables to nftables redirection.
+const MigrationAnnotation = "cni.istio.io/nftables-migration"
+
+// Phases of a migration, set as the value of MigrationAnnotation.
+const (
+	MigrationInstalling       = "installing"
+	MigrationVerifying        = "verifying"
+	MigrationRemovingIptables = "removing-iptables"
+	MigrationCompleted        = "completed"
+	MigrationFailed           = "failed"
+)
+
+const ReasonMigratePod = "MigratePodToNftables"
+
+// errNothingToMigrate is returned by a podMigrator when the pod has no Istio iptables rules.
+var errNothingToMigrate = errors.New("no istio iptables rules found")
+
+// migrationReport lists the rules of a pod migrated (or, in dry-run mode, that would be migrated).
+type migrationReport struct {
+	// IptablesRules are the Istio iptables rules removed, as iptables commands.
+	IptablesRules []string
+	// NftablesRules are the nftables rules added, as nft commands.
+	NftablesRules []string
+}
+
+// String returns the rules of the report, one per line.
+func (r migrationReport) String() string {
+	var b strings.Builder
+	b.WriteString("iptables rules removed:\n")
+	for _, rule := range r.IptablesRules {
+		b.WriteString("  " + rule + "\n")
+	}
+	b.WriteString("nftables rules added:\n")
+	for _, rule := range r.NftablesRules {
+		b.WriteString("  " + rule + "\n")
+	}
+	return b.String()
+}
+
+// podMigrator replaces the iptables redirection rules of a running pod with equivalent nftables rules.
+// progress is called as the migration enters each phase. In dry-run mode, the rules are only computed.
+type podMigrator func(pod *corev1.Pod, pi *plugin.PodInfo, dryRun bool, progress func(phase string)) (migrationReport, error)
+
+// matchesMigrationFilter returns true if the pod is a running sidecar pod that has not been migrated yet.
+func (c *Controller) matchesMigrationFilter(pod *corev1.Pod) bool {
+	if c.cfg.SidecarAnnotation != "" {
+		if _, ok := pod.Annotations[c.cfg.SidecarAnnotation]; !ok {
+			return false
+		}
+	}
+	if pod.Spec.HostNetwork || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+		return false
+	}
+	switch pod.Annotations[MigrationAnnotation] {
+	case MigrationCompleted, MigrationFailed:
+		return false
+	}
+	return true
+}
+
+// migratePod moves the traffic redirection of a running sidecar pod from iptables to nftables rules.
+// Once the rules of the pod were modified, a failed migration is not retried: the pod is annotated, and left for an
+// operator to investigate (or restart).
+func (c *Controller) migratePod(pod *corev1.Pod) error {
+	m := podsRepaired.With(typeLabel.Value(migrateType))
+	log := repairLog.WithLabels("pod", pod.Namespace+"/"+pod.Name)
+	key := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
+	// Finding the network namespace of a pod is expensive, so each pod is only handled once. Unlike repaired pods, the
+	// annotation persists the outcome of a migration across restarts of the controller.
+	if uid, f := c.migratedPods[key]; f && uid == pod.UID {
+		log.Debugf("Skipping pod, already handled")
+		return nil
+	}
+
+	dryRun := c.cfg.MigrateDryRun
+	// Whether the rules of the pod were modified
+	started := false
+	progress := func(phase string) {
+		if dryRun {
+			return
+		}
+		started = true
+		if err := c.annotateMigration(pod, phase); err != nil {
+			log.Warnf("failed to annotate migration phase %s: %v", phase, err)
+		}
+	}
+
+	report, err := c.migrator(pod, plugin.ExtractPodInfo(pod, c.cfg.ExcludeSecondaryInterfaces), dryRun, progress)
+	if errors.Is(err, errNothingToMigrate) {
+		log.Debugf("Skipping pod, no iptables rules to migrate")
+		c.migratedPods[key] = pod.UID
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+	if err != nil && !started {
+		// Nothing was changed yet, which may be transient (for instance, the pod network namespace was not found), so retry.
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return err
+	}
+	c.migratedPods[key] = pod.UID
+	if err != nil {
+		log.Errorf("failed to migrate pod to nftables: %v", err)
+		progress(MigrationFailed)
+		c.events.Write(pod, corev1.EventTypeWarning, ReasonMigratePod, "failed to migrate traffic redirection to nftables: %v", err)
+		m.With(resultLabel.Value(resultFail)).Increment()
+		return nil
+	}
+	if dryRun {
+		log.Infof("dry-run: pod would be migrated to nftables\n%s", report)
+		c.events.Write(pod, corev1.EventTypeNormal, ReasonMigratePod,
+			"dry-run: traffic redirection would be migrated to nftables, replacing %d iptables rules with %d nftables rules, "+
+				"the rules are listed in the logs of the istio-cni node agent", len(report.IptablesRules), len(report.NftablesRules))
+		m.With(resultLabel.Value(resultDryRun)).Increment()
+		return nil
+	}
+	progress(MigrationCompleted)
+	log.Infof("pod migrated to nftables, replacing %d iptables rules with %d nftables rules", len(report.IptablesRules), len(report.NftablesRules))
+	log.Debugf("migrated rules of the pod\n%s", report)
+	c.events.Write(pod, corev1.EventTypeNormal, ReasonMigratePod,
+		"traffic redirection migrated to nftables, replaced %d iptables rules with %d nftables rules",
+		len(report.IptablesRules), len(report.NftablesRules))
+	m.With(resultLabel.Value(resultSuccess)).Increment()
+	return nil
+}
+
+func (c *Controller) annotateMigration(pod *corev1.Pod, phase string) error {
+	patchBytes := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, MigrationAnnotation, phase)
+	// Both "pods" and "pods/status" can mutate the metadata. However, pods/status is lower privilege, so we use that instead.
+	_, err := c.client.Kube().CoreV1().Pods(pod.Namespace).Patch(context.Background(), pod.Name, types.MergePatchType,
+		[]byte(patchBytes), metav1.PatchOptions{}, "status")
+	return err
+}
@@ -0,0 +1,232 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/knftables"
+
+	"istio.io/istio/cni/pkg/plugin"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/tools/common/config"
+	iptablesbuilder "istio.io/istio/tools/istio-iptables/pkg/builder"
+	iptablescapture "istio.io/istio/tools/istio-iptables/pkg/capture"
+	iptablesconstants "istio.io/istio/tools/istio-iptables/pkg/constants"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+	nftablesbuilder "istio.io/istio/tools/istio-nftables/pkg/builder"
+	nftablescapture "istio.io/istio/tools/istio-nftables/pkg/capture"
+)
+
+// migrateRunningPod enters the provided pod, that is already running, and replaces its iptables redirection rules with
+// the equivalent nftables rules. The nftables rules are installed and verified before the iptables rules are removed,
+// so the traffic of the pod is redirected throughout the migration: while both are present, the first NAT rule matching
+// a connection wins, and both redirect to the same ports.
+func migrateRunningPod(pod *corev1.Pod, pi *plugin.PodInfo, dryRun bool, progress func(phase string)) (migrationReport, error) {
+	var report migrationReport
+	redirect, err := plugin.NewRedirect(pi)
+	if err != nil {
+		return report, fmt.Errorf("setup redirect: %v", err)
+	}
+	// See repairPod for why this runs in the host network namespace.
+	netns, err := runInHost(func() (string, error) { return getPodNetNs(pod) })
+	if err != nil {
+		return report, fmt.Errorf("get netns: %v", err)
+	}
+	netNs, err := ns.GetNS(netns)
+	if err != nil {
+		return report, fmt.Errorf("failed to open netns %q: %v", netns, err)
+	}
+	defer netNs.Close()
+
+	log := repairLog.WithLabels("pod", pod.Namespace+"/"+pod.Name, "netns", netns)
+	err = netNs.Do(func(ns.NetNS) error {
+		// Important: run within the pod network namespace since some attributes are namespace specific
+		cfg := redirect.SidecarConfig(netns)
+		if err := cfg.FillConfigFromEnvironment(); err != nil {
+			return err
+		}
+		m := &sidecarMigration{
+			log: log,
+			cfg: cfg,
+			ext: &dep.RealDependencies{UsePodScopedXtablesLock: true, NetworkNamespace: netns},
+		}
+		report, err = m.run(dryRun, progress)
+		return err
+	})
+	return report, err
+}
+
+type sidecarMigration struct {
+	log *istiolog.Scope
+	cfg *config.Config
+	ext dep.Dependencies
+}
+
+func (m *sidecarMigration) run(dryRun bool, progress func(phase string)) (migrationReport, error) {
+	var report migrationReport
+	leftovers, err := m.iptablesLeftovers()
+	if err != nil {
+		return report, err
+	}
+	for _, l := range leftovers {
+		report.IptablesRules = append(report.IptablesRules, l.rules...)
+	}
+	if len(report.IptablesRules) == 0 {
+		return report, errNothingToMigrate
+	}
+
+	if dryRun {
+		// Build the nftables rules against a fake, to report what would be installed.
+		mock := nftablesbuilder.NewMockNftables("", "")
+		nft, err := nftablescapture.NewNftablesConfigurator(m.cfg, func(knftables.Family, string) (nftablesbuilder.NftablesAPI, error) {
+			return mock, nil
+		})
+		if err != nil {
+			return report, err
+		}
+		nft.EnableRuleFingerprints()
+		tx, err := nft.Run()
+		if err != nil {
+			return report, fmt.Errorf("build nftables rules: %v", err)
+		}
+		report.NftablesRules = nftablesRules(tx)
+		return report, nil
+	}
+
+	progress(MigrationInstalling)
+	nft, err := nftablescapture.NewNftablesConfigurator(m.cfg, nil)
+	if err != nil {
+		return report, err
+	}
+	nft.EnableRuleFingerprints()
+	tx, err := nft.Run()
+	if err != nil {
+		return report, errors.Join(fmt.Errorf("install nftables rules: %v", err), m.removeNftables())
+	}
+	report.NftablesRules = nftablesRules(tx)
+
+	progress(MigrationVerifying)
+	drift, err := nft.Verify()
+	if err == nil && drift {
+		err = fmt.Errorf("nftables rules differ from the rules installed")
+	}
+	if err != nil {
+		return report, errors.Join(fmt.Errorf("verify nftables rules: %v", err), m.removeNftables())
+	}
+
+	// From now on, the nftables rules are kept: they redirect the traffic of the pod on their own.
+	progress(MigrationRemovingIptables)
+	for _, l := range leftovers {
+		for _, cmd := range iptablesbuilder.BuildCleanupFromState(l.state) {
+			_, _ = m.ext.Run(m.log, true, iptablesconstants.IPTables, l.version, nil, cmd...)
+		}
+	}
+	remaining, err := m.iptablesLeftovers()
+	if err != nil {
+		return report, err
+	}
+	for _, l := range remaining {
+		if len(l.state) > 0 {
+			return report, fmt.Errorf("istio iptables rules remain after cleanup: %+v", l.state)
+		}
+	}
+	return report, nil
+}
+
+// iptablesLeftovers is the Istio iptables state of a pod, for one iptables version.
+type iptablesLeftovers struct {
+	version *dep.IptablesVersion
+	state   map[string]struct{ Chains, Rules []string }
+	rules   []string
+}
+
+// iptablesLeftovers returns the Istio chains and jumps found in the iptables rules of the pod.
+func (m *sidecarMigration) iptablesLeftovers() ([]iptablesLeftovers, error) {
+	ipVersions := []bool{false}
+	if m.cfg.EnableIPv6 || m.cfg.DualStack {
+		ipVersions = append(ipVersions, true)
+	}
+	var res []iptablesLeftovers
+	for _, ipv6 := range ipVersions {
+		ver, err := m.ext.DetectIptablesVersion(ipv6)
+		if err != nil {
+			return nil, fmt.Errorf("detect iptables version: %v", err)
+		}
+		output, err := m.ext.Run(m.log, true, iptablesconstants.IPTablesSave, &ver, nil)
+		if err != nil {
+			return nil, fmt.Errorf("read iptables rules: %v", err)
+		}
+		state := iptablesbuilder.NewIptablesRuleBuilder(m.cfg).GetStateFromSave(output.String())
+		leftovers := iptablescapture.HasIstioLeftovers(state)
+		res = append(res, iptablesLeftovers{version: &ver, state: leftovers, rules: istioRules(ipv6, state, leftovers)})
+	}
+	return res, nil
+}
+
+// istioRules returns the rules in the Istio chains, and the jumps to them from the other chains, in the format of
+// iptables-save prefixed with the command and table.
+func istioRules(ipv6 bool, state map[string]map[string][]string, leftovers map[string]struct{ Chains, Rules []string }) []string {
+	cmd := "iptables"
+	if ipv6 {
+		cmd = "ip6tables"
+	}
+	var res []string
+	for _, table := range slices.Sorted(maps.Keys(leftovers)) {
+		l := leftovers[table]
+		istioChains := sets.New(l.Chains...)
+		jumps := sets.New(l.Rules...)
+		for _, chain := range slices.Sorted(maps.Keys(state[table])) {
+			for _, rule := range state[table][chain] {
+				if istioChains.Contains(chain) || jumps.Contains(rule) {
+					res = append(res, fmt.Sprintf("%s -t %s %s", cmd, table, rule))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// nftablesRules returns the rules added by the transaction, as nft commands.
+func nftablesRules(tx *knftables.Transaction) []string {
+	var res []string
+	for _, line := range strings.Split(tx.String(), "\n") {
+		if strings.HasPrefix(line, "add rule ") || strings.HasPrefix(line, "insert rule ") {
+			res = append(res, line)
+		}
+	}
+	return res
+}
+
+// removeNftables rolls back the nftables rules, leaving the iptables rules in charge of the redirection.
+func (m *sidecarMigration) removeNftables() error {
+	cleanup := *m.cfg
+	cleanup.CleanupOnly = true
+	nft, err := nftablescapture.NewNftablesConfigurator(&cleanup, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := nft.Run(); err != nil {
+		return fmt.Errorf("remove nftables rules: %v", err)
+	}
+	return nil
+}
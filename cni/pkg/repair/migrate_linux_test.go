@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+)
+
+const sidecarIptablesSave = `*nat
+:PREROUTING ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:ISTIO_INBOUND - [0:0]
+:ISTIO_OUTPUT - [0:0]
+:ISTIO_REDIRECT - [0:0]
+-A PREROUTING -p tcp -j ISTIO_INBOUND
+-A OUTPUT -j ISTIO_OUTPUT
+-A ISTIO_INBOUND -p tcp -j ISTIO_IN_REDIRECT
+-A ISTIO_OUTPUT -o lo -j RETURN
+-A ISTIO_OUTPUT -j ISTIO_REDIRECT
+-A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001
+COMMIT
+`
+
+// saveStub returns the iptables-save output, and records the other commands.
+type saveStub struct {
+	dep.DependenciesStub
+	save string
+}
+
+func (s *saveStub) Run(logger *istiolog.Scope, quietLogging bool, cmd constants.IptablesCmd, iptVer *dep.IptablesVersion,
+	stdin io.ReadSeeker, args ...string,
+) (*bytes.Buffer, error) {
+	if cmd == constants.IPTablesSave {
+		return bytes.NewBufferString(s.save), nil
+	}
+	return s.DependenciesStub.Run(logger, quietLogging, cmd, iptVer, stdin, args...)
+}
+
+func TestSidecarMigrationDryRun(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ProxyUID = "1337"
+	cfg.ProxyGID = "1337"
+	cfg.InboundPortsInclude = "*"
+	cfg.OutboundIPRangesInclude = "*"
+
+	t.Run("iptables rules", func(t *testing.T) {
+		ext := &saveStub{save: sidecarIptablesSave}
+		m := &sidecarMigration{log: repairLog, cfg: cfg, ext: ext}
+		report, err := m.run(true, func(string) { t.Fatal("dry-run should not report progress") })
+		assert.NoError(t, err)
+		// 2 jumps, and the 4 rules of the Istio chains
+		assert.Equal(t, report.IptablesRules, []string{
+			"iptables -t nat -A ISTIO_INBOUND -p tcp -j ISTIO_IN_REDIRECT",
+			"iptables -t nat -A ISTIO_OUTPUT -o lo -j RETURN",
+			"iptables -t nat -A ISTIO_OUTPUT -j ISTIO_REDIRECT",
+			"iptables -t nat -A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001",
+			"iptables -t nat -A OUTPUT -j ISTIO_OUTPUT",
+			"iptables -t nat -A PREROUTING -p tcp -j ISTIO_INBOUND",
+		})
+		assert.Equal(t, len(report.NftablesRules) > 0, true)
+		for _, rule := range report.NftablesRules {
+			assert.Equal(t, strings.HasPrefix(rule, "add rule inet istio-proxy-") || strings.HasPrefix(rule, "insert rule inet istio-proxy-"), true)
+		}
+		// Nothing was removed
+		assert.Equal(t, len(ext.ExecutedAll), 0)
+	})
+	t.Run("no iptables rules", func(t *testing.T) {
+		m := &sidecarMigration{log: repairLog, cfg: cfg, ext: &saveStub{save: "*nat\n:OUTPUT ACCEPT [0:0]\nCOMMIT\n"}}
+		_, err := m.run(true, func(string) {})
+		assert.Equal(t, err, errNothingToMigrate)
+	})
+}
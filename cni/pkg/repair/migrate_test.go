@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repair
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/plugin"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+func makeRunningPod(name string, annotations map[string]string) *corev1.Pod {
+	pod := makePod(makePodArgs{PodName: name, Annotations: annotations, InitContainerStatus: &workingInitContainer})
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.PodIP = "10.0.0.1"
+	return pod
+}
+
+var (
+	iptablesPod  = makeRunningPod("iptables-pod", map[string]string{"sidecar.istio.io/status": "something"})
+	noRulesPod   = makeRunningPod("no-rules-pod", map[string]string{"sidecar.istio.io/status": "something"})
+	noSidecarPod = makeRunningPod("no-sidecar-pod", nil)
+	migratedPod  = makeRunningPod("migrated-pod", map[string]string{
+		"sidecar.istio.io/status": "something",
+		MigrationAnnotation:       MigrationCompleted,
+	})
+)
+
+// fakeMigrator migrates the pods with iptables rules, and records the phases of each migration.
+type fakeMigrator struct {
+	mu     sync.Mutex
+	err    error
+	phases map[string][]string
+}
+
+func (f *fakeMigrator) migrate(pod *corev1.Pod, _ *plugin.PodInfo, dryRun bool, progress func(phase string)) (migrationReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if pod.Name != iptablesPod.Name {
+		if pod.Name != noRulesPod.Name {
+			return migrationReport{}, fmt.Errorf("unexpected migration of pod %s", pod.Name)
+		}
+		return migrationReport{}, errNothingToMigrate
+	}
+	report := migrationReport{
+		IptablesRules: []string{"iptables -t nat -A OUTPUT -j ISTIO_OUTPUT"},
+		NftablesRules: []string{"add rule inet istio-proxy-nat output counter jump istio-output"},
+	}
+	if dryRun {
+		return report, nil
+	}
+	for _, phase := range []string{MigrationInstalling, MigrationVerifying} {
+		f.phases[pod.Name] = append(f.phases[pod.Name], phase)
+		progress(phase)
+	}
+	if f.err != nil {
+		return report, f.err
+	}
+	f.phases[pod.Name] = append(f.phases[pod.Name], MigrationRemovingIptables)
+	progress(MigrationRemovingIptables)
+	return report, nil
+}
+
+func TestMigratePods(t *testing.T) {
+	tests := []struct {
+		name            string
+		dryRun          bool
+		err             error
+		wantAnnotations map[string]string
+		wantPhases      map[string][]string
+		wantTags        map[string]string
+	}{
+		{
+			name: "migrate",
+			wantAnnotations: map[string]string{
+				iptablesPod.Name:  MigrationCompleted,
+				noRulesPod.Name:   "",
+				noSidecarPod.Name: "",
+				migratedPod.Name:  MigrationCompleted,
+			},
+			wantPhases: map[string][]string{iptablesPod.Name: {MigrationInstalling, MigrationVerifying, MigrationRemovingIptables}},
+			wantTags:   map[string]string{"result": resultSuccess, "type": migrateType},
+		},
+		{
+			name: "failed migration",
+			err:  fmt.Errorf("nftables rules differ"),
+			wantAnnotations: map[string]string{
+				iptablesPod.Name:  MigrationFailed,
+				noRulesPod.Name:   "",
+				noSidecarPod.Name: "",
+				migratedPod.Name:  MigrationCompleted,
+			},
+			wantPhases: map[string][]string{iptablesPod.Name: {MigrationInstalling, MigrationVerifying}},
+			wantTags:   map[string]string{"result": resultFail, "type": migrateType},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			wantAnnotations: map[string]string{
+				iptablesPod.Name:  "",
+				noRulesPod.Name:   "",
+				noSidecarPod.Name: "",
+				migratedPod.Name:  MigrationCompleted,
+			},
+			wantPhases: map[string][]string{},
+			wantTags:   map[string]string{"result": resultDryRun, "type": migrateType},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := monitortest.New(t)
+			client := fakeClient(iptablesPod, noRulesPod, noSidecarPod, migratedPod)
+			c, err := NewRepairController(client, config.RepairConfig{
+				SidecarAnnotation: "sidecar.istio.io/status",
+				MigratePods:       true,
+				MigrateDryRun:     tt.dryRun,
+				NativeNftables:    true,
+			})
+			assert.NoError(t, err)
+			migrator := &fakeMigrator{err: tt.err, phases: map[string][]string{}}
+			c.migrator = migrator.migrate
+			t.Cleanup(func() {
+				assert.NoError(t, c.queue.WaitForClose(time.Second))
+			})
+			stop := test.NewStop(t)
+			client.RunAndWait(stop)
+			go c.Run(stop)
+			kube.WaitForCacheSync("test", stop, c.queue.HasSynced)
+
+			assert.EventuallyEqual(t, func() map[string]string {
+				annotations := map[string]string{}
+				for _, pod := range c.pods.List(metav1.NamespaceAll, klabels.Everything()) {
+					annotations[pod.Name] = pod.Annotations[MigrationAnnotation]
+				}
+				return annotations
+			}, tt.wantAnnotations)
+			mt.Assert(podsRepaired.Name(), tt.wantTags, monitortest.Exactly(1))
+			mt.Assert(podsRepaired.Name(), map[string]string{"result": resultSkip, "type": migrateType}, monitortest.Exactly(1))
+			migrator.mu.Lock()
+			defer migrator.mu.Unlock()
+			assert.Equal(t, migrator.phases, tt.wantPhases)
+		})
+	}
+}
+
+func TestMigratePodsRequiresNftables(t *testing.T) {
+	_, err := NewRepairController(fakeClient(), config.RepairConfig{MigratePods: true})
+	assert.Error(t, err)
+}
+
+func TestMigratePodsRepairsBrokenPods(t *testing.T) {
+	client := fakeClient(iptablesPod, brokenPodWaiting)
+	c, err := NewRepairController(client, config.RepairConfig{
+		InitContainerName:  constants.ValidationContainerName,
+		InitExitCode:       126,
+		InitTerminationMsg: "Died for some reason",
+		LabelPods:          true,
+		LabelKey:           "testkey",
+		LabelValue:         "testval",
+		MigratePods:        true,
+		NativeNftables:     true,
+	})
+	assert.NoError(t, err)
+	migrator := &fakeMigrator{phases: map[string][]string{}}
+	c.migrator = migrator.migrate
+	t.Cleanup(func() {
+		assert.NoError(t, c.queue.WaitForClose(time.Second))
+	})
+	stop := test.NewStop(t)
+	client.RunAndWait(stop)
+	go c.Run(stop)
+	kube.WaitForCacheSync("test", stop, c.queue.HasSynced)
+
+	// The running pod is migrated, and the broken pod is labeled.
+	assert.EventuallyEqual(t, func() map[string]string {
+		res := map[string]string{}
+		for _, pod := range c.pods.List(metav1.NamespaceAll, klabels.Everything()) {
+			res[pod.Name] = pod.Annotations[MigrationAnnotation] + "," + pod.Labels["testkey"]
+		}
+		return res
+	}, map[string]string{iptablesPod.Name: MigrationCompleted + ",", brokenPodWaiting.Name: ",testval"})
+}
@@ -0,0 +1,28 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package repair
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/cni/pkg/plugin"
+)
+
+func migrateRunningPod(pod *corev1.Pod, pi *plugin.PodInfo, dryRun bool, progress func(phase string)) (migrationReport, error) {
+	panic("not implemented")
+}
@@ -19,15 +19,17 @@ import (
 )
 
 var (
-	typeLabel  = monitoring.CreateLabel("type")
-	deleteType = "delete"
-	repairType = "repair"
-	labelType  = "label"
+	typeLabel   = monitoring.CreateLabel("type")
+	deleteType  = "delete"
+	repairType  = "repair"
+	labelType   = "label"
+	migrateType = "migrate"
 
 	resultLabel   = monitoring.CreateLabel("result")
 	resultSuccess = "success"
 	resultSkip    = "skip"
 	resultFail    = "fail"
+	resultDryRun  = "dry-run"
 
 	podsRepaired = monitoring.NewSum(
 		"istio_cni_repair_pods_repaired_total",
@@ -37,14 +37,21 @@ type Controller struct {
 	cfg          config.RepairConfig
 	events       kclient.EventRecorder
 	repairedPods map[types.NamespacedName]types.UID
+	migratedPods map[types.NamespacedName]types.UID
+	migrator     podMigrator
 }
 
 func NewRepairController(client kube.Client, cfg config.RepairConfig) (*Controller, error) {
+	if cfg.MigratePods && !cfg.NativeNftables {
+		return nil, fmt.Errorf("migrating pods to nftables requires native nftables to be enabled")
+	}
 	c := &Controller{
 		cfg:          cfg,
 		client:       client,
 		events:       kclient.NewEventRecorder(client, "cni-repair"),
 		repairedPods: map[types.NamespacedName]types.UID{},
+		migratedPods: map[types.NamespacedName]types.UID{},
+		migrator:     migrateRunningPod,
 	}
 	fieldSelectors := []string{}
 	if cfg.FieldSelectors != "" {
@@ -74,6 +81,7 @@ func (c *Controller) Reconcile(key types.NamespacedName) error {
 	pod := c.pods.Get(key.Name, key.Namespace)
 	if pod == nil {
 		delete(c.repairedPods, key) // Ensure we do not leak
+		delete(c.migratedPods, key)
 		// Pod deleted, nothing to do
 		return nil
 	}
@@ -81,6 +89,11 @@ func (c *Controller) Reconcile(key types.NamespacedName) error {
 }
 
 func (c *Controller) ReconcilePod(pod *corev1.Pod) (err error) {
+	// Broken pods are not running, so a pod is either repaired or migrated.
+	if c.cfg.MigratePods && c.matchesMigrationFilter(pod) {
+		repairLog.Debugf("Migrating pod %s", pod.Name)
+		return c.migratePod(pod)
+	}
 	if !c.matchesFilter(pod) {
 		return err // Skip, pod doesn't need repair
 	}
@@ -97,8 +110,7 @@ func (c *Controller) ReconcilePod(pod *corev1.Pod) (err error) {
 }
 
 // redirectRunningPod dynamically enters the provided pod, that is already running, and programs it's networking configuration.
-func redirectRunningPod(pod *corev1.Pod, netns string) error {
-	pi := plugin.ExtractPodInfo(pod)
+func redirectRunningPod(pod *corev1.Pod, pi *plugin.PodInfo, netns string) error {
 	redirect, err := plugin.NewRedirect(pi)
 	if err != nil {
 		return fmt.Errorf("setup redirect: %v", err)
@@ -112,8 +124,7 @@ func redirectRunningPod(pod *corev1.Pod, netns string) error {
 
 // redirectRunningPodNFT dynamically enters the provided pod, that is already running,
 // and programs it's networking configuration using nftables rules.
-func redirectRunningPodNFT(pod *corev1.Pod, netns string) error {
-	pi := plugin.ExtractPodInfo(pod)
+func redirectRunningPodNFT(pod *corev1.Pod, pi *plugin.PodInfo, netns string) error {
 	redirect, err := plugin.NewRedirect(pi)
 	if err != nil {
 		return fmt.Errorf("setup redirect: %v", err)
@@ -19,6 +19,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/cni/pkg/plugin"
 )
 
 // repairPod actually dynamically repairs a pod. This is done by entering the pods network namespace and setting up rules.
@@ -62,7 +64,7 @@ func (c *Controller) repairPod(pod *corev1.Pod) error {
 		redirector = redirectRunningPodNFT
 	}
 
-	if err := redirector(pod, netns); err != nil {
+	if err := redirector(pod, plugin.ExtractPodInfo(pod, c.cfg.ExcludeSecondaryInterfaces), netns); err != nil {
 		log.Errorf("failed to setup redirection: %v", err)
 		m.With(resultLabel.Value(resultFail)).Increment()
 		return err
@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficmanager
+
+import (
+	"fmt"
+
+	"istio.io/istio/cni/pkg/config"
+	"istio.io/istio/cni/pkg/ebpf"
+	istiolog "istio.io/istio/pkg/log"
+)
+
+// EbpfTrafficManager implements TrafficRuleManager with eBPF programs in the pod network namespace.
+// Host rules, which SNAT the health check probes of the node to the address exempted by the pod programs,
+// are managed by the iptables or nftables backend.
+type EbpfTrafficManager struct {
+	host    TrafficRuleManager
+	podEbpf *ebpf.EbpfConfigurator
+}
+
+var _ TrafficRuleManager = &EbpfTrafficManager{}
+
+// NewEbpfTrafficManager creates host iptables or nftables-based and pod eBPF-based traffic managers
+func NewEbpfTrafficManager(cfg *TrafficRuleManagerConfig) (hostManager, podManager TrafficRuleManager, err error) {
+	if cfg.PodConfig != nil && cfg.PodConfig.RedirectDNS {
+		return nil, nil, fmt.Errorf("%w, disable DNS capture to use it", ebpf.ErrDNSCaptureUnsupported)
+	}
+	if cfg.NativeNftables {
+		hostManager, _, err = NewNftablesTrafficManager(cfg)
+	} else {
+		hostManager, _, err = NewIptablesTrafficManager(cfg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostManager = &EbpfTrafficManager{
+		host:    hostManager,
+		podEbpf: nil, // Host manager doesn't need pod eBPF
+	}
+
+	podManager = &EbpfTrafficManager{
+		host:    nil, // Pod manager doesn't need host rules
+		podEbpf: ebpf.NewEbpfConfigurator(cfg.PodConfig, cfg.NlDeps),
+	}
+
+	return hostManager, podManager, nil
+}
+
+// CreateInpodRules attaches the eBPF redirection programs within a pod's network namespace
+func (m *EbpfTrafficManager) CreateInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) error {
+	if m.podEbpf == nil {
+		return fmt.Errorf("pod eBPF configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podEbpf.CreateInpodRules(log, podOverrides)
+}
+
+// DeleteInpodRules detaches the eBPF redirection programs from a pod's network namespace
+func (m *EbpfTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
+	if m.podEbpf == nil {
+		return fmt.Errorf("pod eBPF configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podEbpf.DeleteInpodRules(log)
+}
+
+// VerifyInpodRules reports whether the eBPF programs within a pod's network namespace differ from the expected programs
+func (m *EbpfTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	if m.podEbpf == nil {
+		return false, fmt.Errorf("pod eBPF configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podEbpf.VerifyInpodRules(log, podOverrides)
+}
+
+// CreateHostRulesForHealthChecks creates host-level rules for health check handling
+func (m *EbpfTrafficManager) CreateHostRulesForHealthChecks() error {
+	if m.host == nil {
+		return fmt.Errorf("host traffic manager not available (this is likely a pod-only traffic manager)")
+	}
+	return m.host.CreateHostRulesForHealthChecks()
+}
+
+// DeleteHostRules removes host-level rules
+func (m *EbpfTrafficManager) DeleteHostRules() {
+	if m.host != nil {
+		m.host.DeleteHostRules()
+	}
+}
+
+// ReconcileModeEnabled returns true if reconciliation mode is enabled
+func (m *EbpfTrafficManager) ReconcileModeEnabled() bool {
+	if m.podEbpf == nil {
+		// Default to false if pod eBPF configurator not available
+		return false
+	}
+	return m.podEbpf.ReconcileModeEnabled()
+}
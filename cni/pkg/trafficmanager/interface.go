@@ -26,6 +26,9 @@ import (
 type TrafficRuleManager interface {
 	CreateInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) error
 	DeleteInpodRules(log *istiolog.Scope) error
+	// VerifyInpodRules reports whether the rules in the current network namespace differ from the rules
+	// CreateInpodRules would create.
+	VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error)
 	CreateHostRulesForHealthChecks() error
 	DeleteHostRules()
 	ReconcileModeEnabled() bool
@@ -35,6 +38,10 @@ type TrafficRuleManagerConfig struct {
 	// Use native nftables instead of iptables
 	NativeNftables bool
 
+	// Use eBPF programs instead of iptables or nftables rules in the pod network namespace.
+	// Host rules still use the backend selected by NativeNftables.
+	EbpfRedirection bool
+
 	// Host-level configuration
 	HostConfig *config.AmbientConfig
 
@@ -50,6 +57,9 @@ type TrafficRuleManagerConfig struct {
 
 // NewTrafficRuleManager creates both host and pod traffic rule managers based on configuration
 func NewTrafficRuleManager(cfg *TrafficRuleManagerConfig) (hostManager, podManager TrafficRuleManager, err error) {
+	if cfg.EbpfRedirection {
+		return NewEbpfTrafficManager(cfg)
+	}
 	if cfg.NativeNftables {
 		return NewNftablesTrafficManager(cfg)
 	}
@@ -89,6 +89,14 @@ func (m *IptablesTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
 	return m.podIptables.DeleteInpodRules(log)
 }
 
+// VerifyInpodRules reports whether the iptables rules within a pod's network namespace differ from the expected rules
+func (m *IptablesTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	if m.podIptables == nil {
+		return false, fmt.Errorf("pod iptables configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podIptables.VerifyInpodRules(log, podOverrides)
+}
+
 // CreateHostRulesForHealthChecks creates host-level iptables rules for health check handling
 func (m *IptablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	if m.hostIptables == nil {
@@ -72,6 +72,14 @@ func (m *NftablesTrafficManager) DeleteInpodRules(log *istiolog.Scope) error {
 	return m.podNftables.DeleteInpodRules(log)
 }
 
+// VerifyInpodRules reports whether the nftables rules within a pod's network namespace differ from the expected rules
+func (m *NftablesTrafficManager) VerifyInpodRules(log *istiolog.Scope, podOverrides config.PodLevelOverrides) (bool, error) {
+	if m.podNftables == nil {
+		return false, fmt.Errorf("pod nftables configurator not available (this is likely a host-only traffic manager)")
+	}
+	return m.podNftables.VerifyInpodRules(log, podOverrides)
+}
+
 // CreateHostRulesForHealthChecks creates host-level nftables rules for health check handling
 func (m *NftablesTrafficManager) CreateHostRulesForHealthChecks() error {
 	if m.hostNftables == nil {
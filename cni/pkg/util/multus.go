@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// MultusNetworksAnnotation requests the secondary networks attached to a pod by Multus, as a list of
+	// NetworkAttachmentDefinitions.
+	MultusNetworksAnnotation = "k8s.v1.cni.cncf.io/networks"
+	// MultusNetworkStatusAnnotation is set by Multus to the networks attached to a pod, with their interfaces.
+	MultusNetworkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+
+	// CaptureInterfacesAnnotation selects the interfaces of the secondary networks of a pod whose traffic is
+	// redirected, as a comma-separated list of interface names or NetworkAttachmentDefinition names ("name" in the
+	// namespace of the pod, or "namespace/name"). "*" selects all of them. By default, only the traffic of the
+	// interface of the cluster default network is redirected.
+	CaptureInterfacesAnnotation = "istio.io/capture-interfaces"
+	// BypassedInterfacesAnnotation is set by the CNI agent on the pods enrolled in ambient to the interfaces of their
+	// secondary networks whose traffic is not redirected.
+	BypassedInterfacesAnnotation = "ambient.istio.io/bypassed-interfaces"
+
+	// AmbientEnrolledCondition is set by the CNI agent on the pods enrolled in ambient. Its message lists the
+	// interfaces of their secondary networks whose traffic is not redirected, if any.
+	AmbientEnrolledCondition corev1.PodConditionType = "ambient.istio.io/Enrolled"
+
+	ReasonAmbientEnrolled             = "Enrolled"
+	ReasonSecondaryInterfacesBypassed = "SecondaryInterfacesBypassed"
+)
+
+// SecondaryNetwork is a network attached to a pod, in addition to the cluster default network.
+type SecondaryNetwork struct {
+	// Name of the NetworkAttachmentDefinition, as namespace/name.
+	Name string
+	// Interface of the network in the pod.
+	Interface string
+}
+
+// multusNetworkStatus is an entry of the network status annotation.
+type multusNetworkStatus struct {
+	Name      string `json:"name"`
+	Interface string `json:"interface"`
+	Default   bool   `json:"default"`
+}
+
+// multusNetworkSelection is an entry of the networks annotation, in its JSON format.
+type multusNetworkSelection struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Interface string `json:"interface"`
+}
+
+// SecondaryNetworks returns the secondary networks of the pod. They are read from the network status set by Multus,
+// or if it is not set yet, from the networks requested by the pod, named as Multus names their interfaces.
+//
+// Only the pod annotations are read, not the NetworkAttachmentDefinitions, which hold the configuration of the networks
+// but not the interfaces they get in the pod. The networks Multus attaches without being requested by the pod, like its
+// default networks, are only known once Multus set the network status, which is after the Istio CNI plugin ran in the
+// delegate of the cluster default network: they are only bypassed for the pods enrolled by the node agent once the pod
+// is running, e.g. when the pod or its namespace is later labeled for ambient.
+func SecondaryNetworks(pod *corev1.Pod) ([]SecondaryNetwork, error) {
+	if status, ok := pod.Annotations[MultusNetworkStatusAnnotation]; ok {
+		var statuses []multusNetworkStatus
+		if err := json.Unmarshal([]byte(status), &statuses); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", MultusNetworkStatusAnnotation, err)
+		}
+		var networks []SecondaryNetwork
+		for _, s := range statuses {
+			if s.Default || s.Interface == "" {
+				continue
+			}
+			networks = append(networks, SecondaryNetwork{Name: qualifyNetworkName(pod.Namespace, s.Name), Interface: s.Interface})
+		}
+		return networks, nil
+	}
+
+	requested := strings.TrimSpace(pod.Annotations[MultusNetworksAnnotation])
+	if requested == "" {
+		return nil, nil
+	}
+	var selections []multusNetworkSelection
+	if strings.HasPrefix(requested, "[") {
+		if err := json.Unmarshal([]byte(requested), &selections); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", MultusNetworksAnnotation, err)
+		}
+	} else {
+		// The short format is a comma-separated list of [namespace/]name[@interface]
+		for _, s := range strings.Split(requested, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			var sel multusNetworkSelection
+			s, sel.Interface, _ = strings.Cut(s, "@")
+			if ns, name, found := strings.Cut(s, "/"); found {
+				sel.Namespace, sel.Name = ns, name
+			} else {
+				sel.Name = s
+			}
+			selections = append(selections, sel)
+		}
+	}
+	networks := make([]SecondaryNetwork, 0, len(selections))
+	for i, sel := range selections {
+		if sel.Name == "" {
+			return nil, fmt.Errorf("invalid %s annotation: network without a name", MultusNetworksAnnotation)
+		}
+		ns := sel.Namespace
+		if ns == "" {
+			ns = pod.Namespace
+		}
+		iface := sel.Interface
+		if iface == "" {
+			// Multus names the interfaces after their position in the annotation
+			iface = fmt.Sprintf("net%d", i+1)
+		}
+		networks = append(networks, SecondaryNetwork{Name: ns + "/" + sel.Name, Interface: iface})
+	}
+	return networks, nil
+}
+
+// UncapturedInterfaces returns the interfaces of the secondary networks of the pod whose traffic is not redirected,
+// as selected by the CaptureInterfacesAnnotation. The interface of the cluster default network is always captured.
+func UncapturedInterfaces(pod *corev1.Pod) ([]string, error) {
+	networks, err := SecondaryNetworks(pod)
+	if err != nil || len(networks) == 0 {
+		return nil, err
+	}
+	captured := sets.New[string]()
+	for _, c := range strings.Split(pod.Annotations[CaptureInterfacesAnnotation], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			// An entry is either an interface, or a NetworkAttachmentDefinition
+			captured.InsertAll(c, qualifyNetworkName(pod.Namespace, c))
+		}
+	}
+	if captured.Contains("*") {
+		return nil, nil
+	}
+	uncaptured := sets.New[string]()
+	for _, n := range networks {
+		if !captured.Contains(n.Interface) && !captured.Contains(n.Name) {
+			uncaptured.Insert(n.Interface)
+		}
+	}
+	return sets.SortedList(uncaptured), nil
+}
+
+// qualifyNetworkName returns the name of a NetworkAttachmentDefinition as namespace/name.
+func qualifyNetworkName(namespace, name string) string {
+	if strings.Contains(name, "/") {
+		return name
+	}
+	return namespace + "/" + name
+}
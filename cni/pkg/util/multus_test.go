@@ -0,0 +1,157 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func multusPod(annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "app", Annotations: annotations}}
+}
+
+func TestSecondaryNetworks(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        []SecondaryNetwork
+		wantErr     bool
+	}{
+		{
+			name: "no networks",
+		},
+		{
+			name:        "short format",
+			annotations: map[string]string{MultusNetworksAnnotation: "macvlan, other/sriov@data0,bridge"},
+			want: []SecondaryNetwork{
+				{Name: "app/macvlan", Interface: "net1"},
+				{Name: "other/sriov", Interface: "data0"},
+				{Name: "app/bridge", Interface: "net3"},
+			},
+		},
+		{
+			name: "json format",
+			annotations: map[string]string{
+				MultusNetworksAnnotation: `[{"name":"macvlan"},{"name":"sriov","namespace":"other","interface":"data0"}]`,
+			},
+			want: []SecondaryNetwork{
+				{Name: "app/macvlan", Interface: "net1"},
+				{Name: "other/sriov", Interface: "data0"},
+			},
+		},
+		{
+			name: "network status preferred",
+			annotations: map[string]string{
+				MultusNetworksAnnotation: "macvlan",
+				MultusNetworkStatusAnnotation: `[
+					{"name":"k8s-pod-network","interface":"eth0","default":true},
+					{"name":"app/macvlan","interface":"mac0"}
+				]`,
+			},
+			want: []SecondaryNetwork{{Name: "app/macvlan", Interface: "mac0"}},
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{MultusNetworksAnnotation: `[{"name":`},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SecondaryNetworks(multusPod(tt.annotations))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestUncapturedInterfaces(t *testing.T) {
+	networks := "macvlan, other/sriov@data0, bridge"
+	tests := []struct {
+		name    string
+		capture string
+		want    []string
+	}{
+		{name: "default", want: []string{"data0", "net1", "net3"}},
+		{name: "all", capture: "*", want: nil},
+		{name: "by interface", capture: "net1, data0", want: []string{"net3"}},
+		{name: "by network", capture: "bridge,other/sriov", want: []string{"net1"}},
+		{name: "network of another namespace", capture: "app/sriov", want: []string{"data0", "net1", "net3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UncapturedInterfaces(multusPod(map[string]string{
+				MultusNetworksAnnotation:    networks,
+				CaptureInterfacesAnnotation: tt.capture,
+			}))
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestAnnotateEnrolledPodBypassedInterfaces(t *testing.T) {
+	pod := multusPod(nil)
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	client := fake.NewClientset(pod)
+
+	assert.NoError(t, AnnotateEnrolledPod(client, &pod.ObjectMeta, []string{"net1", "net2"}))
+	got, err := client.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, got.Annotations, map[string]string{
+		annotation.AmbientRedirection.Name: constants.AmbientRedirectionEnabled,
+		BypassedInterfacesAnnotation:       "net1,net2",
+	})
+	assert.Equal(t, len(got.Status.Conditions), 2)
+	enrolled := enrolledCondition(got)
+	assert.Equal(t, enrolled.Status, corev1.ConditionTrue)
+	assert.Equal(t, enrolled.Reason, ReasonSecondaryInterfacesBypassed)
+	assert.Equal(t, strings.HasSuffix(enrolled.Message, ": net1,net2"), true)
+
+	assert.NoError(t, AnnotateEnrolledPod(client, &pod.ObjectMeta, nil))
+	got, err = client.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, got.Annotations, map[string]string{annotation.AmbientRedirection.Name: constants.AmbientRedirectionEnabled})
+	assert.Equal(t, len(got.Status.Conditions), 2)
+	assert.Equal(t, enrolledCondition(got).Reason, ReasonAmbientEnrolled)
+
+	assert.NoError(t, AnnotateUnenrollPod(client, &pod.ObjectMeta))
+	got, err = client.CoreV1().Pods(pod.Namespace).Get(context.Background(), pod.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(got.Annotations), 0)
+	// Only the enrollment condition is removed.
+	assert.Equal(t, got.Status.Conditions, pod.Status.Conditions)
+}
+
+func enrolledCondition(pod *corev1.Pod) *corev1.PodCondition {
+	return slices.FindFunc(pod.Status.Conditions, func(c corev1.PodCondition) bool {
+		return c.Type == AmbientEnrolledCondition
+	})
+}
@@ -142,3 +142,33 @@ func MarshalCNIConfig(cniConfigMap map[string]any) ([]byte, error) {
 	cniConfig = append(cniConfig, "\n"...)
 	return cniConfig, nil
 }
+
+// GetChainedConfigList returns the network config list in which Istio CNI is chained. This is the config itself,
+// unless it is the config of the Multus thin plugin, delegating the cluster default network to a config of its own:
+// Istio CNI is then chained in that delegate, so it runs once per pod, for the interface of the default network rather
+// than after all the networks of the pod. A delegate that is a single network config is turned into a list.
+func GetChainedConfigList(cniConfigMap map[string]any) (map[string]any, error) {
+	if cniConfigMap["type"] != "multus" {
+		return cniConfigMap, nil
+	}
+	delegates, ok := cniConfigMap["delegates"].([]any)
+	if !ok || len(delegates) == 0 {
+		// The default network is referenced by name, and cannot be modified here
+		return cniConfigMap, nil
+	}
+	delegate, ok := delegates[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("error reading the cluster default network delegate from Multus CNI config")
+	}
+	if _, ok := delegate["plugins"]; ok {
+		return delegate, nil
+	}
+	list := map[string]any{
+		"name":       delegate["name"],
+		"cniVersion": delegate["cniVersion"],
+		"plugins":    []any{delegate},
+	}
+	delete(delegate, "cniVersion")
+	delegates[0] = list
+	return list, nil
+}
@@ -16,10 +16,12 @@ package util
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/netip"
 	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -32,23 +34,52 @@ import (
 	"istio.io/istio/pkg/log"
 )
 
-var annotationEnabledPatch = []byte(fmt.Sprintf(
-	`{"metadata":{"annotations":{"%s":"%s"}}}`,
-	annotation.AmbientRedirection.Name,
-	constants.AmbientRedirectionEnabled,
-))
-
 var annotationPendingPatch = []byte(fmt.Sprintf(
 	`{"metadata":{"annotations":{"%s":"%s"}}}`,
 	annotation.AmbientRedirection.Name,
 	constants.AmbientRedirectionPending,
 ))
 
+// annotationRemovePatch is a strategic merge patch, so that only the enrollment condition is removed from the pod
+// conditions.
 var annotationRemovePatch = []byte(fmt.Sprintf(
-	`{"metadata":{"annotations":{"%s":null}}}`,
+	`{"metadata":{"annotations":{"%s":null,"%s":null}},"status":{"conditions":[{"type":"%s","$patch":"delete"}]}}`,
 	annotation.AmbientRedirection.Name,
+	BypassedInterfacesAnnotation,
+	AmbientEnrolledCondition,
 ))
 
+// annotationEnabledPatch returns the strategic merge patch annotating a pod as enrolled, and setting its enrollment
+// condition, with the interfaces of its secondary networks whose traffic is not redirected, if any.
+func annotationEnabledPatch(bypassedInterfaces []string, now time.Time) []byte {
+	var bypassed any
+	condition := corev1.PodCondition{
+		Type:               AmbientEnrolledCondition,
+		Status:             corev1.ConditionTrue,
+		Reason:             ReasonAmbientEnrolled,
+		Message:            "traffic of the pod is redirected",
+		LastTransitionTime: metav1.NewTime(now),
+	}
+	if len(bypassedInterfaces) > 0 {
+		bypassed = strings.Join(bypassedInterfaces, ",")
+		condition.Reason = ReasonSecondaryInterfacesBypassed
+		condition.Message = fmt.Sprintf("traffic of the pod is redirected, except on the interfaces of its secondary networks: %s",
+			bypassed)
+	}
+	patch, _ := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				annotation.AmbientRedirection.Name: constants.AmbientRedirectionEnabled,
+				BypassedInterfacesAnnotation:       bypassed,
+			},
+		},
+		"status": map[string]any{
+			"conditions": []corev1.PodCondition{condition},
+		},
+	})
+	return patch
+}
+
 // SplitExcludeNamespaces splits a comma-separated namespace string into a slice,
 // filtering out empty strings that result from splitting an empty input or consecutive/trailing commas.
 func SplitExcludeNamespaces(s string) []string {
@@ -101,14 +132,16 @@ func IsZtunnelPod(systemNs string, pod *corev1.Pod) bool {
 	return pod.Namespace == systemNs && pod.GetLabels()["app"] == "ztunnel"
 }
 
-func AnnotateEnrolledPod(client kubernetes.Interface, pod *metav1.ObjectMeta) error {
+// AnnotateEnrolledPod annotates the pod as enrolled and sets its AmbientEnrolledCondition, along with the interfaces
+// of its secondary networks whose traffic is not redirected.
+func AnnotateEnrolledPod(client kubernetes.Interface, pod *metav1.ObjectMeta, bypassedInterfaces []string) error {
 	_, err := client.CoreV1().
 		Pods(pod.Namespace).
 		Patch(
 			context.Background(),
 			pod.Name,
-			types.MergePatchType,
-			annotationEnabledPatch,
+			types.StrategicMergePatchType,
+			annotationEnabledPatch(bypassedInterfaces, time.Now()),
 			metav1.PatchOptions{},
 			// Both "pods" and "pods/status" can mutate the metadata. However, pods/status is lower privilege, so we use that instead.
 			"status",
@@ -137,7 +170,7 @@ func AnnotateUnenrollPod(client kubernetes.Interface, pod *metav1.ObjectMeta) er
 		Patch(
 			context.Background(),
 			pod.Name,
-			types.MergePatchType,
+			types.StrategicMergePatchType,
 			annotationRemovePatch,
 			metav1.PatchOptions{},
 			// Both "pods" and "pods/status" can mutate the metadata. However, pods/status is lower privilege, so we use that instead.
@@ -0,0 +1,535 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkambient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/api/security/v1beta1"
+	securityclient "istio.io/client-go/pkg/apis/security/v1"
+	"istio.io/istio/cni/pkg/util"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/pilot/pkg/serviceregistry/ambient"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/slices"
+)
+
+// cniConfigMapName is the ConfigMap configuring the node agent, installed by the istio-cni chart.
+const cniConfigMapName = "istio-cni-config"
+
+type options struct {
+	// files to read the resources from, instead of the cluster
+	files []string
+	// cniNamespace is the namespace of the node agent ConfigMap, the Istio namespace if empty
+	cniNamespace string
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "check-ambient [<namespace>]",
+		Short: "Show what would change if a namespace was labeled for ambient, without labeling it",
+		Long: `
+Computes the effect of labeling a namespace with istio.io/dataplane-mode=ambient, without changing anything:
+the pods the node agent would enroll, and the ones it would skip; the AuthorizationPolicies and
+PeerAuthentications ztunnel would start enforcing on the enrolled pods; and the Services selected by
+policies with HTTP rules that have no waypoint, whose HTTP rules would silently stop applying.
+
+The pods are selected with the enablement selectors of the node agent, read from the istio-cni-config ConfigMap,
+or the default ones if it is not found.
+
+The resources are read from the cluster, or from files with --filename, without any cluster access.`,
+		Example: `  # Check the effect of enrolling the namespace bookinfo in ambient
+  istioctl experimental check-ambient bookinfo
+
+  # Check the effect of enrolling the resources of a manifest, without accessing the cluster
+  istioctl x check-ambient bookinfo -f bookinfo.yaml -f policies.yaml
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace := ctx.NamespaceOrDefault(ctx.Namespace())
+			if len(args) == 1 {
+				namespace = args[0]
+			}
+			rootNamespace := ctx.IstioNamespace()
+			var (
+				in  *resources
+				err error
+			)
+			cniNamespace := opts.cniNamespace
+			if cniNamespace == "" {
+				cniNamespace = rootNamespace
+			}
+			if len(opts.files) > 0 {
+				in, err = readFiles(namespace, rootNamespace, opts.files)
+			} else {
+				var kubeClient kube.CLIClient
+				kubeClient, err = ctx.CLIClient()
+				if err != nil {
+					return err
+				}
+				in, err = readCluster(kubeClient, namespace, rootNamespace, cniNamespace)
+			}
+			if err != nil {
+				return err
+			}
+			cfg, err := parseNodeAgentConfig(in.cniConfig)
+			if err != nil {
+				return err
+			}
+			return printReport(cmd.OutOrStdout(), dryRun(in, cfg, rootNamespace))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completion.ValidNamespaceArgs(cmd, ctx, args, toComplete)
+		},
+	}
+	cmd.PersistentFlags().StringSliceVarP(&opts.files, "filename", "f", nil,
+		"Read the resources from files instead of the cluster")
+	cmd.PersistentFlags().StringVar(&opts.cniNamespace, "cni-namespace", "",
+		"Namespace of the istio-cni-config ConfigMap of the node agent, defaults to the Istio namespace")
+	return cmd
+}
+
+// resources are the resources of the namespace, and the policies of the root namespace.
+type resources struct {
+	namespace             *corev1.Namespace
+	pods                  []*corev1.Pod
+	services              []*corev1.Service
+	authorizationPolicies []*securityclient.AuthorizationPolicy
+	peerAuthentications   []*securityclient.PeerAuthentication
+	// cniConfig is the ConfigMap of the node agent, if found
+	cniConfig *corev1.ConfigMap
+}
+
+func readCluster(client kube.CLIClient, namespace, rootNamespace, cniNamespace string) (*resources, error) {
+	c := context.Background()
+	ns, err := client.Kube().CoreV1().Namespaces().Get(c, namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	in := &resources{namespace: ns}
+	cm, err := client.Kube().CoreV1().ConfigMaps(cniNamespace).Get(c, cniConfigMapName, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		in.cniConfig = cm
+	}
+	pods, err := client.Kube().CoreV1().Pods(namespace).List(c, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		in.pods = append(in.pods, &pods.Items[i])
+	}
+	services, err := client.Kube().CoreV1().Services(namespace).List(c, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		in.services = append(in.services, &services.Items[i])
+	}
+	for _, ns := range policyNamespaces(namespace, rootNamespace) {
+		aps, err := client.Istio().SecurityV1().AuthorizationPolicies(ns).List(c, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		in.authorizationPolicies = append(in.authorizationPolicies, aps.Items...)
+		pas, err := client.Istio().SecurityV1().PeerAuthentications(ns).List(c, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		in.peerAuthentications = append(in.peerAuthentications, pas.Items...)
+	}
+	return in, nil
+}
+
+func policyNamespaces(namespace, rootNamespace string) []string {
+	if namespace == rootNamespace {
+		return []string{namespace}
+	}
+	return []string{namespace, rootNamespace}
+}
+
+// readFiles reads the resources of the namespace from YAML files. Resources without a namespace are in the namespace.
+func readFiles(namespace, rootNamespace string, filenames []string) (*resources, error) {
+	in := &resources{}
+	inNamespace := func(o metav1.Object, namespaces ...string) bool {
+		if o.GetNamespace() == "" {
+			o.SetNamespace(namespace)
+		}
+		return slices.Contains(namespaces, o.GetNamespace())
+	}
+	for _, f := range filenames {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		reader := kubeyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(b)))
+		for {
+			doc, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", f, err)
+			}
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+			obj, _, err := kube.IstioCodec.UniversalDeserializer().Decode(doc, nil, nil)
+			if err != nil {
+				// Resources of other kinds are not relevant
+				continue
+			}
+			switch o := obj.(type) {
+			case *corev1.ConfigMap:
+				if o.Name == cniConfigMapName {
+					in.cniConfig = o
+				}
+			case *corev1.Namespace:
+				if o.Name == namespace {
+					in.namespace = o
+				}
+			case *corev1.Pod:
+				if inNamespace(o, namespace) {
+					in.pods = append(in.pods, o)
+				}
+			case *corev1.Service:
+				if inNamespace(o, namespace) {
+					in.services = append(in.services, o)
+				}
+			case *securityclient.AuthorizationPolicy:
+				if inNamespace(o, namespace, rootNamespace) {
+					in.authorizationPolicies = append(in.authorizationPolicies, o)
+				}
+			case *securityclient.PeerAuthentication:
+				if inNamespace(o, namespace, rootNamespace) {
+					in.peerAuthentications = append(in.peerAuthentications, o)
+				}
+			}
+		}
+	}
+	if in.namespace == nil {
+		in.namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	}
+	return in, nil
+}
+
+// defaultEnablementSelectors are the enablement selectors of the node agent when its ConfigMap is not found.
+var defaultEnablementSelectors = []util.EnablementSelector{
+	{
+		PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}},
+	},
+	{
+		PodSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      label.IoIstioDataplaneMode.Name,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{constants.DataplaneModeNone},
+		}}},
+		NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}},
+	},
+}
+
+// nodeAgentConfig is the configuration of the node agent deciding which pods are enrolled, and how.
+type nodeAgentConfig struct {
+	enablementSelectors        *util.CompiledEnablementSelectors
+	excludeSecondaryInterfaces bool
+}
+
+// parseNodeAgentConfig reads the configuration of the node agent from its ConfigMap, as the node agent does. Without
+// ConfigMap, the defaults of the node agent are used.
+func parseNodeAgentConfig(cm *corev1.ConfigMap) (*nodeAgentConfig, error) {
+	selectors := defaultEnablementSelectors
+	cfg := &nodeAgentConfig{}
+	if cm != nil {
+		if s, f := cm.Data["AMBIENT_ENABLEMENT_SELECTOR"]; f {
+			selectors = nil
+			if err := yaml.Unmarshal([]byte(s), &selectors); err != nil {
+				return nil, fmt.Errorf("failed to parse the ambient enablement selector of %s/%s: %v", cm.Namespace, cm.Name, err)
+			}
+		}
+		if s, f := cm.Data["EXCLUDE_SECONDARY_INTERFACES"]; f {
+			exclude, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EXCLUDE_SECONDARY_INTERFACES of %s/%s: %v", cm.Namespace, cm.Name, err)
+			}
+			cfg.excludeSecondaryInterfaces = exclude
+		}
+	}
+	compiled, err := util.NewCompiledEnablementSelectors(selectors)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ambient enablement selector: %v", err)
+	}
+	cfg.enablementSelectors = compiled
+	return cfg, nil
+}
+
+type podResult struct {
+	name     string
+	enrolled bool
+	mtls     string
+	reason   string
+}
+
+type policyResult struct {
+	kind     string
+	name     string
+	pods     int
+	enforced bool
+	note     string
+}
+
+type serviceResult struct {
+	name     string
+	policies []string
+}
+
+type report struct {
+	pods     []podResult
+	policies []policyResult
+	services []serviceResult
+}
+
+// dryRun computes the effect of labeling the namespace for ambient.
+func dryRun(in *resources, cfg *nodeAgentConfig, rootNamespace string) report {
+	var res report
+	nsLabels := map[string]string{}
+	for k, v := range in.namespace.Labels {
+		nsLabels[k] = v
+	}
+	nsLabels[label.IoIstioDataplaneMode.Name] = constants.DataplaneModeAmbient
+
+	// The pods newly enrolled, on which ztunnel starts enforcing the policies
+	var enrolled []*corev1.Pod
+	for _, pod := range in.pods {
+		r := podResult{name: pod.Name}
+		switch {
+		case util.IsZtunnelPod(rootNamespace, pod):
+			r.reason = "ztunnel is never enrolled"
+		case kube.CheckPodTerminal(pod):
+			r.reason = "the pod is terminated"
+		case pod.Spec.HostNetwork:
+			r.reason = "the pod uses the host network"
+		case pod.Annotations[annotation.SidecarStatus.Name] != "":
+			r.reason = "the pod has a sidecar"
+		case pod.Labels[label.IoIstioDataplaneMode.Name] == constants.DataplaneModeNone:
+			r.reason = fmt.Sprintf("opted out with the %s=%s label", label.IoIstioDataplaneMode.Name, constants.DataplaneModeNone)
+		case util.PodFullyEnrolled(pod):
+			r.enrolled = true
+			r.reason = "already enrolled"
+		case cfg.enablementSelectors.Matches(pod.Labels, pod.Annotations, nsLabels):
+			r.enrolled = true
+			enrolled = append(enrolled, pod)
+			if cfg.excludeSecondaryInterfaces {
+				if bypassed, err := util.UncapturedInterfaces(pod); err == nil && len(bypassed) > 0 {
+					r.reason = fmt.Sprintf("the traffic of the secondary interfaces %s is not redirected", strings.Join(bypassed, ","))
+				}
+			}
+		default:
+			r.reason = "not selected by the enablement selectors"
+		}
+		if r.enrolled {
+			r.mtls = mtlsMode(ambient.EffectivePeerAuthentications(rootNamespace, pod.Namespace, pod.Labels, in.peerAuthentications))
+		}
+		res.pods = append(res.pods, r)
+	}
+
+	// Policies with rules ztunnel does not enforce, by the pods they select
+	l7Policies := map[*corev1.Pod][]string{}
+	for _, ap := range in.authorizationPolicies {
+		authz, msg := ambient.ConvertAuthorizationPolicy(rootNamespace, ap)
+		selected := slices.Filter(enrolled, func(pod *corev1.Pod) bool {
+			return authorizationPolicySelects(ap, rootNamespace, pod)
+		})
+		if len(selected) == 0 {
+			continue
+		}
+		r := policyResult{kind: "AuthorizationPolicy", name: ap.Namespace + "/" + ap.Name, pods: len(selected), enforced: authz != nil}
+		if msg != nil {
+			r.note = msg.Message
+		}
+		res.policies = append(res.policies, r)
+		if needsWaypoint(ap, r.enforced, msg != nil) {
+			for _, pod := range selected {
+				l7Policies[pod] = append(l7Policies[pod], r.name)
+			}
+		}
+	}
+	for _, pa := range in.peerAuthentications {
+		selected := slices.Filter(enrolled, func(pod *corev1.Pod) bool {
+			return peerAuthenticationApplies(pa, in.peerAuthentications, rootNamespace, pod)
+		})
+		if len(selected) == 0 {
+			continue
+		}
+		res.policies = append(res.policies, policyResult{
+			kind:     "PeerAuthentication",
+			name:     pa.Namespace + "/" + pa.Name,
+			pods:     len(selected),
+			enforced: true,
+			note:     "mTLS mode " + pa.Spec.GetMtls().GetMode().String(),
+		})
+	}
+
+	for _, svc := range in.services {
+		if len(svc.Spec.Selector) == 0 || hasWaypoint(svc, in.namespace) {
+			continue
+		}
+		selector := klabels.SelectorFromSet(svc.Spec.Selector)
+		var policies []string
+		for _, pod := range enrolled {
+			if selector.Matches(klabels.Set(pod.Labels)) {
+				policies = append(policies, l7Policies[pod]...)
+			}
+		}
+		if len(policies) > 0 {
+			res.services = append(res.services, serviceResult{name: svc.Namespace + "/" + svc.Name, policies: slices.Sort(slices.FilterDuplicates(policies))})
+		}
+	}
+
+	slices.SortBy(res.pods, func(r podResult) string { return r.name })
+	slices.SortBy(res.policies, func(r policyResult) string { return r.kind + "/" + r.name })
+	slices.SortBy(res.services, func(r serviceResult) string { return r.name })
+	return res
+}
+
+// mtlsMode describes the mTLS requirement of ztunnel, from the keys of the effective PeerAuthentications.
+func mtlsMode(keys []string) string {
+	switch {
+	case len(keys) == 0:
+		return "PERMISSIVE"
+	case slices.FindFunc(keys, ambient.IsStaticStrictPeerAuthentication) != nil:
+		return "STRICT"
+	default:
+		return "PORT-LEVEL"
+	}
+}
+
+// authorizationPolicySelects reports whether the policy applies to the pod, as ztunnel or a sidecar applies it.
+func authorizationPolicySelects(ap *securityclient.AuthorizationPolicy, rootNamespace string, pod *corev1.Pod) bool {
+	if ap.Namespace != rootNamespace && ap.Namespace != pod.Namespace {
+		return false
+	}
+	if ap.Spec.TargetRef != nil || len(ap.Spec.TargetRefs) > 0 {
+		// Policies attached to a waypoint or a gateway do not select pods
+		return false
+	}
+	sel := ap.Spec.GetSelector()
+	return sel == nil || klabels.SelectorFromSet(sel.MatchLabels).Matches(klabels.Set(pod.Labels))
+}
+
+// peerAuthenticationApplies reports whether the PeerAuthentication determines the mTLS requirement ztunnel enforces on
+// the pod: either the requirement changes without it, or it requires the same mTLS on its own.
+func peerAuthenticationApplies(pa *securityclient.PeerAuthentication, all []*securityclient.PeerAuthentication,
+	rootNamespace string, pod *corev1.Pod,
+) bool {
+	effective := ambient.EffectivePeerAuthentications(rootNamespace, pod.Namespace, pod.Labels, all)
+	others := slices.Filter(all, func(o *securityclient.PeerAuthentication) bool { return o != pa })
+	if !slices.Equal(effective, ambient.EffectivePeerAuthentications(rootNamespace, pod.Namespace, pod.Labels, others)) {
+		return true
+	}
+	return len(effective) > 0 &&
+		slices.Equal(effective, ambient.EffectivePeerAuthentications(rootNamespace, pod.Namespace, pod.Labels, []*securityclient.PeerAuthentication{pa}))
+}
+
+// needsWaypoint reports whether the policy has rules that only a waypoint enforces in ambient.
+func needsWaypoint(ap *securityclient.AuthorizationPolicy, enforced, partial bool) bool {
+	switch ap.Spec.Action {
+	case v1beta1.AuthorizationPolicy_CUSTOM, v1beta1.AuthorizationPolicy_AUDIT:
+		return true
+	}
+	return enforced && partial
+}
+
+// hasWaypoint reports whether the traffic of the service goes through a waypoint.
+func hasWaypoint(svc *corev1.Service, ns *corev1.Namespace) bool {
+	if w, f := svc.Labels[label.IoIstioUseWaypoint.Name]; f {
+		return w != "" && w != constants.NoTraffic
+	}
+	w := ns.Labels[label.IoIstioUseWaypoint.Name]
+	return w != "" && w != constants.NoTraffic
+}
+
+func printReport(writer io.Writer, r report) error {
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "POD\tENROLLED\tMTLS\tREASON")
+	for _, p := range r.pods {
+		enrolled := "no"
+		if p.enrolled {
+			enrolled = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.name, enrolled, orNone(p.mtls), orNone(p.reason))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(writer)
+	if len(r.policies) == 0 {
+		fmt.Fprintln(writer, "No policy would start applying to the enrolled pods.")
+	} else {
+		fmt.Fprintln(w, "POLICY\tKIND\tPODS\tZTUNNEL ENFORCED\tNOTE")
+		for _, p := range r.policies {
+			enforced := "no"
+			if p.enforced {
+				enforced = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", p.name, p.kind, p.pods, enforced, orNone(p.note))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.services) > 0 {
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "The HTTP rules of the policies selecting these services would stop applying, as they have no waypoint:")
+		fmt.Fprintln(w, "SERVICE\tPOLICIES")
+		for _, s := range r.services {
+			fmt.Fprintf(w, "%s\t%s\n", s.name, strings.Join(s.policies, ","))
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkambient
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const (
+	manifest = "testdata/bookinfo.yaml"
+	golden   = "testdata/bookinfo.golden"
+)
+
+func TestCheckAmbientFiles(t *testing.T) {
+	ctx := cli.NewFakeContext(&cli.NewFakeContextOption{IstioNamespace: "istio-system"})
+	out := &bytes.Buffer{}
+	cmd := Cmd(ctx)
+	cmd.SetArgs([]string{"bookinfo", "-f", manifest})
+	cmd.SetOut(out)
+	assert.NoError(t, cmd.Execute())
+	util.CompareContent(t, out.Bytes(), golden)
+}
+
+func TestCheckAmbientCluster(t *testing.T) {
+	in, err := readFiles("bookinfo", "istio-system", []string{manifest})
+	assert.NoError(t, err)
+	objects := []runtime.Object{in.namespace, in.cniConfig}
+	for _, o := range in.pods {
+		objects = append(objects, o)
+	}
+	for _, o := range in.services {
+		objects = append(objects, o)
+	}
+	for _, o := range in.authorizationPolicies {
+		objects = append(objects, o)
+	}
+	for _, o := range in.peerAuthentications {
+		objects = append(objects, o)
+	}
+	ctx := cli.NewFakeContext(&cli.NewFakeContextOption{IstioNamespace: "istio-system", Objects: objects})
+	out := &bytes.Buffer{}
+	cmd := Cmd(ctx)
+	cmd.SetArgs([]string{"bookinfo"})
+	cmd.SetOut(out)
+	assert.NoError(t, cmd.Execute())
+	util.CompareContent(t, out.Bytes(), golden)
+}
+
+func TestParseNodeAgentConfig(t *testing.T) {
+	pod := map[string]string{"app": "test"}
+	ambientNamespace := map[string]string{label.IoIstioDataplaneMode.Name: constants.DataplaneModeAmbient}
+
+	// Without ConfigMap, the pods of the ambient namespaces are selected
+	cfg, err := parseNodeAgentConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.enablementSelectors.Matches(pod, nil, ambientNamespace), true)
+	assert.Equal(t, cfg.excludeSecondaryInterfaces, false)
+
+	cfg, err = parseNodeAgentConfig(&corev1.ConfigMap{Data: map[string]string{
+		"AMBIENT_ENABLEMENT_SELECTOR":  "- podSelector:\n    matchLabels:\n      ambient: enabled\n",
+		"EXCLUDE_SECONDARY_INTERFACES": "true",
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.enablementSelectors.Matches(pod, nil, ambientNamespace), false)
+	assert.Equal(t, cfg.enablementSelectors.Matches(map[string]string{"ambient": "enabled"}, nil, nil), true)
+	assert.Equal(t, cfg.excludeSecondaryInterfaces, true)
+
+	_, err = parseNodeAgentConfig(&corev1.ConfigMap{Data: map[string]string{"AMBIENT_ENABLEMENT_SELECTOR": "{"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/cni/pkg/constants"
+	"istio.io/istio/cni/pkg/nodeagent"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/ztunnelconfig"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/model"
+)
+
+const (
+	jsonOutput    = "json"
+	yamlOutput    = "yaml"
+	summaryOutput = "short"
+
+	cniDaemonSet = "istio-cni-node"
+	// defaultDebugPort is the default localhost port of the node agent debug server.
+	defaultDebugPort = 15016
+)
+
+func CNIConfig(ctx cli.Context) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "cni-config",
+		Short: "Retrieve the state of the Istio CNI node agent.",
+		Long:  "A group of commands used to retrieve the state of the pods handled by an Istio CNI node agent instance.",
+		Example: `  # Retrieve the pods handled by the node agent on a node
+  istioctl x cni-config pods --node worker-1`,
+	}
+
+	configCmd.AddCommand(podsCmd(ctx))
+
+	return configCmd
+}
+
+func podsCmd(ctx cli.Context) *cobra.Command {
+	var outputFormat, node string
+	var debugPort int
+	cmd := &cobra.Command{
+		Use:   "pods [<istio-cni-node-pod|pod-name>[.namespace]]",
+		Short: "Retrieves the pods handled by the specified Istio CNI node agent.",
+		Long: `Retrieve the pods handled by an Istio CNI node agent, with their network namespace, pod IPs, membership in
+the host probe ipset, presence in the snapshot sent to ztunnel, and the last error adding them to or removing them from the mesh.
+If a workload pod is given, the node agent of its node is queried and the output is filtered to this pod.`,
+		Example: `  # Retrieve the pods handled by the node agent on a node.
+  istioctl x cni-config pods --node worker-1
+
+  # Retrieve the node agent view of a workload pod.
+  istioctl x cni-config pods productpage-123-456.default -o json
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("at most one pod name must be passed")
+			}
+			if len(args) == 1 && node != "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("at most one of --node or pod name must be passed")
+			}
+			return util.ValidatePort(debugPort)
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			var podName, podNamespace string
+			var target *nodeagent.PodDebugInfo
+			switch {
+			case node != "":
+				nsn, err := ztunnelconfig.PodOnNodeFromDaemonset(node, cniDaemonSet, ctx.IstioNamespace(), kubeClient)
+				if err != nil {
+					return fmt.Errorf("failed to find %s on node %s: %v", cniDaemonSet, node, err)
+				}
+				podName, podNamespace = nsn.Name, nsn.Namespace
+			default:
+				lookup := "daemonset/" + cniDaemonSet
+				if len(args) > 0 {
+					lookup = args[0]
+				}
+				podName, podNamespace, err = ctx.InferPodInfoFromTypedResource(lookup,
+					model.GetOrDefault(ctx.Namespace(), ctx.IstioNamespace()))
+				if err != nil {
+					return err
+				}
+				if !isCNIPod(kubeClient, podName, podNamespace) {
+					// The user targeted a workload pod. Resolve to the node agent on the same node
+					// and filter output to this workload.
+					workloadPod, err := kubeClient.Kube().CoreV1().Pods(podNamespace).Get(context.Background(), podName, metav1.GetOptions{})
+					if err != nil {
+						return fmt.Errorf("failed to get pod %s.%s: %v", podName, podNamespace, err)
+					}
+					if workloadPod.Spec.NodeName == "" {
+						return fmt.Errorf("pod %s.%s is not scheduled to a node", podName, podNamespace)
+					}
+					target = &nodeagent.PodDebugInfo{UID: string(workloadPod.UID), Name: podName, Namespace: podNamespace}
+					nsn, err := ztunnelconfig.PodOnNodeFromDaemonset(workloadPod.Spec.NodeName, cniDaemonSet, ctx.IstioNamespace(), kubeClient)
+					if err != nil {
+						return fmt.Errorf("failed to find %s on node %s for pod %s.%s: %v",
+							cniDaemonSet, workloadPod.Spec.NodeName, target.Name, target.Namespace, err)
+					}
+					podName, podNamespace = nsn.Name, nsn.Namespace
+				}
+			}
+
+			info, err := fetchDebugInfo(kubeClient, podName, podNamespace, debugPort)
+			if err != nil {
+				return err
+			}
+			if target != nil {
+				info.Pods = filterPods(info.Pods, target)
+			}
+			switch outputFormat {
+			case summaryOutput:
+				return printPodSummary(c.OutOrStdout(), info)
+			case jsonOutput, yamlOutput:
+				return printPodDump(c.OutOrStdout(), info, outputFormat)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	cmd.PersistentFlags().StringVar(&node, "node", "", "Query the Istio CNI node agent running on this node")
+	cmd.PersistentFlags().IntVar(&debugPort, "debug-port", defaultDebugPort, "Istio CNI node agent debug port")
+	return cmd
+}
+
+func isCNIPod(client kube.CLIClient, podName, podNamespace string) bool {
+	isCNI := strings.HasPrefix(podName, cniDaemonSet)
+	pod, err := client.Kube().CoreV1().Pods(podNamespace).Get(context.Background(), podName, metav1.GetOptions{})
+	if err != nil {
+		return isCNI
+	}
+	if v, ok := pod.Labels["k8s-app"]; ok {
+		return v == cniDaemonSet
+	}
+	return isCNI
+}
+
+func fetchDebugInfo(kubeClient kube.CLIClient, podName, podNamespace string, port int) (*nodeagent.DebugInfo, error) {
+	out, err := kubeClient.EnvoyDoWithPort(context.TODO(), podName, podNamespace, "GET", strings.TrimPrefix(constants.DebugPodsEndpoint, "/"), port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on %s.%s Istio CNI node agent: %v", podName, podNamespace, err)
+	}
+	info := &nodeagent.DebugInfo{}
+	if err := json.Unmarshal(out, info); err != nil {
+		return nil, fmt.Errorf("failed to parse response from %s.%s Istio CNI node agent: %v", podName, podNamespace, err)
+	}
+	return info, nil
+}
+
+func filterPods(pods []nodeagent.PodDebugInfo, target *nodeagent.PodDebugInfo) []nodeagent.PodDebugInfo {
+	var res []nodeagent.PodDebugInfo
+	for _, p := range pods {
+		if p.UID == target.UID || (p.Name == target.Name && p.Namespace == target.Namespace) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func printPodSummary(out io.Writer, info *nodeagent.DebugInfo) error {
+	w := new(tabwriter.Writer).Init(out, 0, 8, 1, ' ', 0)
+	if info.HostProbeSetError != "" {
+		fmt.Fprintf(out, "failed to list host probe set: %v\n", info.HostProbeSetError)
+	}
+	fmt.Fprintln(w, "NAMESPACE\tPOD NAME\tNETNS\tIPS\tPROBE SET\tZTUNNEL SNAPSHOT\tLAST ERROR")
+	for _, p := range info.Pods {
+		netns := "-"
+		if p.Netns != 0 {
+			netns = fmt.Sprint(p.Netns)
+		}
+		lastErr := "-"
+		if p.LastError != nil {
+			lastErr = fmt.Sprintf("%s: %s", p.LastError.Operation, p.LastError.Error)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			p.Namespace, p.Name, netns, strings.Join(p.PodIPs, ","), p.InHostProbeSet, p.InZtunnelSnapshot, lastErr)
+	}
+	return w.Flush()
+}
+
+func printPodDump(out io.Writer, info *nodeagent.DebugInfo, outputFormat string) error {
+	b, err := json.MarshalIndent(info, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pods: %v", err)
+	}
+	if outputFormat == yamlOutput {
+		if b, err = yaml.JSONToYAML(b); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(out, string(b))
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cniconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/istioctl/pkg/cli"
+)
+
+const debugResponse = `{
+  "node": "worker-1",
+  "connectedZtunnels": ["a9e6b3a4-6a4f-4c4e-9d0e-3f7d1b7c2a10"],
+  "pods": [
+    {
+      "uid": "uid-1",
+      "name": "productpage",
+      "namespace": "default",
+      "netns": 4026532890,
+      "podIPs": ["10.0.0.5"],
+      "inHostProbeSet": true,
+      "inZtunnelSnapshot": true
+    },
+    {
+      "uid": "uid-2",
+      "name": "reviews",
+      "namespace": "default",
+      "podIPs": ["10.0.0.6"],
+      "inHostProbeSet": false,
+      "inZtunnelSnapshot": false,
+      "lastError": {"operation": "AddPodToMesh", "error": "failed to open netns", "time": "2026-01-01T00:00:00Z"}
+    }
+  ]
+}`
+
+func TestPods(t *testing.T) {
+	objects := []runtime.Object{
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: cniDaemonSet, Namespace: "istio-system"},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": cniDaemonSet}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "istio-cni-node-abcde",
+				Namespace: "istio-system",
+				Labels:    map[string]string{"k8s-app": cniDaemonSet},
+			},
+			Spec: corev1.PodSpec{NodeName: "worker-1"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "default", UID: "uid-2"},
+			Spec:       corev1.PodSpec{NodeName: "worker-1"},
+		},
+	}
+	cases := []struct {
+		name     string
+		args     []string
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{
+			name:     "node",
+			args:     []string{"pods", "--node", "worker-1"},
+			contains: []string{"productpage", "4026532890", "10.0.0.5", "reviews", "AddPodToMesh: failed to open netns"},
+		},
+		{
+			name:     "node agent pod",
+			args:     []string{"pods", "istio-cni-node-abcde.istio-system", "-o", "json"},
+			contains: []string{`"connectedZtunnels"`, `"productpage"`},
+		},
+		{
+			name:     "workload pod",
+			args:     []string{"pods", "reviews.default", "-o", "yaml"},
+			contains: []string{"name: reviews", "operation: AddPodToMesh"},
+			excludes: []string{"productpage"},
+		},
+		{
+			name:    "pod and node",
+			args:    []string{"pods", "reviews.default", "--node", "worker-1"},
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := CNIConfig(cli.NewFakeContext(&cli.NewFakeContextOption{
+				IstioNamespace: "istio-system",
+				Results:        map[string][]byte{"istio-cni-node-abcde": []byte(debugResponse)},
+				Objects:        objects,
+			}))
+			var out bytes.Buffer
+			cmd.SetArgs(c.args)
+			cmd.SilenceUsage = true
+			cmd.SetOut(&out)
+			cmd.SetErr(&out)
+			err := cmd.Execute()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("unexpected error %v, output %q", err, out.String())
+			}
+			for _, s := range c.contains {
+				if !strings.Contains(out.String(), s) {
+					t.Fatalf("output %q does not contain %q", out.String(), s)
+				}
+			}
+			for _, s := range c.excludes {
+				if strings.Contains(out.String(), s) {
+					t.Fatalf("output %q unexpectedly contains %q", out.String(), s)
+				}
+			}
+		})
+	}
+}
@@ -46,6 +46,8 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/multicluster"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
 )
 
 var (
@@ -77,6 +79,11 @@ const (
 	remoteSecretPrefix = "istio-remote-secret-"
 	configSecretName   = "istio-kubeconfig"
 	configSecretKey    = "config"
+
+	// rootCAConfigMapName is the ConfigMap Kubernetes publishes the CA of the API server to, in every namespace.
+	rootCAConfigMapName = "kube-root-ca.crt"
+
+	defaultExecAPIVersion = "client.authentication.k8s.io/v1"
 )
 
 func remoteSecretNameFromClusterName(clusterName string) string {
@@ -104,6 +111,17 @@ func NewCreateRemoteSecretCommand(ctx cli.Context) *cobra.Command {
 
   # Create a secret access a remote cluster with an auth plugin
   istioctl --kubeconfig=c0.yaml create-remote-secret --name c0 --auth-type=plugin --auth-plugin-name=gcp \
+    | kubectl --kubeconfig=c1.yaml apply -f -
+
+  # Create a secret access a remote cluster with a token istiod reads from a file, such as a projected service account
+  # token with the audience of the remote cluster. The token is read again whenever the file changes.
+  istioctl --kubeconfig=c0.yaml create-remote-secret --name c0 --auth-type=token-file \
+    --auth-token-file=/var/run/secrets/tokens/c0/token \
+    | kubectl --kubeconfig=c1.yaml apply -f -
+
+  # Create a secret access a remote cluster with credentials returned by a command run by istiod
+  istioctl --kubeconfig=c0.yaml create-remote-secret --name c0 --auth-type=exec \
+    --auth-exec-command=/usr/local/bin/token-exchange --auth-exec-arg=--audience=c0 \
     | kubectl --kubeconfig=c1.yaml apply -f -`,
 		Args: cobra.NoArgs,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -192,6 +210,12 @@ func createPluginKubeconfig(caData []byte, clusterName, server, tlsServerName st
 	return c
 }
 
+func createAuthInfoKubeconfig(caData []byte, clusterName, server, tlsServerName string, authInfo *api.AuthInfo) *api.Config {
+	c := createBaseKubeconfig(caData, clusterName, server, tlsServerName)
+	c.AuthInfos[c.CurrentContext] = authInfo
+	return c
+}
+
 func createRemoteSecretFromPlugin(
 	tokenSecret *v1.Secret,
 	server, tlsServerName, clusterName, secName string,
@@ -212,6 +236,29 @@ func createRemoteSecretFromPlugin(
 	return createRemoteServiceAccountSecret(kubeconfig, clusterName, secName)
 }
 
+// createRemoteSecretFromAuthInfo creates a remote secret using credentials that istiod obtains itself, rather than
+// credentials stored in the secret.
+func createRemoteSecretFromAuthInfo(caData []byte, server, tlsServerName, clusterName, secName string, authInfo *api.AuthInfo) (*v1.Secret, error) {
+	kubeconfig := createAuthInfoKubeconfig(caData, clusterName, server, tlsServerName, authInfo)
+	if err := clientcmd.Validate(*kubeconfig); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+	return createRemoteServiceAccountSecret(kubeconfig, clusterName, secName)
+}
+
+// getRootCAData returns the CA of the API server, as published to every namespace.
+func getRootCAData(client kube.CLIClient, namespace string) ([]byte, error) {
+	cm, err := client.Kube().CoreV1().ConfigMaps(namespace).Get(context.TODO(), rootCAConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the root CA of the local kube-apiserver: %v", err)
+	}
+	caData, f := cm.Data[v1.ServiceAccountRootCAKey]
+	if !f {
+		return nil, fmt.Errorf("no %q data found in %s/%s", v1.ServiceAccountRootCAKey, namespace, rootCAConfigMapName)
+	}
+	return []byte(caData), nil
+}
+
 var (
 	errMissingRootCAKey = fmt.Errorf("no %q data found", v1.ServiceAccountRootCAKey)
 	errMissingTokenKey  = fmt.Errorf("no %q data found", v1.ServiceAccountTokenKey)
@@ -542,6 +589,14 @@ const (
 	// Use a custom authentication plugin for the remote kubernetes cluster.
 	RemoteSecretAuthTypePlugin RemoteSecretAuthType = "plugin"
 
+	// Use a token istiod reads from a file, such as a projected service account token. The file is read again when
+	// it changes, so the token can be short-lived.
+	RemoteSecretAuthTypeTokenFile RemoteSecretAuthType = "token-file"
+
+	// Use credentials returned by a command run by istiod, such as a token exchange. The command is run again when
+	// the credentials expire.
+	RemoteSecretAuthTypeExec RemoteSecretAuthType = "exec"
+
 	// Secret generated from remote cluster
 	SecretTypeRemote SecretType = "remote"
 
@@ -569,6 +624,13 @@ type RemoteSecretOptions struct {
 	// Authenticator plugin configuration
 	AuthPluginName   string
 	AuthPluginConfig map[string]string
+	// Path of the token file read by istiod
+	AuthTokenFile string
+	// Credential plugin run by istiod
+	AuthExecCommand    string
+	AuthExecArgs       []string
+	AuthExecEnv        map[string]string
+	AuthExecAPIVersion string
 
 	// Type of the generated secret
 	Type SecretType
@@ -609,7 +671,9 @@ func (o *RemoteSecretOptions) addFlags(flagset *pflag.FlagSet) {
 	flagset.StringVar(&o.SecretName, "secret-name", "",
 		"The name of the specific secret to use from the service-account. Needed when there are multiple secrets in the service account.")
 	var supportedAuthType []string
-	for _, at := range []RemoteSecretAuthType{RemoteSecretAuthTypeBearerToken, RemoteSecretAuthTypePlugin} {
+	for _, at := range []RemoteSecretAuthType{
+		RemoteSecretAuthTypeBearerToken, RemoteSecretAuthTypePlugin, RemoteSecretAuthTypeTokenFile, RemoteSecretAuthTypeExec,
+	} {
 		supportedAuthType = append(supportedAuthType, string(at))
 	}
 	var supportedSecretType []string
@@ -625,6 +689,21 @@ func (o *RemoteSecretOptions) addFlags(flagset *pflag.FlagSet) {
 	flagset.StringToString("auth-plugin-config", o.AuthPluginConfig,
 		fmt.Sprintf("Authenticator plug-in configuration. --auth-type=%v must be set with this option",
 			RemoteSecretAuthTypePlugin))
+	flagset.StringVar(&o.AuthTokenFile, "auth-token-file", o.AuthTokenFile,
+		fmt.Sprintf("Path of the file istiod reads the token from, such as a projected service account token. "+
+			"--auth-type=%v must be set with this option", RemoteSecretAuthTypeTokenFile))
+	flagset.StringVar(&o.AuthExecCommand, "auth-exec-command", o.AuthExecCommand,
+		fmt.Sprintf("Command istiod runs to get credentials. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
+	flagset.StringArrayVar(&o.AuthExecArgs, "auth-exec-arg", o.AuthExecArgs,
+		fmt.Sprintf("Argument of the command istiod runs to get credentials. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
+	flagset.StringToStringVar(&o.AuthExecEnv, "auth-exec-env", o.AuthExecEnv,
+		fmt.Sprintf("Environment of the command istiod runs to get credentials. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
+	flagset.StringVar(&o.AuthExecAPIVersion, "auth-exec-api-version", defaultExecAPIVersion,
+		fmt.Sprintf("API version of the credentials returned by the command istiod runs. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
 	flagset.Var(&o.Type, "type",
 		fmt.Sprintf("Type of the generated secret. supported values = %v", supportedSecretType))
 	flagset.StringVarP(&o.ManifestsPath, "manifests", "d", "", util.ManifestsFlagHelpStr)
@@ -638,11 +717,58 @@ func (o *RemoteSecretOptions) prepare(ctx cli.Context) error {
 			return fmt.Errorf("%v is not a valid DNS 1123 label", o.ClusterName)
 		}
 	}
+	if o.AuthType == RemoteSecretAuthTypeTokenFile && o.AuthTokenFile == "" {
+		return fmt.Errorf("--auth-token-file must be set with --auth-type=%v", RemoteSecretAuthTypeTokenFile)
+	}
+	if o.AuthType == RemoteSecretAuthTypeExec && o.AuthExecCommand == "" {
+		return fmt.Errorf("--auth-exec-command must be set with --auth-type=%v", RemoteSecretAuthTypeExec)
+	}
 	return nil
 }
 
+// authInfo returns the credentials of the remote secret for the auth types istiod obtains credentials itself with,
+// and the warning about enabling them in istiod.
+func (o *RemoteSecretOptions) authInfo() (*api.AuthInfo, Warning) {
+	switch o.AuthType {
+	case RemoteSecretAuthTypeTokenFile:
+		return &api.AuthInfo{TokenFile: o.AuthTokenFile},
+			fmt.Errorf("istiod only accepts this secret if PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS includes tokenFile, "+
+				"and %s is mounted in istiod", o.AuthTokenFile)
+	case RemoteSecretAuthTypeExec:
+		var env []api.ExecEnvVar
+		for _, k := range slices.Sort(maps.Keys(o.AuthExecEnv)) {
+			env = append(env, api.ExecEnvVar{Name: k, Value: o.AuthExecEnv[k]})
+		}
+		apiVersion := o.AuthExecAPIVersion
+		if apiVersion == "" {
+			apiVersion = defaultExecAPIVersion
+		}
+		exec := &api.ExecConfig{
+			APIVersion:      apiVersion,
+			Command:         o.AuthExecCommand,
+			Args:            o.AuthExecArgs,
+			Env:             env,
+			InteractiveMode: api.NeverExecInteractiveMode,
+		}
+		return &api.AuthInfo{Exec: exec},
+			fmt.Errorf("istiod only accepts this secret if PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS includes exec, "+
+				"and %s is available in istiod", o.AuthExecCommand)
+	}
+	return nil, nil
+}
+
 type Warning error
 
+func joinWarnings(a, b Warning) Warning {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return fmt.Errorf("%v; %v", a, b)
+}
+
 func createRemoteSecret(opt RemoteSecretOptions, client kube.CLIClient) (*v1.Secret, Warning, error) {
 	// generate the clusterName if not specified
 	if opt.ClusterName == "" {
@@ -668,9 +794,15 @@ func createRemoteSecret(opt RemoteSecretOptions, client kube.CLIClient) (*v1.Sec
 	default:
 		return nil, nil, fmt.Errorf("unsupported type: %v", opt.Type)
 	}
-	tokenSecret, err := getServiceAccountSecret(client, opt)
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not get access token to read resources from local kube-apiserver: %v", err)
+	// Credentials obtained by istiod do not need a service account token.
+	authInfo, authWarn := opt.authInfo()
+	var tokenSecret *v1.Secret
+	var err error
+	if authInfo == nil {
+		tokenSecret, err = getServiceAccountSecret(client, opt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get access token to read resources from local kube-apiserver: %v", err)
+		}
 	}
 
 	var server string
@@ -695,6 +827,14 @@ func createRemoteSecret(opt RemoteSecretOptions, client kube.CLIClient) (*v1.Sec
 		}
 		remoteSecret, err = createRemoteSecretFromPlugin(tokenSecret, server, opt.TLSServerName, opt.ClusterName, secretName,
 			authProviderConfig)
+	case RemoteSecretAuthTypeTokenFile, RemoteSecretAuthTypeExec:
+		var caData []byte
+		caData, err = getRootCAData(client, opt.Namespace)
+		if err != nil {
+			break
+		}
+		remoteSecret, err = createRemoteSecretFromAuthInfo(caData, server, opt.TLSServerName, opt.ClusterName, secretName, authInfo)
+		warn = joinWarnings(warn, authWarn)
 	default:
 		err = fmt.Errorf("unsupported authentication type: %v", opt.AuthType)
 	}
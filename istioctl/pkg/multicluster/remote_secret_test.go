@@ -792,3 +792,144 @@ func TestRemoteSecretOptions(t *testing.T) {
 	})).Should(Succeed())
 	g.Expect(o.prepare(ctx)).Should(Not(Succeed()))
 }
+
+func TestCreateRemoteSecretWithIstiodCredentials(t *testing.T) {
+	prevOutputWriterStub := makeOutputWriterTestHook
+	defer func() { makeOutputWriterTestHook = prevOutputWriterStub }()
+	makeOutputWriterTestHook = func() writer { return &fakeOutputWriter{} }
+
+	rootCA := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: rootCAConfigMapName, Namespace: testNamespace},
+		Data:       map[string]string{v1.ServiceAccountRootCAKey: "caData"},
+	}
+	want := func(user string) string {
+		return `# This file is autogenerated, do not edit.
+apiVersion: v1
+kind: Secret
+metadata:
+  annotations:
+    networking.istio.io/cluster: primary
+  labels:
+    istio/multiCluster: "true"
+  name: istio-remote-secret-primary
+  namespace: istio-system-test
+stringData:
+  primary: |
+    apiVersion: v1
+    clusters:
+    - cluster:
+        certificate-authority-data: Y2FEYXRh
+        server: server
+      name: primary
+    contexts:
+    - context:
+        cluster: primary
+        user: primary
+      name: primary
+    current-context: primary
+    kind: Config
+    users:
+    - name: primary
+      user:
+` + user + `---
+`
+	}
+
+	cases := []struct {
+		name       string
+		objs       []runtime.Object
+		opts       RemoteSecretOptions
+		want       string
+		wantWarn   string
+		wantErrStr string
+	}{
+		{
+			name: "token file",
+			objs: []runtime.Object{rootCA},
+			opts: RemoteSecretOptions{
+				AuthType:      RemoteSecretAuthTypeTokenFile,
+				AuthTokenFile: "/var/run/secrets/tokens/primary/token",
+			},
+			want: want(`        tokenFile: /var/run/secrets/tokens/primary/token
+`),
+			wantWarn: "PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS includes tokenFile",
+		},
+		{
+			name: "exec",
+			objs: []runtime.Object{rootCA},
+			opts: RemoteSecretOptions{
+				AuthType:        RemoteSecretAuthTypeExec,
+				AuthExecCommand: "/usr/local/bin/token-exchange",
+				AuthExecArgs:    []string{"--audience=primary"},
+				AuthExecEnv:     map[string]string{"B": "2", "A": "1"},
+			},
+			want: want(`        exec:
+          apiVersion: client.authentication.k8s.io/v1
+          args:
+          - --audience=primary
+          command: /usr/local/bin/token-exchange
+          env:
+          - name: A
+            value: "1"
+          - name: B
+            value: "2"
+          interactiveMode: Never
+          provideClusterInfo: false
+`),
+			wantWarn: "PILOT_INSECURE_MULTICLUSTER_KUBECONFIG_OPTIONS includes exec",
+		},
+		{
+			name: "missing root CA",
+			opts: RemoteSecretOptions{
+				AuthType:      RemoteSecretAuthTypeTokenFile,
+				AuthTokenFile: "/var/run/secrets/tokens/primary/token",
+			},
+			wantErrStr: "could not get the root CA",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := cli.NewFakeContext(&cli.NewFakeContextOption{
+				Objects:   c.objs,
+				Namespace: testNamespace,
+			})
+			client, err := ctx.CLIClient()
+			assert.NoError(t, err)
+			opts := c.opts
+			opts.Namespace = testNamespace
+			opts.ClusterName = "primary"
+			opts.Type = SecretTypeRemote
+			opts.ServerOverride = "server"
+			got, warn, err := CreateRemoteSecret(client, opts)
+			if c.wantErrStr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, strings.Contains(err.Error(), c.wantErrStr), true)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, got, c.want)
+			assert.Equal(t, strings.Contains(warn.Error(), c.wantWarn), true)
+		})
+	}
+}
+
+func TestRemoteSecretOptionsIstiodCredentials(t *testing.T) {
+	ctx := cli.NewFakeContext(nil)
+	for _, args := range [][]string{
+		{"--auth-type=token-file"},
+		{"--auth-type=exec"},
+	} {
+		o := RemoteSecretOptions{}
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		o.addFlags(flags)
+		assert.NoError(t, flags.Parse(args))
+		assert.Error(t, o.prepare(ctx))
+	}
+	o := RemoteSecretOptions{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.addFlags(flags)
+	assert.NoError(t, flags.Parse([]string{"--auth-type=exec", "--auth-exec-command=token-exchange", "--auth-exec-arg=a", "--auth-exec-arg=b"}))
+	assert.NoError(t, o.prepare(ctx))
+	assert.Equal(t, o.AuthExecArgs, []string{"a", "b"})
+	assert.Equal(t, o.AuthExecAPIVersion, defaultExecAPIVersion)
+}
@@ -21,6 +21,7 @@ import (
 	"io"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,13 +82,36 @@ func writeMulticlusterStatus(out io.Writer, input map[string][]byte, istiodRevis
 	// determine the output order
 	sortedClusters := getSortedKeys(statuses)
 
+	// Health is only reported by istiod versions health checking remote clusters
+	withHealth := false
+	for _, clusters := range statuses {
+		for _, c := range clusters {
+			withHealth = withHealth || c.Health != ""
+		}
+	}
+
 	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
-	_, _ = fmt.Fprintln(w, "NAME\tSECRET\tSTATUS\tISTIOD\tREVISION")
+	if withHealth {
+		_, _ = fmt.Fprintln(w, "NAME\tSECRET\tSTATUS\tHEALTH\tUNHEALTHY SINCE\tISTIOD\tREVISION")
+	} else {
+		_, _ = fmt.Fprintln(w, "NAME\tSECRET\tSTATUS\tISTIOD\tREVISION")
+	}
 	for _, istiod := range sortedClusters {
 		clusters := statuses[istiod]
 		revision := istiodRevisionMap[istiod]
 		for _, c := range clusters {
-			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID, c.SecretName, c.SyncStatus, istiod, revision)
+			if !withHealth {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID, c.SecretName, c.SyncStatus, istiod, revision)
+				continue
+			}
+			health, unhealthySince := "-", "-"
+			if c.Health != "" {
+				health = c.Health
+			}
+			if c.UnhealthySince != nil {
+				unhealthySince = c.UnhealthySince.UTC().Format(time.RFC3339)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.SecretName, c.SyncStatus, health, unhealthySince, istiod, revision)
 		}
 	}
 	_ = w.Flush()
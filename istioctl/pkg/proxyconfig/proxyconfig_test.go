@@ -409,6 +409,21 @@ func TestWriteMulticlusterStatus(t *testing.T) {
 				"cluster-2     cluster-2-secret     SYNCED     istiod-6d8f97c8d9-abc123     stable\n",
 			expectError: false,
 		},
+		{
+			name: "clusters with health",
+			input: map[string][]byte{
+				// nolint: lll
+				"istiod-6d8f97c8d9-abc123": []byte(`[{"id":"config","secretName":"","syncStatus":"synced"},{"id":"cluster-1","secretName":"cluster-1-secret","syncStatus":"synced","health":"healthy"},{"id":"cluster-2","secretName":"cluster-2-secret","syncStatus":"synced","health":"dropped","unhealthySince":"2024-01-02T03:04:05Z","lastHealthCheckError":"connection refused"}]`),
+			},
+			istiodRevisionMap: map[string]string{
+				"istiod-6d8f97c8d9-abc123": "default",
+			},
+			expectedOutput: "NAME          SECRET               STATUS     HEALTH      UNHEALTHY SINCE          ISTIOD                       REVISION\n" +
+				"config                             synced     -           -                        istiod-6d8f97c8d9-abc123     default\n" +
+				"cluster-1     cluster-1-secret     synced     healthy     -                        istiod-6d8f97c8d9-abc123     default\n" +
+				"cluster-2     cluster-2-secret     synced     dropped     2024-01-02T03:04:05Z     istiod-6d8f97c8d9-abc123     default\n",
+			expectError: false,
+		},
 		{
 			name: "invalid JSON input",
 			input: map[string][]byte{
@@ -96,6 +96,13 @@ var (
 		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
 	credIdentityProvider = env.Register("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
+	workloadAttestationEnv = env.Register("WORKLOAD_ATTESTATION", "",
+		"The type of attestation evidence sent to the CA with certificate requests, allowing VMs to bootstrap their identity. "+
+			"Supported types are join_token, aws_iid and gcp_iit.").Get()
+	workloadAttestationJoinTokenPathEnv = env.Register("WORKLOAD_ATTESTATION_JOIN_TOKEN_PATH", "",
+		"The file holding the join token, when WORKLOAD_ATTESTATION is join_token.").Get()
+	workloadAttestationAudienceEnv = env.Register("WORKLOAD_ATTESTATION_AUDIENCE", "",
+		"The audience of the instance identity token, when WORKLOAD_ATTESTATION is gcp_iit.").Get()
 	// EnableSelfDiscovery controls whether pilot-agent adds a local_cluster static cluster to the bootstrap
 	// for zone-aware routing support. Set ISTIO_META_ENABLE_SELF_DISCOVERY=true via proxyMetadata.
 	EnableSelfDiscovery = env.Register("ISTIO_META_ENABLE_SELF_DISCOVERY", false,
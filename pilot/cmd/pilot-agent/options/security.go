@@ -21,11 +21,15 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/bootstrap"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/slices"
 	"istio.io/istio/security/pkg/credentialfetcher"
+	"istio.io/istio/security/pkg/nodeagent/attestor"
 	"istio.io/istio/security/pkg/nodeagent/cafile"
 )
 
@@ -70,7 +74,38 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 
 	extractCAHeadersFromEnv(o)
 
-	return o, err
+	o.Attestor, err = attestor.New(workloadAttestationEnv, attestor.Options{
+		JoinTokenPath: workloadAttestationJoinTokenPathEnv,
+		Audience:      workloadAttestationAudienceEnv,
+	})
+	if err != nil {
+		return o, fmt.Errorf("failed to create workload attestor: %v", err)
+	}
+
+	o.UserSecrets = userSecretsFromAnnotations("")
+
+	return o, nil
+}
+
+// userSecretsFromAnnotations reads the user-defined secrets from the pod annotations, if present. An invalid
+// annotation is logged and ignored, so that it does not prevent the proxy from starting.
+func userSecretsFromAnnotations(path string) map[string]security.UserSecret {
+	annotations, err := bootstrap.ReadPodAnnotations(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("failed to read pod annotations: %v", err)
+		}
+		return map[string]security.UserSecret{}
+	}
+	secrets, err := security.ParseUserSecrets(annotations[security.UserSecretsAnnotation.Name])
+	if err != nil {
+		log.Errorf("ignoring invalid %s annotation: %v", security.UserSecretsAnnotation.Name, err)
+		return map[string]security.UserSecret{}
+	}
+	if len(secrets) > 0 {
+		log.Infof("serving user secrets %v", slices.Sort(maps.Keys(secrets)))
+	}
+	return secrets
 }
 
 func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.Options, jwtPolicy,
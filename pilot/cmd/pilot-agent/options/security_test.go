@@ -15,7 +15,9 @@
 package options
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"istio.io/istio/pkg/security"
@@ -137,3 +139,28 @@ func TestExtractCAHeadersFromEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestUserSecretsFromAnnotations(t *testing.T) {
+	dir := t.TempDir()
+	if got := userSecretsFromAnnotations(filepath.Join(dir, "missing")); len(got) != 0 {
+		t.Fatalf("expected no user secrets without annotations file, got %v", got)
+	}
+
+	path := filepath.Join(dir, "annotations")
+	value := `{"partner":{"certificateChain":"/etc/partner/tls.crt","privateKey":"/etc/partner/tls.key"}}`
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%s=%q\n", security.UserSecretsAnnotation.Name, value)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := userSecretsFromAnnotations(path)
+	if got["partner"].CertificateChain != "/etc/partner/tls.crt" {
+		t.Fatalf("unexpected user secrets %v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%s=%q\n", security.UserSecretsAnnotation.Name, `{"partner":{}}`)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// An invalid annotation is ignored rather than failing the agent startup.
+	if got := userSecretsFromAnnotations(path); len(got) != 0 {
+		t.Fatalf("expected invalid annotation to be ignored, got %v", got)
+	}
+}
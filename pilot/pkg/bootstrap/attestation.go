@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/server/ca/authenticate/attestation"
+)
+
+// initWorkloadAttestation creates the authenticator for workloads attesting with platform evidence,
+// as configured by PILOT_WORKLOAD_ATTESTATION_CONFIG. Returns nil if attestation is not configured.
+// Consumed join tokens and instance identities are recorded in a Secret of the istiod namespace, shared by the replicas.
+func (s *Server) initWorkloadAttestation(args *PilotArgs) (security.Authenticator, error) {
+	if features.WorkloadAttestationConfig == "" {
+		return nil, nil
+	}
+	cfg, err := attestation.ReadConfig(features.WorkloadAttestationConfig)
+	if err != nil {
+		return nil, err
+	}
+	var store attestation.UsageStore = attestation.NewMemoryUsageStore()
+	if s.kubeClient != nil {
+		store = attestation.NewSecretUsageStore(s.kubeClient.Kube(), args.Namespace)
+	}
+	verifiers, err := attestation.NewVerifiers(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	if len(verifiers) == 0 {
+		return nil, fmt.Errorf("no attestation verifiers configured in %s", features.WorkloadAttestationConfig)
+	}
+	lookup := func(name types.NamespacedName) (string, bool) {
+		wg := s.environment.ConfigStore.Get(gvk.WorkloadGroup, name.Name, name.Namespace)
+		if wg == nil {
+			return "", false
+		}
+		return wg.Spec.(*v1alpha3.WorkloadGroup).GetTemplate().GetServiceAccount(), true
+	}
+	for _, v := range verifiers {
+		log.Infof("workload attestation enabled for %s", v.Type())
+	}
+	return attestation.NewAuthenticator(s.environment.Watcher, lookup, verifiers...), nil
+}
@@ -152,7 +152,7 @@ func (s *Server) initDNSCertsIstiod() error {
 			return fmt.Errorf("failed reading %s: %v", fileBundle.RootCertFile, err)
 		}
 	}
-	s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, caBundle)
+	s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, s.withExternalSignerRoot(caBundle))
 	return nil
 }
 
@@ -172,7 +172,7 @@ func (s *Server) watchRootCertAndGenKeyCert(stop <-chan struct{}) {
 			if err != nil {
 				log.Errorf("failed generating istiod key cert %v", err)
 			} else {
-				s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, caBundle)
+				s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, s.withExternalSignerRoot(caBundle))
 				log.Infof("regenerated istiod dns cert: %s", certChain)
 			}
 		}
@@ -223,7 +223,7 @@ func (s *Server) updateRootCertAndGenKeyCert() error {
 		}
 	}
 
-	s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, caBundle)
+	s.istiodCertBundleWatcher.SetAndNotify(keyPEM, certChain, s.withExternalSignerRoot(caBundle))
 	return nil
 }
 
@@ -17,6 +17,8 @@ package bootstrap
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -27,6 +29,8 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -37,6 +41,7 @@ import (
 	"istio.io/istio/pkg/env"
 	"istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/security/pkg/cmd"
 	"istio.io/istio/security/pkg/pki/ca"
@@ -131,7 +136,18 @@ var (
 
 	// TODO: Likely to be removed and added to mesh config
 	externalCaType = env.Register("EXTERNAL_CA", "",
-		"External CA Integration Type. Permitted value is ISTIOD_RA_KUBERNETES_API.").Get()
+		"External CA Integration Type. Permitted values are ISTIOD_RA_KUBERNETES_API and ISTIOD_RA_EXTERNAL_SIGNER.").Get()
+
+	externalSignerAddresses = env.Register("EXTERNAL_SIGNER_ADDRESSES", "",
+		"Comma separated list of gRPC targets of external signers, in order of preference. "+
+			"Used when EXTERNAL_CA is ISTIOD_RA_EXTERNAL_SIGNER. If all targets use the unix:// scheme, they are dialed without TLS.").Get()
+
+	externalSignerCACert = env.Register("EXTERNAL_SIGNER_CA_CERT", "",
+		"Path to the PEM encoded CA bundle used to verify the TLS certificates of external signers. "+
+			"If unset, the system roots are used.").Get()
+
+	externalSignerTimeout = env.Register("EXTERNAL_SIGNER_TIMEOUT", 10*time.Second,
+		"Timeout of each signing request to an external signer.").Get()
 
 	// TODO: Likely to be removed and added to mesh config
 	k8sSigner = env.Register("K8S_SIGNER", "",
@@ -624,7 +640,11 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 		}
 
 		// File does not exist.
-		if certSignerDomain == "" {
+		if opts.ExternalCAType == ra.ExtCAGrpc {
+			// The root is provided by the external signer.
+			log.Infof("CA cert file %q not found - using roots returned by the external signer.", caCertFile)
+			caCertFile = ""
+		} else if certSignerDomain == "" {
 			log.Infof("CA cert file %q not found, using %q.", caCertFile, defaultCACertPath)
 			caCertFile = defaultCACertPath
 		} else {
@@ -647,10 +667,23 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 		TrustDomain:      opts.TrustDomain,
 		CertSignerDomain: opts.CertSignerDomain,
 	}
+	if opts.ExternalCAType == ra.ExtCAGrpc {
+		if raOpts.ExternalSignerDialOptions, err = externalSignerDialOptions(); err != nil {
+			return nil, err
+		}
+		raOpts.ExternalSignerAddresses = splitSignerAddresses(externalSignerAddresses)
+		raOpts.ExternalSignerTimeout = externalSignerTimeout
+	}
 	raServer, err := ra.NewIstioRA(raOpts)
 	if err != nil {
 		return nil, err
 	}
+	if signerRA, ok := raServer.(*ra.ExternalSignerRA); ok {
+		s.addStartFunc("external signer health check", func(stop <-chan struct{}) error {
+			go signerRA.Run(stop)
+			return nil
+		})
+	}
 	raServer.SetCACertificatesFromMeshConfig(s.environment.Mesh().CaCertificates)
 	s.environment.AddMeshHandler(func() {
 		meshConfig := s.environment.Mesh()
@@ -660,6 +693,63 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 	return raServer, err
 }
 
+// splitSignerAddresses splits a comma separated list of signer addresses, ignoring whitespace and empty entries.
+func splitSignerAddresses(addresses string) []string {
+	var out []string
+	for _, addr := range strings.Split(addresses, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// withExternalSignerRoot appends the root of the external signers to roots distributed to workloads. With an external
+// signer, workload certificates are issued under the root of the signers while istiod certificates are issued by the
+// Istio CA, so workloads need to trust both.
+func (s *Server) withExternalSignerRoot(roots []byte) []byte {
+	signerRA, ok := s.RA.(*ra.ExternalSignerRA)
+	if !ok {
+		return roots
+	}
+	signerRoot := signerRA.GetCAKeyCertBundle().GetRootCertPem()
+	if bytes.Contains(roots, signerRoot) {
+		return roots
+	}
+	return append(slices.Clone(roots), signerRoot...)
+}
+
+// externalSignerDialOptions returns the dial options for the external signers. If all targets are local
+// unix sockets they are dialed without TLS; otherwise signers are verified using EXTERNAL_SIGNER_CA_CERT
+// or the system roots.
+func externalSignerDialOptions() ([]grpc.DialOption, error) {
+	if len(splitSignerAddresses(externalSignerAddresses)) == 0 {
+		return nil, fmt.Errorf("EXTERNAL_SIGNER_ADDRESSES must be set when EXTERNAL_CA is %s", ra.ExtCAGrpc)
+	}
+	allLocal := true
+	for _, addr := range splitSignerAddresses(externalSignerAddresses) {
+		allLocal = allLocal && strings.HasPrefix(addr, "unix://")
+	}
+	if allLocal {
+		return []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, nil
+	}
+	var roots *x509.CertPool
+	if externalSignerCACert != "" {
+		caCert, err := os.ReadFile(externalSignerCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read external signer CA cert: %v", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in external signer CA cert %s", externalSignerCACert)
+		}
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+	}))}, nil
+}
+
 // checkCABundleCompleteness checks if all required CA certificate files exist
 // this function may return bundleExists as false even when some files exist in case of an error
 func checkCABundleCompleteness(
@@ -337,3 +337,10 @@ func TestPemBundleHasSubsetRelation(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitSignerAddresses(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(splitSignerAddresses("unix:///var/run/signer.sock, signer-1:9443 ,,signer-2:9443 ")).
+		To(Equal([]string{"unix:///var/run/signer.sock", "signer-1:9443", "signer-2:9443"}))
+	g.Expect(splitSignerAddresses(" ")).To(BeEmpty())
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"time"
 
+	"istio.io/istio/pilot/pkg/features"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/ctrlz"
@@ -151,6 +152,12 @@ func (p *PilotArgs) applyDefaults() {
 	p.KeepaliveOptions = keepalive.DefaultOption()
 	p.RegistryOptions.ClusterRegistriesNamespace = p.Namespace
 	p.KrtDebugger = new(krt.DebugHandler)
+	if features.KrtHistorySize > 0 {
+		p.KrtDebugger.EnableHistory(features.KrtHistorySize)
+	}
+	if features.KrtProfiling {
+		p.KrtDebugger.EnableProfiling()
+	}
 }
 
 func (p *PilotArgs) Complete() error {
@@ -40,7 +40,9 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pilot/pkg/config/kube/agentgateway"
+	"istio.io/istio/pilot/pkg/config/kube/divergence"
 	"istio.io/istio/pilot/pkg/controllers/ipallocate"
+	"istio.io/istio/pilot/pkg/controllers/mcs"
 	"istio.io/istio/pilot/pkg/controllers/untaint"
 	kubecredentials "istio.io/istio/pilot/pkg/credentials/kube"
 	"istio.io/istio/pilot/pkg/features"
@@ -78,6 +80,7 @@ import (
 	"istio.io/istio/pkg/monitoring"
 	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/util/sets"
 	xdspkg "istio.io/istio/pkg/xds"
@@ -296,6 +299,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		namespaces := kclient.New[*corev1.Namespace](s.kubeClient)
 		filter := namespace.NewDiscoveryNamespacesFilter(namespaces, s.environment.Watcher, s.internalStop)
 		s.kubeClient = kubelib.SetObjectFilter(s.kubeClient, filter)
+		if d, ok := filter.(namespace.DebuggableFilter); ok {
+			s.XDSServer.DiscoveryFilter = d.Dump
+		}
 	}
 
 	s.initMeshNetworks(args, s.fileWatcher)
@@ -344,6 +350,10 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		return nil, err
 	}
 
+	if err := s.initSpiffeFederation(); err != nil {
+		return nil, fmt.Errorf("error initializing SPIFFE federation: %v", err)
+	}
+
 	// Parse and validate Istiod Address.
 	istiodHost, _, err := e.GetDiscoveryAddress()
 	if err != nil {
@@ -363,6 +373,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	// common https server for webhooks (e.g. injection, validation)
 	if s.kubeClient != nil {
 		s.initSecureWebhookServer(args)
+		s.initSpiffeBundleEndpoint()
 		wh, err := s.initSidecarInjector(args)
 		if err != nil {
 			return nil, fmt.Errorf("error initializing sidecar injector: %v", err)
@@ -418,6 +429,14 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		s.XDSServer.Authenticators = authenticators
 	}
 	caOpts.Authenticators = authenticators
+	// Attestation is only accepted for certificate signing, as join tokens are single use.
+	attestationAuthn, err := s.initWorkloadAttestation(args)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing workload attestation: %v", err)
+	}
+	if attestationAuthn != nil {
+		caOpts.Authenticators = append(slices.Clone(authenticators), attestationAuthn)
+	}
 
 	// Start CA or RA server. This should be called after CA and Istiod certs have been created.
 	s.startCA(caOpts)
@@ -601,7 +620,7 @@ func (s *Server) initKubeClient(args *PilotArgs) error {
 			return fmt.Errorf("failed creating kube config: %v", err)
 		}
 
-		s.kubeClient, err = kubelib.NewClient(kubelib.NewClientConfigForRestConfig(kubeRestConfig), s.clusterID)
+		s.kubeClient, err = kubelib.NewClient(kubelib.NewClientConfigForRestConfig(kubeRestConfig), s.clusterID, multicluster.ClientOptions()...)
 		if err != nil {
 			return fmt.Errorf("failed creating kube client: %v", err)
 		}
@@ -815,6 +834,8 @@ func (s *Server) initSecureDiscoveryService(args *PilotArgs, trustDomain string)
 	if args.ServerOptions.TLSOptions.MinVersion != 0 {
 		cfg.MinVersion = args.ServerOptions.TLSOptions.MinVersion
 	}
+	// Key exchange groups, including hybrid post-quantum ones, follow the mesh mTLS settings.
+	sec_model.ApplyGoCurvePreferences(cfg, s.environment.Mesh().GetMeshMTLS().GetEcdhCurves())
 	// Compliance for xDS server TLS.
 	sec_model.EnforceGoCompliance(cfg)
 
@@ -1088,6 +1109,7 @@ func (s *Server) createPeerCertVerifier(tlsOptions TLSOptions, trustDomain strin
 		if rootCertBytes, err = os.ReadFile(caCertPath); err != nil {
 			return nil, err
 		}
+		rootCertBytes = s.withExternalSignerRoot(rootCertBytes)
 	} else {
 		if s.RA != nil {
 			if after, ok := strings.CutPrefix(features.PilotCertProvider, constants.CertProviderKubernetesSignerPrefix); ok {
@@ -1187,6 +1209,18 @@ func (s *Server) initControllers(args *PilotArgs) error {
 		s.initIPAutoallocateController(args)
 	}
 
+	if features.TrackMulticlusterConfigDivergence && s.multiclusterController != nil {
+		s.XDSServer.ConfigDivergences = divergence.NewTracker(s.multiclusterController).Divergences
+	}
+
+	if features.EnableMCSController {
+		prefix, err := netip.ParsePrefix(features.MCSClusterSetIPPrefix)
+		if err != nil {
+			return fmt.Errorf("invalid MCS ClusterSet IP prefix %s: %v", features.MCSClusterSetIPPrefix, err)
+		}
+		s.initMCSController(args, prefix)
+	}
+
 	s.initKubeOptions(args)
 
 	if err := s.initConfigController(args); err != nil {
@@ -1228,6 +1262,22 @@ func (s *Server) initIPAutoallocateController(args *PilotArgs) {
 	})
 }
 
+func (s *Server) initMCSController(args *PilotArgs, prefix netip.Prefix) {
+	if s.kubeClient == nil {
+		return
+	}
+	// The controller must be created before the clusters are added, but only writes while it is the leader.
+	controller := mcs.NewController(s.kubeClient, s.clusterID, s.multiclusterController, prefix)
+	s.addStartFunc("mcs controller", func(stop <-chan struct{}) error {
+		go leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.MCSController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				controller.Run(leaderStop)
+			}).Run(stop)
+		return nil
+	})
+}
+
 func (s *Server) initMulticluster(args *PilotArgs) {
 	if s.kubeClient == nil {
 		return
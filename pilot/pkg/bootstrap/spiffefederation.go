@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"istio.io/istio/pilot/pkg/features"
+	tb "istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// initSpiffeFederation configures fetching of foreign trust domain bundles from their SPIFFE bundle endpoints.
+// Must be called after the workload trust bundle is created.
+func (s *Server) initSpiffeFederation() error {
+	domains, err := tb.ParseFederationConfig(features.SpiffeFederationConfig)
+	if err != nil {
+		return err
+	}
+	if len(domains) == 0 {
+		return nil
+	}
+	if s.workloadTrustBundle == nil {
+		return fmt.Errorf("PILOT_SPIFFE_FEDERATION requires ISTIO_MULTIROOT_MESH to be enabled")
+	}
+	webRoots, err := x509.SystemCertPool()
+	if err != nil {
+		log.Warnf("failed to load system cert pool for SPIFFE federation: %v", err)
+	}
+	federation, err := tb.NewFederation(s.workloadTrustBundle, domains, webRoots)
+	if err != nil {
+		return err
+	}
+	s.addStartFunc("spiffe federation", func(stop <-chan struct{}) error {
+		go federation.Run(stop)
+		return nil
+	})
+	log.Infof("SPIFFE federation configured for trust domains %v", federation.TrustDomains())
+	return nil
+}
+
+// initSpiffeBundleEndpoint serves the local trust domain bundle on the https server, so meshes in
+// other trust domains can federate with this one.
+func (s *Server) initSpiffeBundleEndpoint() {
+	if !features.EnableSpiffeBundleEndpoint || s.httpsMux == nil {
+		return
+	}
+	server := spiffe.NewBundleServer(s.environment.Mesh().GetTrustDomain(), tb.FederationDefaultRefreshPeriod, s.localTrustAnchors)
+	s.httpsMux.Handle(spiffe.BundleEndpointPath, server)
+	log.Infof("serving SPIFFE bundle endpoint at %s", spiffe.BundleEndpointPath)
+}
+
+// localTrustAnchors returns the roots of the local trust domain, excluding any federated roots.
+func (s *Server) localTrustAnchors() []*x509.Certificate {
+	var pems []string
+	if s.workloadTrustBundle != nil {
+		pems = s.workloadTrustBundle.GetLocalTrustBundle()
+	} else if s.CA != nil {
+		pems = []string{string(s.CA.GetCAKeyCertBundle().GetRootCertPem())}
+	}
+	var certs []*x509.Certificate
+	for _, p := range pems {
+		rest := []byte(p)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				log.Warnf("skipping invalid trust anchor in SPIFFE bundle: %v", err)
+				continue
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return certs
+}
@@ -60,6 +60,7 @@ func (s *Server) initSecureWebhookServer(args *PilotArgs) {
 	if args.ServerOptions.TLSOptions.MinVersion != 0 {
 		tlsConfig.MinVersion = args.ServerOptions.TLSOptions.MinVersion
 	}
+	sec_model.ApplyGoCurvePreferences(tlsConfig, s.environment.Mesh().GetTlsDefaults().GetEcdhCurves())
 	// Compliance for control plane validation and injection webhook server.
 	sec_model.EnforceGoCompliance(tlsConfig)
 
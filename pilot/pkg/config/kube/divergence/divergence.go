@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package divergence compares the Istio configuration of the clusters of a multi-primary mesh. Each istiod only reads
+// the configuration of its own cluster, so copies of a configuration that differ across clusters (for instance when a
+// GitOps sync failed in one of them) cause asymmetric routing.
+package divergence
+
+import (
+	"cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	networkingclient "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/analysis/analyzers/multicluster"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/gvr"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/kube/kubetypes"
+	kubemulticluster "istio.io/istio/pkg/kube/multicluster"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+)
+
+var log = istiolog.RegisterScope("divergence", "multicluster configuration divergence")
+
+// Divergence is a configuration whose copies differ across clusters.
+type Divergence struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// SpecHashes is the hash of the spec of each copy of the configuration, by cluster.
+	SpecHashes map[cluster.ID]string `json:"specHashes"`
+}
+
+// Tracker reads the configuration of all the clusters of the mesh, through the clients of the multicluster controller,
+// and reports the configuration that diverges.
+type Tracker struct {
+	clusters *kubemulticluster.Component[clusterConfig]
+}
+
+// clusterConfig holds the configuration of a cluster compared across clusters.
+type clusterConfig struct {
+	cluster          cluster.ID
+	virtualServices  kclient.Informer[*networkingclient.VirtualService]
+	destinationRules kclient.Informer[*networkingclient.DestinationRule]
+}
+
+func (c clusterConfig) Close() {
+	c.virtualServices.ShutdownHandlers()
+	c.destinationRules.ShutdownHandlers()
+}
+
+func (c clusterConfig) HasSynced() bool {
+	return c.virtualServices.HasSynced() && c.destinationRules.HasSynced()
+}
+
+// NewTracker creates a tracker of the configuration of the clusters. It must be created before the clusters are added.
+func NewTracker(clusters kubemulticluster.ComponentBuilder) *Tracker {
+	return &Tracker{
+		clusters: kubemulticluster.BuildMultiClusterComponent(clusters, func(cl *kubemulticluster.Cluster) clusterConfig {
+			filter := kubetypes.Filter{ObjectFilter: cl.Client.ObjectFilter()}
+			return clusterConfig{
+				cluster: cl.ID,
+				virtualServices: kclient.NewDelayedInformer[*networkingclient.VirtualService](cl.Client,
+					gvr.VirtualService, kubetypes.StandardInformer, filter),
+				destinationRules: kclient.NewDelayedInformer[*networkingclient.DestinationRule](cl.Client,
+					gvr.DestinationRule, kubetypes.StandardInformer, filter),
+			}
+		}),
+	}
+}
+
+type configKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// Divergences returns the configuration whose copies differ across the clusters that synced, sorted by kind, namespace
+// and name. Configuration that only exists in some of the clusters is not reported.
+func (t *Tracker) Divergences() []Divergence {
+	hashes := map[configKey]map[cluster.ID]string{}
+	add := func(c cluster.ID, key configKey, spec any) {
+		h, err := multicluster.SpecHash(spec)
+		if err != nil {
+			log.Debugf("failed to hash %s %s/%s of cluster %s: %v", key.kind, key.namespace, key.name, c, err)
+			return
+		}
+		if hashes[key] == nil {
+			hashes[key] = map[cluster.ID]string{}
+		}
+		hashes[key][c] = h
+	}
+	for _, c := range t.clusters.All() {
+		if !c.HasSynced() {
+			// We may not have read all the copies of the cluster yet.
+			continue
+		}
+		for _, vs := range c.virtualServices.List(metav1.NamespaceAll, klabels.Everything()) {
+			add(c.cluster, configKey{gvk.VirtualService.Kind, vs.Namespace, vs.Name}, &vs.Spec)
+		}
+		for _, dr := range c.destinationRules.List(metav1.NamespaceAll, klabels.Everything()) {
+			add(c.cluster, configKey{gvk.DestinationRule.Kind, dr.Namespace, dr.Name}, &dr.Spec)
+		}
+	}
+
+	var res []Divergence
+	for key, clusterHashes := range hashes {
+		if !multicluster.Diverges(clusterHashes) {
+			continue
+		}
+		res = append(res, Divergence{Kind: key.kind, Namespace: key.namespace, Name: key.name, SpecHashes: clusterHashes})
+	}
+	return slices.SortFunc(res, func(a, b Divergence) int {
+		if r := cmp.Compare(a.Kind, b.Kind); r != 0 {
+			return r
+		}
+		if r := cmp.Compare(a.Namespace, b.Namespace); r != 0 {
+			return r
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
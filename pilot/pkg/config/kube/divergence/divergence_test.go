@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package divergence
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	networking "istio.io/api/networking/v1"
+	networkingclient "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/schema/gvr"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/kube/multicluster"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestTracker(t *testing.T) {
+	stop := test.NewStop(t)
+	mc := multicluster.NewFakeController()
+	tracker := NewTracker(mc)
+
+	clients := map[cluster.ID]kube.Client{}
+	for _, id := range []cluster.ID{"c1", "c2"} {
+		c := kube.NewFakeClient()
+		clienttest.MakeCRD(t, c, gvr.VirtualService)
+		clienttest.MakeCRD(t, c, gvr.DestinationRule)
+		mc.Add(id, c, stop)
+		c.RunAndWait(stop)
+		clients[id] = c
+	}
+	virtualService := func(c cluster.ID, name string, subset string) {
+		clienttest.NewWriter[*networkingclient.VirtualService](t, clients[c]).CreateOrUpdate(&networkingclient.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"cluster": c.String()}},
+			Spec: networking.VirtualService{
+				Hosts: []string{name},
+				Http: []*networking.HTTPRoute{{
+					Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: name, Subset: subset}}},
+				}},
+			},
+		})
+	}
+	destinationRule := func(c cluster.ID, name string) {
+		clienttest.NewWriter[*networkingclient.DestinationRule](t, clients[c]).CreateOrUpdate(&networkingclient.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       networking.DestinationRule{Host: name},
+		})
+	}
+	divergences := func() []string {
+		return slices.Map(tracker.Divergences(), func(d Divergence) string {
+			return d.Kind + "/" + d.Namespace + "/" + d.Name
+		})
+	}
+
+	// Copies that only differ by their metadata, or only exist in one cluster, do not diverge.
+	virtualService("c1", "reviews", "v1")
+	virtualService("c2", "reviews", "v1")
+	virtualService("c1", "details", "v1")
+	destinationRule("c1", "reviews")
+	destinationRule("c2", "reviews")
+	assert.EventuallyEqual(t, func() int {
+		n := 0
+		for _, c := range tracker.clusters.All() {
+			n += len(c.virtualServices.List(metav1.NamespaceAll, klabels.Everything()))
+			n += len(c.destinationRules.List(metav1.NamespaceAll, klabels.Everything()))
+		}
+		return n
+	}, 5)
+	assert.Equal(t, divergences(), nil)
+
+	virtualService("c2", "reviews", "v2")
+	assert.EventuallyEqual(t, divergences, []string{"VirtualService/ns/reviews"})
+	d := tracker.Divergences()[0]
+	assert.Equal(t, len(d.SpecHashes), 2)
+	assert.Equal(t, d.SpecHashes["c1"] != d.SpecHashes["c2"], true)
+
+	// Removing a cluster removes its copies.
+	mc.Delete("c2")
+	assert.EventuallyEqual(t, divergences, nil)
+}
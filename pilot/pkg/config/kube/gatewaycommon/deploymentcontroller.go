@@ -92,7 +92,7 @@ type DeploymentController struct {
 	client              kube.Client
 	clusterID           cluster.ID
 	env                 *model.Environment
-	queue               controllers.Queue
+	queue               controllers.TypedQueue[types.NamespacedName]
 	patcher             patcher
 	gateways            kclient.Client[*gateway.Gateway]
 	gatewayClasses      kclient.Client[*gateway.GatewayClass]
@@ -159,9 +159,7 @@ func NewDeploymentController(
 		}
 		return []types.NamespacedName{*p}
 	})
-	dc.queue = controllers.NewQueue("gateway deployment",
-		controllers.WithReconciler(dc.Reconcile),
-		controllers.WithMaxAttempts(5))
+	dc.queue = controllers.NewTypedQueue("gateway deployment", dc.Reconcile, controllers.WithMaxAttempts(5))
 
 	dc.listenerSets = kclient.NewDelayedInformer[*gateway.ListenerSet](client, gvr.ListenerSet, kubetypes.StandardInformer, filter)
 	dc.listenerSetByParent = kclient.CreateIndex(dc.listenerSets, "parent", func(o *gateway.ListenerSet) []types.NamespacedName {
@@ -174,7 +172,7 @@ func NewDeploymentController(
 	// Set up a handler that will add the parent Gateway object onto the queue.
 	// The queue will only handle Gateway objects; if child resources (Service, etc) are updated we re-add
 	// the Gateway to the queue and reconcile the state of the world.
-	parentHandler := controllers.ObjectHandler(controllers.EnqueueForParentHandler(dc.queue, gvk.KubernetesGateway))
+	parentHandler := controllers.ObjectHandler(controllers.TypedEnqueueForParentHandler(dc.queue, gvk.KubernetesGateway))
 
 	dc.services = kclient.NewFiltered[*corev1.Service](client, filter)
 	dc.services.AddEventHandler(parentHandler)
@@ -185,14 +183,14 @@ func NewDeploymentController(
 		// This could be a configmap referenced by a Gateway paramsRef
 		impacted := gatewaysByParamsRef.Lookup(config.NamespacedName(o))
 		for _, gw := range impacted {
-			dc.queue.AddObject(gw)
+			dc.queue.Add(config.NamespacedName(gw))
 		}
 		// Or it could also be a global GatewayClass config
 		classDefaults, classDefaultsF := o.GetLabels()[GatewayClassDefaults]
 		if classDefaultsF && o.GetNamespace() == dc.systemNamespace {
 			for _, gw := range dc.gateways.List(metav1.NamespaceAll, klabels.Everything()) {
 				if string(gw.Spec.GatewayClassName) == classDefaults {
-					dc.queue.AddObject(gw)
+					dc.queue.Add(config.NamespacedName(gw))
 				}
 			}
 		}
@@ -219,7 +217,7 @@ func NewDeploymentController(
 		// TODO: make this more intelligent, checking if something we care about has changed
 		// requeue this namespace
 		for _, gw := range dc.gateways.List(o.GetName(), klabels.Everything()) {
-			dc.queue.AddObject(gw)
+			dc.queue.Add(config.NamespacedName(gw))
 		}
 	}))
 
@@ -227,7 +225,7 @@ func NewDeploymentController(
 		// if there is a change to the meshconfig we need to reprocess all gateways
 		// to reconcile things like the deployment image
 		for _, gw := range dc.gateways.List(corev1.NamespaceAll, klabels.Everything()) {
-			dc.queue.AddObject(gw)
+			dc.queue.Add(config.NamespacedName(gw))
 		}
 	})
 
@@ -238,23 +236,23 @@ func NewDeploymentController(
 		controllers.FromEventHandler(func(o controllers.Event) {
 			switch o.Event {
 			case controllers.EventAdd:
-				dc.queue.AddObject(o.New)
+				dc.queue.Add(config.NamespacedName(o.New))
 			case controllers.EventUpdate:
 				if o.New.GetGeneration() != o.Old.GetGeneration() {
-					dc.queue.AddObject(o.New)
+					dc.queue.Add(config.NamespacedName(o.New))
 					break
 				}
 				if !maps.Equal(o.New.GetLabels(), o.Old.GetLabels()) {
-					dc.queue.AddObject(o.New)
+					dc.queue.Add(config.NamespacedName(o.New))
 					break
 				}
 				if !maps.Equal(o.New.GetAnnotations(), o.Old.GetAnnotations()) {
-					dc.queue.AddObject(o.New)
+					dc.queue.Add(config.NamespacedName(o.New))
 					break
 				}
 				log.Debugf("skip unchanged gateway %s", o.New.GetName())
 			case controllers.EventDelete:
-				dc.queue.AddObject(o.Old)
+				dc.queue.Add(config.NamespacedName(o.Old))
 			default:
 				log.Errorf("unhandled event for gateway object %v", o)
 			}
@@ -263,7 +261,7 @@ func NewDeploymentController(
 	gatewayClasses.AddEventHandler(controllers.ObjectHandler(func(o controllers.Object) {
 		for _, g := range dc.gateways.List(metav1.NamespaceAll, klabels.Everything()) {
 			if string(g.Spec.GatewayClassName) == o.GetName() {
-				dc.queue.AddObject(g)
+				dc.queue.Add(config.NamespacedName(g))
 			}
 		}
 	}))
@@ -271,7 +269,7 @@ func NewDeploymentController(
 	// On injection template change, requeue all gateways
 	injectionHandler(func() {
 		for _, gw := range dc.gateways.List(metav1.NamespaceAll, klabels.Everything()) {
-			dc.queue.AddObject(gw)
+			dc.queue.Add(config.NamespacedName(gw))
 		}
 	})
 
@@ -861,7 +859,7 @@ func (d *DeploymentController) apply(controller string, yml string, input Templa
 
 func (d *DeploymentController) HandleTagChange(newTags sets.String) {
 	for _, gw := range d.gateways.List(metav1.NamespaceAll, klabels.Everything()) {
-		d.queue.AddObject(gw)
+		d.queue.Add(config.NamespacedName(gw))
 	}
 }
 
@@ -1282,8 +1282,7 @@ func TestHandlerEnqueueFunction(t *testing.T) {
 			}, tw, "", "")
 			d.queue.ShutDownEarly()
 			client.RunAndWait(stop)
-			d.queue = controllers.NewQueue("fake gateway queue",
-				controllers.WithReconciler(dummyReconcile))
+			d.queue = controllers.NewTypedQueue("fake gateway queue", dummyReconcile)
 			go d.queue.Run(stop)
 			kube.WaitForCacheSync("test", stop, d.queue.HasSynced, d.gateways.HasSynced, tw.HasSynced)
 
@@ -48,7 +48,7 @@ type IPAllocator struct {
 	serviceEntryWriter kclient.Writer[*networkingv1.ServiceEntry]
 	index              kclient.Index[netip.Addr, *networkingv1.ServiceEntry]
 	stopChan           <-chan struct{}
-	queue              controllers.Queue
+	queue              controllers.TypedQueue[allocatorKey]
 
 	// This controller is not safe for concurrency but it exists outside the critical path performing important but minimal functionality in a single thread.
 	// If we want the multi-thread this controller we must add locking around accessing the allocators which would otherwise be racy
@@ -71,6 +71,12 @@ type conflictDetectedEvent struct {
 	conflictingAddresses          string
 }
 
+// allocatorKey is a key of the allocator queue: either a ServiceEntry to reconcile, or an address conflict to resolve.
+type allocatorKey struct {
+	serviceEntry types.NamespacedName
+	conflict     conflictDetectedEvent
+}
+
 const conflictDetectedEventDelim = `,`
 
 func (event conflictDetectedEvent) getAddresses() []netip.Addr {
@@ -129,8 +135,10 @@ func NewIPAllocator(stop <-chan struct{}, c kubelib.Client) *IPAllocator {
 		v4allocator: newPrefixUse(netip.MustParsePrefix(features.IPAutoallocateIPv4Prefix)),
 		v6allocator: newPrefixUse(netip.MustParsePrefix(features.IPAutoallocateIPv6Prefix)),
 	}
-	allocator.queue = controllers.NewQueue(controllerName, controllers.WithGenericReconciler(allocator.reconcile), controllers.WithMaxAttempts(5))
-	client.AddEventHandler(controllers.ObjectHandler(allocator.queue.AddObject))
+	allocator.queue = controllers.NewTypedQueue(controllerName, allocator.reconcile, controllers.WithMaxAttempts(5))
+	client.AddEventHandler(controllers.ObjectHandler(func(o controllers.Object) {
+		allocator.queue.Add(allocatorKey{serviceEntry: config.NamespacedName(o)})
+	}))
 	return allocator
 }
 
@@ -161,15 +169,11 @@ func (c *IPAllocator) populateControllerDatastructures() {
 	log.Debugf("discovered %v during warming", count)
 }
 
-func (c *IPAllocator) reconcile(a any) error {
-	if nn, ok := a.(types.NamespacedName); ok {
-		return c.reconcileServiceEntry(nn)
-	}
-	if conflict, ok := a.(conflictDetectedEvent); ok {
-		return c.resolveConflict(conflict)
+func (c *IPAllocator) reconcile(key allocatorKey) error {
+	if key.conflict != (conflictDetectedEvent{}) {
+		return c.resolveConflict(key.conflict)
 	}
-
-	return nil
+	return c.reconcileServiceEntry(key.serviceEntry)
 }
 
 func (c *IPAllocator) reconcileServiceEntry(se types.NamespacedName) error {
@@ -348,7 +352,7 @@ func (c *IPAllocator) markUsedOrQueueConflict(maybeMappedAddrs []netip.Addr, own
 		}
 	}
 	if len(conflictingAddrs) > 0 {
-		c.queue.Add(newConflictDetectedEvent(owner, conflictingAddrs))
+		c.queue.Add(allocatorKey{conflict: newConflictDetectedEvent(owner, conflictingAddrs)})
 	}
 }
 
@@ -609,3 +613,36 @@ func (i prefixUse) markUsed(n netip.Addr, owner types.NamespacedName) (used, use
 
 	return false, false
 }
+
+// PrefixAllocator allocates the addresses of a prefix to owners, like the allocator does for ServiceEntries.
+// It is not safe for concurrent use.
+type PrefixAllocator struct {
+	prefix *prefixUse
+}
+
+// NewPrefixAllocator creates an allocator of the addresses of p.
+func NewPrefixAllocator(p netip.Prefix) *PrefixAllocator {
+	return &PrefixAllocator{prefix: newPrefixUse(p)}
+}
+
+// Allocate allocates the next free address to owner.
+func (a *PrefixAllocator) Allocate(owner types.NamespacedName) (netip.Addr, error) {
+	return a.prefix.allocateNext(owner)
+}
+
+// Claim marks addr as used by owner. It returns false if addr is already used by another owner.
+// Addresses outside of the prefix are not tracked, and can always be claimed.
+func (a *PrefixAllocator) Claim(addr netip.Addr, owner types.NamespacedName) bool {
+	used, usedByOwner := a.prefix.markUsed(addr, owner)
+	return !used || usedByOwner
+}
+
+// Free releases addr, if it is used by owner.
+func (a *PrefixAllocator) Free(addr netip.Addr, owner types.NamespacedName) {
+	a.prefix.free(addr, owner)
+}
+
+// Contains returns true if addr is in the prefix of the allocator.
+func (a *PrefixAllocator) Contains(addr netip.Addr) bool {
+	return a.prefix.prefix.Contains(addr)
+}
@@ -0,0 +1,437 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mcs implements a Kubernetes Multi-Cluster Services (MCS) controller: it creates the ServiceImports of the
+// services exported in any cluster of the mesh, allocates their ClusterSet VIPs, and reports export conflicts.
+package mcs
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/controllers/ipallocate"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/kube/kubetypes"
+	kubemcs "istio.io/istio/pkg/kube/mcs"
+	"istio.io/istio/pkg/kube/multicluster"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+)
+
+var log = istiolog.RegisterScope("mcs", "Kubernetes Multi-Cluster Services controller")
+
+const (
+	// ManagedByLabel marks the ServiceImports created by the controller. ServiceImports without it are left alone.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "istiod"
+)
+
+// Controller creates a ServiceImport in the config cluster for each service exported in any cluster of the mesh.
+// It reads the exports of all clusters, but only writes while it is running, which should be done by a single istiod
+// per cluster.
+type Controller struct {
+	clusterID cluster.ID
+	client    kube.Client
+	imports   kclient.Untyped
+	clusters  *multicluster.Component[clusterExports]
+
+	// queue is only set while the controller is running.
+	queue atomic.Pointer[controllers.TypedQueue[types.NamespacedName]]
+	// vips is only accessed by the queue.
+	vips *ipallocate.PrefixAllocator
+
+	prefix netip.Prefix
+}
+
+// clusterExports holds the ServiceExports and Services of a cluster.
+type clusterExports struct {
+	cluster  cluster.ID
+	exports  kclient.Untyped
+	services kclient.Client[*corev1.Service]
+	enqueue  func(key types.NamespacedName)
+}
+
+func (e clusterExports) Close() {
+	// Reconcile the services the cluster exported, once it is removed.
+	for _, se := range e.exports.List(metav1.NamespaceAll, klabels.Everything()) {
+		e.enqueue(config.NamespacedName(se))
+	}
+	e.exports.ShutdownHandlers()
+	e.services.ShutdownHandlers()
+}
+
+func (e clusterExports) HasSynced() bool {
+	return e.exports.HasSynced() && e.services.HasSynced()
+}
+
+// NewController creates a controller writing ServiceImports to client, the client of the config cluster, with VIPs
+// allocated from prefix. It must be created before the clusters are added.
+func NewController(client kube.Client, clusterID cluster.ID, clusters multicluster.ComponentBuilder, prefix netip.Prefix) *Controller {
+	c := &Controller{
+		clusterID: clusterID,
+		client:    client,
+		prefix:    prefix,
+	}
+	c.imports = kclient.NewDelayedInformer[controllers.Object](client, kubemcs.ServiceImportGVR, kubetypes.DynamicInformer, kclient.Filter{
+		ObjectFilter: client.ObjectFilter(),
+	})
+	c.imports.AddEventHandler(controllers.FilteredObjectHandler(func(o controllers.Object) {
+		c.enqueue(config.NamespacedName(o))
+	}, isManaged))
+
+	c.clusters = multicluster.BuildMultiClusterComponent(clusters, func(cl *multicluster.Cluster) clusterExports {
+		filter := kclient.Filter{ObjectFilter: cl.Client.ObjectFilter()}
+		e := clusterExports{
+			cluster:  cl.ID,
+			exports:  kclient.NewDelayedInformer[controllers.Object](cl.Client, kubemcs.ServiceExportGVR, kubetypes.DynamicInformer, filter),
+			services: kclient.NewFiltered[*corev1.Service](cl.Client, filter),
+			enqueue:  c.enqueue,
+		}
+		e.exports.AddEventHandler(controllers.ObjectHandler(func(o controllers.Object) {
+			c.enqueue(config.NamespacedName(o))
+		}))
+		// Only Services that are exported matter.
+		e.services.AddEventHandler(controllers.FilteredObjectHandler(func(o controllers.Object) {
+			c.enqueue(config.NamespacedName(o))
+		}, func(o controllers.Object) bool {
+			return e.exports.Get(o.GetName(), o.GetNamespace()) != nil
+		}))
+		return e
+	})
+	return c
+}
+
+// Run reconciles the ServiceImports until stop is closed. It can be called again once it returned, for instance when
+// regaining leadership.
+func (c *Controller) Run(stop <-chan struct{}) {
+	configCluster := func() bool {
+		e := c.clusters.ForCluster(c.clusterID)
+		return e != nil && e.HasSynced()
+	}
+	if !kube.WaitForCacheSync("mcs controller", stop, c.imports.HasSynced, configCluster) {
+		return
+	}
+	// Rebuild the allocations from the ServiceImports we manage.
+	c.vips = ipallocate.NewPrefixAllocator(c.prefix)
+	for _, si := range c.imports.List(metav1.NamespaceAll, klabels.Everything()) {
+		if !isManaged(si) {
+			continue
+		}
+		owner := config.NamespacedName(si)
+		for _, ip := range importIPs(si) {
+			if !c.vips.Claim(ip, owner) {
+				log.Warnf("ServiceImport %v has VIP %v, which is already allocated; it will be reallocated", owner, ip)
+			}
+		}
+	}
+
+	q := controllers.NewTypedQueue[types.NamespacedName]("mcs controller", c.reconcile, controllers.WithMaxAttempts(5))
+	c.queue.Store(&q)
+	defer c.queue.Store(nil)
+	// Events received while we were not running were dropped, so reconcile everything.
+	for _, e := range c.clusters.All() {
+		for _, se := range e.exports.List(metav1.NamespaceAll, klabels.Everything()) {
+			q.Add(config.NamespacedName(se))
+		}
+	}
+	for _, si := range c.imports.List(metav1.NamespaceAll, klabels.Everything()) {
+		if isManaged(si) {
+			q.Add(config.NamespacedName(si))
+		}
+	}
+	q.Run(stop)
+}
+
+// HasSynced returns true once the controller is running and reconciled the initial state.
+func (c *Controller) HasSynced() bool {
+	q := c.queue.Load()
+	return q != nil && q.HasSynced()
+}
+
+func (c *Controller) enqueue(key types.NamespacedName) {
+	if q := c.queue.Load(); q != nil {
+		q.Add(key)
+	}
+}
+
+func (c *Controller) reconcile(key types.NamespacedName) error {
+	var exports []export
+	allSynced := true
+	clusters := c.clusters.All()
+	for _, e := range clusters {
+		if !e.HasSynced() {
+			allSynced = false
+		}
+		u := e.exports.Get(key.Name, key.Namespace)
+		if u == nil {
+			continue
+		}
+		se, err := fromUnstructured[mcsapi.ServiceExport](u)
+		if err != nil {
+			log.Warnf("invalid ServiceExport %v in cluster %v: %v", key, e.cluster, err)
+			continue
+		}
+		exports = append(exports, export{cluster: e.cluster, export: se, service: e.services.Get(key.Name, key.Namespace)})
+	}
+	res := resolve(exports)
+
+	for _, e := range exports {
+		if e.cluster != c.clusterID {
+			// We can only write the status of the exports of our cluster; each cluster's controller does the same.
+			continue
+		}
+		if err := c.writeExportStatus(e.export, res.statuses[e.cluster]); err != nil {
+			return err
+		}
+	}
+
+	if res.spec == nil && !allSynced {
+		// The exports may be in a cluster we did not read yet; its events will reconcile the service.
+		return nil
+	}
+	return c.writeImport(key, res)
+}
+
+func (c *Controller) writeExportStatus(se *mcsapi.ServiceExport, status exportStatus) error {
+	conditions := slices.Clone(se.Status.Conditions)
+	changed := false
+	for _, cond := range []*metav1.Condition{&status.valid, status.conflict} {
+		if cond == nil {
+			continue
+		}
+		cond.ObservedGeneration = se.Generation
+		changed = meta.SetStatusCondition(&conditions, *cond) || changed
+	}
+	if status.conflict == nil {
+		changed = meta.RemoveStatusCondition(&conditions, string(mcsapi.ServiceExportConditionConflict)) || changed
+	}
+	if !changed {
+		return nil
+	}
+	se = se.DeepCopy()
+	se.Status.Conditions = conditions
+	u, err := toUnstructured(se, mcsapi.ServiceExportKindName)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.Dynamic().Resource(kubemcs.ServiceExportGVR).Namespace(se.Namespace).UpdateStatus(context.Background(), u, metav1.UpdateOptions{})
+	if kerrors.IsNotFound(err) || kerrors.IsConflict(err) {
+		// The export was deleted or changed; we will be notified.
+		return nil
+	}
+	return err
+}
+
+func (c *Controller) writeImport(key types.NamespacedName, res resolution) error {
+	var existing *mcsapi.ServiceImport
+	if u := c.imports.Get(key.Name, key.Namespace); u != nil {
+		if !isManaged(u) {
+			log.Debugf("ServiceImport %v is not managed by istiod, leaving it alone", key)
+			return nil
+		}
+		si, err := fromUnstructured[mcsapi.ServiceImport](u)
+		if err != nil {
+			return err
+		}
+		existing = si
+	}
+	imports := c.client.Dynamic().Resource(kubemcs.ServiceImportGVR).Namespace(key.Namespace)
+
+	if res.spec == nil {
+		if existing == nil {
+			return nil
+		}
+		c.freeIPs(key, existing.Spec.IPs)
+		log.Infof("deleting ServiceImport %v, which is no longer exported", key)
+		if err := imports.Delete(context.Background(), key.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	spec := *res.spec
+	var prevIPs []string
+	if existing != nil {
+		prevIPs = existing.Spec.IPs
+	}
+	if spec.Type == mcsapi.ClusterSetIP {
+		ips, err := c.allocateIPs(key, prevIPs)
+		if err != nil {
+			return err
+		}
+		spec.IPs = ips
+	} else {
+		c.freeIPs(key, prevIPs)
+	}
+
+	existing, err := c.writeImportSpec(key, existing, spec)
+	if err != nil || existing == nil {
+		// Free the VIPs we allocated for the write; the next reconciliation will allocate them again if needed.
+		c.freeIPs(key, slices.Filter(spec.IPs, func(ip string) bool {
+			return !slices.Contains(prevIPs, ip)
+		}))
+		if kerrors.IsAlreadyExists(err) || kerrors.IsConflict(err) {
+			// Our view of the ServiceImport is stale; we will be notified of its current version.
+			return nil
+		}
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Status.Clusters, res.clusters) {
+		return nil
+	}
+	existing = existing.DeepCopy()
+	existing.Status.Clusters = res.clusters
+	u, err := toUnstructured(existing, mcsapi.ServiceImportKindName)
+	if err != nil {
+		return err
+	}
+	_, err = imports.UpdateStatus(context.Background(), u, metav1.UpdateOptions{})
+	if kerrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+// writeImportSpec creates or updates the ServiceImport key with spec. It returns the resulting ServiceImport, which is
+// nil if it cannot be created.
+func (c *Controller) writeImportSpec(key types.NamespacedName, existing *mcsapi.ServiceImport, spec mcsapi.ServiceImportSpec) (*mcsapi.ServiceImport, error) {
+	imports := c.client.Dynamic().Resource(kubemcs.ServiceImportGVR).Namespace(key.Namespace)
+	if existing != nil {
+		if reflect.DeepEqual(existing.Spec, spec) {
+			return existing, nil
+		}
+		existing = existing.DeepCopy()
+		existing.Spec = spec
+		u, err := toUnstructured(existing, mcsapi.ServiceImportKindName)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := imports.Update(context.Background(), u, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return fromUnstructured[mcsapi.ServiceImport](updated)
+	}
+
+	si := &mcsapi.ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels:    map[string]string{ManagedByLabel: ManagedByValue},
+		},
+		Spec: spec,
+	}
+	u, err := toUnstructured(si, mcsapi.ServiceImportKindName)
+	if err != nil {
+		return nil, err
+	}
+	created, err := imports.Create(context.Background(), u, metav1.CreateOptions{})
+	if kerrors.IsNotFound(err) {
+		// The namespace does not exist in this cluster.
+		log.Debugf("cannot create ServiceImport %v: %v", key, err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("created ServiceImport %v with VIPs %v", key, spec.IPs)
+	return fromUnstructured[mcsapi.ServiceImport](created)
+}
+
+// allocateIPs returns the VIP of a ServiceImport, keeping its previous one if possible.
+func (c *Controller) allocateIPs(owner types.NamespacedName, prev []string) ([]string, error) {
+	var keep []string
+	for _, ip := range prev {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil || !c.vips.Contains(addr) || !c.vips.Claim(addr, owner) {
+			continue
+		}
+		keep = append(keep, ip)
+	}
+	if len(keep) > 0 {
+		return keep, nil
+	}
+	addr, err := c.vips.Allocate(owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate a VIP for ServiceImport %v: %v", owner, err)
+	}
+	return []string{addr.String()}, nil
+}
+
+func (c *Controller) freeIPs(owner types.NamespacedName, ips []string) {
+	for _, ip := range ips {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			c.vips.Free(addr, owner)
+		}
+	}
+}
+
+func isManaged(o controllers.Object) bool {
+	return o.GetLabels()[ManagedByLabel] == ManagedByValue
+}
+
+func importIPs(o controllers.Object) []netip.Addr {
+	si, err := fromUnstructured[mcsapi.ServiceImport](o)
+	if err != nil {
+		return nil
+	}
+	return slices.MapFilter(si.Spec.IPs, func(ip string) *netip.Addr {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil
+		}
+		return &addr
+	})
+}
+
+func fromUnstructured[T any](o controllers.Object) (*T, error) {
+	u, ok := o.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", o)
+	}
+	res := new(T)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func toUnstructured(o runtime.Object, kind string) (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	u.SetAPIVersion(kubemcs.MCSSchemeGroupVersion.String())
+	u.SetKind(kind)
+	return u, nil
+}
@@ -0,0 +1,233 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcs
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/host"
+	dnsServer "istio.io/istio/pkg/dns/server"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	kubemcs "istio.io/istio/pkg/kube/mcs"
+	"istio.io/istio/pkg/kube/multicluster"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type fixture struct {
+	t      *testing.T
+	local  kube.Client
+	remote kube.Client
+	mc     *multicluster.Fake
+}
+
+// newFixture runs the controller of the local cluster. setup is called with the local client once the MCS CRDs exist,
+// before the controller is created.
+func newFixture(t *testing.T, prefix string, setup ...func(local kube.Client)) *fixture {
+	f := &fixture{t: t, local: kube.NewFakeClient(), remote: kube.NewFakeClient(), mc: multicluster.NewFakeController()}
+	for _, c := range []kube.Client{f.local, f.remote} {
+		clienttest.MakeCRD(t, c, kubemcs.ServiceExportGVR)
+		clienttest.MakeCRD(t, c, kubemcs.ServiceImportGVR)
+	}
+	for _, s := range setup {
+		s(f.local)
+	}
+	// Created after setup, so that it is closed before anything setup cleans up.
+	stop := test.NewStop(t)
+	c := NewController(f.local, "local", f.mc, netip.MustParsePrefix(prefix))
+	f.mc.Add("local", f.local, stop)
+	f.mc.Add("remote", f.remote, stop)
+	f.local.RunAndWait(stop)
+	f.remote.RunAndWait(stop)
+	go c.Run(stop)
+	kube.WaitForCacheSync("test", stop, c.HasSynced)
+	return f
+}
+
+func (f *fixture) export(c kube.Client, name string, clusterIP string, ports ...int32) {
+	f.t.Helper()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       corev1.ServiceSpec{ClusterIP: clusterIP, SessionAffinity: corev1.ServiceAffinityNone},
+	}
+	for _, p := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Port: p, Protocol: corev1.ProtocolTCP})
+	}
+	clienttest.NewWriter[*corev1.Service](f.t, c).CreateOrUpdate(svc)
+	f.create(c, kubemcs.ServiceExportGVR, &mcsapi.ServiceExport{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}},
+		mcsapi.ServiceExportKindName)
+}
+
+func (f *fixture) create(c kube.Client, gvr schema.GroupVersionResource, o runtime.Object, kind string) {
+	f.t.Helper()
+	u, err := toUnstructured(o, kind)
+	assert.NoError(f.t, err)
+	_, err = c.Dynamic().Resource(gvr).Namespace(u.GetNamespace()).Create(context.Background(), u, metav1.CreateOptions{})
+	assert.NoError(f.t, err)
+}
+
+func (f *fixture) unexport(c kube.Client, name string) {
+	f.t.Helper()
+	err := c.Dynamic().Resource(kubemcs.ServiceExportGVR).Namespace("ns").Delete(context.Background(), name, metav1.DeleteOptions{})
+	assert.NoError(f.t, err)
+}
+
+// serviceImport returns the ServiceImport of the local cluster, or nil if it does not exist.
+func (f *fixture) serviceImport(name string) *mcsapi.ServiceImport {
+	u, err := f.local.Dynamic().Resource(kubemcs.ServiceImportGVR).Namespace("ns").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	si, err := fromUnstructured[mcsapi.ServiceImport](u)
+	assert.NoError(f.t, err)
+	return si
+}
+
+func (f *fixture) exportCondition(c kube.Client, name string, t mcsapi.ServiceExportConditionType) string {
+	u, err := c.Dynamic().Resource(kubemcs.ServiceExportGVR).Namespace("ns").Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	se, err := fromUnstructured[mcsapi.ServiceExport](u)
+	assert.NoError(f.t, err)
+	if cond := meta.FindStatusCondition(se.Status.Conditions, string(t)); cond != nil {
+		return cond.Reason
+	}
+	return ""
+}
+
+func TestControllerVIPInDNS(t *testing.T) {
+	test.SetForTest(t, &features.EnableMCSHost, true)
+	var registry *kubecontroller.FakeController
+	f := newFixture(t, "240.241.0.0/30", func(local kube.Client) {
+		registry, _ = kubecontroller.NewFakeControllerWithOptions(t, kubecontroller.FakeControllerOptions{
+			Client:       local,
+			ClusterID:    "local",
+			DomainSuffix: "cluster.local",
+		})
+	})
+
+	// The registry of the local cluster turns the ServiceImport into a clusterset.local service, with the allocated VIP.
+	f.export(f.local, "a", "10.0.0.1", 80)
+	hostname := host.Name("a.ns.svc.clusterset.local")
+	assert.EventuallyEqual(t, func() bool { return registry.GetService(hostname) != nil }, true)
+	vip := f.serviceImport("a").Spec.IPs[0]
+
+	push := model.NewPushContext()
+	push.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
+	push.AddPublicServices(registry.Services())
+	proxy := &model.Proxy{
+		IPAddresses: []string{"9.9.9.9"},
+		Metadata:    &model.NodeMetadata{ClusterID: "local"},
+		Type:        model.SidecarProxy,
+		DNSDomain:   "ns.svc.cluster.local",
+	}
+	proxy.SetSidecarScope(push)
+	proxy.DiscoverIPMode()
+	nt := dnsServer.BuildNameTable(dnsServer.Config{Node: proxy, Push: push})
+	assert.Equal(t, nt.Table[hostname.String()].GetIps(), []string{vip})
+	assert.Equal(t, nt.Table["a.ns.svc.cluster.local"].GetIps(), []string{"10.0.0.1"})
+}
+
+func TestController(t *testing.T) {
+	f := newFixture(t, "240.241.0.0/30")
+
+	// A service exported by a remote cluster is imported in the local cluster, with a VIP.
+	f.export(f.remote, "a", "10.0.0.1", 80)
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("a") != nil }, true)
+	si := f.serviceImport("a")
+	assert.Equal(t, si.Labels[ManagedByLabel], ManagedByValue)
+	assert.Equal(t, si.Spec.Type, mcsapi.ClusterSetIP)
+	assert.Equal(t, len(si.Spec.IPs), 1)
+	assert.Equal(t, netip.MustParsePrefix("240.241.0.0/30").Contains(netip.MustParseAddr(si.Spec.IPs[0])), true)
+	vip := si.Spec.IPs[0]
+	clusters := func(name string) []string {
+		si := f.serviceImport(name)
+		if si == nil {
+			return nil
+		}
+		var res []string
+		for _, c := range si.Status.Clusters {
+			res = append(res, c.Cluster)
+		}
+		return res
+	}
+	assert.EventuallyEqual(t, func() []string { return clusters("a") }, []string{"remote"})
+
+	// Exporting it locally as well adds the cluster and keeps the VIP. Only the local export gets a status.
+	f.export(f.local, "a", "10.0.1.1", 80)
+	assert.EventuallyEqual(t, func() []string { return clusters("a") }, []string{"local", "remote"})
+	assert.Equal(t, f.serviceImport("a").Spec.IPs, []string{vip})
+	assert.EventuallyEqual(t, func() string {
+		return f.exportCondition(f.local, "a", mcsapi.ServiceExportConditionValid)
+	}, string(mcsapi.ServiceExportReasonValid))
+	assert.Equal(t, f.exportCondition(f.remote, "a", mcsapi.ServiceExportConditionValid), "")
+
+	// A headless export gets no VIP, and a conflicting one is reported.
+	f.export(f.local, "b", corev1.ClusterIPNone, 80)
+	assert.EventuallyEqual(t, func() mcsapi.ServiceImportType {
+		if si := f.serviceImport("b"); si != nil {
+			return si.Spec.Type
+		}
+		return ""
+	}, mcsapi.Headless)
+	assert.Equal(t, len(f.serviceImport("b").Spec.IPs), 0)
+	f.export(f.remote, "b", "10.0.0.2", 80)
+	assert.EventuallyEqual(t, func() []string { return clusters("b") }, []string{"local", "remote"})
+
+	// Imports not managed by istiod are left alone.
+	f.create(f.local, kubemcs.ServiceImportGVR, &mcsapi.ServiceImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "ns"},
+		Spec:       mcsapi.ServiceImportSpec{Type: mcsapi.ClusterSetIP, IPs: []string{"1.2.3.4"}},
+	}, mcsapi.ServiceImportKindName)
+	f.export(f.remote, "c", "10.0.0.3", 80)
+	f.export(f.remote, "d", "10.0.0.4", 80)
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("d") != nil }, true)
+	assert.Equal(t, f.serviceImport("c").Spec.IPs, []string{"1.2.3.4"})
+	assert.Equal(t, len(f.serviceImport("c").Status.Clusters), 0)
+
+	// Once no cluster exports the service, the import is deleted and its VIP is freed.
+	f.unexport(f.remote, "a")
+	f.unexport(f.local, "a")
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("a") == nil }, true)
+	// The prefix only has room for three VIPs, so the last one needs the VIP of a.
+	f.export(f.remote, "e", "10.0.0.5", 80)
+	f.export(f.remote, "f", "10.0.0.6", 80)
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("e") != nil && f.serviceImport("f") != nil }, true)
+	assert.Equal(t, f.serviceImport("e").Spec.IPs[0] == vip || f.serviceImport("f").Spec.IPs[0] == vip, true)
+}
+
+func TestControllerRemovedCluster(t *testing.T) {
+	f := newFixture(t, "240.241.0.0/16")
+
+	f.export(f.remote, "a", "10.0.0.1", 80)
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("a") != nil }, true)
+	f.mc.Delete("remote")
+	assert.EventuallyEqual(t, func() bool { return f.serviceImport("a") == nil }, true)
+}
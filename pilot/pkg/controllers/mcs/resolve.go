@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcs
+
+import (
+	"cmp"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
+)
+
+// export is a ServiceExport of a cluster, with the Service it exports.
+type export struct {
+	cluster cluster.ID
+	export  *mcsapi.ServiceExport
+	// service is nil if the exported Service does not exist.
+	service *corev1.Service
+}
+
+// exportStatus is the outcome of the conflict resolution for an export.
+type exportStatus struct {
+	valid    metav1.Condition
+	conflict *metav1.Condition
+}
+
+// resolution is the ServiceImport resulting from the exports of a service across the cluster set.
+type resolution struct {
+	// spec is the spec of the ServiceImport, without its IPs. It is nil if no export is valid.
+	spec     *mcsapi.ServiceImportSpec
+	clusters []mcsapi.ClusterStatus
+	statuses map[cluster.ID]exportStatus
+}
+
+// resolve merges the exports of a service, as defined by the MCS API: the oldest export wins any conflict, and the
+// ports of all the exports are merged.
+func resolve(exports []export) resolution {
+	exports = slices.SortFunc(slices.Clone(exports), func(a, b export) int {
+		if r := a.export.CreationTimestamp.Compare(b.export.CreationTimestamp.Time); r != 0 {
+			return r
+		}
+		return cmp.Compare(a.cluster, b.cluster)
+	})
+	res := resolution{statuses: map[cluster.ID]exportStatus{}}
+	var valid []export
+	for _, e := range exports {
+		switch {
+		case e.service == nil:
+			res.statuses[e.cluster] = exportStatus{valid: newCondition(mcsapi.ServiceExportConditionValid, metav1.ConditionFalse,
+				mcsapi.ServiceExportReasonNoService, "Service does not exist")}
+		case e.service.Spec.Type == corev1.ServiceTypeExternalName:
+			res.statuses[e.cluster] = exportStatus{valid: newCondition(mcsapi.ServiceExportConditionValid, metav1.ConditionFalse,
+				mcsapi.ServiceExportReasonInvalidServiceType, "Service of type ExternalName cannot be exported")}
+		default:
+			valid = append(valid, e)
+		}
+	}
+	if len(valid) == 0 {
+		return res
+	}
+
+	oldest := valid[0]
+	spec := &mcsapi.ServiceImportSpec{
+		Type:            importType(oldest.service),
+		SessionAffinity: oldest.service.Spec.SessionAffinity,
+	}
+	ports := map[int32]mcsapi.ServicePort{}
+	for _, e := range valid {
+		var conflict *metav1.Condition
+		setConflict := func(reason mcsapi.ServiceExportConditionReason, msg string) {
+			if conflict == nil {
+				conflict = ptr.Of(newCondition(mcsapi.ServiceExportConditionConflict, metav1.ConditionTrue, reason, msg))
+			}
+		}
+		if t := importType(e.service); t != spec.Type {
+			setConflict(mcsapi.ServiceExportReasonTypeConflict,
+				fmt.Sprintf("type %s conflicts with type %s of the export of cluster %s", t, spec.Type, oldest.cluster))
+		}
+		for _, p := range e.service.Spec.Ports {
+			port := mcsapi.ServicePort{Name: p.Name, Protocol: p.Protocol, AppProtocol: p.AppProtocol, Port: p.Port}
+			existing, f := ports[p.Port]
+			if !f {
+				ports[p.Port] = port
+				continue
+			}
+			if existing.Name != port.Name || existing.Protocol != port.Protocol || ptr.OrEmpty(existing.AppProtocol) != ptr.OrEmpty(port.AppProtocol) {
+				setConflict(mcsapi.ServiceExportReasonPortConflict, fmt.Sprintf("port %d conflicts with the export of another cluster", p.Port))
+			}
+		}
+		if e.service.Spec.SessionAffinity != spec.SessionAffinity {
+			setConflict(mcsapi.ServiceExportReasonSessionAffinityConflict,
+				fmt.Sprintf("session affinity %s conflicts with session affinity %s of the export of cluster %s",
+					e.service.Spec.SessionAffinity, spec.SessionAffinity, oldest.cluster))
+		}
+		if conflict == nil {
+			conflict = ptr.Of(newCondition(mcsapi.ServiceExportConditionConflict, metav1.ConditionFalse, mcsapi.ServiceExportReasonNoConflicts, ""))
+		}
+		res.statuses[e.cluster] = exportStatus{
+			valid:    newCondition(mcsapi.ServiceExportConditionValid, metav1.ConditionTrue, mcsapi.ServiceExportReasonValid, ""),
+			conflict: conflict,
+		}
+		res.clusters = append(res.clusters, mcsapi.ClusterStatus{Cluster: e.cluster.String()})
+	}
+	spec.Ports = slices.SortFunc(maps.Values(ports), func(a, b mcsapi.ServicePort) int {
+		return cmp.Compare(a.Port, b.Port)
+	})
+	res.clusters = slices.SortFunc(res.clusters, func(a, b mcsapi.ClusterStatus) int {
+		return cmp.Compare(a.Cluster, b.Cluster)
+	})
+	res.spec = spec
+	return res
+}
+
+func importType(svc *corev1.Service) mcsapi.ServiceImportType {
+	if svc.Spec.ClusterIP == corev1.ClusterIPNone {
+		return mcsapi.Headless
+	}
+	return mcsapi.ClusterSetIP
+}
+
+func newCondition(t mcsapi.ServiceExportConditionType, status metav1.ConditionStatus, reason mcsapi.ServiceExportConditionReason,
+	msg string,
+) metav1.Condition {
+	return metav1.Condition{
+		Type:    string(t),
+		Status:  status,
+		Reason:  string(reason),
+		Message: msg,
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mcs
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Now()
+	newExport := func(c cluster.ID, age time.Duration, svc *corev1.Service) export {
+		return export{
+			cluster: c,
+			export:  &mcsapi.ServiceExport{ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "ns", CreationTimestamp: metav1.NewTime(now.Add(-age))}},
+			service: svc,
+		}
+	}
+	newService := func(clusterIP string, affinity corev1.ServiceAffinity, ports ...corev1.ServicePort) *corev1.Service {
+		return &corev1.Service{Spec: corev1.ServiceSpec{ClusterIP: clusterIP, SessionAffinity: affinity, Ports: ports}}
+	}
+	http := corev1.ServicePort{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}
+	grpc := corev1.ServicePort{Name: "grpc", Port: 90, Protocol: corev1.ProtocolTCP}
+	renamed := corev1.ServicePort{Name: "web", Port: 80, Protocol: corev1.ProtocolTCP}
+	toImport := func(p corev1.ServicePort) mcsapi.ServicePort {
+		return mcsapi.ServicePort{Name: p.Name, Port: p.Port, Protocol: p.Protocol}
+	}
+
+	cases := []struct {
+		name      string
+		exports   []export
+		spec      *mcsapi.ServiceImportSpec
+		clusters  []string
+		valid     map[cluster.ID]string
+		conflicts map[cluster.ID]string
+	}{
+		{
+			name:    "no exports",
+			exports: nil,
+		},
+		{
+			name: "invalid exports",
+			exports: []export{
+				newExport("c1", time.Hour, nil),
+				newExport("c2", time.Minute, &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName}}),
+			},
+			valid: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonNoService),
+				"c2": string(mcsapi.ServiceExportReasonInvalidServiceType),
+			},
+		},
+		{
+			name: "merged ports",
+			exports: []export{
+				newExport("c2", time.Minute, newService("10.0.0.2", corev1.ServiceAffinityNone, http, grpc)),
+				newExport("c1", time.Hour, newService("10.0.0.1", corev1.ServiceAffinityNone, http)),
+			},
+			spec: &mcsapi.ServiceImportSpec{
+				Type:            mcsapi.ClusterSetIP,
+				SessionAffinity: corev1.ServiceAffinityNone,
+				Ports:           []mcsapi.ServicePort{toImport(http), toImport(grpc)},
+			},
+			clusters: []string{"c1", "c2"},
+			valid: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonValid),
+				"c2": string(mcsapi.ServiceExportReasonValid),
+			},
+			conflicts: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonNoConflicts),
+				"c2": string(mcsapi.ServiceExportReasonNoConflicts),
+			},
+		},
+		{
+			name: "oldest export wins",
+			exports: []export{
+				newExport("c1", time.Minute, newService(corev1.ClusterIPNone, corev1.ServiceAffinityClientIP, renamed)),
+				newExport("c2", time.Hour, newService("10.0.0.2", corev1.ServiceAffinityNone, http)),
+				newExport("c3", time.Second, newService("10.0.0.3", corev1.ServiceAffinityClientIP, http)),
+				newExport("c4", time.Second, nil),
+			},
+			spec: &mcsapi.ServiceImportSpec{
+				Type:            mcsapi.ClusterSetIP,
+				SessionAffinity: corev1.ServiceAffinityNone,
+				Ports:           []mcsapi.ServicePort{toImport(http)},
+			},
+			clusters: []string{"c1", "c2", "c3"},
+			valid: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonValid),
+				"c2": string(mcsapi.ServiceExportReasonValid),
+				"c3": string(mcsapi.ServiceExportReasonValid),
+				"c4": string(mcsapi.ServiceExportReasonNoService),
+			},
+			conflicts: map[cluster.ID]string{
+				// The type conflict is reported first.
+				"c1": string(mcsapi.ServiceExportReasonTypeConflict),
+				"c2": string(mcsapi.ServiceExportReasonNoConflicts),
+				"c3": string(mcsapi.ServiceExportReasonSessionAffinityConflict),
+			},
+		},
+		{
+			name: "port conflict",
+			exports: []export{
+				newExport("c1", time.Hour, newService(corev1.ClusterIPNone, corev1.ServiceAffinityNone, http)),
+				newExport("c2", time.Minute, newService(corev1.ClusterIPNone, corev1.ServiceAffinityNone, renamed)),
+			},
+			spec: &mcsapi.ServiceImportSpec{
+				Type:            mcsapi.Headless,
+				SessionAffinity: corev1.ServiceAffinityNone,
+				Ports:           []mcsapi.ServicePort{toImport(http)},
+			},
+			clusters: []string{"c1", "c2"},
+			valid: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonValid),
+				"c2": string(mcsapi.ServiceExportReasonValid),
+			},
+			conflicts: map[cluster.ID]string{
+				"c1": string(mcsapi.ServiceExportReasonNoConflicts),
+				"c2": string(mcsapi.ServiceExportReasonPortConflict),
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res := resolve(tt.exports)
+			assert.Equal(t, res.spec, tt.spec)
+			var clusters []string
+			for _, c := range res.clusters {
+				clusters = append(clusters, c.Cluster)
+			}
+			assert.Equal(t, clusters, tt.clusters)
+			valid := map[cluster.ID]string{}
+			conflicts := map[cluster.ID]string{}
+			for c, status := range res.statuses {
+				valid[c] = status.valid.Reason
+				if status.conflict != nil {
+					conflicts[c] = status.conflict.Reason
+				}
+			}
+			if tt.valid == nil {
+				tt.valid = map[cluster.ID]string{}
+			}
+			if tt.conflicts == nil {
+				tt.conflicts = map[cluster.ID]string{}
+			}
+			assert.Equal(t, valid, tt.valid)
+			assert.Equal(t, conflicts, tt.conflicts)
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
 	kubelib "istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
@@ -43,7 +44,7 @@ type NodeUntainter struct {
 	nodesClient kclient.Client[*v1.Node]
 	cnilabels   labels.Instance
 	ourNs       string
-	queue       controllers.Queue
+	queue       controllers.TypedQueue[types.NamespacedName]
 	taintName   string
 }
 
@@ -114,9 +115,7 @@ func (n *NodeUntainter) setup(stop <-chan struct{}, debugger *krt.DebugHandler)
 		return pnode
 	}, opts.WithName("ready-cni-nodes")...)
 
-	n.queue = controllers.NewQueue("untaint nodes",
-		controllers.WithReconciler(n.reconcileNode),
-		controllers.WithMaxAttempts(5))
+	n.queue = controllers.NewTypedQueue("untaint nodes", n.reconcileNode, controllers.WithMaxAttempts(5))
 
 	// remove the taints from readyCniNodes
 	readyCniNodes.Register(func(o krt.Event[*v1.Node]) {
@@ -125,7 +124,7 @@ func (n *NodeUntainter) setup(stop <-chan struct{}, debugger *krt.DebugHandler)
 		}
 		if o.New != nil {
 			log.Debugf("adding node to queue event: %s", (*o.New).Name)
-			n.queue.AddObject(*o.New)
+			n.queue.Add(config.NamespacedName(*o.New))
 		}
 	})
 }
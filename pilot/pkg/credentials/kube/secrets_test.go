@@ -41,6 +41,9 @@ func makeSecret(name string, data map[string]string, secretType corev1.SecretTyp
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: "default",
+			// Stripped from the cache of istiod, see TestSecretsController
+			Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: fmt.Sprintf(`{"kind":"Secret","type":%q}`, secretType)},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
 		},
 		Data: bdata,
 		Type: secretType,
@@ -119,7 +122,11 @@ func TestSecretsController(t *testing.T) {
 		emptyCert,
 		wrongKeys,
 	}
+	// Strip the unused fields of the Secrets as istiod does, to check the controller does not read them
 	client := kube.NewFakeClient(secrets...)
+	for _, opt := range multicluster.ClientOptions() {
+		opt(client)
+	}
 	sc := NewCredentialsController(client, nil, true)
 	client.RunAndWait(test.NewStop(t))
 	cases := []struct {
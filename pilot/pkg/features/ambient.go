@@ -94,6 +94,18 @@ var (
 			"and are skipped entirely for sidecar and gateway proxies. If disabled, every Address update triggers a "+
 			"full LDS/CDS/EDS push to all waypoints and an RDS push to all proxies.").Get()
 
+	EnableScopedZtunnelAddresses = registerAmbient("AMBIENT_ENABLE_SCOPED_ZTUNNEL_ADDRESSES", false, false,
+		"If enabled, ztunnels with a wildcard subscription only receive the workloads on their node, the services "+
+			"visible from the namespaces of these workloads, by exportTo and the egress hosts of the Sidecar of the "+
+			"namespace, and the workloads of these services, instead of all the "+
+			"addresses of the mesh. Destinations outside of this scope, such as the pod IPs of other nodes outside of "+
+			"any visible service, are unknown to ztunnel.")
+
+	EnableAmbientHeadlessHostnames = registerAmbient("AMBIENT_ENABLE_HEADLESS_POD_HOSTNAMES", false, false,
+		"If enabled, pods with a subdomain matching a headless Service are sent to ztunnel with their own hostname "+
+			"(\"<hostname>.<subdomain>.<namespace>.svc.<domain>\"), and are bound to the waypoint of that Service, so "+
+			"traffic addressed to an individual pod, as is common for StatefulSets, is handled like traffic to the Service.")
+
 	EnableWdsDryRunAuthzPol = registerAmbient("AMBIENT_ENABLE_DRY_RUN_AUTHORIZATION_POLICY", false, false,
 		"If enabled, ztunnel will be configured with dry-run authorizationPolicies. "+
 			"Ensure ztunnel is 1.29 or above before enabling this feature. "+
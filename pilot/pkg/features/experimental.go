@@ -111,6 +111,26 @@ var (
 	).Get() &&
 		EnableMCSServiceDiscovery
 
+	EnableMCSController = env.Register(
+		"ENABLE_MCS_CONTROLLER",
+		false,
+		"If enabled, istiod will act as the Kubernetes Multi-Cluster "+
+			"Services (MCS) controller of its cluster: it creates a ServiceImport, "+
+			"with a ClusterSet VIP, for each service exported (via ServiceExport) "+
+			"in any cluster of the mesh, and reports export conflicts in the "+
+			"conditions of the ServiceExports of its cluster. Do not enable this "+
+			"if another MCS controller is used. Requires that ENABLE_MCS_HOST "+
+			"also be enabled.",
+	).Get() &&
+		EnableMCSHost
+
+	MCSClusterSetIPPrefix = env.Register(
+		"MCS_CLUSTERSET_IP_PREFIX",
+		"240.241.0.0/16",
+		"The CIDR range/prefix of the ClusterSet VIPs allocated when ENABLE_MCS_CONTROLLER is enabled. "+
+			"This should be a private range, and not conflict with any other IPs in the cluster, "+
+			"including PILOT_IP_AUTOALLOCATE_IPV4_PREFIX.").Get()
+
 	EnableMCSClusterLocal = env.Register(
 		"ENABLE_MCS_CLUSTER_LOCAL",
 		false,
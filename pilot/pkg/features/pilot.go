@@ -112,6 +112,16 @@ var (
 		return sets.New(strings.Split(v, ",")...)
 	}()
 
+	KrtHistorySize = env.Register("PILOT_KRT_HISTORY_SIZE", 0,
+		"If set, the internal krt collections of istiod record the last changes of each of their outputs, up to this "+
+			"number of changes per output, with what triggered them. The history is served by /debug/krtz?collection=<name>&key=<key>. "+
+			"This has a significant memory cost, and is meant for debugging.").Get()
+
+	KrtProfiling = env.Register("PILOT_KRT_PROFILING", false,
+		"If enabled, the internal krt collections of istiod record their activity: events in and out, transformation "+
+			"calls and latency, and the fan-out of their fetches. The activity is served by /debug/krtz?graph=json, and "+
+			"exported as the krt_collection_* metrics. This adds overhead to every transformation, and is meant for debugging.").Get()
+
 	EnableServiceEntrySelectPods = env.Register("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS", true,
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
@@ -134,6 +144,35 @@ var (
 			"Setting the timeout to 0 disables this behavior.",
 	).Get()
 
+	RemoteClusterHealthCheckInterval = env.Register(
+		"PILOT_REMOTE_CLUSTER_HEALTH_CHECK_INTERVAL",
+		15*time.Second,
+		"Interval at which the API server of each cluster added via remote-secrets is probed. "+
+			"Setting the interval to 0 disables health checking.",
+	).Get()
+
+	RemoteClusterStaleEndpointsTimeout = env.Register(
+		"PILOT_REMOTE_CLUSTER_STALE_ENDPOINTS_TIMEOUT",
+		time.Duration(0),
+		"How long a cluster added via remote-secrets can fail its health checks before its services and endpoints are "+
+			"removed, until it is healthy again. Setting the timeout to 0 keeps the last-known endpoints of unhealthy clusters.",
+	).Get()
+
+	RemoteClusterUnhealthyBlocksReadiness = env.Register(
+		"PILOT_REMOTE_CLUSTER_UNHEALTHY_BLOCKS_READINESS",
+		false,
+		"If enabled, pilot waits for clusters added via remote-secrets to sync even when they fail their health checks, "+
+			"or are being restored after their endpoints were removed, up to PILOT_REMOTE_CLUSTER_TIMEOUT. "+
+			"By default, such clusters do not block pilot from becoming ready.",
+	).Get()
+
+	TrackMulticlusterConfigDivergence = env.Register(
+		"PILOT_TRACK_MULTICLUSTER_CONFIG_DIVERGENCE",
+		false,
+		"If enabled, istiod also reads the VirtualServices and DestinationRules of the clusters added via remote-secrets, "+
+			"and reports the ones whose copies differ across clusters at /debug/configdivergencez.",
+	).Get()
+
 	DisableMxALPN = env.Register("PILOT_DISABLE_MX_ALPN", false,
 		"If true, pilot will not put istio-peer-exchange ALPN into TLS handshake configuration.",
 	).Get()
@@ -100,4 +100,25 @@ var (
 		"If enabled, allows multiple CUSTOM authorization providers per workload, "+
 			"enabling different authentication schemes (OAuth, LDAP, API keys) for different API paths. "+
 			"Each provider gets its own filter chain with provider-specific metadata matching.").Get()
+
+	SpiffeFederationConfig = env.Register(
+		"PILOT_SPIFFE_FEDERATION",
+		"",
+		"A JSON list of federated trust domains whose trust bundles are fetched from SPIFFE bundle endpoints "+
+			"and trusted only for identities in their own trust domain. Each entry has trustDomain, bundleEndpointURL, "+
+			"bundleEndpointProfile (https_web or https_spiffe), and for https_spiffe endpointSPIFFEID and bootstrapBundle. "+
+			"Requires ISTIO_MULTIROOT_MESH.").Get()
+
+	EnableSpiffeBundleEndpoint = env.Register(
+		"PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT",
+		false,
+		"If enabled, istiod serves the trust bundle of its trust domain as a SPIFFE bundle endpoint on "+
+			"the https webhook port, allowing meshes in other trust domains to federate with it.").Get()
+
+	WorkloadAttestationConfig = env.Register(
+		"PILOT_WORKLOAD_ATTESTATION_CONFIG",
+		"",
+		"Path to a JSON file configuring workload attestation for the CA. Workloads without Kubernetes credentials, "+
+			"such as VMs, can then obtain a certificate for the service account of a WorkloadGroup by presenting a "+
+			"one-time join token or a signed AWS or GCP instance identity document.").Get()
 )
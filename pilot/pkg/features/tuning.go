@@ -23,6 +23,13 @@ import (
 
 // Define performance tuning related features here.
 var (
+	StripInformerFields = env.Register(
+		"PILOT_STRIP_INFORMER_FIELDS",
+		true,
+		"If enabled, the fields istiod never reads are stripped from the Pods, Nodes, EndpointSlices and Secrets it caches, "+
+			"such as managed fields, most of the containers of Pods, or the last applied configuration of Secrets, to save memory.",
+	).Get()
+
 	MaxConcurrentStreams = env.Register(
 		"ISTIO_GPRC_MAXSTREAMS",
 		100000,
@@ -59,6 +59,8 @@ const (
 	InferencePoolController     = "istio-gateway-inferencepool"
 	NodeUntaintController       = "istio-node-untaint"
 	IPAutoallocateController    = "istio-ip-autoallocate"
+	// MCSController creates the ServiceImports of the services exported in the mesh, and allocates their VIPs.
+	MCSController = "istio-mcs-controller"
 )
 
 // Leader election key prefix for remote istiod managed clusters
@@ -408,6 +408,9 @@ type Proxy struct {
 	// WatchedResources contains the list of watched resources for the proxy, keyed by the DiscoveryRequest TypeUrl.
 	WatchedResources map[string]*WatchedResource
 
+	// ZtunnelScope is the scope of the addresses sent to a ztunnel, when they are scoped to its node.
+	ZtunnelScope *ZtunnelScope
+
 	// XdsNode is the xDS node identifier
 	XdsNode *core.Node
 
@@ -274,6 +274,9 @@ type PushContext struct {
 
 	Networks *meshconfig.MeshNetworks
 
+	// trustBundlesByDomain holds the roots for each trust domain when SPIFFE federation is in use.
+	trustBundlesByDomain map[string][]string
+
 	InitDone        atomic.Bool
 	initializeMutex sync.Mutex
 	ambientIndex    AmbientIndexes
@@ -1360,6 +1363,10 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 
 	ps.Mesh = env.Mesh()
 	ps.Networks = env.MeshNetworks()
+	if env.TrustBundle != nil {
+		ps.trustBundlesByDomain = env.TrustBundle.GetTrustBundlesByDomain(
+			append([]string{ps.Mesh.GetTrustDomain()}, ps.Mesh.GetTrustDomainAliases()...))
+	}
 
 	// Must be initialized first as initServiceRegistry/VirtualServices/Destrules
 	// use the default export map.
@@ -2531,6 +2538,14 @@ func (ps *PushContext) NetworkManager() *NetworkManager {
 	return ps.networkMgr
 }
 
+// TrustBundlesByDomain returns the roots to validate each trust domain with, or nil if no trust domain is federated.
+func (ps *PushContext) TrustBundlesByDomain() map[string][]string {
+	if ps == nil {
+		return nil
+	}
+	return ps.trustBundlesByDomain
+}
+
 // AllInstancesSupportHBONE checks whether all instances of a service support HBONE. This is used in cases where we need
 // to decide if we are always going to send HBONE, so we can set service-level properties.
 // Mostly this works around limitations in the dataplane that don't support per-endpoint properties we would want to be
@@ -1387,6 +1387,10 @@ func (i ServiceInfo) GetConditions(currentConditions map[string]Condition) Condi
 			buildMsg.WriteString(". Canary waypoint not attached: ")
 			buildMsg.WriteString(i.Waypoint.Error.Message)
 		}
+		if i.Waypoint.HeadlessPodsError != nil {
+			buildMsg.WriteString(". Pods addressed by their hostnames do not use the waypoint: ")
+			buildMsg.WriteString(i.Waypoint.HeadlessPodsError.Message)
+		}
 
 		set[WaypointBound] = &Condition{
 			Status:  true,
@@ -1455,6 +1459,8 @@ type WaypointBindingStatus struct {
 	IngressLabelPresent bool
 	// Error represents some error
 	Error *StatusMessage
+	// HeadlessPodsError is set when the pods of a headless Service, addressed by their hostnames, cannot use its waypoint.
+	HeadlessPodsError *StatusMessage
 }
 
 type StatusMessage struct {
@@ -1466,7 +1472,8 @@ func (i WaypointBindingStatus) Equals(other WaypointBindingStatus) bool {
 	return i.ResourceName == other.ResourceName &&
 		i.IngressUseWaypoint == other.IngressUseWaypoint &&
 		i.IngressLabelPresent == other.IngressLabelPresent &&
-		ptr.Equal(i.Error, other.Error)
+		ptr.Equal(i.Error, other.Error) &&
+		ptr.Equal(i.HeadlessPodsError, other.HeadlessPodsError)
 }
 
 func (i ServiceInfo) NamespacedName() types.NamespacedName {
@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/pkg/workloadapi"
+)
+
+// ZtunnelScope is the part of the mesh sent to a ztunnel when its addresses are scoped: the workloads on its node,
+// the services visible from the namespaces of these workloads, and the workloads of these services. As for sidecars,
+// the services visible from a namespace are the ones exported to it and imported by its Sidecar, if any.
+type ZtunnelScope struct {
+	// Node of the ztunnel.
+	Node string
+	// Namespaces of the workloads on the node.
+	Namespaces sets.String
+	// Services in scope, by resource name.
+	Services sets.String
+	// Sent are the versions of the addresses sent to the ztunnel, by resource name.
+	Sent map[string]string
+}
+
+// NewZtunnelScope computes the scope of a ztunnel on the node, from all the addresses of the mesh.
+func NewZtunnelScope(push *PushContext, node string, addrs []AddressInfo) *ZtunnelScope {
+	s := &ZtunnelScope{
+		Node:       node,
+		Namespaces: sets.New[string](),
+		Services:   sets.New[string](),
+		Sent:       map[string]string{},
+	}
+	for _, addr := range addrs {
+		if w := addr.GetWorkload(); w != nil && w.Node == node {
+			s.Namespaces.Insert(w.Namespace)
+		}
+	}
+	for _, addr := range addrs {
+		if svc := addr.GetService(); svc != nil && s.ServiceVisible(push, svc) {
+			s.Services.Insert(addr.ResourceName())
+		}
+	}
+	return s
+}
+
+// ServiceVisible reports whether the service is visible from the namespaces of the workloads on the node.
+func (s *ZtunnelScope) ServiceVisible(push *PushContext, svc *workloadapi.Service) bool {
+	for ns := range s.Namespaces {
+		if workloadServiceVisible(push, svc, ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// workloadServiceVisible reports whether the service is visible from the namespace, by its visibility, by the
+// exportTo of the Service or ServiceEntry it is built from, which is not part of the workload API, and by the egress
+// hosts of the Sidecar of the namespace.
+func workloadServiceVisible(push *PushContext, svc *workloadapi.Service, namespace string) bool {
+	if svc.GetVisibility() == workloadapi.Service_NAMESPACE && svc.Namespace != namespace {
+		return false
+	}
+	if push == nil {
+		return true
+	}
+	if s := push.ServiceIndex.HostnameAndNamespace[host.Name(svc.Hostname)][svc.Namespace]; s != nil {
+		return push.IsServiceVisible(s, namespace) && push.namespaceSidecarImports(namespace, s)
+	}
+	return true
+}
+
+// namespaceSidecarImports reports whether the Sidecar applying to all the workloads of the namespace, either its own
+// or the one of the root namespace, imports the service. Sidecars with a workload selector are ignored: ztunnel serves
+// all the workloads of its node, and the workload API carries no labels to select them.
+func (ps *PushContext) namespaceSidecarImports(namespace string, svc *Service) bool {
+	if len(ps.sidecarIndex.sidecarsByNamespace) == 0 && ps.sidecarIndex.meshRootSidecarConfig == nil {
+		// No Sidecar in the mesh, so the services exported to the namespace are all imported
+		return true
+	}
+	sc := ps.getSidecarScope(&Proxy{Type: SidecarProxy, ConfigNamespace: namespace}, nil)
+	if sc == nil || sc.Sidecar == nil {
+		return true
+	}
+	return sc.GetService(svc.Hostname) != nil
+}
+
+// Contains reports whether the address is in scope.
+func (s *ZtunnelScope) Contains(addr AddressInfo) bool {
+	if svc := addr.GetService(); svc != nil {
+		return s.Services.Contains(addr.ResourceName())
+	}
+	w := addr.GetWorkload()
+	if w == nil {
+		return false
+	}
+	if w.Node == s.Node {
+		return true
+	}
+	for svc := range w.Services {
+		if s.Services.Contains(svc) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stable reports whether the updated addresses leave the scope unchanged: no workload on the node is in a new
+// namespace, and no service changed visibility. Changes to Sidecars are not addresses, and always rescope. Otherwise, the scope must be computed again.
+func (s *ZtunnelScope) Stable(push *PushContext, addrs []AddressInfo) bool {
+	for _, addr := range addrs {
+		if svc := addr.GetService(); svc != nil {
+			if s.ServiceVisible(push, svc) != s.Services.Contains(addr.ResourceName()) {
+				return false
+			}
+		} else if w := addr.GetWorkload(); w != nil && w.Node == s.Node && !s.Namespaces.Contains(w.Namespace) {
+			return false
+		}
+	}
+	return true
+}
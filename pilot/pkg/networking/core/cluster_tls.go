@@ -120,7 +120,9 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 	// that would result in the CredentialName being supplied to all the sidecars which the DestinationRule is scoped to,
 	// resulting in delayed startup of sidecars who do not have access to the credentials.
 	// `filterAuthorizedResources` allows ConfigMap to anyone, so do not exclude it here.
-	privilegedCredentialLookup := tls.CredentialName != "" && !(strings.HasPrefix(tls.CredentialName, credentials.KubernetesConfigMapTypeURI))
+	// User-defined secrets are served by the agent of the pod itself, so do not exclude them either.
+	privilegedCredentialLookup := tls.CredentialName != "" && !strings.HasPrefix(tls.CredentialName, credentials.KubernetesConfigMapTypeURI) &&
+		!strings.HasPrefix(tls.CredentialName, security.UserSecretPrefix)
 	if privilegedCredentialLookup && cb.sidecarProxy() && !opts.isDrWithSelector {
 		if tls.Mode == networking.ClientTLSSettings_SIMPLE || tls.Mode == networking.ClientTLSSettings_MUTUAL {
 			return nil, nil
@@ -138,6 +140,7 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 			CommonTlsContext: defaultUpstreamCommonTLSContext(),
 			Sni:              tls.Sni,
 		}
+		tlsContext.CommonTlsContext.TlsParams.EcdhCurves = opts.mesh.GetMeshMTLS().GetEcdhCurves()
 
 		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs,
 			sec_model.ConstructSdsSecretConfig(sec_model.SDSDefaultResourceName))
@@ -148,6 +151,9 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 				ValidationContextSdsSecretConfig: sec_model.ConstructSdsSecretConfig(sec_model.SDSRootResourceName),
 			},
 		}
+		if cb.req != nil {
+			sec_model.ApplySPIFFEValidator(tlsContext.CommonTlsContext, cb.req.Push.TrustBundlesByDomain())
+		}
 		// Set default SNI of cluster name for istio_mutual if sni is not set and if not a DFP cluster.
 		if len(tlsContext.Sni) == 0 && !c.isDFPCluster {
 			tlsContext.Sni = c.cluster.Name
@@ -1611,7 +1611,7 @@ func TestBuildUpstreamClusterTLSContext(t *testing.T) {
 				err: nil,
 			},
 		},
-		// ecdh curves from MeshConfig should be ignored for ISTIO_MUTUAL mode
+		// ecdh curves from MeshConfig TLS defaults should be ignored for ISTIO_MUTUAL mode, mesh mTLS curves apply instead
 		{
 			name: "tls mode ISTIO_MUTUAL with EcdhCurves specified in Mesh Config",
 			opts: &buildClusterOpts{
@@ -1620,6 +1620,9 @@ func TestBuildUpstreamClusterTLSContext(t *testing.T) {
 					TlsDefaults: &meshconfig.MeshConfig_TLSConfig{
 						EcdhCurves: []string{"P-256", "P-384"},
 					},
+					MeshMTLS: &meshconfig.MeshConfig_TLSConfig{
+						EcdhCurves: []string{"X25519MLKEM768", "X25519"},
+					},
 				},
 			},
 			tls: &networking.ClientTLSSettings{
@@ -1634,6 +1637,7 @@ func TestBuildUpstreamClusterTLSContext(t *testing.T) {
 							// if not specified, envoy use TLSv1_2 as default for client.
 							TlsMaximumProtocolVersion: tls.TlsParameters_TLSv1_3,
 							TlsMinimumProtocolVersion: tls.TlsParameters_TLSv1_2,
+							EcdhCurves:                []string{"X25519MLKEM768", "X25519"},
 						},
 						TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{
 							{
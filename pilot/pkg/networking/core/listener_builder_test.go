@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	xds "github.com/cncf/xds/go/xds/core/v3"
 	matcher "github.com/cncf/xds/go/xds/type/matcher/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
@@ -47,6 +48,7 @@ import (
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/test"
@@ -1461,6 +1463,93 @@ func TestWaypointInternalHeadlessServiceNoEmptyRanges(t *testing.T) {
 	}
 }
 
+// TestWaypointInternalHeadlessServicePods verifies that the pods of a headless service, which clients address
+// directly, are matched to the filter chains of the service rather than to the direct pod access chains, by their
+// addresses and, with multi-network, by their hostnames. Without headless hostnames, they keep the direct pod access
+// chains.
+func TestWaypointInternalHeadlessServicePods(t *testing.T) {
+	test.SetForTest(t, &features.EnableAmbient, true)
+	test.SetForTest(t, &features.EnableAmbientMultiNetwork, true)
+	t.Run("headless hostnames enabled", func(t *testing.T) {
+		test.SetForTest(t, &features.EnableAmbientHeadlessHostnames, true)
+		testWaypointInternalHeadlessServicePods(t,
+			[][]string{{"10.0.0.1", "10.0.0.2"}, {"10.0.0.3"}},
+			[]string{"mysql-0.mysql.default.svc.cluster.local:80", "mysql-1.mysql.default.svc.cluster.local:80"})
+	})
+	t.Run("headless hostnames disabled", func(t *testing.T) {
+		test.SetForTest(t, &features.EnableAmbientHeadlessHostnames, false)
+		testWaypointInternalHeadlessServicePods(t, [][]string{{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}, nil)
+	})
+}
+
+func testWaypointInternalHeadlessServicePods(t *testing.T, wantRanges [][]string, wantAuthorities []string) {
+
+	cg := NewConfigGenTest(t, TestOptions{})
+
+	svc := &model.Service{
+		Hostname:   host.Name("mysql.default.svc.cluster.local"),
+		Resolution: model.Passthrough,
+		Attributes: model.ServiceAttributes{
+			Namespace: "default",
+		},
+		Ports: model.PortList{
+			&model.Port{
+				Name:     "http",
+				Port:     80,
+				Protocol: protocol.HTTP,
+			},
+		},
+	}
+	workload := func(name, ip, hostname string, services ...string) model.WorkloadInfo {
+		w := &workloadapi.Workload{
+			Uid:       name,
+			Name:      name,
+			Namespace: "default",
+			Addresses: [][]byte{netip.MustParseAddr(ip).AsSlice()},
+			Hostname:  hostname,
+			Services:  map[string]*workloadapi.PortList{},
+		}
+		for _, s := range services {
+			w.Services[s] = &workloadapi.PortList{}
+		}
+		return model.WorkloadInfo{Workload: w}
+	}
+	wls := []model.WorkloadInfo{
+		workload("mysql-0", "10.0.0.1", "mysql-0.mysql.default.svc.cluster.local", "default/mysql.default.svc.cluster.local"),
+		workload("mysql-1", "10.0.0.2", "mysql-1.mysql.default.svc.cluster.local", "default/mysql.default.svc.cluster.local"),
+		workload("other", "10.0.0.3", ""),
+	}
+
+	proxy := cg.SetupProxy(&model.Proxy{
+		Type:            model.Waypoint,
+		ConfigNamespace: "default",
+		Metadata: &model.NodeMetadata{
+			ClusterID: "cluster-1",
+			Network:   "network-a",
+		},
+	})
+	lb := &ListenerBuilder{
+		push: cg.PushContext(),
+		node: proxy,
+	}
+
+	l := lb.buildWaypointInternal(wls, []*model.Service{svc})
+	ipMatcher := extractIPMatcherFromListener(t, l)
+	if ipMatcher == nil {
+		t.Fatal("expected an IPMatcher")
+	}
+	var ranges [][]string
+	for _, rm := range ipMatcher.RangeMatchers {
+		ranges = append(ranges, slices.Map(rm.Ranges, func(r *xds.CidrRange) string {
+			return r.AddressPrefix
+		}))
+	}
+	assert.Equal(t, ranges, wantRanges)
+
+	authorities := sets.New(maps.Keys(l.FilterChainMatcher.GetMatcherTree().GetExactMatchMap().GetMap())...)
+	assert.Equal(t, sets.SortedList(authorities), wantAuthorities)
+}
+
 func TestPreserveHeader(t *testing.T) {
 	cg := NewConfigGenTest(t, TestOptions{
 		MeshConfig: &meshconfig.MeshConfig{
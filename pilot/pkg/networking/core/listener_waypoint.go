@@ -438,6 +438,7 @@ func (lb *ListenerBuilder) buildWaypointInternal(wls []model.WorkloadInfo, svcs
 		}
 	}
 
+	headlessPodAddresses := sets.New[string]()
 	for _, svc := range svcs {
 		var waypoint host.Name
 		var ingressUseWaypoint bool
@@ -449,6 +450,12 @@ func (lb *ListenerBuilder) buildWaypointInternal(wls []model.WorkloadInfo, svcs
 			svc.GetAllAddressesForProxy(lb.node),
 			lb.globalServiceVIPs(svc)...,
 		)...))
+		// A headless service has no VIP: its pods are addressed directly, by IP or by their own hostname.
+		var podAddresses, podHostnames []string
+		if features.EnableAmbientHeadlessHostnames && len(svcAddresses) == 0 && !isAmbientEastWestGateway {
+			podAddresses, podHostnames = headlessServicePods(svc, wls)
+			headlessPodAddresses.InsertAll(podAddresses...)
+		}
 
 		portMapper := match.NewDestinationPort()
 		for _, port := range svc.Ports {
@@ -588,9 +595,18 @@ func (lb *ListenerBuilder) buildWaypointInternal(wls []model.WorkloadInfo, svcs
 
 			// If the service has no addresses, we don't want this waypoint to be able to serve its hostname
 			if len(svcAddresses) == 0 {
+				if onMatch, f := svcHostnameMap.Map[authorityKey]; f {
+					// but a headless service can still be reached by the hostnames of its pods
+					for _, h := range podHostnames {
+						svcHostnameMap.Map[fmt.Sprintf("%s:%d", h, port.Port)] = onMatch
+					}
+				}
 				delete(svcHostnameMap.Map, authorityKey)
 			}
 		}
+		if len(svcAddresses) == 0 {
+			svcAddresses = podAddresses
+		}
 		// Skip the IP-range matcher when there are no addresses; envoy
 		// rejects an empty Ranges (proto min_items=1). Mirrors the
 		// svcHostnameMap guard above. See https://github.com/istio/istio/issues/60310.
@@ -647,6 +663,10 @@ func (lb *ListenerBuilder) buildWaypointInternal(wls []model.WorkloadInfo, svcs
 			for _, wl := range wls {
 				for _, ip := range wl.Workload.Addresses {
 					addr, _ := netip.AddrFromSlice(ip)
+					if headlessPodAddresses.Contains(addr.String()) {
+						// Already matched to the chains of its headless service
+						continue
+					}
 					cidr := util.ConvertAddressToCidr(addr.String())
 					ipRange = append(ipRange, &xds.CidrRange{
 						AddressPrefix: cidr.AddressPrefix,
@@ -1108,6 +1128,28 @@ func getTCPRouteMatch(tcp []*networking.TCPRoute, port int) []*networking.RouteD
 	return nil
 }
 
+// headlessServicePods returns the addresses and the hostnames of the workloads of a headless service, among the
+// workloads of the waypoint.
+func headlessServicePods(svc *model.Service, wls []model.WorkloadInfo) (addresses []string, hostnames []string) {
+	if svc.Resolution != model.Passthrough {
+		return nil, nil
+	}
+	key := svc.Attributes.Namespace + "/" + svc.Hostname.String()
+	for _, wl := range wls {
+		if _, f := wl.Workload.Services[key]; !f {
+			continue
+		}
+		for _, ip := range wl.Workload.Addresses {
+			addr, _ := netip.AddrFromSlice(ip)
+			addresses = append(addresses, addr.String())
+		}
+		if wl.Workload.Hostname != "" {
+			hostnames = append(hostnames, wl.Workload.Hostname)
+		}
+	}
+	return sets.SortedList(sets.New(addresses...)), hostnames
+}
+
 func buildRouteVHostDomains(svc *model.Service) []string {
 	// This avoids HTTP Host injection to take advantage of dynamic forward proxy
 	// hostnames when wildcards are present by restricting accepted hostnames to those
@@ -21,6 +21,8 @@ import (
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/security/authn"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
 )
 
 var authnLog = log.RegisterScope("authn", "authn debugging")
@@ -38,6 +40,10 @@ func NewBuilder(push *model.PushContext, proxy *model.Proxy) *Builder {
 func NewBuilderForService(push *model.PushContext, proxy *model.Proxy, svc *model.Service) *Builder {
 	applier := authn.NewPolicyApplier(push, proxy, svc)
 	trustDomains := TrustDomainsForValidation(push.Mesh)
+	if len(trustDomains) > 0 {
+		// Peers from federated trust domains are accepted as well; the SPIFFE validator checks each against its own roots.
+		trustDomains = dedupTrustDomains(append(trustDomains, slices.Sort(maps.Keys(push.TrustBundlesByDomain()))...))
+	}
 	return &Builder{
 		applier:      applier,
 		proxy:        proxy,
@@ -130,7 +130,7 @@ func (a policyApplier) InboundMTLSSettings(
 	// For MUTUAL and SIMPLE TLS modes specified via ServerTLSSettings in Sidecar or Gateway,
 	// TLS version is configured in the BuildListenerContext.
 	minTLSVersion := authn_utils.GetMinTLSVersion(mc.GetMeshMTLS().GetMinProtocolVersion())
-	return MTLSSettings{
+	settings := MTLSSettings{
 		Port: endpointPort,
 		Mode: effectiveMTLSMode,
 		TCP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolTCP,
@@ -138,6 +138,15 @@ func (a policyApplier) InboundMTLSSettings(
 		HTTP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolHTTP,
 			trustDomainAliases, minTLSVersion, mc),
 	}
+	// With federated trust domains, each peer must be validated against the roots of its own trust domain.
+	if bundles := a.push.TrustBundlesByDomain(); len(bundles) > 0 {
+		for _, ctx := range []*tlsv3.DownstreamTlsContext{settings.TCP, settings.HTTP} {
+			if ctx != nil {
+				authn_model.ApplySPIFFEValidator(ctx.CommonTlsContext, bundles)
+			}
+		}
+	}
+	return settings
 }
 
 // convertToEnvoyJwtConfig converts a list of JWT rules into Envoy JWT filter config to enforce it.
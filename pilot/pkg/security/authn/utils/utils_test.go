@@ -100,3 +100,24 @@ func TestGetMTLSCipherSuites(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMTLSEcdhCurves(t *testing.T) {
+	testNode := &model.Proxy{
+		Labels:   map[string]string{"app": "foo"},
+		Metadata: &model.NodeMetadata{},
+	}
+	mesh := &meshconfig.MeshConfig{
+		MeshMTLS: &meshconfig.MeshConfig_TLSConfig{
+			EcdhCurves: []string{"X25519MLKEM768", "X25519"},
+		},
+	}
+	got := BuildInboundTLS(model.MTLSStrict, testNode, networking.ListenerProtocolTCP, []string{}, tls.TlsParameters_TLSv1_2, mesh)
+	if diff := cmp.Diff([]string{"X25519MLKEM768", "X25519"}, got.CommonTlsContext.TlsParams.EcdhCurves); diff != "" {
+		t.Errorf("unexpected ECDH curves: %v", diff)
+	}
+
+	got = BuildInboundTLS(model.MTLSStrict, testNode, networking.ListenerProtocolTCP, []string{}, tls.TlsParameters_TLSv1_2, nil)
+	if len(got.CommonTlsContext.TlsParams.EcdhCurves) != 0 {
+		t.Errorf("expected default ECDH curves, got %v", got.CommonTlsContext.TlsParams.EcdhCurves)
+	}
+}
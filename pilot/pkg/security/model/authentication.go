@@ -25,7 +25,9 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
 	pm "istio.io/istio/pkg/model"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/spiffe"
@@ -50,6 +52,9 @@ const (
 	// EnvoyJwtFilterName is the name of the Envoy JWT filter. This should be the same as the name defined
 	// in https://github.com/envoyproxy/envoy/blob/v1.9.1/source/extensions/filters/http/well_known_names.h#L48
 	EnvoyJwtFilterName = "envoy.filters.http.jwt_authn"
+
+	// SPIFFECertValidatorName is the name of Envoy's SPIFFE certificate validator extension.
+	SPIFFECertValidatorName = "envoy.tls.cert_validator.spiffe"
 )
 
 var SDSAdsConfig = &core.ConfigSource{
@@ -74,6 +79,10 @@ func ConstructSdsSecretConfigForCredential(name string, credentialSocketExist bo
 	if name == credentials.BuiltinGatewaySecretTypeURI+SdsCaSuffix {
 		return ConstructSdsSecretConfig(SDSRootResourceName)
 	}
+	// User-defined secrets are configured on the pod and served by the local agent.
+	if strings.HasPrefix(name, security.UserSecretPrefix) {
+		return ConstructSdsSecretConfig(name)
+	}
 	// External SDS: credentialName format is "sds://<resource-name>".
 	// The resource name (after stripping the sds:// prefix) is sent as-is to the SDS server,
 	// allowing the server to differentiate between different certificates.
@@ -222,6 +231,39 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 	}
 }
 
+// ApplySPIFFEValidator replaces the root CA validation of a mesh mTLS context with Envoy's SPIFFE certificate
+// validator, so a peer is only validated against the roots of the trust domain in its SPIFFE ID. bundles maps
+// each trust domain to its PEM encoded roots. Contexts validated against a custom root certificate are left as is.
+func ApplySPIFFEValidator(tlsContext *tls.CommonTlsContext, bundles map[string][]string) {
+	combined := tlsContext.GetCombinedValidationContext()
+	if len(bundles) == 0 || combined.GetValidationContextSdsSecretConfig().GetName() != SDSRootResourceName {
+		return
+	}
+	validator := &tls.SPIFFECertValidatorConfig{}
+	for td, certs := range maps.SeqStable(bundles) {
+		if len(certs) == 0 {
+			continue
+		}
+		validator.TrustDomains = append(validator.TrustDomains, &tls.SPIFFECertValidatorConfig_TrustDomain{
+			Name: td,
+			TrustBundle: &core.DataSource{
+				Specifier: &core.DataSource_InlineString{InlineString: strings.Join(certs, "\n")},
+			},
+		})
+	}
+	validationContext := combined.DefaultValidationContext
+	if validationContext == nil {
+		validationContext = &tls.CertificateValidationContext{}
+	}
+	validationContext.CustomValidatorConfig = &core.TypedExtensionConfig{
+		Name:        SPIFFECertValidatorName,
+		TypedConfig: protoconv.MessageToAny(validator),
+	}
+	tlsContext.ValidationContextType = &tls.CommonTlsContext_ValidationContext{
+		ValidationContext: validationContext,
+	}
+}
+
 // constructSdsSecretConfig allows passing a file name and a fallback.
 // If the filename is set, it is used and the customFileSDSServer flag is respected.
 func constructSdsSecretConfig(maybeFileName string, fallbackName string, customFileSDSServer bool) *tls.SdsSecretConfig {
@@ -316,6 +358,10 @@ func EnforceGoCompliance(ctx *gotls.Config) {
 	pm.EnforceGoCompliance(ctx)
 }
 
+func ApplyGoCurvePreferences(ctx *gotls.Config, curves []string) {
+	pm.ApplyGoCurvePreferences(ctx, curves)
+}
+
 func EnforceCompliance(ctx *tls.CommonTlsContext) {
 	pm.EnforceCompliance(ctx)
 }
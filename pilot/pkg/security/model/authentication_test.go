@@ -647,6 +647,66 @@ func TestApplyToCommonTLSContext(t *testing.T) {
 	}
 }
 
+func TestApplySPIFFEValidator(t *testing.T) {
+	bundles := map[string][]string{
+		"foreign.td":    {"foreign-root"},
+		"cluster.local": {"local-root-1", "local-root-2"},
+		"empty.td":      nil,
+	}
+	san := []*matcher.StringMatcher{{MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "spiffe://cluster.local/"}}}
+	newContext := func(rootName string) *auth.CommonTlsContext {
+		return &auth.CommonTlsContext{
+			ValidationContextType: &auth.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &auth.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext:         &auth.CertificateValidationContext{MatchSubjectAltNames: san},
+					ValidationContextSdsSecretConfig: ConstructSdsSecretConfig(rootName),
+				},
+			},
+		}
+	}
+
+	ctx := newContext(SDSRootResourceName)
+	ApplySPIFFEValidator(ctx, bundles)
+	validator := &auth.SPIFFECertValidatorConfig{}
+	vc := ctx.GetValidationContext()
+	if vc.GetCustomValidatorConfig().GetName() != SPIFFECertValidatorName {
+		t.Fatalf("expected SPIFFE validator, got %v", ctx.ValidationContextType)
+	}
+	if err := vc.GetCustomValidatorConfig().GetTypedConfig().UnmarshalTo(validator); err != nil {
+		t.Fatal(err)
+	}
+	want := &auth.SPIFFECertValidatorConfig{
+		TrustDomains: []*auth.SPIFFECertValidatorConfig_TrustDomain{
+			{
+				Name:        "cluster.local",
+				TrustBundle: &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "local-root-1\nlocal-root-2"}},
+			},
+			{
+				Name:        "foreign.td",
+				TrustBundle: &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "foreign-root"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, validator, protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected SPIFFE validator config (-want +got): %s", diff)
+	}
+	if diff := cmp.Diff(san, vc.GetMatchSubjectAltNames(), protocmp.Transform()); diff != "" {
+		t.Fatalf("expected SAN matchers to be retained (-want +got): %s", diff)
+	}
+
+	// Contexts validated against a custom root certificate, or without federated bundles, are left as is.
+	custom := newContext("file-root:/etc/certs/root-cert.pem")
+	ApplySPIFFEValidator(custom, bundles)
+	if custom.GetCombinedValidationContext() == nil {
+		t.Fatalf("expected custom root validation to be retained, got %v", custom.ValidationContextType)
+	}
+	unfederated := newContext(SDSRootResourceName)
+	ApplySPIFFEValidator(unfederated, nil)
+	if unfederated.GetCombinedValidationContext() == nil {
+		t.Fatalf("expected root CA validation without federated bundles, got %v", unfederated.ValidationContextType)
+	}
+}
+
 func TestConstructSdsSecretConfigForCredential(t *testing.T) {
 	cases := []struct {
 		name                   string
@@ -712,6 +772,18 @@ func TestConstructSdsSecretConfigForCredential(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                   "user://partner",
+			credentialSocketExists: false,
+			push:                   &model.PushContext{Mesh: &meshconfig.MeshConfig{}},
+			expected:               ConstructSdsSecretConfig("user://partner"),
+		},
+		{
+			name:                   "user://partner-cacert",
+			credentialSocketExists: true,
+			push:                   &model.PushContext{Mesh: &meshconfig.MeshConfig{}},
+			expected:               ConstructSdsSecretConfig("user://partner-cacert"),
+		},
 		{
 			name:                   "sds://my-credential",
 			credentialSocketExists: true,
@@ -394,6 +394,33 @@ func convertPeerAuthentication(rootNamespace string, cfg, nsCfg, rootCfg *securi
 	return opol
 }
 
+// ConvertAuthorizationPolicy converts an AuthorizationPolicy to the authorization sent to ztunnel by the
+// WorkloadRBACGenerator. A nil authorization means ztunnel does not enforce the policy. The status message, if any,
+// explains which parts of the policy ztunnel does not enforce.
+func ConvertAuthorizationPolicy(rootNamespace string, obj *securityclient.AuthorizationPolicy) (*security.Authorization, *model.StatusMessage) {
+	return convertAuthorizationPolicy(rootNamespace, obj)
+}
+
+// EffectivePeerAuthentications returns the keys of the authorizations converted from the PeerAuthentications that
+// ztunnel enforces for a workload with the labels in the namespace. No key means mTLS is not required.
+func EffectivePeerAuthentications(
+	rootNamespace, ns string,
+	workloadLabels map[string]string,
+	policies []*securityclient.PeerAuthentication,
+) []string {
+	selected := slices.Filter(policies, func(pol *securityclient.PeerAuthentication) bool {
+		return peerAuthenticationSelects(pol, rootNamespace, ns, workloadLabels)
+	})
+	return convertedSelectorPeerAuthentications(rootNamespace, selected)
+}
+
+// IsStaticStrictPeerAuthentication reports whether the key returned by EffectivePeerAuthentications is the static
+// policy requiring mTLS on all ports.
+func IsStaticStrictPeerAuthentication(key string) bool {
+	_, name, _ := strings.Cut(key, "/")
+	return name == staticStrictPolicyName
+}
+
 func convertAuthorizationPolicy(rootns string, obj *securityclient.AuthorizationPolicy) (*security.Authorization, *model.StatusMessage) {
 	pol := &obj.Spec
 
@@ -33,6 +33,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/mesh/labelselector"
 	"istio.io/istio/pkg/config/protocol"
@@ -286,6 +287,11 @@ func serviceServiceBuilder(
 			waypointStatus.IngressLabelPresent, waypointStatus.IngressUseWaypoint = ingressUseWaypointFromLabels(s.Labels, nsLabels)
 		}
 		waypointStatus.Error = wperr
+		if waypoint != nil && features.EnableAmbientHeadlessHostnames && s.Spec.ClusterIP == v1.ClusterIPNone &&
+			waypoint.TrafficType != constants.WorkloadTraffic && waypoint.TrafficType != constants.AllTraffic {
+			// The pods of a headless Service are addressed directly, so they only inherit a waypoint for workload traffic.
+			waypointStatus.HeadlessPodsError = ReportWaypointUnsupportedTrafficType(waypoint.ResourceName(), constants.WorkloadTraffic)
+		}
 
 		var nsAnnotations map[string]string
 		if ns != nil {
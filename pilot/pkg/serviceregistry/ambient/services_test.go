@@ -31,6 +31,7 @@ import (
 	meshConfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	networkingclient "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/mesh/meshwatcher"
@@ -39,6 +40,7 @@ import (
 	"istio.io/istio/pkg/kube/krt/krttest"
 	"istio.io/istio/pkg/ptr"
 	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/workloadapi"
 )
@@ -2365,6 +2367,7 @@ func TestPreferSamePresetNotMutated(t *testing.T) {
 }
 
 func TestServiceConditions(t *testing.T) {
+	test.SetForTest(t, &features.EnableAmbientHeadlessHostnames, true)
 	waypointAddr := &workloadapi.GatewayAddress{
 		Destination: &workloadapi.GatewayAddress_Hostname{
 			Hostname: &workloadapi.NamespacedHostname{
@@ -2389,6 +2392,10 @@ func TestServiceConditions(t *testing.T) {
 		},
 	}
 
+	serviceWaypoint := waypoint
+	serviceWaypoint.Name = "service-waypoint"
+	serviceWaypoint.TrafficType = constants.ServiceTraffic
+
 	ns := &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "ns",
@@ -2479,6 +2486,30 @@ func TestServiceConditions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "headless service bound to service waypoint",
+			inputs: []any{
+				serviceWaypoint,
+				ns,
+			},
+			svc: func() *v1.Service {
+				svc := makeServiceWithLabels(map[string]string{
+					label.IoIstioUseWaypoint.Name:          "service-waypoint",
+					label.IoIstioUseWaypointNamespace.Name: "waypoint-ns",
+				})
+				svc.Spec.ClusterIP = v1.ClusterIPNone
+				return svc
+			}(),
+			conditions: map[model.ConditionType]*model.Condition{
+				model.WaypointBound: {
+					Status: true,
+					Reason: string(model.WaypointAccepted),
+					Message: "Successfully attached to waypoint waypoint-ns/service-waypoint. " +
+						"Pods addressed by their hostnames do not use the waypoint: " +
+						`attempting to bind to traffic type "workload" which the waypoint "waypoint-ns/service-waypoint" does not support`,
+				},
+			},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
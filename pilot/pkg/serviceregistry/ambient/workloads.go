@@ -726,6 +726,22 @@ func podWorkloadBuilder(
 
 		appTunnel, targetWaypoint, _ := computeWaypoint(ctx, waypoints, namespaces, p.ObjectMeta)
 
+		var hostname string
+		if features.EnableAmbientHeadlessHostnames {
+			if headless := headlessServiceForPod(p, services); headless != nil {
+				hostname = podHostname(p, domainSuffix)
+				// The pod is addressed directly by clients of the headless Service, so its traffic goes through the
+				// waypoint of the Service, unless the pod has its own. That traffic is workload traffic: a waypoint that
+				// only handles service traffic is not inherited, which is reported on the status of the Service.
+				if targetWaypoint == nil && appTunnel == nil && headless.Waypoint.ResourceName != "" {
+					w := krt.FetchOne(ctx, waypoints, krt.FilterKey(headless.Waypoint.ResourceName))
+					if w != nil && (w.TrafficType == constants.WorkloadTraffic || w.TrafficType == constants.AllTraffic) {
+						targetWaypoint = w
+					}
+				}
+			}
+		}
+
 		// enforce traversing waypoints
 		policies = append(policies, implicitWaypointPolicies(flags, ctx, waypoints, targetWaypoint, services)...)
 
@@ -737,6 +753,7 @@ func podWorkloadBuilder(
 			NetworkGateway:        getNetworkGatewayAddress(ctx, network, gatewaysByNetwork),
 			ClusterId:             string(clusterID),
 			Addresses:             podIPs,
+			Hostname:              hostname,
 			ServiceAccount:        p.Spec.ServiceAccountName,
 			Waypoint:              targetWaypoint.GetAddress(),
 			Node:                  p.Spec.NodeName,
@@ -1227,21 +1244,25 @@ func fetchPeerAuthentications(
 	matchLabels map[string]string,
 ) []*securityclient.PeerAuthentication {
 	return krt.Fetch(ctx, peerAuths, krt.FilterGeneric(func(a any) bool {
-		pol := a.(*securityclient.PeerAuthentication)
-		if pol.Namespace == meshCfg.GetRootNamespace() && pol.Spec.Selector == nil {
-			return true
-		}
-		if pol.Namespace != ns {
-			return false
-		}
-		sel := pol.Spec.Selector
-		if sel == nil {
-			return true // No selector matches everything
-		}
-		return labels.Instance(sel.MatchLabels).SubsetOf(matchLabels)
+		return peerAuthenticationSelects(a.(*securityclient.PeerAuthentication), meshCfg.GetRootNamespace(), ns, matchLabels)
 	}))
 }
 
+// peerAuthenticationSelects reports whether the PeerAuthentication applies to a workload with the labels in the namespace.
+func peerAuthenticationSelects(pol *securityclient.PeerAuthentication, rootNamespace, ns string, matchLabels map[string]string) bool {
+	if pol.Namespace == rootNamespace && pol.Spec.Selector == nil {
+		return true
+	}
+	if pol.Namespace != ns {
+		return false
+	}
+	sel := pol.Spec.Selector
+	if sel == nil {
+		return true // No selector matches everything
+	}
+	return labels.Instance(sel.MatchLabels).SubsetOf(matchLabels)
+}
+
 func constructServicesFromWorkloadEntry(p *networkingv1alpha3.WorkloadEntry, services []model.ServiceInfo) map[string]*workloadapi.PortList {
 	res := map[string]*workloadapi.PortList{}
 	for _, svc := range services {
@@ -1292,6 +1313,31 @@ func constructServicesFromWorkloadEntry(p *networkingv1alpha3.WorkloadEntry, ser
 	return res
 }
 
+// headlessServiceForPod returns the headless Service giving the pod its own DNS name, among the services selecting it.
+// As in Kubernetes, this is the Service named after the subdomain of the pod.
+func headlessServiceForPod(p *v1.Pod, services []model.ServiceInfo) *model.ServiceInfo {
+	if p.Spec.Subdomain == "" {
+		return nil
+	}
+	for i, svc := range services {
+		if svc.Source.Kind == kind.Service && svc.Source.Name == p.Spec.Subdomain && svc.Source.Namespace == p.Namespace &&
+			len(svc.Service.Addresses) == 0 {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// podHostname returns the DNS name of a pod of a headless Service: "<hostname>.<subdomain>.<namespace>.svc.<domain>",
+// where the hostname defaults to the name of the pod.
+func podHostname(p *v1.Pod, domainSuffix string) string {
+	hostname := p.Spec.Hostname
+	if hostname == "" {
+		hostname = p.Name
+	}
+	return hostname + "." + p.Spec.Subdomain + "." + p.Namespace + ".svc." + domainSuffix
+}
+
 func workloadName(pod *v1.Pod) string {
 	objMeta, _ := kubeutil.GetWorkloadMetaFromPod(pod)
 	return objMeta.Name
@@ -48,6 +48,7 @@ import (
 )
 
 func TestPodWorkloads(t *testing.T) {
+	test.SetForTest(t, &features.EnableAmbientHeadlessHostnames, true)
 	waypointAddr := &workloadapi.GatewayAddress{
 		Destination: &workloadapi.GatewayAddress_Hostname{
 			Hostname: &workloadapi.NamespacedHostname{
@@ -506,6 +507,252 @@ func TestPodWorkloads(t *testing.T) {
 				Waypoint:          waypointAddr,
 			},
 		},
+		{
+			name: "pod of headless service",
+			inputs: []any{
+				model.ServiceInfo{
+					Service: &workloadapi.Service{
+						Name:      "mysql",
+						Namespace: "ns",
+						Hostname:  "mysql.ns.svc.domain.suffix",
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+					LabelSelector: model.NewSelector(map[string]string{"app": "mysql"}),
+					Source:        model.TypedObject{NamespacedName: types.NamespacedName{Name: "mysql", Namespace: "ns"}, Kind: kind.Service},
+				},
+			},
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysql-0",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "mysql",
+					},
+				},
+				Spec: v1.PodSpec{Hostname: "mysql-0", Subdomain: "mysql"},
+				Status: v1.PodStatus{
+					Phase:      v1.PodRunning,
+					Conditions: podReady,
+					PodIP:      "1.2.3.4",
+				},
+			},
+			result: &workloadapi.Workload{
+				Uid:               "cluster0//Pod/ns/mysql-0",
+				Name:              "mysql-0",
+				Namespace:         "ns",
+				Addresses:         [][]byte{netip.AddrFrom4([4]byte{1, 2, 3, 4}).AsSlice()},
+				Hostname:          "mysql-0.mysql.ns.svc.domain.suffix",
+				Network:           testNW,
+				CanonicalName:     "mysql",
+				CanonicalRevision: "latest",
+				WorkloadType:      workloadapi.WorkloadType_POD,
+				WorkloadName:      "mysql-0",
+				Status:            workloadapi.WorkloadStatus_HEALTHY,
+				ClusterId:         testC,
+				Services: map[string]*workloadapi.PortList{
+					"ns/mysql.ns.svc.domain.suffix": {
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+				},
+			},
+		},
+		{
+			name: "pod of headless service with waypoint",
+			inputs: []any{
+				Waypoint{
+					Named: krt.Named{
+						Name:      "waypoint",
+						Namespace: "ns",
+					},
+					TrafficType: constants.AllTraffic,
+					Address:     waypointAddr,
+				},
+				model.ServiceInfo{
+					Service: &workloadapi.Service{
+						Name:      "mysql",
+						Namespace: "ns",
+						Hostname:  "mysql.ns.svc.domain.suffix",
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+						Waypoint: waypointAddr,
+					},
+					LabelSelector: model.NewSelector(map[string]string{"app": "mysql"}),
+					Source:        model.TypedObject{NamespacedName: types.NamespacedName{Name: "mysql", Namespace: "ns"}, Kind: kind.Service},
+					Waypoint:      model.WaypointBindingStatus{ResourceName: "ns/waypoint"},
+				},
+			},
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysql-0",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "mysql",
+					},
+				},
+				// The hostname defaults to the name of the pod
+				Spec: v1.PodSpec{Subdomain: "mysql"},
+				Status: v1.PodStatus{
+					Phase:      v1.PodRunning,
+					Conditions: podReady,
+					PodIP:      "1.2.3.4",
+				},
+			},
+			result: &workloadapi.Workload{
+				Uid:               "cluster0//Pod/ns/mysql-0",
+				Name:              "mysql-0",
+				Namespace:         "ns",
+				Addresses:         [][]byte{netip.AddrFrom4([4]byte{1, 2, 3, 4}).AsSlice()},
+				Hostname:          "mysql-0.mysql.ns.svc.domain.suffix",
+				Network:           testNW,
+				CanonicalName:     "mysql",
+				CanonicalRevision: "latest",
+				WorkloadType:      workloadapi.WorkloadType_POD,
+				WorkloadName:      "mysql-0",
+				Status:            workloadapi.WorkloadStatus_HEALTHY,
+				ClusterId:         testC,
+				Waypoint:          waypointAddr,
+				Services: map[string]*workloadapi.PortList{
+					"ns/mysql.ns.svc.domain.suffix": {
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+				},
+			},
+		},
+		{
+			name: "pod of headless service with service waypoint",
+			inputs: []any{
+				Waypoint{
+					Named: krt.Named{
+						Name:      "waypoint",
+						Namespace: "ns",
+					},
+					TrafficType: constants.ServiceTraffic,
+					Address:     waypointAddr,
+				},
+				model.ServiceInfo{
+					Service: &workloadapi.Service{
+						Name:      "mysql",
+						Namespace: "ns",
+						Hostname:  "mysql.ns.svc.domain.suffix",
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+						Waypoint: waypointAddr,
+					},
+					LabelSelector: model.NewSelector(map[string]string{"app": "mysql"}),
+					Source:        model.TypedObject{NamespacedName: types.NamespacedName{Name: "mysql", Namespace: "ns"}, Kind: kind.Service},
+					Waypoint:      model.WaypointBindingStatus{ResourceName: "ns/waypoint"},
+				},
+			},
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysql-0",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "mysql",
+					},
+				},
+				Spec: v1.PodSpec{Subdomain: "mysql"},
+				Status: v1.PodStatus{
+					Phase:      v1.PodRunning,
+					Conditions: podReady,
+					PodIP:      "1.2.3.4",
+				},
+			},
+			// The waypoint only handles service traffic, so the pod does not inherit it
+			result: &workloadapi.Workload{
+				Uid:               "cluster0//Pod/ns/mysql-0",
+				Name:              "mysql-0",
+				Namespace:         "ns",
+				Addresses:         [][]byte{netip.AddrFrom4([4]byte{1, 2, 3, 4}).AsSlice()},
+				Hostname:          "mysql-0.mysql.ns.svc.domain.suffix",
+				Network:           testNW,
+				CanonicalName:     "mysql",
+				CanonicalRevision: "latest",
+				WorkloadType:      workloadapi.WorkloadType_POD,
+				WorkloadName:      "mysql-0",
+				Status:            workloadapi.WorkloadStatus_HEALTHY,
+				ClusterId:         testC,
+				Services: map[string]*workloadapi.PortList{
+					"ns/mysql.ns.svc.domain.suffix": {
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+				},
+			},
+		},
+		{
+			name: "pod with subdomain of service with VIP",
+			inputs: []any{
+				model.ServiceInfo{
+					Service: &workloadapi.Service{
+						Name:      "mysql",
+						Namespace: "ns",
+						Hostname:  "mysql.ns.svc.domain.suffix",
+						Addresses: []*workloadapi.NetworkAddress{{
+							Network: testNW,
+							Address: netip.AddrFrom4([4]byte{10, 0, 0, 1}).AsSlice(),
+						}},
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+					LabelSelector: model.NewSelector(map[string]string{"app": "mysql"}),
+					Source:        model.TypedObject{NamespacedName: types.NamespacedName{Name: "mysql", Namespace: "ns"}, Kind: kind.Service},
+				},
+			},
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mysql-0",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "mysql",
+					},
+				},
+				Spec: v1.PodSpec{Hostname: "mysql-0", Subdomain: "mysql"},
+				Status: v1.PodStatus{
+					Phase:      v1.PodRunning,
+					Conditions: podReady,
+					PodIP:      "1.2.3.4",
+				},
+			},
+			result: &workloadapi.Workload{
+				Uid:               "cluster0//Pod/ns/mysql-0",
+				Name:              "mysql-0",
+				Namespace:         "ns",
+				Addresses:         [][]byte{netip.AddrFrom4([4]byte{1, 2, 3, 4}).AsSlice()},
+				Network:           testNW,
+				CanonicalName:     "mysql",
+				CanonicalRevision: "latest",
+				WorkloadType:      workloadapi.WorkloadType_POD,
+				WorkloadName:      "mysql-0",
+				Status:            workloadapi.WorkloadStatus_HEALTHY,
+				ClusterId:         testC,
+				Services: map[string]*workloadapi.PortList{
+					"ns/mysql.ns.svc.domain.suffix": {
+						Ports: []*workloadapi.Port{{
+							ServicePort: 3306,
+							TargetPort:  3306,
+						}},
+					},
+				},
+			},
+		},
 		{
 			name: "pod that is a waypoint",
 			inputs: []any{
@@ -23,6 +23,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
 
 	"istio.io/api/label"
@@ -31,10 +32,14 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pilot/pkg/serviceregistry/util/xdsfake"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvr"
+	kubelib "istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/ptr"
 	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
 )
 
 func TestEndpointSliceFromMCSShouldBeIgnored(t *testing.T) {
@@ -624,3 +629,59 @@ func TestUpdateEndpointCacheForSliceWithMultiAddrs(t *testing.T) {
 		})
 	}
 }
+
+// TestStripEndpointSliceUnusedFields checks that the endpoints built from EndpointSlices with their unused fields stripped
+// are the same as without.
+func TestStripEndpointSliceUnusedFields(t *testing.T) {
+	const (
+		ns      = "nsa"
+		svcName = "svc1"
+		podName = "pod1"
+	)
+	portName := "tcp-port"
+	portNum := int32(8080)
+	ready := true
+
+	endpoints := func(client kubelib.Client) []*model.IstioEndpoint {
+		controller, fx := NewFakeControllerWithOptions(t, FakeControllerOptions{Client: client})
+		addNodes(t, controller, generateNode("node1", map[string]string{NodeZoneLabel: "zone1", NodeRegionLabel: "region1"}))
+		pod := generatePod([]string{"128.0.0.1"}, podName, ns, "svcaccount", "node1", map[string]string{"app": "prod-app"}, nil)
+		addPods(t, controller, fx, pod)
+		createServiceWait(controller, svcName, ns, []string{"10.0.0.1"}, nil, nil,
+			[]int32{portNum}, map[string]string{"app": "prod-app"}, t)
+
+		clienttest.Wrap(t, controller.endpoints.slices).CreateOrUpdate(&discovery.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:          svcName,
+				Namespace:     ns,
+				Labels:        map[string]string{discovery.LabelServiceName: svcName},
+				Annotations:   map[string]string{corev1.LastAppliedConfigAnnotation: `{"kind":"EndpointSlice"}`},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kube-controller-manager"}},
+			},
+			AddressType: discovery.AddressTypeIPv4,
+			Endpoints: []discovery.Endpoint{{
+				Addresses:          []string{"128.0.0.1"},
+				Conditions:         discovery.EndpointConditions{Ready: &ready},
+				Hostname:           ptr.Of(podName),
+				TargetRef:          &corev1.ObjectReference{Kind: "Pod", Namespace: ns, Name: podName},
+				NodeName:           ptr.Of("node1"),
+				Zone:               ptr.Of("zone1"),
+				Hints:              &discovery.EndpointHints{ForZones: []discovery.ForZone{{Name: "zone1"}}},
+				DeprecatedTopology: map[string]string{NodeZoneLabel: "zone1"},
+			}},
+			Ports: []discovery.EndpointPort{{Name: &portName, Port: &portNum}},
+		})
+		hostname := kube.ServiceHostname(svcName, ns, controller.opts.DomainSuffix)
+		var res []*model.IstioEndpoint
+		retry.UntilOrFail(t, func() bool {
+			res = controller.endpoints.endpointCache.Get(hostname)
+			return len(res) == 1
+		})
+		return res
+	}
+
+	stripped := kubelib.WithObjectTransforms(map[schema.GroupVersionResource]func(obj any) (any, error){
+		gvr.EndpointSlice: kubelib.StripEndpointSliceUnusedFields,
+	})(kubelib.NewFakeClient())
+	assert.Equal(t, endpoints(stripped), endpoints(kubelib.NewFakeClient()))
+}
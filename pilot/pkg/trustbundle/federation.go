@@ -0,0 +1,286 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// FederationDefaultRefreshPeriod is used when a federated bundle does not carry a refresh hint.
+	FederationDefaultRefreshPeriod = 5 * time.Minute
+	federationMinRefreshPeriod     = 30 * time.Second
+	federationRetryPeriod          = 30 * time.Second
+)
+
+// FederatedTrustDomain configures a foreign trust domain whose bundle is fetched from a SPIFFE bundle endpoint.
+type FederatedTrustDomain struct {
+	TrustDomain string `json:"trustDomain"`
+	// BundleEndpointURL is the https URL of the foreign bundle endpoint.
+	BundleEndpointURL string `json:"bundleEndpointURL"`
+	// BundleEndpointProfile is either https_web or https_spiffe.
+	BundleEndpointProfile spiffe.BundleEndpointProfile `json:"bundleEndpointProfile"`
+	// EndpointSPIFFEID is the identity of the bundle endpoint server, required for https_spiffe.
+	EndpointSPIFFEID string `json:"endpointSPIFFEID,omitempty"`
+	// BootstrapBundle is a PEM encoded bundle of the foreign trust domain, used to authenticate
+	// https_spiffe endpoints until the first successful fetch.
+	BootstrapBundle string `json:"bootstrapBundle,omitempty"`
+}
+
+// ParseFederationConfig parses a JSON list of FederatedTrustDomain.
+func ParseFederationConfig(cfg string) ([]FederatedTrustDomain, error) {
+	if cfg == "" {
+		return nil, nil
+	}
+	var out []FederatedTrustDomain
+	if err := json.Unmarshal([]byte(cfg), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse federation config: %v", err)
+	}
+	return out, nil
+}
+
+func (f FederatedTrustDomain) endpoint() spiffe.BundleEndpoint {
+	return spiffe.BundleEndpoint{
+		TrustDomain:      f.TrustDomain,
+		URL:              f.BundleEndpointURL,
+		Profile:          f.BundleEndpointProfile,
+		EndpointSPIFFEID: f.EndpointSPIFFEID,
+	}
+}
+
+type federatedState struct {
+	config      FederatedTrustDomain
+	bundle      *spiffe.Bundle
+	fetched     bool
+	nextRefresh time.Time
+	lastError   error
+}
+
+// Federation fetches the trust bundles of federated trust domains and feeds them into the TrustBundle, keyed by
+// trust domain.
+type Federation struct {
+	tb       *TrustBundle
+	webRoots *x509.CertPool
+
+	mutex   sync.RWMutex
+	domains map[string]*federatedState
+
+	now func() time.Time
+}
+
+// NewFederation validates the federation config and returns a Federation updating tb.
+func NewFederation(tb *TrustBundle, domains []FederatedTrustDomain, webRoots *x509.CertPool) (*Federation, error) {
+	f := &Federation{
+		tb:       tb,
+		webRoots: webRoots,
+		domains:  map[string]*federatedState{},
+		now:      time.Now,
+	}
+	for _, d := range domains {
+		if err := d.endpoint().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid federated trust domain %q: %v", d.TrustDomain, err)
+		}
+		if _, exists := f.domains[d.TrustDomain]; exists {
+			return nil, fmt.Errorf("duplicate federated trust domain %q", d.TrustDomain)
+		}
+		st := &federatedState{config: d}
+		if d.BootstrapBundle != "" {
+			certs, err := parsePemCerts(d.BootstrapBundle)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bootstrap bundle for trust domain %q: %v", d.TrustDomain, err)
+			}
+			st.bundle = &spiffe.Bundle{TrustDomain: d.TrustDomain, X509Authorities: certs}
+		}
+		f.domains[d.TrustDomain] = st
+	}
+	return f, nil
+}
+
+func parsePemCerts(in string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(in)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
+
+// Refresh fetches every federated bundle that is due, and returns the time of the next scheduled refresh.
+func (f *Federation) Refresh(ctx context.Context) time.Time {
+	f.mutex.RLock()
+	due := []*federatedState{}
+	for _, st := range f.domains {
+		if !f.now().Before(st.nextRefresh) {
+			due = append(due, st)
+		}
+	}
+	f.mutex.RUnlock()
+
+	changed := false
+	for _, st := range due {
+		f.mutex.RLock()
+		var endpointRoots []*x509.Certificate
+		if st.bundle != nil {
+			endpointRoots = st.bundle.X509Authorities
+		}
+		f.mutex.RUnlock()
+
+		bundle, err := spiffe.FetchBundle(ctx, st.config.endpoint(), f.webRoots, endpointRoots)
+
+		f.mutex.Lock()
+		if err != nil {
+			trustBundleLog.Errorf("unable to fetch federated bundle for trust domain %s from %s: %v",
+				st.config.TrustDomain, st.config.BundleEndpointURL, err)
+			st.lastError = err
+			st.nextRefresh = f.now().Add(federationRetryPeriod)
+			f.mutex.Unlock()
+			continue
+		}
+		st.lastError = nil
+		if !st.fetched || !sameAuthorities(st.bundle.X509Authorities, bundle.X509Authorities) {
+			trustBundleLog.Infof("updated federated bundle for trust domain %s: %d certs, sequence %d",
+				st.config.TrustDomain, len(bundle.X509Authorities), bundle.Sequence)
+			changed = true
+		}
+		st.bundle = bundle
+		st.fetched = true
+		st.nextRefresh = f.now().Add(refreshPeriod(bundle.RefreshHint))
+		f.mutex.Unlock()
+	}
+
+	if changed {
+		if err := f.tb.UpdateFederatedTrustAnchors(f.federatedCerts()); err != nil {
+			trustBundleLog.Errorf("failed to update federated trustAnchors: %v", err)
+		}
+	}
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	next := f.now().Add(FederationDefaultRefreshPeriod)
+	for _, st := range f.domains {
+		if st.nextRefresh.Before(next) {
+			next = st.nextRefresh
+		}
+	}
+	return next
+}
+
+func refreshPeriod(hint time.Duration) time.Duration {
+	if hint == 0 {
+		return FederationDefaultRefreshPeriod
+	}
+	return max(hint, federationMinRefreshPeriod)
+}
+
+func sameAuthorities(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// federatedCerts returns the PEM encoded authorities of each federated trust domain that has been fetched,
+// keyed by trust domain.
+func (f *Federation) federatedCerts() map[string][]string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	out := make(map[string][]string, len(f.domains))
+	for td, st := range f.domains {
+		// Bootstrap bundles only authenticate the endpoint; they are not trusted until confirmed by a fetch.
+		if !st.fetched {
+			continue
+		}
+		certs := sets.New[string]()
+		for _, c := range st.bundle.X509Authorities {
+			certs.Insert(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})))
+		}
+		out[td] = sets.SortedList(certs)
+	}
+	return out
+}
+
+// FederatedBundles returns the current authorities for each federated trust domain, keyed by trust domain.
+func (f *Federation) FederatedBundles() map[string][]*x509.Certificate {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	out := make(map[string][]*x509.Certificate, len(f.domains))
+	for td, st := range f.domains {
+		if st.bundle == nil {
+			continue
+		}
+		out[td] = st.bundle.X509Authorities
+	}
+	return out
+}
+
+// TrustDomains returns the configured federated trust domains.
+func (f *Federation) TrustDomains() []string {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	tds := make([]string, 0, len(f.domains))
+	for td := range f.domains {
+		tds = append(tds, td)
+	}
+	sort.Strings(tds)
+	return tds
+}
+
+// Run periodically refreshes the federated bundles until stop is closed.
+func (f *Federation) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+	for {
+		next := f.Refresh(ctx)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			trustBundleLog.Infof("stop processing federated trust bundles")
+			return
+		}
+	}
+}
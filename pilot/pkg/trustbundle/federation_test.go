@@ -0,0 +1,221 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/config/mesh/meshwatcher"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestParseFederationConfig(t *testing.T) {
+	domains, err := ParseFederationConfig(`[{"trustDomain":"foreign.td","bundleEndpointURL":"https://example.com/bundle",` +
+		`"bundleEndpointProfile":"https_web"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0].TrustDomain != "foreign.td" || domains[0].BundleEndpointProfile != spiffe.ProfileHTTPSWeb {
+		t.Fatalf("unexpected domains %+v", domains)
+	}
+	if _, err := ParseFederationConfig("not-json"); err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+	if domains, err := ParseFederationConfig(""); err != nil || domains != nil {
+		t.Fatalf("expected empty config, got %v %v", domains, err)
+	}
+}
+
+func TestNewFederationValidation(t *testing.T) {
+	tb := NewTrustBundle(nil, meshwatcher.NewTestWatcher(nil))
+	web := FederatedTrustDomain{TrustDomain: "a", BundleEndpointURL: "https://a/bundle", BundleEndpointProfile: spiffe.ProfileHTTPSWeb}
+	if _, err := NewFederation(tb, []FederatedTrustDomain{web, web}, nil); err == nil {
+		t.Fatal("expected error for duplicate trust domain")
+	}
+	bad := FederatedTrustDomain{
+		TrustDomain: "b", BundleEndpointURL: "https://b/bundle", BundleEndpointProfile: spiffe.ProfileHTTPSSPIFFE,
+		EndpointSPIFFEID: "spiffe://b/endpoint", BootstrapBundle: malformedCert,
+	}
+	if _, err := NewFederation(tb, []FederatedTrustDomain{bad}, nil); err == nil {
+		t.Fatal("expected error for malformed bootstrap bundle")
+	}
+}
+
+func TestFederationRefresh(t *testing.T) {
+	foreignRoot, err := parsePemCerts(intermediateCACert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := foreignRoot
+	server := httptest.NewTLSServer(spiffe.NewBundleServer("foreign.td", 0, func() []*x509.Certificate { return served }))
+	defer server.Close()
+	webRoots := x509.NewCertPool()
+	webRoots.AddCert(server.Certificate())
+
+	tb := NewTrustBundle(nil, meshwatcher.NewTestWatcher(nil))
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{rootCACert}},
+		Source:            SourceIstioCA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	updates := 0
+	tb.UpdateCb(func() { updates++ })
+
+	f, err := NewFederation(tb, []FederatedTrustDomain{{
+		TrustDomain:           "foreign.td",
+		BundleEndpointURL:     server.URL + spiffe.BundleEndpointPath,
+		BundleEndpointProfile: spiffe.ProfileHTTPSWeb,
+	}}, webRoots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	next := f.Refresh(context.Background())
+	if !next.Equal(now.Add(FederationDefaultRefreshPeriod)) {
+		t.Fatalf("unexpected next refresh %v", next)
+	}
+	if updates != 1 {
+		t.Fatalf("expected 1 trust bundle update, got %d", updates)
+	}
+	if got := tb.GetTrustBundle(); len(got) != 1 || got[0] != rootCACert {
+		t.Fatalf("expected federated roots to be kept out of the trust bundle, got %v", got)
+	}
+	if got := tb.GetFederatedTrustBundles()["foreign.td"]; len(got) != 1 {
+		t.Fatalf("expected federated roots keyed by trust domain, got %v", got)
+	}
+	if got := tb.GetLocalTrustBundle(); len(got) != 1 || got[0] != rootCACert {
+		t.Fatalf("expected only the local root in the local trust bundle, got %v", got)
+	}
+
+	// Nothing is due yet.
+	f.Refresh(context.Background())
+	if updates != 1 {
+		t.Fatalf("expected no trust bundle update before refresh is due, got %d", updates)
+	}
+
+	// The foreign endpoint goes away; the last known bundle is retained and a retry is scheduled.
+	server.Close()
+	now = now.Add(FederationDefaultRefreshPeriod)
+	next = f.Refresh(context.Background())
+	if !next.Equal(now.Add(federationRetryPeriod)) {
+		t.Fatalf("expected retry to be scheduled, got %v", next)
+	}
+	if got := f.FederatedBundles()["foreign.td"]; len(got) != 1 {
+		t.Fatalf("expected last known bundle to be retained, got %v", got)
+	}
+	if got := tb.GetFederatedTrustBundles()["foreign.td"]; len(got) != 1 {
+		t.Fatalf("expected federated roots to be retained, got %v", got)
+	}
+}
+
+func TestFederatedCACannotMintLocalIdentity(t *testing.T) {
+	localCA, localKey := genCA(t, "local")
+	federatedCA, federatedKey := genCA(t, "federated")
+
+	tb := NewTrustBundle(nil, meshwatcher.NewTestWatcher(nil))
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{string(localCA)}},
+		Source:            SourceIstioCA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.UpdateFederatedTrustAnchors(map[string][]string{"foreign.td": {string(federatedCA)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := spiffe.NewPeerCertVerifier()
+	for td, certs := range tb.GetTrustBundlesByDomain([]string{"cluster.local"}) {
+		if err := verifier.AddMappingFromPEM(td, []byte(strings.Join(certs, "\n"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name     string
+		signer   []byte
+		key      []byte
+		identity string
+		accepted bool
+	}{
+		{"local CA, local identity", localCA, localKey, "spiffe://cluster.local/ns/default/sa/a", true},
+		{"federated CA, federated identity", federatedCA, federatedKey, "spiffe://foreign.td/ns/default/sa/a", true},
+		{"federated CA, local identity", federatedCA, federatedKey, "spiffe://cluster.local/ns/default/sa/a", false},
+		{"local CA, federated identity", localCA, localKey, "spiffe://foreign.td/ns/default/sa/a", false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := genLeaf(t, tt.signer, tt.key, tt.identity)
+			err := verifier.VerifyPeerCert([][]byte{leaf.Raw}, nil)
+			if tt.accepted && err != nil {
+				t.Fatalf("expected %s to be accepted: %v", tt.identity, err)
+			}
+			if !tt.accepted && err == nil {
+				t.Fatalf("expected %s to be rejected", tt.identity)
+			}
+		})
+	}
+}
+
+func genCA(t *testing.T, org string) ([]byte, []byte) {
+	t.Helper()
+	cert, key, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          org,
+		TTL:          time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func genLeaf(t *testing.T, signerCert, signerKey []byte, identity string) *x509.Certificate {
+	t.Helper()
+	signer, err := util.ParsePemEncodedCertificate(signerCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := util.ParsePemEncodedKey(signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPem, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:       identity,
+		TTL:        time.Hour,
+		SignerCert: signer,
+		SignerPriv: priv,
+		RSAKeySize: 2048,
+		IsServer:   true,
+		IsClient:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := util.ParsePemEncodedCertificate(leafPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf
+}
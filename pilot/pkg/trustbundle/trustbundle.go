@@ -26,6 +26,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/util/sets"
@@ -68,9 +69,12 @@ type TrustAnchorUpdate struct {
 }
 
 type TrustBundle struct {
-	sourceConfig       map[Source]TrustAnchorConfig
-	mutex              sync.RWMutex
-	mergedCerts        []string
+	sourceConfig map[Source]TrustAnchorConfig
+	mutex        sync.RWMutex
+	mergedCerts  []string
+	// federatedCerts holds the roots of each federated trust domain, keyed by trust domain. They are kept
+	// apart from mergedCerts so a federated CA is only trusted for identities in its own trust domain.
+	federatedCerts     map[string][]string
 	updatecb           func()
 	endpointMutex      sync.RWMutex
 	endpoints          []string
@@ -95,6 +99,7 @@ func NewTrustBundle(remoteCaCertPool *x509.CertPool, meshConfig mesh.Watcher) *T
 			sourceSpiffeEndpoints: {Certs: []string{}},
 		},
 		mergedCerts:        []string{},
+		federatedCerts:     map[string][]string{},
 		updatecb:           nil,
 		endpointUpdateChan: make(chan struct{}, 1),
 		endpoints:          []string{},
@@ -124,6 +129,50 @@ func (tb *TrustBundle) GetTrustBundle() []string {
 	return trustedCerts
 }
 
+// GetLocalTrustBundle retrieves the roots issued by this mesh's own CA or RA. This is the bundle published to
+// federated peers; roots configured from MeshConfig or remote SPIFFE endpoints are not ours to publish.
+func (tb *TrustBundle) GetLocalTrustBundle() []string {
+	certMap := sets.New[string]()
+	tb.mutex.RLock()
+	certMap.InsertAll(tb.sourceConfig[SourceIstioCA].Certs...)
+	certMap.InsertAll(tb.sourceConfig[SourceIstioRA].Certs...)
+	tb.mutex.RUnlock()
+	return sets.SortedList(certMap)
+}
+
+// GetFederatedTrustBundles retrieves the roots of each federated trust domain, keyed by trust domain.
+func (tb *TrustBundle) GetFederatedTrustBundles() map[string][]string {
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	out := make(map[string][]string, len(tb.federatedCerts))
+	for td, certs := range tb.federatedCerts {
+		out[td] = slices.Clone(certs)
+	}
+	return out
+}
+
+// GetTrustBundlesByDomain returns the roots to validate each trust domain with: the merged trustAnchors for the
+// local trust domains, and the federated roots for each federated trust domain. It returns nil when no trust domain
+// is federated, as a single root list is then sufficient.
+func (tb *TrustBundle) GetTrustBundlesByDomain(localTrustDomains []string) map[string][]string {
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	if len(tb.federatedCerts) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(localTrustDomains)+len(tb.federatedCerts))
+	for td, certs := range tb.federatedCerts {
+		out[td] = slices.Clone(certs)
+	}
+	// A federated bundle must never be used for a local trust domain.
+	for _, td := range localTrustDomains {
+		if td != "" {
+			out[td] = slices.Clone(tb.mergedCerts)
+		}
+	}
+	return out
+}
+
 func verifyTrustAnchor(trustAnchor string) error {
 	block, _ := pem.Decode([]byte(trustAnchor))
 	if block == nil {
@@ -196,6 +245,37 @@ func (tb *TrustBundle) UpdateTrustAnchor(anchorConfig *TrustAnchorUpdate) error
 	return nil
 }
 
+// UpdateFederatedTrustAnchors replaces the roots of the federated trust domains.
+func (tb *TrustBundle) UpdateFederatedTrustAnchors(bundles map[string][]string) error {
+	tb.mutex.RLock()
+	unchanged := maps.EqualFunc(bundles, tb.federatedCerts, slices.Equal[string])
+	tb.mutex.RUnlock()
+	if unchanged {
+		trustBundleLog.Debugf("no change to federated trustAnchors after recent update")
+		return nil
+	}
+
+	federated := make(map[string][]string, len(bundles))
+	for td, certs := range bundles {
+		for _, cert := range certs {
+			if err := verifyTrustAnchor(cert); err != nil {
+				return fmt.Errorf("invalid trustAnchor for federated trust domain %s: %v", td, err)
+			}
+		}
+		federated[td] = slices.Clone(certs)
+	}
+	tb.mutex.Lock()
+	tb.federatedCerts = federated
+	tb.mutex.Unlock()
+
+	trustBundleLog.Infof("updating federated trust domains %v", slices.Sort(maps.Keys(federated)))
+
+	if tb.updatecb != nil {
+		tb.updatecb()
+	}
+	return nil
+}
+
 func (tb *TrustBundle) updateRemoteEndpoint(spiffeEndpoints []string) {
 	tb.endpointMutex.RLock()
 	remoteEndpoints := tb.endpoints
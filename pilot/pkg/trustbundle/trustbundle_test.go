@@ -268,6 +268,37 @@ func expectTbCount(t *testing.T, tb *TrustBundle, expAnchorCount int, ti time.Du
 	}, retry.Timeout(ti))
 }
 
+func TestGetLocalTrustBundle(t *testing.T) {
+	tb := NewTrustBundle(nil, nil)
+	updates := []*TrustAnchorUpdate{
+		{TrustAnchorConfig: TrustAnchorConfig{Certs: []string{rootCACert}}, Source: SourceIstioCA},
+		{TrustAnchorConfig: TrustAnchorConfig{Certs: []string{intermediateCACert}}, Source: SourceMeshConfig},
+	}
+	for _, u := range updates {
+		if err := tb.UpdateTrustAnchor(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := tb.GetTrustBundle(); len(got) != 2 {
+		t.Fatalf("expected roots from all sources in the trust bundle, got %d", len(got))
+	}
+	// Roots configured from MeshConfig are trusted but are not published as this trust domain's bundle.
+	if got := tb.GetLocalTrustBundle(); !slices.Equal(got, []string{rootCACert}) {
+		t.Fatalf("expected only the CA root in the local trust bundle, got %v", got)
+	}
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{intermediateCACert}},
+		Source:            SourceIstioRA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{rootCACert, intermediateCACert}
+	sort.Strings(want)
+	if got := tb.GetLocalTrustBundle(); !slices.Equal(got, want) {
+		t.Fatalf("expected the CA and RA roots in the local trust bundle, got %v", got)
+	}
+}
+
 func TestAddMeshConfigUpdate(t *testing.T) {
 	caCertPool, err := x509.SystemCertPool()
 	if err != nil {
@@ -17,6 +17,7 @@ package informermetric
 import (
 	"sync"
 
+	"go.uber.org/atomic"
 	"k8s.io/client-go/tools/cache"
 
 	"istio.io/istio/pkg/cluster"
@@ -25,13 +26,21 @@ import (
 )
 
 var (
-	clusterLabel = monitoring.CreateLabel("cluster")
+	clusterLabel  = monitoring.CreateLabel("cluster")
+	resourceLabel = monitoring.CreateLabel("resource")
 
 	errorMetric = monitoring.NewSum(
 		"controller_sync_errors_total",
 		"Total number of errorMetric syncing controllers.",
 	)
 
+	transformSavedBytes = monitoring.NewSum(
+		"informer_transform_saved_bytes",
+		"Estimated total size of the fields stripped by the transforms of the informers from the objects they cache, "+
+			"as the protobuf size of the objects, extrapolated from a sample of the objects.",
+		monitoring.WithUnit(monitoring.Bytes),
+	)
+
 	mu       sync.RWMutex
 	handlers = map[cluster.ID]cache.WatchErrorHandler{}
 )
@@ -56,3 +65,35 @@ func ErrorHandlerForCluster(clusterID cluster.ID) cache.WatchErrorHandler {
 	handlers[clusterID] = h
 	return h
 }
+
+// sizer is implemented by the Kubernetes types, with the size of their protobuf encoding.
+type sizer interface {
+	Size() int
+}
+
+// savingsSampleRate is the number of objects transformed for each object measured by TransformWithSavings. Measuring
+// the size of an object walks all of it, twice, which is too expensive to do on every event.
+const savingsSampleRate = 64
+
+// TransformWithSavings wraps an informer transform to report the size of the fields it strips from the objects. Only one
+// object out of savingsSampleRate is measured, and its savings are counted for all of them. Objects that cannot report
+// their size are transformed without being measured.
+func TransformWithSavings(clusterID cluster.ID, resource string, transform func(obj any) (any, error)) func(obj any) (any, error) {
+	saved := transformSavedBytes.With(clusterLabel.Value(clusterID.String()), resourceLabel.Value(resource))
+	transformed := atomic.NewUint64(0)
+	return func(obj any) (any, error) {
+		before, ok := obj.(sizer)
+		if !ok || transformed.Inc()%savingsSampleRate != 1 {
+			return transform(obj)
+		}
+		// Transforms usually modify the object in place, so the size must be taken first
+		size := before.Size()
+		res, err := transform(obj)
+		if after, ok := res.(sizer); ok && err == nil {
+			if diff := size - after.Size(); diff > 0 {
+				saved.RecordInt(int64(diff) * savingsSampleRate)
+			}
+		}
+		return res, err
+	}
+}
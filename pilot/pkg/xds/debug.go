@@ -47,6 +47,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/schema/resource"
 	"istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/kube/controllers"
 	istiolog "istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/security"
@@ -211,7 +212,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 	s.addDebugHandler(mux, internalMux, "/debug/ambientz", "Debug support for ambient", s.ambientz)
-	s.addDebugHandler(mux, internalMux, "/debug/krtz", "Debug support for krt (internal state)", s.krtz)
+	s.addDebugHandler(mux, internalMux, "/debug/krtz", "Debug support for krt (internal state); use ?graph=json or ?graph=dot for the collection graph, "+
+		"or ?collection=<name>&key=<key> for the history of an output", s.krtz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/authorizationz", "Internal authorization policies", s.authorizationz)
 	s.addDebugHandler(mux, internalMux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
@@ -223,6 +225,11 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.injectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.meshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
+	s.addDebugHandler(mux, internalMux, "/debug/discoveryfilterz", "The active discovery selectors, the namespaces they select and their last changes",
+		s.discoveryfilterz)
+	s.addDebugHandler(mux, internalMux, "/debug/configdivergencez", "List the VirtualServices and DestinationRules whose copies differ across clusters",
+		s.configdivergencez)
+	s.addDebugHandler(mux, internalMux, "/debug/controllerz", "List the running controller queues and the keys they gave up reconciling", s.controllerz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
 
@@ -1087,8 +1094,40 @@ func (s *DiscoveryServer) ambientz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, res, req)
 }
 
+// krtz dumps the state of the krt collections. With the graph query parameter, it returns the graph of the
+// collections instead, as JSON (graph=json) or in the Graphviz DOT language (graph=dot), with their activity when
+// PILOT_KRT_PROFILING is set.
+// With the collection and key query parameters, it returns the last changes of an output of a collection, when
+// PILOT_KRT_HISTORY_SIZE is set.
 func (s *DiscoveryServer) krtz(w http.ResponseWriter, req *http.Request) {
-	writeJSON(w, s.krtDebugger, req)
+	if collection := req.URL.Query().Get("collection"); collection != "" {
+		key := req.URL.Query().Get("key")
+		if key == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("key is required with collection"))
+			return
+		}
+		history, err := s.krtDebugger.History(collection, key)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		writeJSON(w, history, req)
+		return
+	}
+	switch format := req.URL.Query().Get("graph"); format {
+	case "":
+		writeJSON(w, s.krtDebugger, req)
+	case "json":
+		writeJSON(w, s.krtDebugger.Graph(), req)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		_, _ = w.Write([]byte(s.krtDebugger.Graph().DOT()))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "unsupported graph format %q, expected json or dot", format)
+	}
 }
 
 func (s *DiscoveryServer) networkz(w http.ResponseWriter, req *http.Request) {
@@ -1124,6 +1163,32 @@ func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.ListRemoteClusters(), req)
 }
 
+// discoveryfilterz reports the namespaces selected by the discovery selectors, and how they changed.
+func (s *DiscoveryServer) discoveryfilterz(w http.ResponseWriter, req *http.Request) {
+	if s.DiscoveryFilter == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("discovery filter is not enabled"))
+		return
+	}
+	writeJSON(w, s.DiscoveryFilter(), req)
+}
+
+// configdivergencez lists the configuration whose copies differ across the clusters of the mesh.
+func (s *DiscoveryServer) configdivergencez(w http.ResponseWriter, req *http.Request) {
+	if s.ConfigDivergences == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("config divergence tracking is not enabled"))
+		return
+	}
+	writeJSON(w, s.ConfigDivergences(), req)
+}
+
+// controllerz lists the running controller queues, with the keys each queue gave up reconciling after exhausting
+// their retries.
+func (s *DiscoveryServer) controllerz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, controllers.DumpQueues(), req)
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
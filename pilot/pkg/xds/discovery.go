@@ -29,12 +29,14 @@ import (
 	"google.golang.org/grpc"
 
 	"istio.io/istio/pilot/pkg/autoregistration"
+	"istio.io/istio/pilot/pkg/config/kube/divergence"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/envoyfilter"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/schema/kind"
 	"istio.io/istio/pkg/kube/krt"
+	"istio.io/istio/pkg/kube/namespace"
 	istiolog "istio.io/istio/pkg/log"
 	"istio.io/istio/pkg/maps"
 	"istio.io/istio/pkg/security"
@@ -127,6 +129,12 @@ type DiscoveryServer struct {
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
 
+	// DiscoveryFilter reports the state of the discovery filter of the config cluster, if any.
+	DiscoveryFilter func() namespace.FilterDump
+
+	// ConfigDivergences reports the configuration whose copies differ across the clusters of the mesh, if tracked.
+	ConfigDivergences func() []divergence.Divergence
+
 	// ClusterAliases are alias names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
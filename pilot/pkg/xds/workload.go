@@ -17,10 +17,13 @@ package xds
 import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -45,6 +48,9 @@ func (e WorkloadGenerator) GenerateDeltas(
 	req *model.PushRequest,
 	w *model.WatchedResource,
 ) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if w.Wildcard && features.EnableScopedZtunnelAddresses && proxy.IsZTunnel() {
+		return e.generateDeltasScoped(proxy, req, w)
+	}
 	addresses := req.AddressesUpdated
 	isReq := req.IsRequest()
 	if !isReq && len(addresses) == 0 {
@@ -85,6 +91,89 @@ func (e WorkloadGenerator) GenerateDeltas(
 	return resources, removed.UnsortedList(), model.XdsLogDetails{}, true, nil
 }
 
+// generateDeltasScoped computes the Workload resources of a wildcard subscription, scoped to the workloads on the node
+// of the ztunnel, the services visible from their namespaces, and the workloads of these services.
+//
+// Updates are filtered by the scope of the ztunnel, as long as they leave it unchanged. When a workload on the node is
+// in a new namespace, or a service or Sidecar changed, possibly its exportTo, the scope is computed again from all the
+// addresses.
+// Only the addresses that changed since they were last sent, or entered or left the scope, are then pushed.
+func (e WorkloadGenerator) generateDeltasScoped(
+	proxy *model.Proxy,
+	req *model.PushRequest,
+	w *model.WatchedResource,
+) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	// Pushes to a proxy are serialized, so only this generator writes the scope. The scope is computed without the
+	// lock of the proxy, which is only held to read and replace it.
+	proxy.RLock()
+	scope := proxy.ZtunnelScope
+	proxy.RUnlock()
+	isReq := req.IsRequest()
+	rescope := scope == nil || isReq || req.Forced ||
+		model.HasConfigsOfKind(req.ConfigsUpdated, kind.ServiceEntry) || model.HasConfigsOfKind(req.ConfigsUpdated, kind.Sidecar)
+	if !rescope {
+		if len(req.AddressesUpdated) == 0 {
+			// Nothing changed...
+			return nil, nil, model.XdsLogDetails{}, false, nil
+		}
+		addrs, removed := e.Server.Env.AmbientIndexes.AddressInformation(req.AddressesUpdated)
+		if scope.Stable(req.Push, addrs) {
+			have := sets.New[string]()
+			resources := make(model.Resources, 0, len(addrs))
+			for _, addr := range addrs {
+				if scope.Contains(addr) {
+					resources = appendAddress(addr, w.TypeUrl, nil, have, scope.Sent, resources)
+					scope.Sent[addr.ResourceName()] = addr.Version
+				} else {
+					// The address left the scope, as a workload no longer in a service in scope
+					removed.Insert(addr.ResourceName())
+				}
+			}
+			removed = sets.New(slices.Filter(removed.UnsortedList(), func(n string) bool {
+				_, sent := scope.Sent[n]
+				return sent
+			})...)
+			for n := range removed {
+				delete(scope.Sent, n)
+				scope.Services.Delete(n)
+			}
+			if len(resources) == 0 && len(removed) == 0 {
+				// Nothing in scope changed
+				return nil, nil, model.XdsLogDetails{}, false, nil
+			}
+			return resources, removed.UnsortedList(), model.XdsLogDetails{}, true, nil
+		}
+	}
+
+	addrs, _ := e.Server.Env.AmbientIndexes.AddressInformation(nil)
+	next := model.NewZtunnelScope(req.Push, proxy.GetNodeName(), addrs)
+	// The client holds the resources it reported on a request, or the ones sent previously.
+	retained := req.Delta.InitialResourceVersions
+	removed := sets.New[string]()
+	if isReq {
+		removed = req.Delta.Subscribed.Copy()
+	} else if scope != nil {
+		retained = scope.Sent
+		removed = sets.New(maps.Keys(scope.Sent)...)
+	}
+	have := sets.New[string]()
+	resources := make(model.Resources, 0, len(addrs))
+	for _, addr := range addrs {
+		if next.Contains(addr) {
+			resources = appendAddress(addr, w.TypeUrl, nil, have, retained, resources)
+			next.Sent[addr.ResourceName()] = addr.Version
+		}
+	}
+	removed = removed.Difference(have)
+	proxy.Lock()
+	proxy.ZtunnelScope = next
+	proxy.Unlock()
+	if !isReq && len(resources) == 0 && len(removed) == 0 {
+		return nil, nil, model.XdsLogDetails{}, false, nil
+	}
+	return resources, removed.UnsortedList(), model.XdsLogDetails{}, true, nil
+}
+
 func appendAddress(
 	addr model.AddressInfo,
 	requestedType string,
@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"istio.io/api/annotation"
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/api/security/v1beta1"
 	metav1beta1 "istio.io/api/type/v1beta1"
 	securityclient "istio.io/client-go/pkg/apis/security/v1"
@@ -387,6 +388,111 @@ func TestWorkload(t *testing.T) {
 	})
 }
 
+func TestWorkloadScoped(t *testing.T) {
+	test.SetForTest(t, &features.EnableScopedZtunnelAddresses, true)
+	expect := buildExpect(t)
+	expectRemoved := buildExpectExpectRemoved(t)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		DebounceTime: time.Millisecond * 25,
+	})
+	ads := s.ConnectDeltaADS().WithTimeout(time.Second * 5).WithType(v3.AddressType).
+		WithID("ztunnel~1.1.1.1~ztunnel.istio-system~istio-system.svc.cluster.local").
+		WithMetadata(model.NodeMetadata{NodeName: "node"})
+
+	ads.Request(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"*"},
+	})
+	ads.ExpectEmptyResponse()
+
+	// A workload on another node, outside of any service, is out of scope
+	createPod(s, "remote", "remote", "127.0.0.1", "not-node")
+	ads.ExpectNoResponse()
+
+	// A workload on the node is in scope
+	createPod(s, "local", "local", "127.0.0.2", "node")
+	expect(ads.ExpectResponse(), "Kubernetes//Pod/default/local")
+
+	// A service visible from the namespace of the local workload brings its workloads in scope
+	createService(s, "svc1", "default", map[string]string{"app": "remote"})
+	expect(ads.ExpectResponse(), "Kubernetes//Pod/default/remote", "default/svc1.default.svc.cluster.local")
+
+	// The workload leaves the scope when the service no longer selects it
+	createService(s, "svc1", "default", map[string]string{"app": "nothing"})
+	expectRemoved(ads.ExpectResponse(), "Kubernetes//Pod/default/remote")
+
+	// A service not exported to the namespace of the local workload is out of scope, along with its workloads
+	clienttest.NewWriter[*corev1.Service](t, s.KubeClient()).Create(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "svc2",
+			Namespace:   "default",
+			Annotations: map[string]string{annotation.NetworkingExportTo.Name: "istio-system"},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.2",
+			Ports:     []corev1.ServicePort{{Name: "tcp", Port: 80, Protocol: "TCP"}},
+			Selector:  map[string]string{"app": "remote"},
+			Type:      corev1.ServiceTypeClusterIP,
+		},
+	})
+	ads.ExpectNoResponse()
+
+	// On reconnection, the ztunnel gets the addresses in scope only
+	ads.Cleanup()
+	ads = s.ConnectDeltaADS().WithTimeout(time.Second * 5).WithType(v3.AddressType).
+		WithID("ztunnel~1.1.1.1~ztunnel.istio-system~istio-system.svc.cluster.local").
+		WithMetadata(model.NodeMetadata{NodeName: "node"})
+	resp := ads.RequestResponseAck(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"*"},
+	})
+	expect(resp, "Kubernetes//Pod/default/local", "default/svc1.default.svc.cluster.local")
+}
+
+func TestWorkloadScopedSidecar(t *testing.T) {
+	test.SetForTest(t, &features.EnableScopedZtunnelAddresses, true)
+	expect := buildExpect(t)
+	expectRemoved := buildExpectExpectRemoved(t)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		DebounceTime: time.Millisecond * 25,
+	})
+	ads := s.ConnectDeltaADS().WithTimeout(time.Second * 5).WithType(v3.AddressType).
+		WithID("ztunnel~1.1.1.1~ztunnel.istio-system~istio-system.svc.cluster.local").
+		WithMetadata(model.NodeMetadata{NodeName: "node"})
+
+	ads.Request(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"*"},
+	})
+	ads.ExpectEmptyResponse()
+
+	createPod(s, "local", "local", "127.0.0.2", "node")
+	expect(ads.ExpectResponse(), "Kubernetes//Pod/default/local")
+	createPod(s, "remote", "remote", "127.0.0.1", "not-node")
+	createService(s, "svc1", "default", map[string]string{"app": "remote"})
+	expect(ads.ExpectResponse(), "Kubernetes//Pod/default/remote", "default/svc1.default.svc.cluster.local")
+
+	// A Sidecar of the namespace not importing the service puts it out of scope, along with its workloads
+	sidecar := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.Sidecar,
+			Name:             "default",
+			Namespace:        "default",
+		},
+		Spec: &networking.Sidecar{
+			Egress: []*networking.IstioEgressListener{{Hosts: []string{"istio-system/*"}}},
+		},
+	}
+	_, err := s.Store().Create(sidecar)
+	assert.NoError(t, err)
+	expectRemoved(ads.ExpectResponse(), "Kubernetes//Pod/default/remote", "default/svc1.default.svc.cluster.local")
+
+	// Importing the service brings it back in scope
+	sidecar.Spec = &networking.Sidecar{
+		Egress: []*networking.IstioEgressListener{{Hosts: []string{"./*"}}},
+	}
+	_, err = s.Store().Update(sidecar)
+	assert.NoError(t, err)
+	expect(ads.ExpectResponse(), "Kubernetes//Pod/default/remote", "default/svc1.default.svc.cluster.local")
+}
+
 // Historically, the debug interface has been a common source of race conditions in the discovery server
 // spamDebugEndpointsToDetectRace hits all the endpoints, attempting to trigger any latent race conditions.
 func spamDebugEndpointsToDetectRace(t *testing.T, s *xds.FakeDiscoveryServer) {
@@ -14,6 +14,8 @@
 
 package cluster
 
+import "time"
+
 // DebugInfo contains minimal information about remote clusters.
 // This struct is defined here, in a package that avoids many imports, since xds/debug usually
 // affects agent binary size. We avoid embedding other parts of a "remote cluster" struct like kube clients.
@@ -21,4 +23,11 @@ type DebugInfo struct {
 	ID         ID     `json:"id"`
 	SecretName string `json:"secretName"`
 	SyncStatus string `json:"syncStatus"`
+	// Health is the result of the health checks of the API server of the cluster. It is unset for the config cluster
+	// and when health checking is disabled.
+	Health string `json:"health,omitempty"`
+	// UnhealthySince is the time of the first failed health check since the cluster was last healthy.
+	UnhealthySince *time.Time `json:"unhealthySince,omitempty"`
+	// LastHealthCheckError is the error of the last failed health check.
+	LastHealthCheckError string `json:"lastHealthCheckError,omitempty"`
 }
@@ -65,7 +65,9 @@ func All() []analysis.Analyzer {
 		&k8sgateway.CRDVersionAnalyzer{},
 		&multicluster.MeshNetworksAnalyzer{},
 		&multicluster.ServiceAnalyzer{},
+		&multicluster.ConfigDivergenceAnalyzer{},
 		&service.PortNameAnalyzer{},
+		&service.HeadlessServiceProtocolAnalyzer{},
 		&sidecar.SelectorAnalyzer{},
 		&virtualservice.ConflictingMeshGatewayHostsAnalyzer{},
 		&virtualservice.DestinationHostAnalyzer{},
@@ -89,6 +91,7 @@ func All() []analysis.Analyzer {
 func AllMultiCluster() []analysis.Analyzer {
 	analyzers := []analysis.Analyzer{
 		&multicluster.ServiceAnalyzer{},
+		&multicluster.ConfigDivergenceAnalyzer{},
 	}
 	return analyzers
 }
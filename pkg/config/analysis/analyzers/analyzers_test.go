@@ -104,6 +104,7 @@ var testGrid = []testCase{
 			{msg.AlphaAnnotation, "Pod anno-not-set-by-default"},
 			{msg.AlphaAnnotation, "Pod anno-not-set-by-default"},
 			{msg.AlphaAnnotation, "Pod anno-not-set-by-default"},
+			{msg.AlphaAnnotation, "Pod user-secrets"},
 		},
 		skipAll: true,
 	},
@@ -388,6 +389,16 @@ var testGrid = []testCase{
 		analyzer:   &service.PortNameAnalyzer{},
 		expected:   []message{},
 	},
+	{
+		name:       "headlessServiceProtocol",
+		inputFiles: []string{"testdata/service-headless-protocol.yaml"},
+		analyzer:   &service.HeadlessServiceProtocolAnalyzer{},
+		expected: []message{
+			{msg.HeadlessServicePortNotL7, "Service ambient/mysql"},
+			{msg.HeadlessServicePortNotL7, "Service ambient/mysql"},
+			{msg.HeadlessServicePortNotL7, "Service ambient/kafka"},
+		},
+	},
 	{
 		name:       "unnamedPortInSystemNamespace",
 		inputFiles: []string{"testdata/service-no-port-name-system-namespace.yaml"},
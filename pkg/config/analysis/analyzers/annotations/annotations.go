@@ -27,13 +27,15 @@ import (
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/slices"
 )
 
 // K8sAnalyzer checks for misplaced and invalid Istio annotations in K8s resources
 type K8sAnalyzer struct{}
 
-var istioAnnotations = annotation.AllResourceAnnotations()
+// istioAnnotations are the annotations of istio.io/api, and the ones defined in this repository.
+var istioAnnotations = append(annotation.AllResourceAnnotations(), &security.UserSecretsAnnotation)
 
 // Metadata implements analyzer.Analyzer
 func (*K8sAnalyzer) Metadata() analysis.Metadata {
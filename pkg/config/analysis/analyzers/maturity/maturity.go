@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/security"
 )
 
 // AlphaAnalyzer checks for alpha Istio annotations in K8s resources
@@ -35,7 +36,8 @@ type AlphaAnalyzer struct{}
 // in too much noise for users, with annotations that are set by default.  Once the noise dies down, this should be
 // added to the CombinedAnalyzers() function.
 
-var istioAnnotations = annotation.AllResourceAnnotations()
+// istioAnnotations are the annotations of istio.io/api, and the ones defined in this repository.
+var istioAnnotations = append(annotation.AllResourceAnnotations(), &security.UserSecretsAnnotation)
 
 // Metadata implements analyzer.Analyzer
 func (*AlphaAnalyzer) Metadata() analysis.Metadata {
@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/hash"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// ConfigDivergenceAnalyzer reports the Istio configuration whose copies differ across clusters. In a multi-primary mesh
+// each istiod only reads the configuration of its own cluster, so divergent copies cause asymmetric routing.
+type ConfigDivergenceAnalyzer struct{}
+
+var _ analysis.Analyzer = &ConfigDivergenceAnalyzer{}
+
+// DivergenceKinds are the kinds of configuration compared across clusters.
+var DivergenceKinds = []config.GroupVersionKind{
+	gvk.VirtualService,
+	gvk.DestinationRule,
+}
+
+// Metadata implements Analyzer
+func (s *ConfigDivergenceAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "multicluster.ConfigDivergenceAnalyzer",
+		Description: "Check that the Istio configuration is consistent across the clusters of a multi-primary mesh",
+		Inputs:      DivergenceKinds,
+	}
+}
+
+// Analyze implements Analyzer
+func (s *ConfigDivergenceAnalyzer) Analyze(c analysis.Context) {
+	for _, kind := range DivergenceKinds {
+		copies := map[resource.FullName]map[cluster.ID]*resource.Instance{}
+		c.ForEach(kind, func(r *resource.Instance) bool {
+			clusterID := r.Origin.ClusterName()
+			if clusterID == "" {
+				return true
+			}
+			if _, ok := copies[r.Metadata.FullName]; !ok {
+				copies[r.Metadata.FullName] = map[cluster.ID]*resource.Instance{}
+			}
+			copies[r.Metadata.FullName][clusterID] = r
+			return true
+		})
+
+		for fullname, clusterCopies := range copies {
+			if len(clusterCopies) == 1 {
+				continue
+			}
+			hashes := map[cluster.ID]string{}
+			for id, r := range clusterCopies {
+				h, err := SpecHash(r.Message)
+				if err != nil {
+					continue
+				}
+				hashes[id] = h
+			}
+			if !Diverges(hashes) {
+				continue
+			}
+			// Report every copy, so that each cluster's istiod flags its own.
+			summary := FormatSpecHashes(hashes)
+			for _, id := range slices.Sort(maps.Keys(hashes)) {
+				c.Report(kind, msg.NewMultiClusterInconsistentConfig(clusterCopies[id], kind.Kind,
+					fullname.Name.String(), fullname.Namespace.String(), summary))
+			}
+		}
+	}
+}
+
+// SpecHash returns a hash of the spec of a configuration, which is the same for equal specs.
+func SpecHash(spec any) (string, error) {
+	m, ok := spec.(proto.Message)
+	if !ok {
+		return "", fmt.Errorf("unexpected spec %T", spec)
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	h := hash.New()
+	h.Write(b)
+	return h.Sum(), nil
+}
+
+// Diverges returns true if the copies of a configuration, given by their spec hashes, are not all the same.
+func Diverges(hashes map[cluster.ID]string) bool {
+	return sets.New(maps.Values(hashes)...).Len() > 1
+}
+
+// FormatSpecHashes formats the spec hashes of the copies of a configuration, sorted by cluster.
+func FormatSpecHashes(hashes map[cluster.ID]string) string {
+	out := make([]string, 0, len(hashes))
+	for _, id := range slices.Sort(maps.Keys(hashes)) {
+		out = append(out, fmt.Sprintf("%s=%s", id, hashes[id]))
+	}
+	return strings.Join(out, ", ")
+}
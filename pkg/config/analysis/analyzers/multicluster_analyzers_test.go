@@ -58,6 +58,23 @@ var mcTestGrid = []mcTestCase{
 			{msg.MultiClusterInconsistentService, "Service my-namespace/mixed-port-protocol"},
 		},
 	},
+	{
+		name: "DivergentMultiClusterConfig",
+		cluster1InputFiles: []string{
+			"testdata/multicluster/divergent-config-1.yaml",
+		},
+		cluster2InputFiles: []string{
+			"testdata/multicluster/divergent-config-2.yaml",
+		},
+		analyzer: &multicluster.ConfigDivergenceAnalyzer{},
+		expected: []message{
+			// Each cluster's copy is reported.
+			{msg.MultiClusterInconsistentConfig, "VirtualService my-namespace/ratings"},
+			{msg.MultiClusterInconsistentConfig, "VirtualService my-namespace/ratings"},
+			{msg.MultiClusterInconsistentConfig, "DestinationRule my-namespace/ratings"},
+			{msg.MultiClusterInconsistentConfig, "DestinationRule my-namespace/ratings"},
+		},
+	},
 }
 
 // TestMultiClusterAnalyzers sets up two clusters and runs the multi-cluster analyzers on them.
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	configKube "istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// HeadlessServiceProtocolAnalyzer checks the protocols of the ports of the headless services bound to a waypoint in
+// ambient mode, whose traffic the waypoint can only handle at L4.
+type HeadlessServiceProtocolAnalyzer struct{}
+
+var _ analysis.Analyzer = &HeadlessServiceProtocolAnalyzer{}
+
+// Metadata implements Analyzer
+func (s *HeadlessServiceProtocolAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "service.HeadlessServiceProtocolAnalyzer",
+		Description: "Checks the port protocols of headless services bound to a waypoint in ambient mode",
+		Inputs: []config.GroupVersionKind{
+			gvk.Service,
+			gvk.Namespace,
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (s *HeadlessServiceProtocolAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(gvk.Service, func(r *resource.Instance) bool {
+		if util.IsIstioControlPlane(r) {
+			return true
+		}
+		s.analyzeService(r, c)
+		return true
+	})
+}
+
+func (s *HeadlessServiceProtocolAnalyzer) analyzeService(r *resource.Instance, c analysis.Context) {
+	svc := r.Message.(*v1.ServiceSpec)
+	if svc.ClusterIP != v1.ClusterIPNone {
+		return
+	}
+	ns := c.Find(gvk.Namespace, resource.NewFullName("", resource.LocalName(r.Metadata.FullName.Namespace)))
+	if ns == nil || !util.NamespaceInAmbientMode(ns) {
+		return
+	}
+	waypoint, ok := r.Metadata.Labels[label.IoIstioUseWaypoint.Name]
+	if !ok {
+		waypoint = ns.Metadata.Labels[label.IoIstioUseWaypoint.Name]
+	}
+	if waypoint == "" || waypoint == "none" {
+		return
+	}
+	for i, port := range svc.Ports {
+		instance := configKube.ConvertProtocol(port.Port, port.Name, port.Protocol, port.AppProtocol)
+		// Undeclared protocols are sniffed by the waypoint, and UDP is not handled by waypoints at all
+		if instance.IsHTTP() || instance.IsUnsupported() || instance == protocol.UDP {
+			continue
+		}
+		m := msg.NewHeadlessServicePortNotL7(r, int(port.Port), instance.String(), waypoint)
+		if line, ok := util.ErrorLine(r, fmt.Sprintf(util.PortInPorts, i)); ok {
+			m.Line = line
+		}
+		c.Report(gvk.Service, m)
+	}
+}
@@ -279,6 +279,14 @@ var (
 	// ConflictingServiceEntryProtocol defines a diag.MessageType for message "ConflictingServiceEntryProtocol".
 	// Description: Multiple ServiceEntries define the same host and port with conflicting protocols.
 	ConflictingServiceEntryProtocol = diag.NewMessageType(diag.Warning, "IST0177", "Multiple ServiceEntries (%s) define the same host %q and port %d with conflicting protocols (%s).")
+
+	// HeadlessServicePortNotL7 defines a diag.MessageType for message "HeadlessServicePortNotL7".
+	// Description: A port of a headless Service bound to a waypoint has a protocol the waypoint cannot process at L7 in ambient mode.
+	HeadlessServicePortNotL7 = diag.NewMessageType(diag.Warning, "IST0178", "Port %d of the headless Service uses protocol %s, so its traffic to the pods is only handled at L4 by the waypoint %s; routing and authorization rules based on HTTP attributes do not apply to it. If the port serves HTTP, declare it with an HTTP port name prefix or appProtocol.")
+
+	// MultiClusterInconsistentConfig defines a diag.MessageType for message "MultiClusterInconsistentConfig".
+	// Description: A copy of an Istio configuration resource differs across the clusters of a multi-primary mesh
+	MultiClusterInconsistentConfig = diag.NewMessageType(diag.Warning, "IST0179", "The %s %s in namespace %q differs across clusters (spec hashes: %s), which can cause asymmetric routing since each istiod only reads the configuration of its own cluster.")
 )
 
 // All returns a list of all known message types.
@@ -352,6 +360,8 @@ func All() []*diag.MessageType {
 		JwksUriFetchUnrestricted,
 		GatewayAPICRDVersionBelowMinimum,
 		ConflictingServiceEntryProtocol,
+		HeadlessServicePortNotL7,
+		MultiClusterInconsistentConfig,
 	}
 }
 
@@ -1026,3 +1036,26 @@ func NewConflictingServiceEntryProtocol(r *resource.Instance, serviceEntryNames
 		protocols,
 	)
 }
+
+// NewHeadlessServicePortNotL7 returns a new diag.Message based on HeadlessServicePortNotL7.
+func NewHeadlessServicePortNotL7(r *resource.Instance, port int, protocol string, waypoint string) diag.Message {
+	return diag.NewMessage(
+		HeadlessServicePortNotL7,
+		r,
+		port,
+		protocol,
+		waypoint,
+	)
+}
+
+// NewMultiClusterInconsistentConfig returns a new diag.Message based on MultiClusterInconsistentConfig.
+func NewMultiClusterInconsistentConfig(r *resource.Instance, kind string, name string, namespace string, specHashes string) diag.Message {
+	return diag.NewMessage(
+		MultiClusterInconsistentConfig,
+		r,
+		kind,
+		name,
+		namespace,
+		specHashes,
+	)
+}
@@ -66,6 +66,9 @@ type ClientGetter interface {
 	// Informers returns an informer factory.
 	Informers() informerfactory.InformerFactory
 
+	// ObjectTransform returns the transform of the informers of the resource that do not set their own, or nil.
+	ObjectTransform(g schema.GroupVersionResource) func(obj any) (any, error)
+
 	// IsWatchListSemanticsUnSupported is used by internal client-go libraries to tell if the client is a fake client (more or less)
 	IsWatchListSemanticsUnSupported() bool
 }
@@ -82,6 +85,7 @@ func GetInformerFiltered[T runtime.Object](
 	if reg != nil {
 		// This is registered type
 		tr := *reg
+		opts = withClientTransform(c, tr.GetGVR(), opts)
 		return c.Informers().InformerFor(tr.GetGVR(), opts, func() cache.SharedIndexInformer {
 			inf := cache.NewSharedIndexInformer(
 				tr.ListWatch(c, opts),
@@ -89,7 +93,7 @@ func GetInformerFiltered[T runtime.Object](
 				0,
 				cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 			)
-			setupInformer(opts, inf)
+			setupInformer(tr.GetGVR(), opts, inf)
 			return inf
 		})
 	}
@@ -107,7 +111,7 @@ func GetInformerFilteredFromGVR(c ClientGetter, opts ktypes.InformerOptions, g s
 	case ktypes.MetadataInformer:
 		return getInformerFilteredMetadata(c, opts, g)
 	default:
-		return getInformerFiltered(c, opts, g)
+		return getInformerFiltered(c, withClientTransform(c, g, opts), g)
 	}
 }
 
@@ -133,7 +137,7 @@ func getInformerFilteredDynamic(c ClientGetter, opts ktypes.InformerOptions, g s
 				ObjectDescription: g.String(),
 			},
 		)
-		setupInformer(opts, inf)
+		setupInformer(g, opts, inf)
 		return inf
 	})
 }
@@ -160,19 +164,19 @@ func getInformerFilteredMetadata(c ClientGetter, opts ktypes.InformerOptions, g
 				ObjectDescription: g.String(),
 			},
 		)
-		setupInformer(opts, inf)
+		setupInformer(g, opts, inf)
 		return inf
 	})
 }
 
-func setupInformer(opts ktypes.InformerOptions, inf cache.SharedIndexInformer) {
+func setupInformer(g schema.GroupVersionResource, opts ktypes.InformerOptions, inf cache.SharedIndexInformer) {
 	// It is important to set this in the newFunc rather than after InformerFor to avoid
 	// https://github.com/kubernetes/kubernetes/issues/117869
-	if opts.ObjectTransform != nil {
-		_ = inf.SetTransform(opts.ObjectTransform)
-	} else {
-		_ = inf.SetTransform(stripUnusedFields)
+	transform := opts.ObjectTransform
+	if transform == nil {
+		transform = stripUnusedFields
 	}
+	_ = inf.SetTransform(informermetric.TransformWithSavings(opts.Cluster, g.Resource, transform))
 	if err := inf.SetWatchErrorHandler(informermetric.ErrorHandlerForCluster(opts.Cluster)); err != nil {
 		log.Debugf("failed to set watch handler, informer may already be started: %v", err)
 	}
@@ -734,7 +734,7 @@ func getInformerFiltered(c ClientGetter, opts ktypes.InformerOptions, g schema.G
 			0,
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		)
-		setupInformer(opts, inf)
+		setupInformer(g, opts, inf)
 		return inf
 	})
 }
@@ -0,0 +1,32 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeclient
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	ktypes "istio.io/istio/pkg/kube/kubetypes"
+)
+
+// withClientTransform sets the transform of the resource configured on the client on opts, if they do not set one.
+// This is done before the informer is looked up, so informers sharing the transform of the client do not conflict.
+func withClientTransform(c ClientGetter, g schema.GroupVersionResource, opts ktypes.InformerOptions) ktypes.InformerOptions {
+	if opts.ObjectTransform != nil || opts.InformerType != ktypes.StandardInformer {
+		// Dynamic and metadata informers do not store the typed objects the transforms expect
+		return opts
+	}
+	opts.ObjectTransform = c.ObjectTransform(g)
+	return opts
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeclient
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config/schema/gvr"
+	"istio.io/istio/pkg/kube"
+	ktypes "istio.io/istio/pkg/kube/kubetypes"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestClientTransform(t *testing.T) {
+	mt := monitortest.New(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				"keep":                             "true",
+				corev1.LastAppliedConfigAnnotation: `{"data":{"key":"dmFsdWU="}}`,
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Data: map[string][]byte{"key": []byte("value")},
+	}
+	client := kube.WithObjectTransforms(map[schema.GroupVersionResource]func(obj any) (any, error){
+		gvr.Secret: kube.StripSecretUnusedFields,
+	})(kube.NewFakeClient(secret))
+	inf := GetInformerFiltered[*corev1.Secret](client, ktypes.InformerOptions{Cluster: "cluster"}, gvr.Secret)
+	inf.Start(test.NewStop(t))
+	assert.Equal(t, kube.WaitForCacheSync("test", test.NewStop(t), inf.Informer.HasSynced), true)
+
+	obj, exists, err := inf.Informer.GetStore().GetByKey("ns/secret")
+	assert.NoError(t, err)
+	assert.Equal(t, exists, true)
+	got := obj.(*corev1.Secret)
+	assert.Equal(t, got.Annotations, map[string]string{"keep": "true"})
+	assert.Equal(t, got.ManagedFields, nil)
+	assert.Equal(t, got.Data, secret.Data)
+	mt.Assert("informer_transform_saved_bytes", map[string]string{"cluster": "cluster", "resource": "secrets"}, monitortest.AtLeast(1))
+
+	// An explicit transform takes precedence
+	explicit := func(obj any) (any, error) { return obj, nil }
+	opts := withClientTransform(client, gvr.Secret, ktypes.InformerOptions{ObjectTransform: explicit})
+	assert.Equal(t, opts.ObjectTransform != nil, true)
+	// Metadata informers do not store typed objects
+	opts = withClientTransform(client, gvr.Secret, ktypes.InformerOptions{InformerType: ktypes.MetadataInformer})
+	assert.Equal(t, opts.ObjectTransform == nil, true)
+	// Other resources are unaffected
+	opts = withClientTransform(client, gvr.ConfigMap, ktypes.InformerOptions{})
+	assert.Equal(t, opts.ObjectTransform == nil, true)
+}
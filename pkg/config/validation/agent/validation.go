@@ -822,35 +822,38 @@ func validateTrustDomainConfig(config *meshconfig.MeshConfig) (errs error) {
 	return errs
 }
 
-func ValidateMeshTLSConfig(mesh *meshconfig.MeshConfig) (errs error) {
+func ValidateMeshTLSConfig(mesh *meshconfig.MeshConfig) (v Validation) {
 	if meshMTLS := mesh.MeshMTLS; meshMTLS != nil {
-		if meshMTLS.EcdhCurves != nil {
-			errs = multierror.Append(errs, errors.New("mesh TLS does not support ECDH curves configuration"))
-		}
+		v = AppendValidation(v, validateECDHCurves("meshMTLS", meshMTLS.EcdhCurves))
 	}
-	return errs
+	return v
 }
 
 func ValidateMeshTLSDefaults(mesh *meshconfig.MeshConfig) (v Validation) {
+	if tlsDefaults := mesh.TlsDefaults; tlsDefaults != nil {
+		v = AppendValidation(v, validateECDHCurves("tlsDefaults", tlsDefaults.EcdhCurves))
+	}
+	return v
+}
+
+func validateECDHCurves(field string, curves []string) (v Validation) {
 	unrecognizedECDHCurves := sets.New[string]()
 	validECDHCurves := sets.New[string]()
 	duplicateECDHCurves := sets.New[string]()
-	if tlsDefaults := mesh.TlsDefaults; tlsDefaults != nil {
-		for _, cs := range tlsDefaults.EcdhCurves {
-			if !security.IsValidECDHCurve(cs) {
-				unrecognizedECDHCurves.Insert(cs)
-			} else if validECDHCurves.InsertContains(cs) {
-				duplicateECDHCurves.Insert(cs)
-			}
+	for _, cs := range curves {
+		if !security.IsValidECDHCurve(cs) {
+			unrecognizedECDHCurves.Insert(cs)
+		} else if validECDHCurves.InsertContains(cs) {
+			duplicateECDHCurves.Insert(cs)
 		}
 	}
 
 	if len(unrecognizedECDHCurves) > 0 {
-		v = AppendWarningf(v, "detected unrecognized ECDH curves: %v", sets.SortedList(unrecognizedECDHCurves))
+		v = AppendWarningf(v, "detected unrecognized ECDH curves in %s: %v", field, sets.SortedList(unrecognizedECDHCurves))
 	}
 
 	if len(duplicateECDHCurves) > 0 {
-		v = AppendWarningf(v, "detected duplicate ECDH curves: %v", sets.SortedList(duplicateECDHCurves))
+		v = AppendWarningf(v, "detected duplicate ECDH curves in %s: %v", field, sets.SortedList(duplicateECDHCurves))
 	}
 	return v
 }
@@ -1133,7 +1133,7 @@ func TestValidateMeshConfig(t *testing.T) {
 			},
 		},
 		MeshMTLS: &meshconfig.MeshConfig_TLSConfig{
-			EcdhCurves: []string{"P-256"},
+			EcdhCurves: []string{"X25519MLKEM768", "unknown"},
 		},
 		TlsDefaults: &meshconfig.MeshConfig_TLSConfig{
 			EcdhCurves: []string{"P-256", "P-256", "invalid"},
@@ -1158,7 +1158,6 @@ func TestValidateMeshConfig(t *testing.T) {
 			"trustDomainAliases[0]",
 			"trustDomainAliases[1]",
 			"trustDomainAliases[2]",
-			"mesh TLS does not support ECDH curves configuration",
 		}
 		switch err := err.(type) {
 		case *multierror.Error:
@@ -1180,8 +1179,9 @@ func TestValidateMeshConfig(t *testing.T) {
 		t.Errorf("expected a warning on invalid proxy mesh config: %v", invalid)
 	} else {
 		wantWarnings := []string{
-			"detected unrecognized ECDH curves",
-			"detected duplicate ECDH curves",
+			"detected unrecognized ECDH curves in meshMTLS: [unknown]",
+			"detected unrecognized ECDH curves in tlsDefaults",
+			"detected duplicate ECDH curves in tlsDefaults",
 		}
 		switch warn := warning.(type) {
 		case *multierror.Error:
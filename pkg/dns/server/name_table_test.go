@@ -230,6 +230,27 @@ func TestNameTable(t *testing.T) {
 		},
 	}
 
+	kubeService := &model.Service{
+		Hostname:       host.Name("svc.testns.svc.cluster.local"),
+		DefaultAddress: "10.0.0.9",
+		Ports: model.PortList{
+			&model.Port{
+				Name:     "http",
+				Port:     80,
+				Protocol: protocol.HTTP,
+			},
+		},
+		Attributes: model.ServiceAttributes{
+			Name:            "svc",
+			Namespace:       "testns",
+			ServiceRegistry: provider.Kubernetes,
+		},
+	}
+	// The synthetic service of the ServiceImport of svc, with its ClusterSet VIP.
+	clusterSetService := kubeService.ShallowCopy()
+	clusterSetService.Hostname = host.Name("svc.testns.svc.clusterset.local")
+	clusterSetService.DefaultAddress = "240.241.0.1"
+
 	push := model.NewPushContext()
 	push.Mesh = mesh
 	push.AddPublicServices([]*model.Service{headlessService})
@@ -649,6 +670,31 @@ func TestNameTable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "multi-cluster service",
+			proxy: proxy,
+			push: func() *model.PushContext {
+				push := model.NewPushContext()
+				push.Mesh = mesh
+				push.AddPublicServices([]*model.Service{kubeService, clusterSetService})
+				return push
+			}(),
+			expectedNameTable: &dnsProto.NameTable{
+				Table: map[string]*dnsProto.NameTable_NameInfo{
+					kubeService.Hostname.String(): {
+						Ips:       []string{kubeService.DefaultAddress},
+						Registry:  provider.Kubernetes.String(),
+						Shortname: kubeService.Attributes.Name,
+						Namespace: kubeService.Attributes.Namespace,
+					},
+					// The agent does not expand clusterset.local hosts, to avoid conflicting with the cluster.local ones.
+					clusterSetService.Hostname.String(): {
+						Ips:      []string{clusterSetService.DefaultAddress},
+						Registry: provider.Kubernetes.String(),
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -56,4 +56,8 @@ not a sufficient requirement to achieve compliance. There are additional
 steps necessary to claim compliance, including using the validated
 cryptographic modules (please consult
 https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/security/ssl#fips-140-2).`).Get()
+
+	EnableMLDSA = env.Register("ENABLE_ML_DSA", false,
+		"If enabled, ML-DSA (FIPS 204) keys, CSRs and certificates can be generated when requested. "+
+			"Requires a Go toolchain with crypto/mldsa support. This feature is experimental.").Get()
 )
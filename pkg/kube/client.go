@@ -147,6 +147,10 @@ type Client interface {
 	// This must be set on a client with SetObjectFilter.
 	ObjectFilter() kubetypes.DynamicObjectFilter
 
+	// ObjectTransform returns the transform of the informers of the resource that do not set their own, or nil.
+	// This must be set on a client with WithObjectTransforms.
+	ObjectTransform(g schema.GroupVersionResource) func(obj any) (any, error)
+
 	// RunAndWait starts all informers and waits for their caches to sync.
 	// Warning: this must be called AFTER .Informer() is called, which will register the informer.
 	// "false" is returned if this prematurely exited without syncing.
@@ -549,8 +553,9 @@ type client struct {
 
 	version lazy.Lazy[*kubeVersion.Info]
 
-	crdWatcher   kubetypes.CrdWatcher
-	objectFilter kubetypes.DynamicObjectFilter
+	crdWatcher       kubetypes.CrdWatcher
+	objectFilter     kubetypes.DynamicObjectFilter
+	objectTransforms map[schema.GroupVersionResource]func(obj any) (any, error)
 
 	// http is a client for HTTP requests
 	http *http.Client
@@ -710,9 +715,20 @@ func WithTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithObjectTransforms creates a ClientOption to set the ObjectTransform of the standard informers of the resources
+// that do not set their own. This allows stripping, from all the objects of a type the client caches, the fields none
+// of its readers use; each transform must therefore retain every field read by any reader of the informers of that type.
+func WithObjectTransforms(transforms map[schema.GroupVersionResource]func(obj any) (any, error)) ClientOption {
+	return func(c CLIClient) CLIClient {
+		client := c.(*client)
+		client.objectTransforms = transforms
+		return client
+	}
+}
+
 // NewClient creates a Kubernetes client from the given rest config.
-func NewClient(clientConfig clientcmd.ClientConfig, cluster cluster.ID) (Client, error) {
-	return newClientInternal(newClientFactory(clientConfig, false), WithCluster(cluster))
+func NewClient(clientConfig clientcmd.ClientConfig, cluster cluster.ID, opts ...ClientOption) (Client, error) {
+	return newClientInternal(newClientFactory(clientConfig, false), append([]ClientOption{WithCluster(cluster)}, opts...)...)
 }
 
 func (c *client) RESTConfig() *rest.Config {
@@ -763,6 +779,10 @@ func (c *client) ObjectFilter() kubetypes.DynamicObjectFilter {
 	return c.objectFilter
 }
 
+func (c *client) ObjectTransform(g schema.GroupVersionResource) func(obj any) (any, error) {
+	return c.objectTransforms[g]
+}
+
 // RunAndWait starts all informers and waits for their caches to sync.
 // Warning: this must be called AFTER .Informer() is called, which will register the informer.
 func (c *client) RunAndWait(stop <-chan struct{}) bool {
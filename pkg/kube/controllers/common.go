@@ -94,6 +94,17 @@ func ObjectToGVR(u Object) (schema.GroupVersionResource, error) {
 
 // EnqueueForParentHandler returns a handler that will enqueue the parent (by ownerRef) resource
 func EnqueueForParentHandler(q Queue, kind config.GroupVersionKind) func(obj Object) {
+	return enqueueForParentHandler(func(key types.NamespacedName) {
+		q.Add(key)
+	}, kind)
+}
+
+// TypedEnqueueForParentHandler is EnqueueForParentHandler for a TypedQueue of types.NamespacedName.
+func TypedEnqueueForParentHandler(q TypedQueue[types.NamespacedName], kind config.GroupVersionKind) func(obj Object) {
+	return enqueueForParentHandler(q.Add, kind)
+}
+
+func enqueueForParentHandler(add func(key types.NamespacedName), kind config.GroupVersionKind) func(obj Object) {
 	handler := func(obj Object) {
 		for _, ref := range obj.GetOwnerReferences() {
 			refGV, err := schema.ParseGroupVersion(ref.APIVersion)
@@ -103,7 +114,7 @@ func EnqueueForParentHandler(q Queue, kind config.GroupVersionKind) func(obj Obj
 			}
 			if refGV.Group == kind.Group && ref.Kind == kind.Kind {
 				// We found a parent we care about, add it to the queue
-				q.Add(types.NamespacedName{
+				add(types.NamespacedName{
 					// Reference doesn't have namespace, but its always same-namespace, so use objects
 					Namespace: obj.GetNamespace(),
 					Name:      ref.Name,
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"cmp"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/monitoring"
+	"istio.io/istio/pkg/slices"
+)
+
+var (
+	controllerLabel = monitoring.CreateLabel("controller")
+
+	queueRetries = monitoring.NewSum(
+		"controller_queue_retries_total",
+		"Number of times a key was requeued by a controller after failing to be reconciled.",
+	)
+
+	queueDeadLetters = monitoring.NewGauge(
+		"controller_queue_dead_letters",
+		"Number of keys a controller gave up reconciling after exhausting their retries, and which did not succeed since. "+
+			"Only queues with a maximum number of attempts record dead letters.",
+	)
+)
+
+// DeadLetter is a key a queue gave up reconciling after exhausting its retries.
+type DeadLetter struct {
+	Key       string    `json:"key"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	LastError time.Time `json:"lastError"`
+}
+
+// QueueDump describes a running queue, for debugging.
+type QueueDump struct {
+	Name        string       `json:"name"`
+	Pending     int          `json:"pending"`
+	DeadLetters []DeadLetter `json:"deadLetters,omitempty"`
+}
+
+// deadLetters tracks the keys a queue gave up on. A key leaves the list once it is reconciled successfully.
+type deadLetters struct {
+	mu      sync.Mutex
+	name    string
+	letters map[any]DeadLetter
+	// stopped is set once the queue stops, after which its dead letters are no longer counted.
+	stopped bool
+}
+
+func newDeadLetters(name string) *deadLetters {
+	return &deadLetters{
+		name:    name,
+		letters: map[any]DeadLetter{},
+	}
+}
+
+func (d *deadLetters) add(key any, err error, attempts int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	if _, f := d.letters[key]; !f {
+		recordDeadLetters(d.name, 1)
+	}
+	d.letters[key] = DeadLetter{
+		Key:       formatKey(key),
+		Error:     err.Error(),
+		Attempts:  attempts,
+		LastError: time.Now(),
+	}
+}
+
+func (d *deadLetters) remove(key any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, f := d.letters[key]; !f || d.stopped {
+		return
+	}
+	delete(d.letters, key)
+	recordDeadLetters(d.name, -1)
+}
+
+// stop removes the dead letters of a stopped queue from the metric.
+func (d *deadLetters) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	recordDeadLetters(d.name, -len(d.letters))
+}
+
+func (d *deadLetters) list() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.SortFunc(maps.Values(d.letters), func(a, b DeadLetter) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+}
+
+// deadLetterCounts sums the dead letters of the queues sharing a name, such as the per-cluster queues of a controller,
+// which report to the same metric.
+var deadLetterCounts = struct {
+	mu     sync.Mutex
+	counts map[string]int
+}{counts: map[string]int{}}
+
+func recordDeadLetters(name string, delta int) {
+	if delta == 0 {
+		return
+	}
+	deadLetterCounts.mu.Lock()
+	defer deadLetterCounts.mu.Unlock()
+	n := deadLetterCounts.counts[name] + delta
+	if n == 0 {
+		delete(deadLetterCounts.counts, name)
+	} else {
+		deadLetterCounts.counts[name] = n
+	}
+	queueDeadLetters.With(controllerLabel.Value(name)).Record(float64(n))
+}
+
+// runningQueues holds the queues currently running, for debugging.
+var runningQueues = struct {
+	mu     sync.Mutex
+	queues map[*deadLetters]Queue
+}{queues: map[*deadLetters]Queue{}}
+
+func registerQueue(q Queue) {
+	runningQueues.mu.Lock()
+	defer runningQueues.mu.Unlock()
+	runningQueues.queues[q.deadLetters] = q
+}
+
+func unregisterQueue(q Queue) {
+	runningQueues.mu.Lock()
+	defer runningQueues.mu.Unlock()
+	delete(runningQueues.queues, q.deadLetters)
+	q.deadLetters.stop()
+}
+
+// DumpQueues returns the state of the queues currently running, sorted by name.
+func DumpQueues() []QueueDump {
+	runningQueues.mu.Lock()
+	queues := maps.Values(runningQueues.queues)
+	runningQueues.mu.Unlock()
+	res := slices.Map(queues, func(q Queue) QueueDump {
+		return QueueDump{
+			Name:        q.name,
+			Pending:     q.queue.Len(),
+			DeadLetters: q.DeadLetters(),
+		}
+	})
+	return slices.SortStableFunc(res, func(a, b QueueDump) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+}
@@ -24,6 +24,7 @@ import (
 
 	"istio.io/istio/pkg/config"
 	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/monitoring"
 )
 
 type ReconcilerFn func(key types.NamespacedName) error
@@ -38,6 +39,8 @@ type Queue struct {
 	workFn      func(key any) error
 	closed      chan struct{}
 	log         *istiolog.Scope
+	deadLetters *deadLetters
+	retries     monitoring.Metric
 }
 
 // WithName sets a name for the queue. This is used for logging
@@ -54,6 +57,12 @@ func WithRateLimiter(r workqueue.TypedRateLimiter[any]) func(q *Queue) {
 	}
 }
 
+// WithBackoff retries each failing key after an exponential backoff, from baseDelay up to maxDelay, independently of
+// other keys. It is typically combined with WithMaxAttempts.
+func WithBackoff(baseDelay, maxDelay time.Duration) func(q *Queue) {
+	return WithRateLimiter(workqueue.NewTypedItemExponentialFailureRateLimiter[any](baseDelay, maxDelay))
+}
+
 // WithMaxAttempts allows defining a custom max attempts for the queue. If not set, items will not be retried
 func WithMaxAttempts(n int) func(q *Queue) {
 	return func(q *Queue) {
@@ -101,6 +110,8 @@ func NewQueue(name string, options ...func(*Queue)) Queue {
 		)
 	}
 	q.log = log.WithLabels("controller", q.name)
+	q.deadLetters = newDeadLetters(q.name)
+	q.retries = queueRetries.With(controllerLabel.Value(q.name))
 	return q
 }
 
@@ -124,6 +135,8 @@ func (q Queue) ShutDownEarly() {
 // Run the queue. This is synchronous, so should typically be called in a goroutine.
 func (q Queue) Run(stop <-chan struct{}) {
 	defer q.queue.ShutDown()
+	registerQueue(q)
+	defer unregisterQueue(q)
 	q.log.Infof("starting")
 	q.queue.Add(defaultSyncSignal)
 	go func() {
@@ -153,6 +166,12 @@ func (q Queue) HasSynced() bool {
 	return q.initialSync.Load()
 }
 
+// DeadLetters returns the keys the queue gave up reconciling after exhausting their retries, and which were not
+// reconciled successfully since. It is always empty for queues without WithMaxAttempts.
+func (q Queue) DeadLetters() []DeadLetter {
+	return q.deadLetters.list()
+}
+
 // Closed returns a chan that will be signaled when the Instance has stopped processing tasks.
 func (q Queue) Closed() <-chan struct{} {
 	return q.closed
@@ -185,10 +204,16 @@ func (q Queue) processNextItem() bool {
 		if retryCount < q.maxAttempts {
 			q.log.Errorf("error handling %v, retrying (retry count: %d): %v", formatKey(key), retryCount, err)
 			q.queue.AddRateLimited(key)
+			q.retries.Increment()
 			// Return early, so we do not call Forget(), allowing the rate limiting to backoff
 			return true
 		}
 		q.log.Errorf("error handling %v, and retry budget exceeded: %v", formatKey(key), err)
+		if q.maxAttempts > 0 {
+			q.deadLetters.add(key, err, retryCount)
+		}
+	} else if q.maxAttempts > 0 {
+		q.deadLetters.remove(key)
 	}
 	// 'Forget indicates that an item is finished being retried.' - should be called whenever we do not want to backoff on this key.
 	q.queue.Forget(key)
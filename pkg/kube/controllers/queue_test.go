@@ -15,12 +15,16 @@
 package controllers
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"go.uber.org/atomic"
 	"k8s.io/apimachinery/pkg/types"
 
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/test/util/retry"
 )
@@ -42,3 +46,88 @@ func TestQueue(t *testing.T) {
 	// event 2 is guaranteed to happen from WaitForClose
 	assert.Equal(t, handles.Load(), 2)
 }
+
+func TestTypedQueueDeadLetters(t *testing.T) {
+	mt := monitortest.New(t)
+	type key struct {
+		name string
+	}
+	failing := atomic.NewBool(true)
+	attempts := atomic.NewInt32(0)
+	q := NewTypedQueue[key]("dead letters", func(k key) error {
+		if k.name == "bad" {
+			attempts.Inc()
+			if failing.Load() {
+				return errors.New("boom")
+			}
+		}
+		return nil
+	}, WithBackoff(time.Millisecond, 10*time.Millisecond), WithMaxAttempts(3))
+	stop := test.NewStop(t)
+	go q.Run(stop)
+	q.Add(key{"good"})
+	q.Add(key{"bad"})
+	retry.UntilOrFail(t, q.HasSynced, retry.Delay(time.Microsecond))
+
+	assert.EventuallyEqual(t, func() []string {
+		return slices.Map(q.DeadLetters(), func(d DeadLetter) string { return d.Key })
+	}, []string{"{bad}"})
+	assert.Equal(t, attempts.Load(), 3)
+	dead := q.DeadLetters()[0]
+	assert.Equal(t, dead.Error, "boom")
+	assert.Equal(t, dead.Attempts, 3)
+	mt.Assert(queueRetries.Name(), map[string]string{"controller": "dead letters"}, monitortest.Exactly(2))
+	mt.Assert(queueDeadLetters.Name(), map[string]string{"controller": "dead letters"}, monitortest.Exactly(1))
+	assert.Equal(t, slices.FindFunc(DumpQueues(), func(d QueueDump) bool { return d.Name == "dead letters" }) != nil, true)
+
+	// A key leaves the dead letters once it is reconciled successfully.
+	failing.Store(false)
+	q.Add(key{"bad"})
+	assert.EventuallyEqual(t, func() int { return len(q.DeadLetters()) }, 0)
+	mt.Assert(queueDeadLetters.Name(), map[string]string{"controller": "dead letters"}, monitortest.Exactly(0))
+}
+
+func TestTypedQueueDeadLettersRequireMaxAttempts(t *testing.T) {
+	handled := atomic.NewBool(false)
+	q := NewTypedQueue[string]("no max attempts", func(k string) error {
+		if k == "done" {
+			handled.Store(true)
+			return nil
+		}
+		return errors.New("boom")
+	})
+	go q.Run(test.NewStop(t))
+	q.Add("bad")
+	// Keys are handled in order, and failing keys are not retried without WithMaxAttempts.
+	q.Add("done")
+	retry.UntilOrFail(t, handled.Load, retry.Delay(time.Millisecond))
+	assert.Equal(t, len(q.DeadLetters()), 0)
+}
+
+func TestTypedQueueDeadLettersSharedName(t *testing.T) {
+	mt := monitortest.New(t)
+	deadLetters := func() int {
+		return len(slices.FilterInPlace(DumpQueues(), func(d QueueDump) bool { return d.Name == "per cluster" && len(d.DeadLetters) > 0 }))
+	}
+	newQueue := func(stop chan struct{}) TypedQueue[string] {
+		q := NewTypedQueue[string]("per cluster", func(k string) error {
+			return errors.New("boom")
+		}, WithMaxAttempts(1))
+		go q.Run(stop)
+		q.Add("bad")
+		return q
+	}
+	stop1, stop2 := make(chan struct{}), make(chan struct{})
+	t.Cleanup(func() { close(stop2) })
+	q1 := newQueue(stop1)
+	newQueue(stop2)
+
+	// Queues sharing a name add up their dead letters rather than overwriting each other.
+	assert.EventuallyEqual(t, deadLetters, 2)
+	mt.Assert(queueDeadLetters.Name(), map[string]string{"controller": "per cluster"}, monitortest.Exactly(2))
+
+	// Stopping a queue removes its dead letters.
+	close(stop1)
+	assert.NoError(t, q1.WaitForClose(time.Second))
+	mt.Assert(queueDeadLetters.Name(), map[string]string{"controller": "per cluster"}, monitortest.Exactly(1))
+}
@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"time"
+)
+
+// TypedQueue is a Queue of keys of type K.
+// Like Queue, keys are deduplicated, failing keys are retried with a per-key backoff (see WithBackoff) up to
+// WithMaxAttempts times, and keys exhausting their retries are reported by DeadLetters.
+type TypedQueue[K comparable] struct {
+	queue Queue
+}
+
+// NewTypedQueue creates a new queue of keys of type K, handled by reconcile.
+func NewTypedQueue[K comparable](name string, reconcile func(key K) error, options ...func(*Queue)) TypedQueue[K] {
+	options = append(options, WithGenericReconciler(func(key any) error {
+		return reconcile(key.(K))
+	}))
+	return TypedQueue[K]{queue: NewQueue(name, options...)}
+}
+
+// Add a key to the queue.
+func (q TypedQueue[K]) Add(key K) {
+	q.queue.Add(key)
+}
+
+// Run the queue. This is synchronous, so should typically be called in a goroutine.
+func (q TypedQueue[K]) Run(stop <-chan struct{}) {
+	q.queue.Run(stop)
+}
+
+// ShutDownEarly shuts down the queue *before* it has been Run.
+func (q TypedQueue[K]) ShutDownEarly() {
+	q.queue.ShutDownEarly()
+}
+
+// HasSynced returns true once the queue has processed all keys added before it was Run.
+func (q TypedQueue[K]) HasSynced() bool {
+	return q.queue.HasSynced()
+}
+
+// DeadLetters returns the keys the queue gave up reconciling.
+func (q TypedQueue[K]) DeadLetters() []DeadLetter {
+	return q.queue.DeadLetters()
+}
+
+// Closed returns a chan that will be signaled when the queue has stopped processing keys.
+func (q TypedQueue[K]) Closed() <-chan struct{} {
+	return q.queue.Closed()
+}
+
+// WaitForClose blocks until the queue has stopped processing keys or the timeout expires.
+func (q TypedQueue[K]) WaitForClose(timeout time.Duration) error {
+	return q.queue.WaitForClose(timeout)
+}
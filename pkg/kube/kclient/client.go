@@ -60,6 +60,8 @@ type informerClient[T controllers.Object] struct {
 	informer      cache.SharedIndexInformer
 	startInformer func(stopCh <-chan struct{})
 	filter        func(t any) bool
+	// scope is set if filter can change atomically.
+	scope kubetypes.AtomicObjectFilter
 
 	handlerMu          sync.RWMutex
 	registeredHandlers []handlerRegistration
@@ -213,7 +215,7 @@ func (neverReadyDoneChecker) Done() <-chan struct{} {
 }
 
 func (n *informerClient[T]) AddEventHandler(h cache.ResourceEventHandler) cache.ResourceEventHandlerRegistration {
-	fh := cache.FilteringResourceEventHandler{
+	var fh cache.ResourceEventHandler = cache.FilteringResourceEventHandler{
 		FilterFunc: func(obj interface{}) bool {
 			if n.filter == nil {
 				return true
@@ -222,6 +224,9 @@ func (n *informerClient[T]) AddEventHandler(h cache.ResourceEventHandler) cache.
 		},
 		Handler: h,
 	}
+	if n.scope != nil {
+		fh = scopedFilteringHandler{scope: n.scope, handler: h}
+	}
 	n.handlerMu.Lock()
 	defer n.handlerMu.Unlock()
 	// AddEventHandler is safe to call under the lock. This will *enqueue* all existing items, but not block on processing them,
@@ -433,6 +438,7 @@ func newInformerClient[T controllers.ComparableObject](
 func applyDynamicFilter[T controllers.ComparableObject](filter Filter, gvr schema.GroupVersionResource, ic *informerClient[T]) {
 	if filter.ObjectFilter != nil {
 		ic.filter = filter.ObjectFilter.Filter
+		ic.scope, _ = filter.ObjectFilter.(kubetypes.AtomicObjectFilter)
 		filter.ObjectFilter.AddHandler(func(added, removed sets.String) {
 			ic.handlerMu.RLock()
 			defer ic.handlerMu.RUnlock()
@@ -440,16 +446,14 @@ func applyDynamicFilter[T controllers.ComparableObject](filter Filter, gvr schem
 				// Namespace is special; we query all namespaces
 				// Note: other cluster-scoped resources should just not use the filter
 				for _, item := range ic.ListUnfiltered(metav1.NamespaceAll, klabels.Everything()) {
-					if !added.Contains(item.GetName()) {
-						continue
-					}
 					for _, c := range ic.registeredHandlers {
-						c.handler.OnAdd(item, false)
+						if added.Contains(item.GetName()) {
+							c.handler.OnAdd(item, false)
+						} else if removed.Contains(item.GetName()) {
+							c.handler.OnDelete(item)
+						}
 					}
 				}
-				// Removes are currently NOT handled. We only have the namespace name here. We would need to have the object
-				// filter passthrough the entire namespace object, so we can pass the last known state to OnDelete.
-				// Fortunately, missing a namespace delete event usually doesn't matter since everything in the namespace gets torn down.
 			} else {
 				for ns := range added {
 					for _, item := range ic.ListUnfiltered(ns, klabels.Everything()) {
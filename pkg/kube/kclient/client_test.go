@@ -649,3 +649,132 @@ func TestFilterDeadlock(t *testing.T) {
 	testns.Update(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"selected": "yes"}}})
 	tracker.WaitOrdered("test/random")
 }
+
+func TestFilterTransitions(t *testing.T) {
+	tracker := assert.NewTracker[string](t)
+	nsTracker := assert.NewTracker[string](t)
+	c := kube.NewFakeClient()
+	meshWatcher := meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{})
+	testns := clienttest.NewWriter[*corev1.Namespace](t, c)
+	testns.Create(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "a"}}})
+	testns.Create(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "b", Labels: map[string]string{"team": "b"}}})
+	stop := test.NewStop(t)
+	discoveryNamespacesFilter := filter.NewDiscoveryNamespacesFilter(kclient.New[*corev1.Namespace](c), meshWatcher, stop)
+	deployments := kclient.NewFiltered[*appsv1.Deployment](c, kubetypes.Filter{ObjectFilter: discoveryNamespacesFilter})
+	deployments.AddEventHandler(clienttest.TrackerHandler(tracker))
+	namespaces := kclient.NewFiltered[*corev1.Namespace](c, kubetypes.Filter{ObjectFilter: discoveryNamespacesFilter})
+	namespaces.AddEventHandler(clienttest.TrackerHandler(nsTracker))
+	c.RunAndWait(stop)
+	nsTracker.WaitUnordered("add/a", "add/b")
+
+	tester := clienttest.Wrap(t, deployments)
+	tester.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "1", Namespace: "a"}})
+	tester.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "2", Namespace: "b"}})
+	tracker.WaitUnordered("add/1", "add/2")
+
+	// Objects and namespaces leaving the scope are deleted.
+	meshWatcher.Set(&meshconfig.MeshConfig{DiscoverySelectors: []*meshconfig.LabelSelector{{MatchLabels: map[string]string{"team": "b"}}}})
+	tracker.WaitOrdered("delete/1")
+	nsTracker.WaitOrdered("delete/a")
+
+	// Deleting an object of a namespace that left the scope is still notified, as its deletion may have been pending when
+	// the scope changed. This includes objects created since, which handlers must tolerate.
+	tester.Delete("1", "a")
+	tracker.WaitOrdered("delete/1")
+	tester.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "3", Namespace: "a"}})
+	tester.Delete("3", "a")
+	tracker.WaitOrdered("delete/3")
+
+	// Objects and namespaces entering the scope are added.
+	testns.Update(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "b"}}})
+	// The update of the namespace itself is seen if it is handled after the namespace entered the scope.
+	retry.UntilOrFail(t, func() bool {
+		return slices.Equal(nsTracker.Events(), []string{"add/a"}) ||
+			slices.Equal(nsTracker.Events(), []string{"add/a", "update/a"})
+	})
+	tester.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "4", Namespace: "a"}})
+	tracker.WaitOrdered("add/4")
+
+	dump := discoveryNamespacesFilter.(filter.DebuggableFilter).Dump()
+	assert.Equal(t, dump.Selectors, []string{"team=b"})
+	assert.Equal(t, dump.Namespaces, []string{"a", "b"})
+	assert.Equal(t, slices.Map(dump.Transitions, func(t filter.FilterTransition) string {
+		return fmt.Sprintf("%s +%v -%v", t.Reason, t.Selected, t.Deselected)
+	}), []string{
+		"initial sync +[] -[]",
+		"mesh config changed +[] -[a]",
+		"namespace labels changed +[a] -[]",
+	})
+}
+
+// reentryCheckingFilter fails the test if ReadScope is re-entered, as a nested ReadScope deadlocks against a pending
+// change of the scope. It must only be used by a single handler, whose events are handled sequentially.
+type reentryCheckingFilter struct {
+	kubetypes.AtomicObjectFilter
+	t       *testing.T
+	inScope *atomic.Bool
+}
+
+func (f reentryCheckingFilter) ReadScope(fn func()) {
+	if f.inScope.Swap(true) {
+		f.t.Errorf("ReadScope was re-entered")
+	}
+	defer f.inScope.Store(false)
+	f.AtomicObjectFilter.ReadScope(fn)
+}
+
+func TestFilterTransitionDuringEvent(t *testing.T) {
+	tracker := assert.NewTracker[string](t)
+	c := kube.NewFakeClient()
+	meshWatcher := meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{})
+	testns := clienttest.NewWriter[*corev1.Namespace](t, c)
+	testns.Create(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "a"}}})
+	testns.Create(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "b", Labels: map[string]string{"team": "b"}}})
+	stop := test.NewStop(t)
+	discoveryNamespacesFilter := reentryCheckingFilter{
+		AtomicObjectFilter: filter.NewDiscoveryNamespacesFilter(kclient.New[*corev1.Namespace](c), meshWatcher, stop).(kubetypes.AtomicObjectFilter),
+		t:                  t,
+		inScope:            atomic.NewBool(false),
+	}
+	deployments := kclient.NewFiltered[*appsv1.Deployment](c, kubetypes.Filter{ObjectFilter: discoveryNamespacesFilter})
+	handling := make(chan struct{})
+	release := make(chan struct{})
+	deployments.AddEventHandler(controllers.EventHandler[*appsv1.Deployment]{
+		AddFunc: func(obj *appsv1.Deployment) {
+			if obj.Name == "1" {
+				close(handling)
+				<-release
+				// Handlers may read the filter and the clients while the scope is changing, as these do not take the
+				// scope lock.
+				assert.Equal(t, discoveryNamespacesFilter.Filter(obj), true)
+				assert.Equal(t, len(deployments.List("a", klabels.Everything())), 1)
+			}
+			tracker.Record("add/" + obj.Name)
+		},
+		DeleteFunc: func(obj *appsv1.Deployment) {
+			tracker.Record("delete/" + obj.Name)
+		},
+	})
+	c.RunAndWait(stop)
+
+	tester := clienttest.Wrap(t, deployments)
+	tester.Create(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "1", Namespace: "a"}})
+	<-handling
+	// The scope changes while the event is handled: the change waits for the handler, and its synthetic events are
+	// ordered after the event.
+	changed := make(chan struct{})
+	go func() {
+		meshWatcher.Set(&meshconfig.MeshConfig{DiscoverySelectors: []*meshconfig.LabelSelector{{MatchLabels: map[string]string{"team": "b"}}}})
+		close(changed)
+	}()
+	selectors := func() []string {
+		return discoveryNamespacesFilter.AtomicObjectFilter.(filter.DebuggableFilter).Dump().Selectors
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, len(selectors()), 0)
+	close(release)
+	<-changed
+	assert.Equal(t, selectors(), []string{"team=b"})
+	tracker.WaitOrdered("add/1", "delete/1")
+	assert.Equal(t, discoveryNamespacesFilter.inScope.Load(), false)
+}
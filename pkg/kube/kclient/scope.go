@@ -0,0 +1,61 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kclient
+
+import (
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pkg/kube/kubetypes"
+)
+
+// scopedFilteringHandler is a cache.FilteringResourceEventHandler for filters that change atomically.
+// Events are filtered and handled while the scope of the filter cannot change, so they are ordered with the synthetic
+// events emitted when it does. Handlers are called by the informer, one event at a time, so ReadScope is not re-entered
+// unless a handler synchronously calls another scopedFilteringHandler, which it must not do.
+type scopedFilteringHandler struct {
+	scope   kubetypes.AtomicObjectFilter
+	handler cache.ResourceEventHandler
+}
+
+func (s scopedFilteringHandler) OnAdd(obj any, isInInitialList bool) {
+	s.scope.ReadScope(func() {
+		if s.scope.Filter(obj) {
+			s.handler.OnAdd(obj, isInInitialList)
+		}
+	})
+}
+
+func (s scopedFilteringHandler) OnUpdate(oldObj, newObj any) {
+	s.scope.ReadScope(func() {
+		newer := s.scope.Filter(newObj)
+		older := s.scope.Filter(oldObj)
+		switch {
+		case newer && older:
+			s.handler.OnUpdate(oldObj, newObj)
+		case newer && !older:
+			s.handler.OnAdd(newObj, false)
+		case !newer && older:
+			s.handler.OnDelete(oldObj)
+		}
+	})
+}
+
+func (s scopedFilteringHandler) OnDelete(obj any) {
+	s.scope.ReadScope(func() {
+		if s.scope.FilterDelete(obj) {
+			s.handler.OnDelete(obj)
+		}
+	})
+}
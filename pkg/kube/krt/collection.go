@@ -16,7 +16,9 @@ package krt
 
 import (
 	"fmt"
+	"hash/fnv"
 	"sync"
+	"time"
 
 	"istio.io/istio/pkg/kube/controllers"
 	istiolog "istio.io/istio/pkg/log"
@@ -42,7 +44,8 @@ type dependencyState[I any] struct {
 	// collectionDependencies specifies the set of collections we depend on from within the transformation functions (via Fetch).
 	// These are keyed by the internal uid() function on collections.
 	// Note this does not include `parent`, which is the *primary* dependency declared outside of transformation functions.
-	collectionDependencies sets.Set[collectionUID]
+	// The channel is closed once the handler on the collection is registered and synced.
+	collectionDependencies map[collectionUID]chan struct{}
 	// Stores a map of I -> secondary dependencies (added via Fetch)
 	objectDependencies  map[Key[I]][]*dependency
 	indexedDependencies map[indexedDependency]sets.Set[Key[I]]
@@ -223,6 +226,8 @@ type manyCollection[I, O any] struct {
 	eventHandlers *handlerSet[O]
 
 	transformation TransformationMulti[I, O]
+	// shards is the number of workers running the transformations of a batch, see WithShards.
+	shards int
 
 	// augmentation allows transforming an object into another for usage throughout the library. See WithObjectAugmentation.
 	augmentation func(a any) any
@@ -235,6 +240,11 @@ type manyCollection[I, O any] struct {
 	onPrimaryInputEventHandler func(o []Event[I])
 
 	syncer Syncer
+
+	// stats records the activity of the collection, for debugging. It is nil unless profiling is enabled.
+	stats *collectionProfile
+	// outputHistory records the changes of each output, for debugging. It is nil unless enabled on the debugger.
+	outputHistory *collectionHistory
 }
 
 type collectionIndex[I, O any] struct {
@@ -413,6 +423,7 @@ func (h *manyCollection[I, O]) index(name string, extract func(o O) []string) in
 // This is called either when I directly changes, or if a secondary dependency changed. In this case, we compute which I's depended
 // on the secondary dependency, and call onPrimaryInputEvent with them
 func (h *manyCollection[I, O]) onPrimaryInputEvent(items []Event[I]) {
+	h.stats.recordEventsIn(len(items))
 	// Between the events being enqueued and now, the input may have changed. Update with latest info.
 	// Note we now have the `blockNewEvents` lock so this is safe; any futures calls will do the same so always have up-to-date information.
 	for idx, ev := range items {
@@ -430,29 +441,39 @@ func (h *manyCollection[I, O]) onPrimaryInputEvent(items []Event[I]) {
 		}
 		items[idx] = ev
 	}
-	h.handleChangedPrimaryInputEvents(items)
+	var trigger historyTrigger
+	if h.outputHistory != nil {
+		trigger = newHistoryTrigger(h.parent.(internalCollection[I]).name(), items)
+	}
+	h.handleChangedPrimaryInputEvents(items, trigger)
 }
 
 // handleChangedPrimaryInputEvents takes a list of I's that changed and reruns the handler over them.
-func (h *manyCollection[I, O]) handleChangedPrimaryInputEvents(items []Event[I]) {
+// trigger describes the events causing the change, for the history of the outputs; it is only set if it is recorded.
+func (h *manyCollection[I, O]) handleChangedPrimaryInputEvents(items []Event[I], trigger historyTrigger) {
 	var events []Event[O]
-	recomputedResults := make([]map[Key[O]]O, len(items))
-
-	pendingDepStateUpdates := make(map[Key[I]]*collectionDependencyTracker[I, O], len(items))
-	for idx, a := range items {
-		if a.Event == controllers.EventDelete {
-			// handled below, with full lock...
-			continue
+	now := time.Now()
+	recordHistory := func(key Key[O], iKey Key[I], e Event[O], deps []*dependency) {
+		if h.outputHistory == nil {
+			return
 		}
-		i := a.Latest()
-		iKey := getTypedKey(i)
-
-		ctx := &collectionDependencyTracker[I, O]{manyCollection: h, key: iKey}
-		results := slices.GroupUnique(h.transformation(ctx, i), getTypedKey[O])
-		recomputedResults[idx] = results
-		// Store new dependency state, to insert in the next loop under the lock
-		pendingDepStateUpdates[iKey] = ctx
+		entry := HistoryEntry{
+			Time:          now,
+			Event:         e.Event.String(),
+			Input:         string(iKey),
+			Trigger:       trigger.collection,
+			TriggerEvents: trigger.events,
+			Dependencies:  dependencyNames(deps),
+		}
+		if e.Old != nil {
+			entry.Old = *e.Old
+		}
+		if e.New != nil {
+			entry.New = *e.New
+		}
+		h.outputHistory.record(string(key), entry)
 	}
+	recomputedResults, pendingDepStateUpdates := h.transformAll(items)
 
 	// Now acquire the full lock.
 	h.mu.Lock()
@@ -472,6 +493,7 @@ func (h *manyCollection[I, O]) handleChangedPrimaryInputEvents(items []Event[I])
 					Old:   &oldRes,
 				}
 				events = append(events, e)
+				recordHistory(oKey, iKey, e, nil)
 				delete(h.collectionState.outputs, oKey)
 				for _, index := range h.indexes {
 					index.delete(oldRes, oKey)
@@ -541,6 +563,7 @@ func (h *manyCollection[I, O]) handleChangedPrimaryInputEvents(items []Event[I])
 					h.log.WithLabels("res", key, "type", e.Event).Debugf("handled")
 				}
 				events = append(events, e)
+				recordHistory(key, iKey, e, ctx.d)
 			}
 		}
 	}
@@ -554,9 +577,88 @@ func (h *manyCollection[I, O]) handleChangedPrimaryInputEvents(items []Event[I])
 	if h.log.DebugEnabled() {
 		h.log.WithLabels("events", len(events)).Debugf("calling handlers")
 	}
+	h.stats.recordEventsOut(len(events))
 	h.eventHandlers.Distribute(events, !h.HasSynced())
 }
 
+// transformAll runs the transformation over the items that are not deleted. It returns the results by index of the item,
+// and the dependencies of each input, to insert under the lock.
+func (h *manyCollection[I, O]) transformAll(items []Event[I]) ([]map[Key[O]]O, map[Key[I]]*collectionDependencyTracker[I, O]) {
+	recomputedResults := make([]map[Key[O]]O, len(items))
+	if h.shards < 2 || len(items) < 2 {
+		pendingDepStateUpdates := make(map[Key[I]]*collectionDependencyTracker[I, O], len(items))
+		for idx := range items {
+			h.transformOne(items, idx, recomputedResults, pendingDepStateUpdates)
+		}
+		return recomputedResults, pendingDepStateUpdates
+	}
+
+	// Partition the items by key, so the events of a given input are still handled in order, by the same worker.
+	shards := make([][]int, h.shards)
+	for idx, a := range items {
+		if a.Event == controllers.EventDelete {
+			continue
+		}
+		s := shardOf(string(getTypedKey(a.Latest())), h.shards)
+		shards[s] = append(shards[s], idx)
+	}
+	pending := make([]map[Key[I]]*collectionDependencyTracker[I, O], h.shards)
+	var wg sync.WaitGroup
+	for s, idxs := range shards {
+		if len(idxs) == 0 {
+			continue
+		}
+		pending[s] = make(map[Key[I]]*collectionDependencyTracker[I, O], len(idxs))
+		wg.Go(func() {
+			for _, idx := range idxs {
+				// Each worker writes to distinct indexes of recomputedResults, and to its own dependency map.
+				h.transformOne(items, idx, recomputedResults, pending[s])
+			}
+		})
+	}
+	wg.Wait()
+	pendingDepStateUpdates := make(map[Key[I]]*collectionDependencyTracker[I, O], len(items))
+	for _, p := range pending {
+		maps.Copy(pendingDepStateUpdates, p)
+	}
+	return recomputedResults, pendingDepStateUpdates
+}
+
+func (h *manyCollection[I, O]) transformOne(
+	items []Event[I],
+	idx int,
+	recomputedResults []map[Key[O]]O,
+	pendingDepStateUpdates map[Key[I]]*collectionDependencyTracker[I, O],
+) {
+	a := items[idx]
+	if a.Event == controllers.EventDelete {
+		// handled by the caller, with full lock...
+		return
+	}
+	i := a.Latest()
+	iKey := getTypedKey(i)
+
+	ctx := &collectionDependencyTracker[I, O]{manyCollection: h, key: iKey}
+	var start time.Time
+	if h.stats != nil {
+		start = time.Now()
+	}
+	results := slices.GroupUnique(h.transformation(ctx, i), getTypedKey[O])
+	if h.stats != nil {
+		h.stats.recordTransformation(time.Since(start))
+	}
+	recomputedResults[idx] = results
+	// Store new dependency state, to insert under the lock
+	pendingDepStateUpdates[iKey] = ctx
+}
+
+// shardOf returns the shard of the key, out of n.
+func shardOf(key string, n int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(n))
+}
+
 func (h *manyCollection[I, O]) Metadata() Metadata {
 	return h.metadata
 }
@@ -607,7 +709,7 @@ func newManyCollection[I, O any](
 		log:            log.WithLabels("owner", opts.name),
 		parent:         c,
 		dependencyState: dependencyState[I]{
-			collectionDependencies:       sets.New[collectionUID](),
+			collectionDependencies:       map[collectionUID]chan struct{}{},
 			objectDependencies:           map[Key[I]][]*dependency{},
 			indexedDependencies:          map[indexedDependency]sets.Set[Key[I]]{},
 			indexedDependenciesExtractor: map[extractorKey]func(o any) []string{},
@@ -623,6 +725,13 @@ func newManyCollection[I, O any](
 		synced:                     make(chan struct{}),
 		stop:                       opts.stop,
 		onPrimaryInputEventHandler: onPrimaryInputEventHandler,
+		shards:                     opts.shards,
+	}
+	if n := opts.debugger.historyEntries(); n > 0 {
+		h.outputHistory = newCollectionHistory[O](n)
+	}
+	if opts.debugger.profilingEnabled() {
+		h.stats = newCollectionProfile(h.collectionName)
 	}
 
 	if opts.metadata != nil {
@@ -674,8 +783,9 @@ func (h *manyCollection[I, O]) runQueue() {
 
 // Handler is called when a dependency changes. We will take as inputs the item that changed.
 // Then we find all of our own values (I) that changed and onPrimaryInputEvent() them
-func (h *manyCollection[I, O]) onSecondaryDependencyEvent(sourceCollection collectionUID, events []Event[any]) {
+func (h *manyCollection[I, O]) onSecondaryDependencyEvent(sourceCollection collectionUID, sourceName string, events []Event[any]) {
 	// A secondary dependency changed...
+	h.stats.recordEventsIn(len(events))
 	// Got an event. Now we need to find out who depends on it..
 	changedInputKeys := h.dependencyState.changedInputKeys(sourceCollection, events)
 	h.log.Debugf("event size %v, impacts %v objects", len(events), changedInputKeys.UnsortedList())
@@ -711,7 +821,11 @@ func (h *manyCollection[I, O]) onSecondaryDependencyEvent(sourceCollection colle
 			})
 		}
 	}
-	h.handleChangedPrimaryInputEvents(toRun)
+	var trigger historyTrigger
+	if h.outputHistory != nil {
+		trigger = newHistoryTrigger(sourceName, events)
+	}
+	h.handleChangedPrimaryInputEvents(toRun, trigger)
 }
 
 // nolint: unused // it is used to implement interface
@@ -773,6 +887,21 @@ func (h *manyCollection[I, O]) uid() collectionUID {
 	return h.id
 }
 
+// nolint: unused // (not true, its to implement an interface)
+func (h *manyCollection[I, O]) inputs() []collectionUID {
+	return collectionUIDs([]Collection[I]{h.parent})
+}
+
+// nolint: unused // (not true, its to implement an interface)
+func (h *manyCollection[I, O]) profile() *collectionProfile {
+	return h.stats
+}
+
+// nolint: unused // (not true, its to implement an interface)
+func (h *manyCollection[I, O]) history() *collectionHistory {
+	return h.outputHistory
+}
+
 func (h *manyCollection[I, O]) assertIndexConsistency() {
 	oToI := map[Key[O]]Key[I]{}
 	for i, os := range h.collectionState.mappings {
@@ -819,19 +948,36 @@ func (i *collectionDependencyTracker[I, O]) registerDependency(
 	i.d = append(i.d, d)
 
 	i.mu.Lock()
-	existed := i.dependencyState.collectionDependencies.InsertContains(d.id)
-	i.mu.Unlock()
-	// For any new collections we depend on, start watching them if its the first time we have watched them.
+	registered, existed := i.dependencyState.collectionDependencies[d.id]
 	if !existed {
-		i.log.WithLabels("collection", d.collectionName).Debugf("register new dependency")
-		syncer.WaitUntilSynced(i.stop)
-		register(func(o []Event[any]) {
-			i.queue.Push(func() error {
-				i.onSecondaryDependencyEvent(d.id, o)
-				return nil
-			})
-		}).WaitUntilSynced(i.stop)
+		registered = make(chan struct{})
+		i.dependencyState.collectionDependencies[d.id] = registered
+	}
+	i.mu.Unlock()
+	if existed {
+		// With shards, another worker may still be registering the collection; wait for it to be synced, as it would be
+		// when processing the inputs one by one.
+		select {
+		case <-registered:
+		case <-i.stop:
+		}
+		return
 	}
+	// For any new collections we depend on, start watching them if its the first time we have watched them.
+	defer close(registered)
+	i.log.WithLabels("collection", d.collectionName).Debugf("register new dependency")
+	syncer.WaitUntilSynced(i.stop)
+	register(func(o []Event[any]) {
+		i.queue.Push(func() error {
+			i.onSecondaryDependencyEvent(d.id, d.collectionName, o)
+			return nil
+		})
+	}).WaitUntilSynced(i.stop)
+}
+
+// recordFetch records a fetch from the transformation.
+func (i *collectionDependencyTracker[I, O]) recordFetch(uid collectionUID, name string, n int) {
+	i.stats.recordFetch(uid, name, n)
 }
 
 func (i *collectionDependencyTracker[I, O]) _internalHandler() {
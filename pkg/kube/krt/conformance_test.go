@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,6 +32,8 @@ import (
 	"istio.io/istio/pkg/kube/kclient"
 	"istio.io/istio/pkg/kube/krt"
 	krtfiles "istio.io/istio/pkg/kube/krt/files"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/ptr"
 	"istio.io/istio/pkg/slices"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
@@ -246,8 +249,8 @@ func TestConformance(t *testing.T) {
 		}
 		runConformance[Named](t, factory)
 	})
-	t.Run("manyCollection", func(t *testing.T) {
-		factory := func(t *testing.T) Rig[Named] {
+	manyFactory := func(opts ...krt.CollectionOption) func(t *testing.T) Rig[Named] {
+		return func(t *testing.T) Rig[Named] {
 			namespaces := krt.NewStaticCollection[string](nil, nil, krt.WithStop(test.NewStop(t)), krt.WithDebugging(krt.GlobalDebugHandler))
 			names := krt.NewStaticCollection[string](nil, nil, krt.WithStop(test.NewStop(t)), krt.WithDebugging(krt.GlobalDebugHandler))
 			col := krt.NewManyCollection(namespaces, func(ctx krt.HandlerContext, ns string) []Named {
@@ -255,7 +258,11 @@ func TestConformance(t *testing.T) {
 				return slices.Map(names, func(e string) Named {
 					return Named{Namespace: ns, Name: e}
 				})
-			}, krt.WithStop(test.NewStop(t)), krt.WithDebugging(krt.GlobalDebugHandler), krt.WithMetadata(metadata))
+			}, append([]krt.CollectionOption{
+				krt.WithStop(test.NewStop(t)),
+				krt.WithDebugging(krt.GlobalDebugHandler),
+				krt.WithMetadata(metadata),
+			}, opts...)...)
 			rig := &manyRig{
 				Collection: col,
 				namespaces: namespaces,
@@ -263,7 +270,12 @@ func TestConformance(t *testing.T) {
 			}
 			return rig
 		}
-		runConformance[Named](t, factory)
+	}
+	t.Run("manyCollection", func(t *testing.T) {
+		runConformance[Named](t, manyFactory())
+	})
+	t.Run("sharded manyCollection", func(t *testing.T) {
+		runConformance[Named](t, manyFactory(krt.WithShards(4)))
 	})
 	t.Run("files", func(t *testing.T) {
 		factory := func(t *testing.T) Rig[Named] {
@@ -423,3 +435,91 @@ func runReplaceConformance[T any](t *testing.T, collection Rig[T]) {
 
 	handler.Empty()
 }
+
+// TestShardedConformance checks that a sharded collection ends up with the same state as the same collection without
+// shards for a random sequence of primary and secondary changes, and that its events, replayed in order, lead to that
+// state.
+func TestShardedConformance(t *testing.T) {
+	stop := test.NewStop(t)
+	opts := krt.NewOptionsBuilder(stop, "", krt.GlobalDebugHandler)
+	pods := krt.NewStaticCollection[Named](nil, nil, opts.WithName("pods")...)
+	ips := krt.NewStaticCollection[SimplePod](nil, nil, opts.WithName("ips")...)
+	// Every pod depends on the suffix, so its changes recompute the whole collection in a single batch
+	suffix := krt.NewStatic[string](ptr.Of("a"), true, opts.WithName("suffix")...)
+	for n := range 50 {
+		pods.UpdateObject(Named{Namespace: "ns", Name: fmt.Sprintf("pod-%d", n)})
+		ips.UpdateObject(SimplePod{Named: Named{Namespace: "ns", Name: fmt.Sprintf("pod-%d", n)}, IP: "init"})
+	}
+	build := func(name string, extra ...krt.CollectionOption) krt.Collection[SimplePod] {
+		return krt.NewCollection(pods, func(ctx krt.HandlerContext, p Named) *SimplePod {
+			ip := krt.FetchOne(ctx, ips, krt.FilterKey(p.ResourceName()))
+			if ip == nil {
+				return nil
+			}
+			return &SimplePod{Named: p, IP: ip.IP + *krt.FetchOne(ctx, suffix.AsCollection())}
+		}, append(opts.WithName(name), extra...)...)
+	}
+	serial := build("serial")
+	sharded := build("sharded", krt.WithShards(8))
+
+	replay := func(c krt.Collection[SimplePod]) (func() []SimplePod, krt.HandlerRegistration) {
+		mu := sync.Mutex{}
+		state := map[string]SimplePod{}
+		reg := c.Register(func(o krt.Event[SimplePod]) {
+			mu.Lock()
+			defer mu.Unlock()
+			if o.New == nil {
+				delete(state, krt.GetKey(*o.Old))
+			} else {
+				state[krt.GetKey(*o.New)] = *o.New
+			}
+		})
+		return func() []SimplePod {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.SortBy(maps.Values(state), krt.GetKey[SimplePod])
+		}, reg
+	}
+	serialState, serialReg := replay(serial)
+	shardedState, shardedReg := replay(sharded)
+	assert.Equal(t, serialReg.WaitUntilSynced(stop), true)
+	assert.Equal(t, shardedReg.WaitUntilSynced(stop), true)
+
+	// nolint: gosec // just for testing
+	rnd := rand.New(rand.NewSource(1))
+	for n := range 2000 {
+		key := fmt.Sprintf("ns/pod-%d", rnd.Intn(50))
+		ns, name, _ := strings.Cut(key, "/")
+		switch rnd.Intn(5) {
+		case 0:
+			pods.UpdateObject(Named{Namespace: ns, Name: name})
+		case 1:
+			pods.DeleteObject(key)
+		case 2:
+			ips.UpdateObject(SimplePod{Named: Named{Namespace: ns, Name: name}, IP: fmt.Sprint(n)})
+		case 3:
+			ips.DeleteObject(key)
+		case 4:
+			suffix.Set(ptr.Of(fmt.Sprint(n)))
+		}
+	}
+
+	// The static collections are updated synchronously, so the expected state is already final
+	want := slices.SortBy(slices.MapFilter(pods.List(), func(p Named) *SimplePod {
+		ip := ips.GetKey(p.ResourceName())
+		if ip == nil {
+			return nil
+		}
+		return &SimplePod{Named: p, IP: ip.IP + *suffix.Get()}
+	}), krt.GetKey[SimplePod])
+	assert.Equal(t, len(want) > 0, true)
+	sortedList := func(c krt.Collection[SimplePod]) func() []SimplePod {
+		return func() []SimplePod {
+			return slices.SortBy(c.List(), krt.GetKey[SimplePod])
+		}
+	}
+	assert.EventuallyEqual(t, sortedList(serial), want)
+	assert.EventuallyEqual(t, sortedList(sharded), want)
+	assert.EventuallyEqual(t, serialState, want)
+	assert.EventuallyEqual(t, shardedState, want)
+}
@@ -15,16 +15,89 @@
 package krt
 
 import (
+	"cmp"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
+
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
 )
 
 // DebugHandler allows attaching a variety of collections to it and then dumping them
 type DebugHandler struct {
 	debugCollections []DebugCollection
+	historySize      int
+	profiling        bool
 	mu               sync.RWMutex
 }
 
+// EnableHistory makes the collections registered afterward record the last size changes of each of their outputs, see
+// History. This has a memory cost proportional to the size of the collections, so it is meant for debugging.
+func (p *DebugHandler) EnableHistory(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.historySize = size
+}
+
+// historyEntries returns the number of changes to record per output, or 0 if the history is disabled.
+func (p *DebugHandler) historyEntries() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.historySize
+}
+
+// EnableProfiling makes the collections registered afterward record their activity: events in and out,
+// transformation calls and latency, and the fan-out of their fetches. The activity is reported by Graph, and as metrics.
+// This adds a clock read per transformation, and is meant for debugging.
+func (p *DebugHandler) EnableProfiling() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profiling = true
+}
+
+// profilingEnabled reports whether the collections record their activity.
+func (p *DebugHandler) profilingEnabled() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.profiling
+}
+
+// History returns the last changes of the output key of the collections named collection, oldest first. An error is
+// returned if no such collection records its history.
+func (p *DebugHandler) History(collection, key string) ([]HistoryEntry, error) {
+	if p == nil {
+		return nil, fmt.Errorf("no collection %q", collection)
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	found := false
+	var res []HistoryEntry
+	for _, c := range p.debugCollections {
+		if c.name != collection || c.history == nil || c.history.history() == nil {
+			continue
+		}
+		found = true
+		res = append(res, c.history.history().get(key)...)
+	}
+	if !found {
+		return nil, fmt.Errorf("no collection %q recording its history", collection)
+	}
+	// Collection names are not unique; interleave the changes of collections sharing a name
+	slices.SortStableFunc(res, func(a, b HistoryEntry) int {
+		return a.Time.Compare(b.Time)
+	})
+	return res, nil
+}
+
 func (p *DebugHandler) MarshalJSON() ([]byte, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -51,6 +124,10 @@ type DebugCollection struct {
 	name string
 	dump func() CollectionDump
 	uid  collectionUID
+	// graph reports the inputs and the activity of the collection, if it supports it
+	graph profiledCollection
+	// history reports the changes of the outputs of the collection, if it supports it
+	history historyCollection
 }
 
 func (p DebugCollection) MarshalJSON() ([]byte, error) {
@@ -69,11 +146,103 @@ func maybeRegisterCollectionForDebugging[T any](c Collection[T], handler *DebugH
 	cc := c.(internalCollection[T])
 	handler.mu.Lock()
 	defer handler.mu.Unlock()
-	handler.debugCollections = append(handler.debugCollections, DebugCollection{
+	dc := DebugCollection{
 		name: cc.name(),
 		dump: cc.dump,
 		uid:  cc.uid(),
+	}
+	if pc, ok := c.(profiledCollection); ok {
+		dc.graph = pc
+	}
+	if hc, ok := c.(historyCollection); ok {
+		dc.history = hc
+	}
+	handler.debugCollections = append(handler.debugCollections, dc)
+}
+
+// CollectionGraph is the graph of the collections of a DebugHandler: each collection is a node, with an edge from each
+// of its inputs, and from each of the collections fetched by its transformation.
+type CollectionGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	UID  uint64 `json:"uid"`
+	Name string `json:"name"`
+	// Profile is the activity of the collection, for collections with a transformation when profiling is enabled.
+	Profile *CollectionProfile `json:"profile,omitempty"`
+}
+
+// GraphEdge is the flow of events from a collection to a collection depending on it.
+type GraphEdge struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	// Fetch is set when the dependency is fetched by the transformation, rather than being its primary input.
+	Fetch *FetchProfile `json:"fetch,omitempty"`
+}
+
+// Graph returns the graph of the collections. Edges from or to collections not registered in the handler are omitted.
+func (p *DebugHandler) Graph() CollectionGraph {
+	g := CollectionGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	if p == nil {
+		return g
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	known := sets.New[collectionUID]()
+	for _, c := range p.debugCollections {
+		known.Insert(c.uid)
+	}
+	for _, c := range p.debugCollections {
+		node := GraphNode{UID: uint64(c.uid), Name: c.name}
+		if c.graph != nil {
+			for _, in := range c.graph.inputs() {
+				if known.Contains(in) {
+					g.Edges = append(g.Edges, GraphEdge{From: uint64(in), To: uint64(c.uid)})
+				}
+			}
+			if prof := c.graph.profile(); prof != nil {
+				snapshot, fetches := prof.snapshot()
+				node.Profile = &snapshot
+				for _, uid := range sortedFetchUIDs(fetches) {
+					if known.Contains(uid) {
+						g.Edges = append(g.Edges, GraphEdge{From: uint64(uid), To: uint64(c.uid), Fetch: ptr.Of(fetches[uid])})
+					}
+				}
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	slices.SortFunc(g.Nodes, func(a, b GraphNode) int {
+		return cmp.Compare(a.UID, b.UID)
 	})
+	return g
+}
+
+// DOT renders the graph in the Graphviz DOT language. Collections are labeled with their activity, so the expensive
+// ones stand out.
+func (g CollectionGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph krt {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		label := n.Name
+		if p := n.Profile; p != nil {
+			label += fmt.Sprintf("\nevents in %d, out %d\ntransformations %d, total %v, p99 %v",
+				p.EventsIn, p.EventsOut, p.Transformations, p.TransformTime, p.TransformP99)
+		}
+		fmt.Fprintf(&b, "\t%d [label=%q];\n", n.UID, label)
+	}
+	for _, e := range g.Edges {
+		if e.Fetch != nil {
+			fmt.Fprintf(&b, "\t%d -> %d [style=dashed, label=%q];\n", e.From, e.To,
+				fmt.Sprintf("fetch %d, objects %d", e.Fetch.Fetches, e.Fetch.Objects))
+		} else {
+			fmt.Fprintf(&b, "\t%d -> %d;\n", e.From, e.To)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
 }
 
 // nolint: unused // (not true, not sure why it thinks it is!)
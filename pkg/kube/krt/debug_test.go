@@ -0,0 +1,262 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package krt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestCollectionGraph(t *testing.T) {
+	mt := monitortest.New(t)
+	stop := test.NewStop(t)
+	debugger := new(DebugHandler)
+	debugger.EnableProfiling()
+	opts := NewOptionsBuilder(stop, "test", debugger)
+	c := kube.NewFakeClient()
+	kpc := kclient.New[*corev1.Pod](c)
+	kcc := kclient.New[*corev1.ConfigMap](c)
+	pc := clienttest.Wrap(t, kpc)
+	cc := clienttest.Wrap(t, kcc)
+	pods := WrapClient[*corev1.Pod](kpc, opts.WithName("Pods")...)
+	configMaps := WrapClient[*corev1.ConfigMap](kcc, opts.WithName("ConfigMaps")...)
+	c.RunAndWait(stop)
+
+	type PodConfigs struct {
+		Named
+		Configs int
+	}
+	podConfigs := NewCollection(pods, func(ctx HandlerContext, p *corev1.Pod) *PodConfigs {
+		cms := Fetch(ctx, configMaps, FilterGeneric(func(a any) bool {
+			return a.(*corev1.ConfigMap).Namespace == p.Namespace
+		}))
+		return &PodConfigs{Named: NewNamed(p), Configs: len(cms)}
+	}, opts.WithName("PodConfigs")...)
+	podConfigs.WaitUntilSynced(stop)
+
+	cc.Create(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "ns"}})
+	cc.Create(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "ns"}})
+	pc.Create(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})
+	assert.EventuallyEqual(t, func() int {
+		if pc := podConfigs.GetKey("ns/pod"); pc != nil {
+			return pc.Configs
+		}
+		return -1
+	}, 2)
+
+	g := debugger.Graph()
+	names := map[uint64]string{}
+	for _, n := range g.Nodes {
+		names[n.UID] = n.Name
+	}
+	assert.Equal(t, len(g.Nodes), 3)
+
+	var edges []string
+	for _, e := range g.Edges {
+		if e.Fetch != nil {
+			edges = append(edges, fmt.Sprintf("%s -fetch-> %s", names[e.From], names[e.To]))
+			assert.Equal(t, e.Fetch.Objects >= 2, true)
+		} else {
+			edges = append(edges, fmt.Sprintf("%s -> %s", names[e.From], names[e.To]))
+		}
+	}
+	assert.Equal(t, edges, []string{"test/Pods -> test/PodConfigs", "test/ConfigMaps -fetch-> test/PodConfigs"})
+
+	for _, n := range g.Nodes {
+		if n.Name != "test/PodConfigs" {
+			assert.Equal(t, n.Profile, nil)
+			continue
+		}
+		assert.Equal(t, n.Profile.EventsIn >= 1, true)
+		assert.Equal(t, n.Profile.EventsOut >= 1, true)
+		assert.Equal(t, n.Profile.Transformations >= 1, true)
+		assert.Equal(t, n.Profile.TransformP99 > 0, true)
+		assert.Equal(t, n.Profile.Fetches["test/ConfigMaps"].Fetches >= 1, true)
+	}
+
+	podConfigsLabel := map[string]string{"collection": "test/PodConfigs"}
+	mt.Assert("krt_collection_events_in_total", podConfigsLabel, monitortest.AtLeast(1))
+	mt.Assert("krt_collection_events_out_total", podConfigsLabel, monitortest.AtLeast(1))
+	mt.Assert("krt_collection_fetched_objects_total",
+		map[string]string{"collection": "test/PodConfigs", "dependency": "test/ConfigMaps"}, monitortest.AtLeast(2))
+
+	dot := g.DOT()
+	for _, want := range []string{"digraph krt {", `label="test/PodConfigs\nevents in`, "[style=dashed, label=\"fetch "} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output does not contain %q:\n%s", want, dot)
+		}
+	}
+}
+
+func TestCollectionProfilingDisabled(t *testing.T) {
+	stop := test.NewStop(t)
+	debugger := new(DebugHandler)
+	opts := NewOptionsBuilder(stop, "test", debugger)
+	c := kube.NewFakeClient()
+	kpc := kclient.New[*corev1.Pod](c)
+	pc := clienttest.Wrap(t, kpc)
+	pods := WrapClient[*corev1.Pod](kpc, opts.WithName("Pods")...)
+	c.RunAndWait(stop)
+	names := NewCollection(pods, func(ctx HandlerContext, p *corev1.Pod) *string {
+		return &p.Name
+	}, opts.WithName("Names")...)
+	names.WaitUntilSynced(stop)
+
+	pc.Create(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})
+	assert.EventuallyEqual(t, func() int { return len(names.List()) }, 1)
+
+	// Without profiling, the graph only has the collections and their inputs
+	g := debugger.Graph()
+	assert.Equal(t, len(g.Nodes), 2)
+	assert.Equal(t, len(g.Edges), 1)
+	for _, n := range g.Nodes {
+		assert.Equal(t, n.Profile, nil)
+	}
+}
+
+func TestCollectionProfilePercentile(t *testing.T) {
+	p := newCollectionProfile("test")
+	assert.Equal(t, p.percentile(0.99), 0)
+	for range 98 {
+		p.recordTransformation(3 * time.Microsecond)
+	}
+	p.recordTransformation(100 * time.Microsecond)
+	p.recordTransformation(time.Second)
+	assert.Equal(t, p.percentile(0.5), 4*time.Microsecond)
+	assert.Equal(t, p.percentile(0.99), 128*time.Microsecond)
+	assert.Equal(t, p.percentile(1), 1<<20*time.Microsecond)
+	assert.Equal(t, p.transformTime.Load(), 98*3*time.Microsecond+100*time.Microsecond+time.Second)
+}
+
+type historyOutput struct {
+	Name   string
+	Suffix string
+}
+
+func (h historyOutput) ResourceName() string {
+	return h.Name
+}
+
+func TestCollectionHistory(t *testing.T) {
+	stop := test.NewStop(t)
+	debugger := new(DebugHandler)
+	debugger.EnableHistory(2)
+	opts := NewOptionsBuilder(stop, "test", debugger)
+	inputs := NewStaticCollection[string](nil, nil, opts.WithName("Inputs")...)
+	suffix := NewStatic[string](ptr.Of("a"), true, opts.WithName("Suffix")...)
+	outputs := NewCollection(inputs, func(ctx HandlerContext, i string) *historyOutput {
+		return &historyOutput{Name: i, Suffix: *FetchOne(ctx, suffix.AsCollection())}
+	}, opts.WithName("Outputs")...)
+	outputs.WaitUntilSynced(stop)
+	current := func() string {
+		if o := outputs.GetKey("x"); o != nil {
+			return o.Suffix
+		}
+		return ""
+	}
+
+	inputs.UpdateObject("x")
+	assert.EventuallyEqual(t, current, "a")
+	suffix.Set(ptr.Of("b"))
+	assert.EventuallyEqual(t, current, "b")
+	suffix.Set(ptr.Of("c"))
+	assert.EventuallyEqual(t, current, "c")
+	inputs.DeleteObject("x")
+	assert.EventuallyEqual(t, current, "")
+
+	history, err := debugger.History("test/Outputs", "x")
+	assert.NoError(t, err)
+	// Only the last 2 changes are kept
+	assert.Equal(t, len(history), 2)
+	update, deletion := history[0], history[1]
+	assert.Equal(t, update.Event, "update")
+	assert.Equal(t, update.Input, "x")
+	assert.Equal(t, update.Trigger, "test/Suffix")
+	assert.Equal(t, update.Dependencies, []string{"test/Suffix {}"})
+	assert.Equal(t, update.Old, any(historyOutput{Name: "x", Suffix: "b"}))
+	assert.Equal(t, update.New, any(historyOutput{Name: "x", Suffix: "c"}))
+	assert.Equal(t, update.Changed, []string{"Suffix"})
+	assert.Equal(t, deletion.Event, "delete")
+	assert.Equal(t, deletion.Trigger, "test/Inputs")
+	assert.Equal(t, deletion.TriggerEvents, []string{"delete/x"})
+	assert.Equal(t, deletion.New, nil)
+
+	history, err = debugger.History("test/Outputs", "unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, len(history), 0)
+	_, err = debugger.History("test/Inputs", "x")
+	assert.Error(t, err)
+}
+
+// historyLabels compares as equal when they have the same keys, whatever their values.
+type historyLabels map[string]string
+
+func (l historyLabels) Equals(o historyLabels) bool {
+	return sets.New(maps.Keys(l)...).Equals(sets.New(maps.Keys(o)...))
+}
+
+type historyPolicy struct {
+	Name   string
+	Labels historyLabels
+	Spec   *metav1.LabelSelector
+	count  int
+}
+
+func TestCollectionHistoryChangedFields(t *testing.T) {
+	h := newCollectionHistory[historyPolicy](1)
+	old := historyPolicy{
+		Name:   "a",
+		Labels: historyLabels{"app": "a"},
+		Spec:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+		count:  1,
+	}
+	// Fields are compared with their Equals method, and unexported fields are ignored
+	relabeled := old
+	relabeled.Labels = historyLabels{"app": "b"}
+	relabeled.count = 2
+	assert.Equal(t, len(h.changedFields(old, relabeled)), 0)
+
+	changed := old
+	changed.Labels = historyLabels{"version": "a"}
+	changed.Spec = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "b"}}
+	assert.Equal(t, h.changedFields(old, changed), []string{"Labels", "Spec"})
+}
+
+func TestCollectionHistoryDeletedBound(t *testing.T) {
+	h := newCollectionHistory[string](1)
+	for n := range maxDeletedHistories + 1 {
+		h.record(fmt.Sprint(n), HistoryEntry{Event: "delete"})
+	}
+	h.record("alive", HistoryEntry{Event: "add"})
+	assert.Equal(t, len(h.get("0")), 0)
+	assert.Equal(t, len(h.get("1")), 1)
+	assert.Equal(t, len(h.get("alive")), 1)
+	assert.Equal(t, len(h.entries), maxDeletedHistories+1)
+}
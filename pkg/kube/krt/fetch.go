@@ -120,6 +120,9 @@ func fetch[T any](ctx HandlerContext, cc Collection[T], allowMissingContext bool
 		o := c.augment(i)
 		return d.filter.Matches(o, true)
 	})
+	if r, ok := ctx.(fetchRecorder); ok {
+		r.recordFetch(d.id, d.collectionName, len(list))
+	}
 	if log.DebugEnabled() {
 		log.WithLabels(
 			"parent", parent,
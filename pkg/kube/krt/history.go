@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package krt
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/slices"
+)
+
+// maxDeletedHistories bounds the number of deleted outputs whose history is retained, so churn does not grow the
+// history forever. The history of the oldest deletions is dropped first.
+const maxDeletedHistories = 1024
+
+// maxTriggerKeys bounds the number of keys of the triggering events recorded in a history entry.
+const maxTriggerKeys = 10
+
+// HistoryEntry is a change of an output of a collection, with what caused it.
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Event is the change of the output: add, update or delete.
+	Event string `json:"event"`
+	// Input is the key of the input whose transformation produced the change.
+	Input string `json:"input"`
+	// Trigger is the name of the collection whose event caused the recomputation: the primary input of the collection,
+	// or one of the collections fetched by its transformation.
+	Trigger string `json:"trigger"`
+	// TriggerEvents are the events of the trigger collection, as "<event>/<key>". It is truncated for large batches.
+	TriggerEvents []string `json:"triggerEvents,omitempty"`
+	// Dependencies are the fetches of the transformation of the input that produced the change, as
+	// "<collection> <filter>". They are unset for deletions.
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Old and New are the output before and after the change.
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+	// Changed are the top level fields of the JSON representation of the output that changed, for updates.
+	Changed []string `json:"changed,omitempty"`
+}
+
+// historyTrigger describes the events that caused a recomputation.
+type historyTrigger struct {
+	collection string
+	events     []string
+}
+
+func newHistoryTrigger[T any](collection string, events []Event[T]) historyTrigger {
+	t := historyTrigger{collection: collection}
+	for _, ev := range events[:min(len(events), maxTriggerKeys)] {
+		t.events = append(t.events, ev.Event.String()+"/"+GetKey(ev.Latest()))
+	}
+	if len(events) > maxTriggerKeys {
+		t.events = append(t.events, fmt.Sprintf("... and %d more", len(events)-maxTriggerKeys))
+	}
+	return t
+}
+
+// collectionHistory records the last changes of each output of a collection.
+type collectionHistory struct {
+	size int
+	// equal compares two outputs of the collection with Equal
+	equal func(a, b any) bool
+
+	mu      sync.RWMutex
+	entries map[string][]HistoryEntry
+	// deleted are the keys of the deleted outputs, oldest first
+	deleted []string
+}
+
+func newCollectionHistory[O any](size int) *collectionHistory {
+	return &collectionHistory{
+		size: size,
+		equal: func(a, b any) bool {
+			return Equal(a.(O), b.(O))
+		},
+		entries: map[string][]HistoryEntry{},
+	}
+}
+
+// record records a change of the output key. Old and New must not be mutated afterward, as for any object of a
+// collection.
+func (h *collectionHistory) record(key string, e HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cur := h.entries[key]
+	if len(cur) >= h.size {
+		cur = slices.Clone(cur[len(cur)-h.size+1:])
+	}
+	h.entries[key] = append(cur, e)
+	if e.Event != controllers.EventDelete.String() {
+		return
+	}
+	// An output may be re-added later; we do not bother removing it from deleted, so it may be dropped early.
+	h.deleted = append(h.deleted, key)
+	if len(h.deleted) > maxDeletedHistories {
+		drop := h.deleted[0]
+		h.deleted = h.deleted[1:]
+		if last := h.entries[drop]; len(last) > 0 && last[len(last)-1].Event == controllers.EventDelete.String() {
+			delete(h.entries, drop)
+		}
+	}
+}
+
+// get returns the history of the output key, oldest first.
+func (h *collectionHistory) get(key string) []HistoryEntry {
+	h.mu.RLock()
+	res := slices.Clone(h.entries[key])
+	h.mu.RUnlock()
+	for i, e := range res {
+		if e.Old != nil && e.New != nil {
+			res[i].Changed = h.changedFields(e.Old, e.New)
+		}
+	}
+	return res
+}
+
+// changedFields returns the exported top level fields that differ between the outputs a and b, compared as the
+// collection compares its outputs. Nothing is returned for outputs that are not structs. This is only computed when
+// the history is queried, to keep the recording cheap.
+func (h *collectionHistory) changedFields(a, b any) []string {
+	if h.equal(a, b) {
+		return nil
+	}
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	for av.Kind() == reflect.Pointer && bv.Kind() == reflect.Pointer && !av.IsNil() && !bv.IsNil() {
+		av, bv = av.Elem(), bv.Elem()
+	}
+	if av.Kind() != reflect.Struct || av.Type() != bv.Type() {
+		return nil
+	}
+	var res []string
+	for i := range av.NumField() {
+		if f := av.Type().Field(i); f.IsExported() && !fieldEqual(av.Field(i), bv.Field(i)) {
+			res = append(res, f.Name)
+		}
+	}
+	return res
+}
+
+// fieldEqual compares two values of a field with their Equals method, as Equaler, if any, or with Equal otherwise.
+func fieldEqual(a, b reflect.Value) bool {
+	if m := a.MethodByName("Equals"); m.IsValid() {
+		if t := m.Type(); t.NumIn() == 1 && t.In(0) == b.Type() && t.NumOut() == 1 && t.Out(0).Kind() == reflect.Bool {
+			return m.Call([]reflect.Value{b})[0].Bool()
+		}
+	}
+	return Equal(a.Interface(), b.Interface())
+}
+
+// historyCollection is implemented by collections recording the history of their outputs.
+type historyCollection interface {
+	history() *collectionHistory
+}
+
+func dependencyNames(deps []*dependency) []string {
+	return slices.Map(deps, func(d *dependency) string {
+		return d.collectionName + " " + d.filter.String()
+	})
+}
@@ -85,6 +85,7 @@ type collectionOptions struct {
 	stop          <-chan struct{}
 	debugger      *DebugHandler
 	joinUnchecked bool
+	shards        int
 
 	indexCollectionFromString func(string) any
 	metadata                  Metadata
@@ -114,6 +115,11 @@ type registerDependency interface {
 	name() string
 }
 
+// fetchRecorder is implemented by the contexts recording the fetches of their transformation, see collectionProfile.
+type fetchRecorder interface {
+	recordFetch(uid collectionUID, name string, n int)
+}
+
 // getLabels returns the labels for an object, if possible.
 // Warning: this will panic if the labels is not available.
 func getLabels(a any) map[string]string {
@@ -307,6 +307,14 @@ func (j *join[T]) name() string { return j.collectionName }
 // nolint: unused // (not true, its to implement an interface)
 func (j *join[T]) uid() collectionUID { return j.id }
 
+// nolint: unused // (not true, its to implement an interface)
+func (j *join[T]) inputs() []collectionUID {
+	return collectionUIDs(slices.Map(j.collections, func(c internalCollection[T]) Collection[T] { return c }))
+}
+
+// nolint: unused // (not true, its to implement an interface)
+func (j *join[T]) profile() *collectionProfile { return nil }
+
 // nolint: unused // (not true, its to implement an interface)
 func (j *join[T]) dump() CollectionDump {
 	inputs := map[string]InputDump{}
@@ -117,6 +117,12 @@ func (m *mapCollection[T, U]) name() string { return m.collectionName }
 // nolint: unused // (not true, its to implement an interface)
 func (m *mapCollection[T, U]) uid() collectionUID { return m.id }
 
+// nolint: unused // (not true, its to implement an interface)
+func (m *mapCollection[T, U]) inputs() []collectionUID { return []collectionUID{m.collection.uid()} }
+
+// nolint: unused // (not true, its to implement an interface)
+func (m *mapCollection[T, U]) profile() *collectionProfile { return nil }
+
 // nolint: unused // (not true, its to implement an interface)
 func (m *mapCollection[T, U]) dump() CollectionDump {
 	return CollectionDump{
@@ -119,6 +119,14 @@ func (j *mergejoin[T]) name() string { return j.collectionName }
 // nolint: unused // (not true, its to implement an interface)
 func (j *mergejoin[T]) uid() collectionUID { return j.id }
 
+// nolint: unused // (not true, its to implement an interface)
+func (j *mergejoin[T]) inputs() []collectionUID {
+	return collectionUIDs(j.collections.getCollections())
+}
+
+// nolint: unused // (not true, its to implement an interface)
+func (j *mergejoin[T]) profile() *collectionProfile { return nil }
+
 func (j *mergejoin[T]) Synced() Syncer {
 	return channelSyncer{
 		name:   j.collectionName,
@@ -101,6 +101,17 @@ func WithJoinUnchecked() CollectionOption {
 	}
 }
 
+// WithShards partitions the inputs of a derived collection across n workers, so that the transformations of a batch of
+// changed inputs run in parallel. An input key is always handled by the same worker, so the changes of a given input are
+// still processed in order, and the results are applied to the collection as a single transaction, as without shards.
+// This is only useful for large collections with costly transformations; the transformation must be safe to call
+// concurrently for distinct inputs. Values below 2 disable sharding.
+func WithShards(n int) CollectionOption {
+	return func(c *collectionOptions) {
+		c.shards = n
+	}
+}
+
 // WithMetadata adds metadata to the collection. This is mainly useful
 // for creating collections of collections where the metadata is needed to
 // fetch a specific collection.
@@ -0,0 +1,263 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package krt
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/monitoring"
+	"istio.io/istio/pkg/slices"
+)
+
+var (
+	collectionLabel = monitoring.CreateLabel("collection")
+	dependencyLabel = monitoring.CreateLabel("dependency")
+
+	collectionEventsIn = monitoring.NewSum(
+		"krt_collection_events_in_total",
+		"Number of events received by a profiled krt collection from its inputs, primary or fetched.",
+	)
+
+	collectionEventsOut = monitoring.NewSum(
+		"krt_collection_events_out_total",
+		"Number of events sent by a profiled krt collection to its handlers.",
+	)
+
+	collectionTransformations = monitoring.NewDistribution(
+		"krt_collection_transformation_seconds",
+		"Latency of the calls to the transformation of a profiled krt collection.",
+		[]float64{.0001, .001, .01, .1, 1, 10},
+		monitoring.WithUnit(monitoring.Seconds),
+	)
+
+	collectionFetches = monitoring.NewSum(
+		"krt_collection_fetches_total",
+		"Number of fetches from the transformations of a profiled krt collection into one of its dependencies.",
+	)
+
+	collectionFetchedObjects = monitoring.NewSum(
+		"krt_collection_fetched_objects_total",
+		"Number of objects returned by the fetches from the transformations of a profiled krt collection into one "+
+			"of its dependencies.",
+	)
+)
+
+// latencyBuckets is the number of buckets of the transformation latency histogram. Bucket i holds the latencies up to
+// 2^i microseconds, so the last bucket covers latencies of more than half an hour.
+const latencyBuckets = 32
+
+// collectionProfile records the activity of a collection, to find where the time of recomputations goes. It is only
+// created for the collections of a DebugHandler with profiling enabled, see DebugHandler.EnableProfiling; the nil
+// profile of the other collections records nothing.
+// All the counters are updated without locks, as they are on the hot path of the transformations.
+type collectionProfile struct {
+	name            string
+	eventsIn        *atomic.Uint64
+	eventsOut       *atomic.Uint64
+	transformations *atomic.Uint64
+	transformTime   *atomic.Duration
+	latency         [latencyBuckets]atomic.Uint64
+
+	eventsInMetric        monitoring.Metric
+	eventsOutMetric       monitoring.Metric
+	transformationsMetric monitoring.Metric
+
+	mu      sync.RWMutex
+	fetches map[collectionUID]*fetchProfile
+}
+
+// fetchProfile records the fetches from the transformations of a collection into one of its dependencies.
+type fetchProfile struct {
+	name    string
+	fetches *atomic.Uint64
+	objects *atomic.Uint64
+
+	fetchesMetric monitoring.Metric
+	objectsMetric monitoring.Metric
+}
+
+func newCollectionProfile(name string) *collectionProfile {
+	label := collectionLabel.Value(name)
+	return &collectionProfile{
+		name:                  name,
+		eventsIn:              atomic.NewUint64(0),
+		eventsOut:             atomic.NewUint64(0),
+		transformations:       atomic.NewUint64(0),
+		transformTime:         atomic.NewDuration(0),
+		eventsInMetric:        collectionEventsIn.With(label),
+		eventsOutMetric:       collectionEventsOut.With(label),
+		transformationsMetric: collectionTransformations.With(label),
+		fetches:               map[collectionUID]*fetchProfile{},
+	}
+}
+
+// recordEventsIn records n events received from the inputs of the collection.
+func (p *collectionProfile) recordEventsIn(n int) {
+	if p == nil {
+		return
+	}
+	p.eventsIn.Add(uint64(n))
+	p.eventsInMetric.RecordInt(int64(n))
+}
+
+// recordEventsOut records n events sent to the handlers of the collection.
+func (p *collectionProfile) recordEventsOut(n int) {
+	if p == nil {
+		return
+	}
+	p.eventsOut.Add(uint64(n))
+	p.eventsOutMetric.RecordInt(int64(n))
+}
+
+// recordTransformation records a call to the transformation of the collection, which took d.
+func (p *collectionProfile) recordTransformation(d time.Duration) {
+	p.transformations.Inc()
+	p.transformTime.Add(d)
+	p.latency[latencyBucket(d)].Inc()
+	p.transformationsMetric.Record(d.Seconds())
+}
+
+// recordFetch records a fetch into the dependency, which returned n objects.
+func (p *collectionProfile) recordFetch(uid collectionUID, name string, n int) {
+	if p == nil {
+		return
+	}
+	p.mu.RLock()
+	f, ok := p.fetches[uid]
+	p.mu.RUnlock()
+	if !ok {
+		p.mu.Lock()
+		if f, ok = p.fetches[uid]; !ok {
+			labels := []monitoring.LabelValue{collectionLabel.Value(p.name), dependencyLabel.Value(name)}
+			f = &fetchProfile{
+				name:          name,
+				fetches:       atomic.NewUint64(0),
+				objects:       atomic.NewUint64(0),
+				fetchesMetric: collectionFetches.With(labels...),
+				objectsMetric: collectionFetchedObjects.With(labels...),
+			}
+			p.fetches[uid] = f
+		}
+		p.mu.Unlock()
+	}
+	f.fetches.Inc()
+	f.objects.Add(uint64(n))
+	f.fetchesMetric.Increment()
+	f.objectsMetric.RecordInt(int64(n))
+}
+
+// latencyBucket returns the bucket of the latency histogram holding d.
+func latencyBucket(d time.Duration) int {
+	us := uint64(d.Microseconds())
+	if us <= 1 {
+		return 0
+	}
+	return min(bits.Len64(us-1), latencyBuckets-1)
+}
+
+// percentile returns an upper bound of the q-th quantile of the transformation latencies.
+func (p *collectionProfile) percentile(q float64) time.Duration {
+	total := p.transformations.Load()
+	if total == 0 {
+		return 0
+	}
+	threshold := uint64(q * float64(total))
+	var seen uint64
+	for i := range p.latency {
+		seen += p.latency[i].Load()
+		if seen >= threshold {
+			return time.Duration(uint64(1)<<i) * time.Microsecond
+		}
+	}
+	return time.Duration(uint64(1)<<(latencyBuckets-1)) * time.Microsecond
+}
+
+// CollectionProfile is a snapshot of the activity of a collection.
+type CollectionProfile struct {
+	// EventsIn is the number of events received from the inputs of the collection, primary or fetched.
+	EventsIn uint64 `json:"eventsIn"`
+	// EventsOut is the number of events sent by the collection to its handlers.
+	EventsOut uint64 `json:"eventsOut"`
+	// Transformations is the number of calls to the transformation of the collection.
+	Transformations uint64 `json:"transformations"`
+	// TransformTime is the cumulative time spent in the transformation of the collection.
+	TransformTime time.Duration `json:"transformTime"`
+	// TransformP99 is an upper bound of the 99th percentile of the latency of the transformation, to the next power
+	// of two microseconds.
+	TransformP99 time.Duration `json:"transformP99"`
+	// Fetches are the fetches of the transformation, by name of the fetched collection.
+	Fetches map[string]FetchProfile `json:"fetches,omitempty"`
+}
+
+// FetchProfile is a snapshot of the fetches from the transformations of a collection into one of its dependencies.
+type FetchProfile struct {
+	// Fetches is the number of calls to Fetch.
+	Fetches uint64 `json:"fetches"`
+	// Objects is the total number of objects returned by these calls; Objects/Fetches is the fan-out of the fetch.
+	Objects uint64 `json:"objects"`
+}
+
+func (p *collectionProfile) snapshot() (CollectionProfile, map[collectionUID]FetchProfile) {
+	res := CollectionProfile{
+		EventsIn:        p.eventsIn.Load(),
+		EventsOut:       p.eventsOut.Load(),
+		Transformations: p.transformations.Load(),
+		TransformTime:   p.transformTime.Load(),
+		TransformP99:    p.percentile(0.99),
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	byUID := make(map[collectionUID]FetchProfile, len(p.fetches))
+	if len(p.fetches) > 0 {
+		res.Fetches = make(map[string]FetchProfile, len(p.fetches))
+	}
+	for uid, f := range p.fetches {
+		fp := FetchProfile{Fetches: f.fetches.Load(), Objects: f.objects.Load()}
+		byUID[uid] = fp
+		// Names are not unique; merge the fetches of collections sharing a name
+		cur := res.Fetches[f.name]
+		res.Fetches[f.name] = FetchProfile{Fetches: cur.Fetches + fp.Fetches, Objects: cur.Objects + fp.Objects}
+	}
+	return res, byUID
+}
+
+// profiledCollection is implemented by collections that can report their inputs and activity for the collection graph.
+type profiledCollection interface {
+	// inputs returns the collections whose events are the primary inputs of the collection.
+	inputs() []collectionUID
+	// profile returns the activity of the collection, and its fetches by dependency. It is nil if the collection does not
+	// record its activity.
+	profile() *collectionProfile
+}
+
+func collectionUIDs[T any](cols []Collection[T]) []collectionUID {
+	return slices.MapFilter(cols, func(c Collection[T]) *collectionUID {
+		if u, ok := c.(uidable); ok {
+			uid := u.uid()
+			return &uid
+		}
+		return nil
+	})
+}
+
+// sortedFetchUIDs returns the dependencies of the fetches, for a stable output.
+func sortedFetchUIDs(fetches map[collectionUID]FetchProfile) []collectionUID {
+	return slices.Sort(maps.Keys(fetches))
+}
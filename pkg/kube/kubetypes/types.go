@@ -59,6 +59,23 @@ type DynamicObjectFilter interface {
 	AddHandler(func(selected, deselected sets.String))
 }
 
+// AtomicObjectFilter is a DynamicObjectFilter whose changes are atomic for the clients using it: events are never
+// filtered with a new scope before the handlers registered with AddHandler were notified of the change, so objects
+// entering or leaving the scope are seen exactly once.
+type AtomicObjectFilter interface {
+	DynamicObjectFilter
+	// ReadScope calls f while the scope of the filter cannot change. The scope lock is not reentrant: f must not call
+	// ReadScope, directly or through handlers it calls synchronously, as a nested call deadlocks against a pending change
+	// of the scope. Handlers registered with AddHandler are called while the scope changes, so they must not call
+	// ReadScope either. Changes of the scope wait for f, so f must not block on other clients' events.
+	ReadScope(f func())
+	// FilterDelete returns true if the deletion of obj should be notified. Besides objects in scope, this includes
+	// objects in namespaces that left the scope, whose deletion may not have been seen when the scope changed. These
+	// include objects created after their namespace left the scope, so handlers must tolerate deletions of objects
+	// they never saw.
+	FilterDelete(obj any) bool
+}
+
 type staticFilter struct {
 	f func(obj interface{}) bool
 }
@@ -124,6 +141,31 @@ func (f composedFilter) AddHandler(fn func(selected, deselected sets.String)) {
 	}
 }
 
+func (f composedFilter) ReadScope(fn func()) {
+	if af, ok := f.filter.(AtomicObjectFilter); ok {
+		af.ReadScope(fn)
+		return
+	}
+	fn()
+}
+
+func (f composedFilter) FilterDelete(obj any) bool {
+	for _, filter := range f.extra {
+		if !filter(obj) {
+			return false
+		}
+	}
+	if af, ok := f.filter.(AtomicObjectFilter); ok {
+		return af.FilterDelete(obj)
+	}
+	if f.filter != nil {
+		return f.filter.Filter(obj)
+	}
+	return true
+}
+
+var _ AtomicObjectFilter = composedFilter{}
+
 func ComposeFilters(filter DynamicObjectFilter, extra ...func(obj any) bool) DynamicObjectFilter {
 	return composedFilter{
 		filter: filter,
@@ -49,6 +49,8 @@ type Cluster struct {
 	Client kube.Client
 
 	kubeConfigSha [sha256.Size]byte
+	// credentials allows rotating the bearer token of remote clusters without rebuilding them.
+	credentials *clusterCredentials
 	// SourceSecret identifies the secret that produced this cluster (for remote clusters).
 	SourceSecret types.NamespacedName
 
@@ -76,6 +78,9 @@ type Cluster struct {
 
 	// remoteClusterCollections holds the KRT collections for remote cluster informers.
 	remoteClusterCollections *atomic.Pointer[remoteClusterCollections]
+
+	// health tracks the health checks of remote clusters. It is nil for the config cluster.
+	health *clusterHealth
 }
 
 // remoteClusterCollections holds per-cluster KRT collections.
@@ -131,6 +136,14 @@ func (c *Cluster) Equals(other *Cluster) bool {
 	return c.ID == other.ID && c.kubeConfigSha == other.kubeConfigSha
 }
 
+// appliedKubeConfig returns true if the cluster currently uses kubeConfig, possibly after rotating its credentials.
+func (c *Cluster) appliedKubeConfig(kubeConfig []byte) bool {
+	if c.credentials != nil {
+		return c.credentials.applied(kubeConfig)
+	}
+	return c.kubeConfigSha == sha256.Sum256(kubeConfig)
+}
+
 // GetStop returns the stop channel for the cluster.
 func (c *Cluster) GetStop() <-chan struct{} {
 	return c.stop
@@ -17,6 +17,7 @@ package multicluster
 import (
 	"sync"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/kube/krt"
 	"istio.io/istio/pkg/log"
@@ -161,6 +162,10 @@ func (c *ClusterStore) AllReady() map[string]map[cluster.ID]*Cluster {
 				log.Warnf("remote cluster %s is closed or timed out, omitting it from the clusters collection", cl.ID)
 				continue
 			}
+			if cl.health != nil && cl.health.isDropped() {
+				log.Debugf("remote cluster %s is unhealthy and was dropped, omitting it from the clusters collection", cl.ID)
+				continue
+			}
 			if !cl.HasSynced() {
 				log.Debugf("remote cluster %s registered informers have not been synced up yet. Skipping and will recompute on sync", cl.ID)
 				c.triggerRecomputeOnSync(cl)
@@ -216,12 +221,18 @@ func (c *ClusterStore) Len() int {
 	return out
 }
 
+// HasSynced returns whether all remote clusters have synced. Unless PILOT_REMOTE_CLUSTER_UNHEALTHY_BLOCKS_READINESS is set,
+// clusters failing their health checks or being restored are not waited for.
 func (c *ClusterStore) HasSynced() bool {
 	c.RLock()
 	defer c.RUnlock()
 	for _, clusterMap := range c.remoteClusters {
 		for _, cl := range clusterMap {
 			if !cl.HasSynced() {
+				if !features.RemoteClusterUnhealthyBlocksReadiness && cl.health.unhealthy() {
+					log.Debugf("remote cluster %s is unhealthy, not waiting for its informers to sync", cl.ID)
+					continue
+				}
 				log.Debugf("remote cluster %s registered informers have not been synced up yet", cl.ID)
 				return false
 			}
@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// clusterCredentials holds the bearer token of a remote cluster, so that it can be rotated without rebuilding the
+// client of the cluster and its informers.
+// Credentials read from a token file or an exec plugin are already refreshed by the client itself.
+type clusterCredentials struct {
+	mu    sync.RWMutex
+	token string
+	// inUse is set once the client of the cluster reads the token from here.
+	inUse bool
+	// configSha is the sha of the kubeconfig of the cluster without its tokens. Only kubeconfigs with the same sha can
+	// be applied by rotating the token.
+	configSha [sha256.Size]byte
+	// kubeConfigSha is the sha of the last kubeconfig applied.
+	kubeConfigSha [sha256.Size]byte
+}
+
+func newClusterCredentials(kubeConfig []byte) *clusterCredentials {
+	c := &clusterCredentials{kubeConfigSha: sha256.Sum256(kubeConfig)}
+	if _, sha, err := splitKubeConfigToken(kubeConfig); err == nil {
+		c.configSha = sha
+	}
+	return c
+}
+
+// configOverride makes the client read its bearer token from c. Clients using other credentials are unchanged.
+func (c *clusterCredentials) configOverride(cfg *rest.Config) {
+	if cfg.BearerToken == "" || cfg.BearerTokenFile != "" || cfg.ExecProvider != nil || cfg.AuthProvider != nil {
+		return
+	}
+	c.mu.Lock()
+	c.token = cfg.BearerToken
+	c.inUse = true
+	c.mu.Unlock()
+	cfg.BearerToken = ""
+	cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &bearerTokenRoundTripper{credentials: c, rt: rt}
+	})
+}
+
+// rotate applies kubeConfig by rotating the bearer token, if it only differs from the current kubeconfig by its tokens.
+// It returns false if the cluster must be rebuilt to apply kubeConfig.
+func (c *clusterCredentials) rotate(kubeConfig []byte) bool {
+	if c == nil {
+		return false
+	}
+	token, configSha, err := splitKubeConfigToken(kubeConfig)
+	if err != nil || token == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.inUse || configSha != c.configSha {
+		return false
+	}
+	c.token = token
+	c.kubeConfigSha = sha256.Sum256(kubeConfig)
+	return true
+}
+
+// applied returns true if kubeConfig was the last kubeconfig applied.
+func (c *clusterCredentials) applied(kubeConfig []byte) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.kubeConfigSha == sha256.Sum256(kubeConfig)
+}
+
+func (c *clusterCredentials) bearerToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// splitKubeConfigToken returns the token of the current context of kubeConfig, and the sha of kubeConfig without any
+// token.
+func splitKubeConfigToken(kubeConfig []byte) (string, [sha256.Size]byte, error) {
+	cfg, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return "", [sha256.Size]byte{}, err
+	}
+	token := ""
+	if ctx, f := cfg.Contexts[cfg.CurrentContext]; f {
+		if authInfo, f := cfg.AuthInfos[ctx.AuthInfo]; f {
+			token = authInfo.Token
+		}
+	}
+	for _, authInfo := range cfg.AuthInfos {
+		authInfo.Token = ""
+	}
+	withoutTokens, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return "", [sha256.Size]byte{}, fmt.Errorf("failed to encode kubeconfig: %v", err)
+	}
+	return token, sha256.Sum256(withoutTokens), nil
+}
+
+// bearerTokenRoundTripper sets the current bearer token of a cluster on requests, like the client does for static
+// tokens.
+type bearerTokenRoundTripper struct {
+	credentials *clusterCredentials
+	rt          http.RoundTripper
+}
+
+func (b *bearerTokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		return b.rt.RoundTrip(req)
+	}
+	req = utilnet.CloneRequest(req)
+	req.Header.Set("Authorization", "Bearer "+b.credentials.bearerToken())
+	return b.rt.RoundTrip(req)
+}
+
+func (b *bearerTokenRoundTripper) WrappedRoundTripper() http.RoundTripper { return b.rt }
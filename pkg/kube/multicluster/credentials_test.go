@@ -0,0 +1,157 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/atomic"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func tokenKubeConfig(server, token string) []byte {
+	return fmt.Appendf(nil, `apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: %s
+`, server, token)
+}
+
+func TestClusterCredentialsRotation(t *testing.T) {
+	lastAuthorization := atomic.NewString("")
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuthorization.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"30"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	kubeConfig := tokenKubeConfig(server.URL, "token-a")
+	credentials := newClusterCredentials(kubeConfig)
+	cfg, err := kube.NewUntrustedRestConfig(kubeConfig, credentials.configOverride)
+	assert.NoError(t, err)
+	client, err := kubernetes.NewForConfig(cfg)
+	assert.NoError(t, err)
+	authorization := func() string {
+		_, err := client.Discovery().ServerVersion()
+		assert.NoError(t, err)
+		return lastAuthorization.Load()
+	}
+	assert.Equal(t, authorization(), "Bearer token-a")
+	assert.Equal(t, credentials.applied(kubeConfig), true)
+
+	rotated := tokenKubeConfig(server.URL, "token-b")
+	assert.Equal(t, credentials.rotate(rotated), true)
+	assert.Equal(t, credentials.applied(rotated), true)
+	assert.Equal(t, credentials.applied(kubeConfig), false)
+	assert.Equal(t, authorization(), "Bearer token-b")
+
+	// Anything but the token changing requires a new client.
+	assert.Equal(t, credentials.rotate(tokenKubeConfig("https://other.example.com", "token-c")), false)
+	assert.Equal(t, credentials.rotate([]byte("not a kubeconfig")), false)
+	assert.Equal(t, authorization(), "Bearer token-b")
+}
+
+func TestClusterCredentialsNotRotatable(t *testing.T) {
+	// Clients not using a static token are left alone.
+	kubeConfig := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example.com
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    tokenFile: /var/run/secrets/remote/token
+`)
+	credentials := newClusterCredentials(kubeConfig)
+	cfg := &rest.Config{BearerTokenFile: "/var/run/secrets/remote/token"}
+	credentials.configOverride(cfg)
+	assert.Equal(t, cfg.WrapTransport == nil, true)
+	assert.Equal(t, credentials.rotate(kubeConfig), false)
+}
+
+func TestControllerRotatesCredentials(t *testing.T) {
+	stop := test.NewStop(t)
+	c := buildTestController(t, true)
+	c.controller.ClientBuilder = func(kubeConfig []byte, _ cluster.ID, configOverrides ...func(*rest.Config)) (kube.Client, error) {
+		if _, err := kube.NewUntrustedRestConfig(kubeConfig, configOverrides...); err != nil {
+			return nil, err
+		}
+		return kube.NewFakeClient(), nil
+	}
+	secrets := clienttest.NewWriter[*v1.Secret](t, c.client)
+	c.Run(stop)
+
+	setKubeConfig := func(kubeConfig []byte) {
+		secrets.CreateOrUpdate(makeSecret(secretNamespace, "s0", clusterCredential{"c0", kubeConfig}))
+	}
+	iter := func() int {
+		if comp := c.component.ForCluster("c0"); comp != nil {
+			return comp.Iter
+		}
+		return 0
+	}
+
+	setKubeConfig(tokenKubeConfig("https://remote.example.com", "token-a"))
+	assert.EventuallyEqual(t, func() bool { return c.controller.cs.GetByID("c0") != nil }, true)
+	assert.EventuallyEqual(t, func() bool { return iter() != 0 }, true)
+	initial := c.controller.cs.GetByID("c0")
+	initialIter := iter()
+
+	// Rotating the token keeps the cluster and its components.
+	rotated := tokenKubeConfig("https://remote.example.com", "token-b")
+	setKubeConfig(rotated)
+	assert.EventuallyEqual(t, func() bool { return initial.credentials.applied(rotated) }, true)
+	assert.Equal(t, c.controller.cs.GetByID("c0") == initial, true)
+	assert.Equal(t, iter(), initialIter)
+	assert.Equal(t, initial.credentials.bearerToken(), "token-b")
+
+	// Other changes rebuild the cluster.
+	setKubeConfig(tokenKubeConfig("https://moved.example.com", "token-b"))
+	assert.EventuallyEqual(t, func() bool { return c.controller.cs.GetByID("c0") != initial }, true)
+	assert.EventuallyEqual(t, func() bool { return iter() > initialIter }, true)
+}
@@ -0,0 +1,248 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/monitoring"
+)
+
+const (
+	HealthStatusUnknown   = "unknown"
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+	// HealthStatusDropped indicates the cluster has been unhealthy for longer than
+	// PILOT_REMOTE_CLUSTER_STALE_ENDPOINTS_TIMEOUT, and its services and endpoints were removed until it is healthy again.
+	HealthStatusDropped = "dropped"
+)
+
+var (
+	remoteClusterHealthy = monitoring.NewGauge(
+		"istiod_remote_cluster_healthy",
+		"Whether the API server of a remote cluster passed its last health check.",
+	)
+
+	remoteClusterStaleness = monitoring.NewGauge(
+		"istiod_remote_cluster_staleness_seconds",
+		"Time in seconds since the API server of a remote cluster started failing its health checks, or 0 if it is healthy. "+
+			"The endpoints of the cluster may be stale for as long.",
+	)
+
+	remoteClusterHealthCheckFailures = monitoring.NewSum(
+		"remote_cluster_health_check_failures_total",
+		"Number of failed health checks of remote clusters.",
+	)
+
+	remoteClusterDrops = monitoring.NewSum(
+		"remote_cluster_endpoints_dropped_total",
+		"Number of times the services and endpoints of a remote cluster were removed because it was unhealthy for too long.",
+	)
+)
+
+// probeClusterHealth checks that the API server of the cluster is reachable and accepts our credentials, by reading a
+// namespace, which istiod is always allowed to do.
+func probeClusterHealth(cl *Cluster, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := cl.Client.Kube().CoreV1().Namespaces().Get(ctx, metav1.NamespaceSystem, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		// The API server answered, which is all we care about.
+		return nil
+	}
+	return err
+}
+
+// clusterHealth tracks the results of the health checks of a cluster.
+type clusterHealth struct {
+	mu             sync.RWMutex
+	status         string
+	unhealthySince time.Time
+	lastError      string
+	dropped        bool
+	// restoring is set on clusters built by restoreCluster to replace a dropped cluster.
+	restoring bool
+}
+
+func newClusterHealth() *clusterHealth {
+	return &clusterHealth{status: HealthStatusUnknown}
+}
+
+// record records the result of a health check made at now. It returns how long the cluster has been unhealthy, and
+// whether the result changed the health of the cluster.
+func (h *clusterHealth) record(now time.Time, err error) (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	prev := h.status
+	if err == nil {
+		h.status = HealthStatusHealthy
+		h.unhealthySince = time.Time{}
+		h.lastError = ""
+		return 0, prev != h.status
+	}
+	h.status = HealthStatusUnhealthy
+	h.lastError = err.Error()
+	if h.unhealthySince.IsZero() {
+		h.unhealthySince = now
+	}
+	return now.Sub(h.unhealthySince), prev != h.status
+}
+
+func (h *clusterHealth) isDropped() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dropped
+}
+
+// unhealthy returns whether the cluster failed its last health check, or is being restored after being dropped. It
+// is false for clusters that are not health checked.
+func (h *clusterHealth) unhealthy() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status == HealthStatusUnhealthy || h.restoring
+}
+
+func (h *clusterHealth) setDropped() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dropped = true
+}
+
+// fillDebugInfo sets the health of info. It is a no-op for clusters that are not health checked.
+func (h *clusterHealth) fillDebugInfo(info *cluster.DebugInfo) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	info.Health = h.status
+	if h.dropped {
+		info.Health = HealthStatusDropped
+	}
+	if !h.unhealthySince.IsZero() {
+		since := h.unhealthySince
+		info.UnhealthySince = &since
+	}
+	info.LastHealthCheckError = h.lastError
+}
+
+// runHealthChecks probes the API server of the cluster every interval until the cluster or the controller is stopped.
+func (c *Controller) runHealthChecks(cl *Cluster, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.checkHealth(cl, interval)
+		select {
+		case <-cl.stop:
+			return
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Controller) checkHealth(cl *Cluster, timeout time.Duration) {
+	err := c.healthProbe(cl, timeout)
+	// Only record results for clusters we still manage, to avoid resurrecting metrics of deleted or replaced clusters.
+	if cl.Closed() || c.cs.Get(cl.SourceSecret.String(), cl.ID) != cl {
+		return
+	}
+	unhealthyFor, changed := cl.health.record(time.Now(), err)
+	clusterID := clusterLabel.Value(string(cl.ID))
+	remoteClusterStaleness.With(clusterID).Record(unhealthyFor.Seconds())
+	if err == nil {
+		remoteClusterHealthy.With(clusterID).Record(1)
+		if changed {
+			log.Infof("remote cluster %s is healthy", cl.ID)
+		}
+		if cl.health.isDropped() {
+			c.restoreCluster(cl)
+		}
+		return
+	}
+	remoteClusterHealthy.With(clusterID).Record(0)
+	remoteClusterHealthCheckFailures.With(clusterID).Increment()
+	if changed {
+		log.Warnf("remote cluster %s failed its health check: %v", cl.ID, err)
+	}
+	if timeout := features.RemoteClusterStaleEndpointsTimeout; timeout > 0 && unhealthyFor >= timeout && !cl.health.isDropped() {
+		c.dropCluster(cl, unhealthyFor)
+	}
+}
+
+// dropCluster removes the services and endpoints of an unhealthy cluster. The cluster keeps being health checked, and is
+// rebuilt by restoreCluster once it is healthy again.
+func (c *Controller) dropCluster(cl *Cluster, unhealthyFor time.Duration) {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	// Clusters that never synced are still being set up by Run, and have no last-known endpoints to drop anyway.
+	if cl.Closed() || !cl.initialSync.Load() || c.cs.Get(cl.SourceSecret.String(), cl.ID) != cl {
+		return
+	}
+	log.Warnf("remote cluster %s has been unhealthy for %v, removing its services and endpoints", cl.ID, unhealthyFor.Round(time.Second))
+	cl.health.setDropped()
+	c.handleDelete(cl.ID)
+	// Let the clusters collection omit the dropped cluster.
+	c.cs.TriggerRecomputation()
+	remoteClusterDrops.With(clusterLabel.Value(string(cl.ID))).Increment()
+}
+
+// restoreCluster rebuilds a dropped cluster that is healthy again, from its current configuration. The dropped cluster
+// is stopped once the new one has synced, as for any cluster update.
+func (c *Controller) restoreCluster(cl *Cluster) {
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
+	configKey := cl.SourceSecret.String()
+	if cl.Closed() || c.cs.Get(configKey, cl.ID) != cl {
+		return
+	}
+	cfg := c.source.Get(cl.SourceSecret)
+	if cfg == nil || cfg.Err != nil {
+		// The configuration is being removed or is broken; processing it will take care of the cluster.
+		return
+	}
+	kubeConfig, f := cfg.Data[string(cl.ID)]
+	if !f {
+		return
+	}
+	log.Infof("remote cluster %s is healthy again, restoring its services and endpoints", cl.ID)
+	remoteCluster, err := c.createRemoteCluster(cl.SourceSecret, kubeConfig, string(cl.ID))
+	if err != nil {
+		log.Errorf("failed to restore remote cluster %s: %v", cl.ID, err)
+		return
+	}
+	// The components of the dropped cluster were removed, so the new ones are added rather than swapped in.
+	remoteCluster.Action = Add
+	// Unless PILOT_REMOTE_CLUSTER_UNHEALTHY_BLOCKS_READINESS is set, istiod does not wait for the restored cluster to sync.
+	remoteCluster.health.restoring = true
+	c.runRemoteCluster(configKey, remoteCluster)
+}
+
+func clearClusterHealth(clusterID cluster.ID) {
+	remoteClusterHealthy.With(clusterLabel.Value(string(clusterID))).Record(0)
+	remoteClusterStaleness.With(clusterLabel.Value(string(clusterID))).Record(0)
+}
@@ -0,0 +1,167 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uberatomic "go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// clusterHealths returns the health of the remote clusters.
+func clusterHealths(c *Controller) map[cluster.ID]string {
+	res := map[cluster.ID]string{}
+	for _, info := range c.ListRemoteClusters() {
+		if info.Health != "" {
+			res[info.ID] = info.Health
+		}
+	}
+	return res
+}
+
+func buildHealthTestController(t *testing.T) (testController, *uberatomic.Error) {
+	test.SetForTest(t, &features.RemoteClusterHealthCheckInterval, 10*time.Millisecond)
+	probeErr := uberatomic.NewError(nil)
+	c := buildTestController(t, true)
+	c.controller.healthProbe = func(cl *Cluster, timeout time.Duration) error {
+		if cl.ID == "c0" {
+			return probeErr.Load()
+		}
+		return nil
+	}
+	return c, probeErr
+}
+
+func TestRemoteClusterHealthKeepsStaleEndpoints(t *testing.T) {
+	mt := monitortest.New(t)
+	test.SetForTest(t, &features.RemoteClusterStaleEndpointsTimeout, 0)
+	c, probeErr := buildHealthTestController(t)
+	c.AddSecret("s0", "c0")
+	c.AddSecret("s1", "c1")
+	c.Run(test.NewStop(t))
+
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c0": HealthStatusHealthy, "c1": HealthStatusHealthy})
+	mt.Assert(remoteClusterHealthy.Name(), map[string]string{"cluster": "c0"}, monitortest.Exactly(1))
+
+	probeErr.Store(errors.New("connection refused"))
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c0": HealthStatusUnhealthy, "c1": HealthStatusHealthy})
+	info := slices.FindFunc(c.controller.ListRemoteClusters(), func(info cluster.DebugInfo) bool { return info.ID == "c0" })
+	assert.Equal(t, info.LastHealthCheckError, "connection refused")
+	assert.Equal(t, info.UnhealthySince != nil, true)
+	mt.Assert(remoteClusterHealthy.Name(), map[string]string{"cluster": "c0"}, monitortest.Exactly(0))
+	mt.Assert(remoteClusterStaleness.Name(), map[string]string{"cluster": "c0"}, monitortest.AtLeast(0.02))
+	mt.Assert(remoteClusterHealthCheckFailures.Name(), map[string]string{"cluster": "c0"}, monitortest.AtLeast(2))
+
+	// Without a stale endpoints timeout, the last-known state of the cluster is kept.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, c.component.ForCluster("c0") != nil, true)
+	assert.Equal(t, len(c.controller.Clusters().List()), 2)
+
+	probeErr.Store(nil)
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c0": HealthStatusHealthy, "c1": HealthStatusHealthy})
+	mt.Assert(remoteClusterStaleness.Name(), map[string]string{"cluster": "c0"}, monitortest.Exactly(0))
+}
+
+func TestRemoteClusterHealthDropsStaleEndpoints(t *testing.T) {
+	mt := monitortest.New(t)
+	test.SetForTest(t, &features.RemoteClusterStaleEndpointsTimeout, 50*time.Millisecond)
+	c, probeErr := buildHealthTestController(t)
+	c.AddSecret("s0", "c0")
+	c.AddSecret("s1", "c1")
+	c.Run(test.NewStop(t))
+
+	componentIter := func() int {
+		if comp := c.component.ForCluster("c0"); comp != nil {
+			return comp.Iter
+		}
+		return 0
+	}
+	clusterIDs := func() []cluster.ID {
+		return slices.Sort(slices.Map(c.controller.Clusters().List(), func(cl *Cluster) cluster.ID { return cl.ID }))
+	}
+	assert.EventuallyEqual(t, clusterIDs, []cluster.ID{"c0", "c1"})
+	initialIter := componentIter()
+	assert.Equal(t, initialIter != 0, true)
+
+	probeErr.Store(errors.New("connection refused"))
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c0": HealthStatusDropped, "c1": HealthStatusHealthy})
+	assert.EventuallyEqual(t, componentIter, 0)
+	assert.EventuallyEqual(t, clusterIDs, []cluster.ID{"c1"})
+	mt.Assert(remoteClusterDrops.Name(), map[string]string{"cluster": "c0"}, monitortest.Exactly(1))
+
+	// Once healthy again, the cluster is rebuilt with new components.
+	probeErr.Store(nil)
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c0": HealthStatusHealthy, "c1": HealthStatusHealthy})
+	assert.EventuallyEqual(t, func() bool { return componentIter() > initialIter }, true)
+	assert.EventuallyEqual(t, clusterIDs, []cluster.ID{"c0", "c1"})
+
+	// Deleting the cluster clears its health.
+	c.DeleteSecret("s0")
+	assert.EventuallyEqual(t, func() map[cluster.ID]string { return clusterHealths(c.controller) },
+		map[cluster.ID]string{"c1": HealthStatusHealthy})
+	mt.Assert(remoteClusterHealthy.Name(), map[string]string{"cluster": "c0"}, monitortest.Exactly(0))
+}
+
+func TestRemoteClusterHealthReadiness(t *testing.T) {
+	newCluster := func(id cluster.ID, status string, restoring bool) *Cluster {
+		health := newClusterHealth()
+		health.status = status
+		health.restoring = restoring
+		return &Cluster{
+			ID:                 id,
+			stop:               make(chan struct{}),
+			initialSync:        uberatomic.NewBool(false),
+			initialSyncTimeout: uberatomic.NewBool(false),
+			health:             health,
+		}
+	}
+	cases := []struct {
+		name   string
+		health string
+		// restoring is set for clusters replacing a dropped cluster
+		restoring bool
+		block     bool
+		synced    bool
+	}{
+		{name: "unknown", health: HealthStatusUnknown, synced: false},
+		{name: "healthy", health: HealthStatusHealthy, synced: false},
+		{name: "unhealthy", health: HealthStatusUnhealthy, synced: true},
+		{name: "restoring", health: HealthStatusHealthy, restoring: true, synced: true},
+		{name: "unhealthy blocking", health: HealthStatusUnhealthy, block: true, synced: false},
+		{name: "restoring blocking", health: HealthStatusHealthy, restoring: true, block: true, synced: false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			test.SetForTest(t, &features.RemoteClusterUnhealthyBlocksReadiness, tt.block)
+			cs := NewClustersStore()
+			cs.Store("s0", "c0", newCluster("c0", tt.health, tt.restoring))
+			assert.Equal(t, cs.HasSynced(), tt.synced)
+		})
+	}
+}
@@ -15,14 +15,15 @@
 package multicluster
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"go.uber.org/atomic"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -30,6 +31,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/mesh/meshwatcher"
+	"istio.io/istio/pkg/config/schema/gvr"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
 	"istio.io/istio/pkg/kube/kclient"
@@ -84,6 +86,7 @@ type handler interface {
 }
 
 // ClientBuilder builds a new kube.Client from a kubeconfig. Mocked out for testing
+// The configOverrides must be applied to the rest.Config of the client; they are used to rotate credentials in place.
 type ClientBuilder = func(kubeConfig []byte, clusterId cluster.ID, configOverrides ...func(*rest.Config)) (kube.Client, error)
 
 // ControllerOptions holds the options for creating a new Controller.
@@ -118,6 +121,11 @@ type Controller struct {
 	handlers    []handler
 
 	clusters krt.Collection[*Cluster]
+
+	// clustersMu serializes processing remote configs with dropping and restoring unhealthy clusters.
+	clustersMu sync.Mutex
+	// healthProbe checks the health of remote clusters. Mocked out for testing
+	healthProbe func(cl *Cluster, timeout time.Duration) error
 }
 
 // NewController returns a new secret controller
@@ -140,7 +148,7 @@ func NewController(opts ControllerOptions) *Controller {
 			}
 			log.Info("Successfully retrieved incluster config.")
 
-			localKubeClient, err := kube.NewClient(kube.NewClientConfigForRestConfig(cfg), opts.ClusterID)
+			localKubeClient, err := kube.NewClient(kube.NewClientConfigForRestConfig(cfg), opts.ClusterID, ClientOptions()...)
 			if err != nil {
 				log.Errorf("Could not create a client to access local cluster API server: %v", err)
 				return nil
@@ -178,6 +186,7 @@ func NewController(opts ControllerOptions) *Controller {
 		meshWatcher:     opts.MeshConfig,
 		debugger:        opts.Debugger,
 		stop:            make(chan struct{}),
+		healthProbe:     probeClusterHealth,
 	}
 
 	if opts.ClientBuilder != nil {
@@ -358,6 +367,8 @@ func (c *Controller) HasSynced() bool {
 
 func (c *Controller) processItem(key types.NamespacedName) error {
 	log.Infof("processing remote config event for %s", key)
+	c.clustersMu.Lock()
+	defer c.clustersMu.Unlock()
 	cfg := c.source.Get(key)
 	if cfg != nil {
 		if cfg.Err != nil {
@@ -378,6 +389,21 @@ func (c *Controller) processItem(key types.NamespacedName) error {
 	return nil
 }
 
+// ClientOptions returns the options of the clients of istiod. If enabled, they strip the fields istiod never reads from
+// the Pods, Nodes, EndpointSlices and Secrets of the informers that do not set their own transform. Each transform must
+// retain all the fields read by any of the controllers of istiod.
+func ClientOptions() []kube.ClientOption {
+	if !features.StripInformerFields {
+		return nil
+	}
+	return []kube.ClientOption{kube.WithObjectTransforms(map[schema.GroupVersionResource]func(obj any) (any, error){
+		gvr.Pod:           kube.StripPodUnusedFields,
+		gvr.Node:          kube.StripNodeUnusedFields,
+		gvr.EndpointSlice: kube.StripEndpointSliceUnusedFields,
+		gvr.Secret:        kube.StripSecretUnusedFields,
+	})}
+}
+
 // DefaultBuildClientsFromConfig creates kube.Clients from the provided kubeconfig. This is overridden for testing only
 func DefaultBuildClientsFromConfig(kubeConfig []byte, clusterID cluster.ID, configOverrides ...func(*rest.Config)) (kube.Client, error) {
 	restConfig, err := kube.NewUntrustedRestConfig(kubeConfig, configOverrides...)
@@ -385,7 +411,7 @@ func DefaultBuildClientsFromConfig(kubeConfig []byte, clusterID cluster.ID, conf
 		return nil, err
 	}
 
-	clients, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig), clusterID)
+	clients, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig), clusterID, ClientOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kube clients: %v", err)
 	}
@@ -399,7 +425,9 @@ func DefaultBuildClientsFromConfig(kubeConfig []byte, clusterID cluster.ID, conf
 }
 
 func (c *Controller) createRemoteCluster(secretKey types.NamespacedName, kubeConfig []byte, clusterID string) (*Cluster, error) {
-	clients, err := c.ClientBuilder(kubeConfig, cluster.ID(clusterID), c.configOverrides...)
+	credentials := newClusterCredentials(kubeConfig)
+	configOverrides := append(slices.Clone(c.configOverrides), credentials.configOverride)
+	clients, err := c.ClientBuilder(kubeConfig, cluster.ID(clusterID), configOverrides...)
 	if err != nil {
 		return nil, err
 	}
@@ -411,9 +439,11 @@ func (c *Controller) createRemoteCluster(secretKey types.NamespacedName, kubeCon
 		initialSync:              atomic.NewBool(false),
 		initialSyncTimeout:       atomic.NewBool(false),
 		kubeConfigSha:            sha256.Sum256(kubeConfig),
+		credentials:              credentials,
 		syncStatusCallback:       c.onClusterSyncStatusChange,
 		SyncedCh:                 make(chan struct{}),
 		remoteClusterCollections: atomic.NewPointer[remoteClusterCollections](nil),
+		health:                   newClusterHealth(),
 	}, nil
 }
 
@@ -441,11 +471,15 @@ func (c *Controller) addRemoteConfig(name types.NamespacedName, cfg *remoteConfi
 		if prev = c.cs.Get(configKey, cluster.ID(clusterID)); prev != nil {
 			action = Update
 			// clusterID must be unique even across multiple secrets
-			kubeConfigSha := sha256.Sum256(kubeConfig)
-			if bytes.Equal(kubeConfigSha[:], prev.kubeConfigSha[:]) {
+			if prev.appliedKubeConfig(kubeConfig) {
 				logger.Infof("skipping update (kubeconfig are identical)")
 				continue
 			}
+			// If only the token changed, rotate it in place rather than rebuilding the cluster and its informers.
+			if prev.credentials.rotate(kubeConfig) {
+				logger.Infof("rotated cluster credentials")
+				continue
+			}
 			// Don't stop the previous cluster here - it will be stopped after the new cluster syncs.
 			// This ensures zero service disruption during credential rotation.
 		} else if c.cs.Contains(cluster.ID(clusterID)) {
@@ -465,18 +499,26 @@ func (c *Controller) addRemoteConfig(name types.NamespacedName, cfg *remoteConfi
 		// Set the action before running so constructors can check it
 		remoteCluster.Action = action
 
-		// We run cluster async so we do not block, as this requires actually connecting to the cluster and loading configuration.
-		// Swap stores the new cluster and returns a PendingClusterSwap that manages cleanup of the previous cluster.
-		swap := c.cs.Swap(configKey, remoteCluster.ID, remoteCluster)
-		go func() {
-			remoteCluster.Run(c.meshWatcher, c.handlers, action, swap, c.debugger)
-		}()
+		c.runRemoteCluster(configKey, remoteCluster)
 	}
 
 	log.Infof("Number of remote clusters: %d", c.cs.Len())
 	return errs.ErrorOrNil()
 }
 
+// runRemoteCluster stores the cluster, replacing any previous one, and runs it.
+func (c *Controller) runRemoteCluster(configKey string, remoteCluster *Cluster) {
+	// We run cluster async so we do not block, as this requires actually connecting to the cluster and loading configuration.
+	// Swap stores the new cluster and returns a PendingClusterSwap that manages cleanup of the previous cluster.
+	swap := c.cs.Swap(configKey, remoteCluster.ID, remoteCluster)
+	go func() {
+		remoteCluster.Run(c.meshWatcher, c.handlers, remoteCluster.Action, swap, c.debugger)
+	}()
+	if interval := features.RemoteClusterHealthCheckInterval; interval > 0 {
+		go c.runHealthChecks(remoteCluster, interval)
+	}
+}
+
 func (c *Controller) deleteRemoteConfig(configKey string) {
 	for _, cluster := range c.cs.GetExistingClustersFor(configKey) {
 		if cluster.ID == c.configClusterID {
@@ -496,6 +538,7 @@ func (c *Controller) deleteCluster(configKey string, cluster *Cluster) {
 	c.handleDelete(cluster.ID)
 	c.cs.Delete(configKey, cluster.ID)
 	c.clearClusterSyncState(cluster.ID)
+	clearClusterHealth(cluster.ID)
 	cluster.Client.Shutdown() // Shutdown all of the informers so that the goroutines won't leak
 
 	log.Infof("Number of remote clusters: %d", c.cs.Len())
@@ -529,11 +572,13 @@ func (c *Controller) ListRemoteClusters() []cluster.DebugInfo {
 	// Append each cluster derived from secrets
 	for secretName, clusters := range c.cs.All() {
 		for clusterID, c := range clusters {
-			out = append(out, cluster.DebugInfo{
+			info := cluster.DebugInfo{
 				ID:         clusterID,
 				SecretName: secretName,
 				SyncStatus: c.SyncStatus(),
-			})
+			}
+			c.health.fillDebugInfo(&info)
+			out = append(out, info)
 		}
 	}
 	return out
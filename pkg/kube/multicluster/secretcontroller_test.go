@@ -379,8 +379,8 @@ func TestListRemoteClusters(t *testing.T) {
 	// before sync
 	assert.EventuallyEqual(t, c.controller.ListRemoteClusters, []cluster.DebugInfo{
 		{ID: "config", SyncStatus: SyncStatusSyncing},
-		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSyncing},
-		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSyncing},
+		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSyncing, Health: HealthStatusHealthy},
+		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSyncing, Health: HealthStatusHealthy},
 	})
 	assert.EventuallyEqual(t, func() int { return len(c.component.All()) }, 3)
 
@@ -392,16 +392,16 @@ func TestListRemoteClusters(t *testing.T) {
 	}
 	assert.EventuallyEqual(t, c.controller.ListRemoteClusters, []cluster.DebugInfo{
 		{ID: "config", SyncStatus: SyncStatusSynced},
-		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced},
-		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSyncing},
+		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced, Health: HealthStatusHealthy},
+		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSyncing, Health: HealthStatusHealthy},
 	})
 
 	// Sync the last one
 	c.component.ForCluster("c1").Synced.Store(true)
 	assert.EventuallyEqual(t, c.controller.ListRemoteClusters, []cluster.DebugInfo{
 		{ID: "config", SyncStatus: SyncStatusSynced},
-		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced},
-		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSynced},
+		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced, Health: HealthStatusHealthy},
+		{ID: "c1", SecretName: "istio-system/s1", SyncStatus: SyncStatusSynced, Health: HealthStatusHealthy},
 	})
 
 	// Verify SourceSecret is set correctly on remote clusters
@@ -425,7 +425,7 @@ func TestListRemoteClusters(t *testing.T) {
 	c.DeleteSecret("s1")
 	assert.EventuallyEqual(t, c.controller.ListRemoteClusters, []cluster.DebugInfo{
 		{ID: "config", SyncStatus: SyncStatusSynced},
-		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced},
+		{ID: "c0", SecretName: "istio-system/s0", SyncStatus: SyncStatusSynced, Health: HealthStatusHealthy},
 	})
 	assert.EventuallyEqual(t, getSimpleClusters, []simpleCluster{
 		{ID: "c0", SourceSecret: types.NamespacedName{Name: "s0", Namespace: secretNamespace}},
@@ -16,6 +16,7 @@ package namespace
 
 import (
 	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,14 +38,54 @@ type DiscoveryFilter func(obj any) bool
 
 type ManualSyncWaiter func(stop <-chan struct{})
 
+// maxFilterTransitions is the number of changes of the filter kept for debugging.
+const maxFilterTransitions = 50
+
 type discoveryNamespacesFilter struct {
+	// transitionLock is held for writing while the filter changes and its handlers are notified, and for reading by
+	// clients handling events, so that no event is filtered with a new scope before the handlers saw the change.
+	// It only guards the scope: Filter, FilterDelete and the clients' listers take lock alone, so that event handlers
+	// can use them while a change of the scope is pending. ReadScope is only called by kclient, once per event, and is
+	// never re-entered.
+	transitionLock      sync.RWMutex
 	lock                sync.RWMutex
 	namespaces          kclient.Client[*corev1.Namespace]
 	discoveryNamespaces sets.String
 	discoverySelectors  []labels.Selector // nil if discovery selectors are not specified, permits all namespaces for discovery
-	handlers            []func(added, removed sets.String)
+	// deselectedNamespaces are the existing namespaces that left the scope of the filter. Deletions of their objects are
+	// still notified, as they may not have been seen when the namespace left the scope.
+	deselectedNamespaces sets.String
+	handlers             []func(added, removed sets.String)
+	transitions          []FilterTransition
+}
+
+// FilterTransition is a change of the namespaces selected by the discovery filter.
+type FilterTransition struct {
+	Time       time.Time `json:"time"`
+	Reason     string    `json:"reason"`
+	Selectors  []string  `json:"selectors,omitempty"`
+	Selected   []string  `json:"selected,omitempty"`
+	Deselected []string  `json:"deselected,omitempty"`
+}
+
+// FilterDump is the state of the discovery filter, for debugging.
+type FilterDump struct {
+	// Selectors are the active discovery selectors. If empty, all namespaces are selected.
+	Selectors   []string           `json:"selectors,omitempty"`
+	Namespaces  []string           `json:"namespaces"`
+	Transitions []FilterTransition `json:"transitions,omitempty"`
+}
+
+// DebuggableFilter is a filter that can report its state for debugging.
+type DebuggableFilter interface {
+	Dump() FilterDump
 }
 
+var (
+	_ kubetypes.AtomicObjectFilter = &discoveryNamespacesFilter{}
+	_ DebuggableFilter             = &discoveryNamespacesFilter{}
+)
+
 func newDiscoveryNamespacesFilter(
 	namespaces kclient.Client[*corev1.Namespace],
 	mesh mesh.Watcher,
@@ -53,11 +94,12 @@ func newDiscoveryNamespacesFilter(
 ) *discoveryNamespacesFilter {
 	// convert LabelSelectors to Selectors
 	f := &discoveryNamespacesFilter{
-		namespaces:          namespaces,
-		discoveryNamespaces: sets.New[string](),
+		namespaces:           namespaces,
+		discoveryNamespaces:  sets.New[string](),
+		deselectedNamespaces: sets.New[string](),
 	}
 	reg := mesh.AddMeshHandler(func() {
-		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), true)
+		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), true, "mesh config changed")
 	})
 
 	// Clean up mesh handler on stop
@@ -68,16 +110,21 @@ func newDiscoveryNamespacesFilter(
 
 	namespaces.AddEventHandler(controllers.EventHandler[*corev1.Namespace]{
 		AddFunc: func(ns *corev1.Namespace) {
+			f.transitionLock.Lock()
+			defer f.transitionLock.Unlock()
 			f.lock.Lock()
 			created := f.namespaceCreatedLocked(ns.ObjectMeta)
 			f.lock.Unlock()
 			// In rare cases, a namespace may be created after objects in the namespace, because there is no synchronization between watches
 			// So we need to notify if we started selecting namespace
 			if created {
+				f.recordTransition("namespace created", sets.New(ns.Name), nil)
 				f.notifyHandlers(sets.New(ns.Name), nil)
 			}
 		},
 		UpdateFunc: func(oldObj, newObj *corev1.Namespace) {
+			f.transitionLock.Lock()
+			defer f.transitionLock.Unlock()
 			f.lock.Lock()
 			membershipChanged, namespaceAdded := f.namespaceUpdatedLocked(oldObj.ObjectMeta, newObj.ObjectMeta)
 			f.lock.Unlock()
@@ -88,6 +135,7 @@ func newDiscoveryNamespacesFilter(
 					removed = added
 					added = nil
 				}
+				f.recordTransition("namespace labels changed", added, removed)
 				f.notifyHandlers(added, removed)
 			}
 		},
@@ -105,7 +153,7 @@ func newDiscoveryNamespacesFilter(
 	namespaces.Start(stop)
 	if wait {
 		kube.WaitForCacheSync("discovery filter", stop, namespaces.HasSynced)
-		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), false)
+		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), false, "initial sync")
 	}
 	return f
 }
@@ -128,10 +176,11 @@ func NewNonBlockingDiscoveryNamespacesFilter(
 	f := newDiscoveryNamespacesFilter(namespaces, mesh, stop, false)
 	return f, func(stop <-chan struct{}) {
 		kube.WaitForCacheSync("discovery filter", stop, namespaces.HasSynced)
-		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), false)
+		f.selectorsChanged(mesh.Mesh().GetDiscoverySelectors(), false, "initial sync")
 	}
 }
 
+// notifyHandlers notifies the handlers of a change of the filter. It must be called with transitionLock held.
 func (d *discoveryNamespacesFilter) notifyHandlers(added sets.Set[string], removed sets.String) {
 	// Clone handlers; we handle dynamic handlers so they can change after the filter has started.
 	// Important: handlers are not called under the lock. If they are, then handlers which eventually call discoveryNamespacesFilter.Filter
@@ -166,6 +215,68 @@ func (d *discoveryNamespacesFilter) Filter(obj any) bool {
 	return d.discoveryNamespaces.Contains(ns)
 }
 
+// ReadScope calls f while the namespaces selected by the filter cannot change. It must not be re-entered from f, see
+// kubetypes.AtomicObjectFilter.
+func (d *discoveryNamespacesFilter) ReadScope(f func()) {
+	d.transitionLock.RLock()
+	defer d.transitionLock.RUnlock()
+	f()
+}
+
+func (d *discoveryNamespacesFilter) FilterDelete(obj any) bool {
+	if d.Filter(obj) {
+		return true
+	}
+	object := controllers.ExtractObject(obj)
+	if object == nil {
+		return false
+	}
+	if _, ok := object.(*corev1.Namespace); ok {
+		// The namespace itself was deleted by the handlers when it left the scope.
+		return false
+	}
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.deselectedNamespaces.Contains(object.GetNamespace())
+}
+
+// Dump returns the state of the filter, for debugging.
+func (d *discoveryNamespacesFilter) Dump() FilterDump {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return FilterDump{
+		Selectors:   selectorStrings(d.discoverySelectors),
+		Namespaces:  sets.SortedList(d.discoveryNamespaces),
+		Transitions: slices.Clone(d.transitions),
+	}
+}
+
+// recordTransition records a change of the selected namespaces, and keeps track of the deselected ones.
+func (d *discoveryNamespacesFilter) recordTransition(reason string, selected, deselected sets.String) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.recordTransitionLocked(reason, selected, deselected)
+}
+
+func (d *discoveryNamespacesFilter) recordTransitionLocked(reason string, selected, deselected sets.String) {
+	d.deselectedNamespaces.DeleteAllSet(selected)
+	d.deselectedNamespaces.Merge(deselected)
+	d.transitions = append(d.transitions, FilterTransition{
+		Time:       time.Now(),
+		Reason:     reason,
+		Selectors:  selectorStrings(d.discoverySelectors),
+		Selected:   sets.SortedList(selected),
+		Deselected: sets.SortedList(deselected),
+	})
+	if len(d.transitions) > maxFilterTransitions {
+		d.transitions = slices.Clone(d.transitions[len(d.transitions)-maxFilterTransitions:])
+	}
+}
+
+func selectorStrings(selectors []labels.Selector) []string {
+	return slices.Map(selectors, labels.Selector.String)
+}
+
 func extractObjectNamespace(obj any) (string, bool) {
 	if ns, ok := obj.(string); ok {
 		return ns, true
@@ -181,11 +292,16 @@ func extractObjectNamespace(obj any) (string, bool) {
 	return object.GetNamespace(), true
 }
 
-// SelectorsChanged initializes the discovery filter state with the discovery selectors and selected namespaces
+// SelectorsChanged initializes the discovery filter state with the discovery selectors and selected namespaces.
+// The handlers are notified of the namespaces entering or leaving the scope before any event is filtered with the new
+// selectors.
 func (d *discoveryNamespacesFilter) selectorsChanged(
 	discoverySelectors []*meshapi.LabelSelector,
 	notify bool,
+	reason string,
 ) {
+	d.transitionLock.Lock()
+	defer d.transitionLock.Unlock()
 	// Call closure to allow safe defer lock handling
 	selectedNamespaces, deselectedNamespaces := func() (sets.String, sets.String) {
 		d.lock.Lock()
@@ -207,14 +323,13 @@ func (d *discoveryNamespacesFilter) selectorsChanged(
 
 		// range over all namespaces to get discovery namespaces
 		for _, ns := range namespaceList {
-			for _, selector := range selectors {
-				if selector.Matches(labels.Set(ns.Labels)) {
-					newDiscoveryNamespaces.Insert(ns.Name)
-				}
-			}
 			// omitting discoverySelectors indicates discovering all namespaces
 			if len(selectors) == 0 {
-				for _, ns := range namespaceList {
+				newDiscoveryNamespaces.Insert(ns.Name)
+				continue
+			}
+			for _, selector := range selectors {
+				if selector.Matches(labels.Set(ns.Labels)) {
 					newDiscoveryNamespaces.Insert(ns.Name)
 				}
 			}
@@ -222,13 +337,20 @@ func (d *discoveryNamespacesFilter) selectorsChanged(
 
 		// update filter state
 		oldDiscoveryNamespaces := d.discoveryNamespaces
+		oldDiscoverySelectors := d.discoverySelectors
 		d.discoveryNamespaces = newDiscoveryNamespaces
 		d.discoverySelectors = selectors
 		if notify {
 			selectedNamespaces := newDiscoveryNamespaces.Difference(oldDiscoveryNamespaces)
 			deselectedNamespaces := oldDiscoveryNamespaces.Difference(newDiscoveryNamespaces)
+			// The mesh config changes for many other reasons; only record actual changes of the filter.
+			if len(selectedNamespaces) > 0 || len(deselectedNamespaces) > 0 ||
+				!slices.Equal(selectorStrings(oldDiscoverySelectors), selectorStrings(selectors)) {
+				d.recordTransitionLocked(reason, selectedNamespaces, deselectedNamespaces)
+			}
 			return selectedNamespaces, deselectedNamespaces
 		}
+		d.recordTransitionLocked(reason, nil, nil)
 		return nil, nil
 	}()
 	if notify {
@@ -262,6 +384,7 @@ func (d *discoveryNamespacesFilter) namespaceUpdatedLocked(oldNs, newNs metav1.O
 // namespaceDeletedLocked : if deleted namespace was a member, remove it
 func (d *discoveryNamespacesFilter) namespaceDeletedLocked(ns metav1.ObjectMeta) {
 	d.discoveryNamespaces.Delete(ns.Name)
+	d.deselectedNamespaces.Delete(ns.Name)
 }
 
 // AddHandler registers a handler on namespace, which will be triggered when namespace selected or deselected.
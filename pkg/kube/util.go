@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -435,6 +436,43 @@ func StripPodUnusedFields(obj any) (any, error) {
 	return obj, nil
 }
 
+// StripEndpointSliceUnusedFields is the transform function for shared EndpointSlice informers,
+// it removes unused fields from objects before they are stored in the cache to save memory.
+func StripEndpointSliceUnusedFields(obj any) (any, error) {
+	t, ok := obj.(metav1.ObjectMetaAccessor)
+	if !ok {
+		// shouldn't happen
+		return obj, nil
+	}
+	// ManagedFields is large and we never use it
+	t.GetObjectMeta().SetManagedFields(nil)
+	// The last applied configuration duplicates the whole object, and is never used
+	delete(t.GetObjectMeta().GetAnnotations(), corev1.LastAppliedConfigAnnotation)
+	// Topology hints and the deprecated topology are never used
+	if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+		for i := range slice.Endpoints {
+			slice.Endpoints[i].DeprecatedTopology = nil
+			slice.Endpoints[i].Hints = nil
+		}
+	}
+	return obj, nil
+}
+
+// StripSecretUnusedFields is the transform function for shared Secret informers,
+// it removes unused fields from objects before they are stored in the cache to save memory.
+func StripSecretUnusedFields(obj any) (any, error) {
+	t, ok := obj.(metav1.ObjectMetaAccessor)
+	if !ok {
+		// shouldn't happen
+		return obj, nil
+	}
+	// ManagedFields is large and we never use it
+	t.GetObjectMeta().SetManagedFields(nil)
+	// The last applied configuration of a Secret applied with kubectl holds a second copy of all its data
+	delete(t.GetObjectMeta().GetAnnotations(), corev1.LastAppliedConfigAnnotation)
+	return obj, nil
+}
+
 // sanitizeKubeConfig sanitizes a kubeconfig file to strip out insecure settings which may leak
 // confidential materials.
 // See https://github.com/kubernetes/kubectl/issues/697
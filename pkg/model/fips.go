@@ -136,3 +136,39 @@ func EnforceCompliance(ctx *tls.CommonTlsContext) {
 		return
 	}
 }
+
+// goCurveIDs maps the key exchange group names accepted in MeshConfig, which follow the
+// BoringSSL naming used by Envoy, to their Go equivalents.
+var goCurveIDs = map[string]gotls.CurveID{
+	"P-256":          gotls.CurveP256,
+	"P-384":          gotls.CurveP384,
+	"P-521":          gotls.CurveP521,
+	"X25519":         gotls.X25519,
+	"X25519MLKEM768": gotls.X25519MLKEM768,
+}
+
+// ApplyGoCurvePreferences sets the key exchange groups of a Go TLS config from MeshConfig group names,
+// such as the hybrid post-quantum X25519MLKEM768. Groups not supported by Go are skipped.
+// This should be called before EnforceGoCompliance, which takes precedence.
+func ApplyGoCurvePreferences(ctx *gotls.Config, curves []string) {
+	if len(curves) == 0 {
+		return
+	}
+	prefs := make([]gotls.CurveID, 0, len(curves))
+	for _, c := range curves {
+		id, ok := goCurveIDs[c]
+		if !ok {
+			log.Warnf("ignoring ECDH curve %q not supported by Go TLS", c)
+			continue
+		}
+		prefs = append(prefs, id)
+	}
+	if len(prefs) == 0 {
+		return
+	}
+	// Hybrid post-quantum key exchange is only defined for TLS 1.3.
+	if len(prefs) == 1 && prefs[0] == gotls.X25519MLKEM768 {
+		ctx.MinVersion = gotls.VersionTLS13
+	}
+	ctx.CurvePreferences = prefs
+}
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	gotls "crypto/tls"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyGoCurvePreferences(t *testing.T) {
+	cases := []struct {
+		name       string
+		curves     []string
+		want       []gotls.CurveID
+		minVersion uint16
+	}{
+		{
+			name:       "unset",
+			minVersion: gotls.VersionTLS12,
+		},
+		{
+			name:       "hybrid with fallback",
+			curves:     []string{"X25519MLKEM768", "X25519", "P-256"},
+			want:       []gotls.CurveID{gotls.X25519MLKEM768, gotls.X25519, gotls.CurveP256},
+			minVersion: gotls.VersionTLS12,
+		},
+		{
+			name:       "hybrid only",
+			curves:     []string{"X25519MLKEM768"},
+			want:       []gotls.CurveID{gotls.X25519MLKEM768},
+			minVersion: gotls.VersionTLS13,
+		},
+		{
+			name:       "unsupported curves are skipped",
+			curves:     []string{"P-224", "X25519Kyber768Draft00", "P-384"},
+			want:       []gotls.CurveID{gotls.CurveP384},
+			minVersion: gotls.VersionTLS12,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &gotls.Config{MinVersion: gotls.VersionTLS12}
+			ApplyGoCurvePreferences(cfg, tt.curves)
+			if diff := cmp.Diff(tt.want, cfg.CurvePreferences); diff != "" {
+				t.Errorf("unexpected curve preferences: %v", diff)
+			}
+			if cfg.MinVersion != tt.minVersion {
+				t.Errorf("expected min version %x, got %x", tt.minVersion, cfg.MinVersion)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package security
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 
+	"istio.io/api/annotation"
 	"istio.io/istio/pkg/env"
 	istiolog "istio.io/istio/pkg/log"
 )
@@ -72,6 +74,13 @@ const (
 	// SDSExternalCredentialPrefix is the prefix for the credentialName which will utilize external SDS connections defined via CredentialNameSocketPath
 	SDSExternalCredentialPrefix = "sds://"
 
+	// UserSecretPrefix is the prefix for the credentialName of user-defined secrets. These are configured per pod
+	// with UserSecretsAnnotation and served by the local agent, from files or from a local secret store socket.
+	UserSecretPrefix = "user://"
+
+	// UserSecretCaSuffix is the suffix of the resource name serving the CA certificates of a user-defined secret.
+	UserSecretCaSuffix = "-cacert"
+
 	// WorkloadIdentityCredentialsPath is the well-known path to a folder with workload certificate files.
 	WorkloadIdentityCredentialsPath = "./var/run/secrets/workload-spiffe-credentials"
 
@@ -130,6 +139,18 @@ const (
 	CACRLFilePath = "/var/run/secrets/istio/crl/ca-crl.pem"
 )
 
+// UserSecretsAnnotation is the pod annotation holding the JSON encoded user-defined secrets, keyed by name.
+// It is defined like the annotations of istio.io/api, and recognized by the annotation analyzers.
+var UserSecretsAnnotation = annotation.Instance{
+	Name: "sidecar.istio.io/userSecrets",
+	Description: "Specifies the user-defined secrets (as a JSON object keyed by name) served by the " +
+		"sidecar over SDS with the user:// prefix, from files or from a local secret store socket.",
+	FeatureStatus: annotation.Alpha,
+	Resources: []annotation.ResourceTypes{
+		annotation.Pod,
+	},
+}
+
 // TODO: For 1.8, make sure MeshConfig is updated with those settings,
 // they should be dynamic to allow migrations without restart.
 // Both are critical.
@@ -153,6 +174,11 @@ const (
 	// This is constrained to only allow identities in CATrustedNodeAccounts, and only to impersonate identities
 	// on their node.
 	ImpersonatedIdentity = "ImpersonatedIdentity"
+
+	// AttestationTypeHeader carries the type of the workload attestation evidence sent to the CA.
+	AttestationTypeHeader = "istio-attestation-type"
+	// AttestationEvidenceHeader carries the base64 encoded workload attestation evidence sent to the CA.
+	AttestationEvidenceHeader = "istio-attestation-evidence"
 )
 
 type ImpersonatedIdentityContextKey struct{}
@@ -272,6 +298,22 @@ type Options struct {
 
 	// Extra headers to add to the CA connection.
 	CAHeaders map[string]string
+
+	// Attestor provides platform attestation evidence sent along certificate signing requests.
+	// If nil, no attestation evidence is sent.
+	Attestor Attestor
+
+	// UserSecrets are the user-defined secrets served over SDS, keyed by name.
+	UserSecrets map[string]UserSecret
+}
+
+// Attestor produces evidence of the identity of the platform the agent runs on, allowing the CA
+// to authenticate workloads, such as VMs, that have no Kubernetes token or certificate yet.
+type Attestor interface {
+	// Type returns the attestation type, which selects the verifier on the CA side.
+	Type() string
+	// Evidence returns the attestation evidence.
+	Evidence(ctx context.Context) ([]byte, error)
 }
 
 // Client interface defines the clients need to implement to talk to CA for CSR.
@@ -329,6 +371,7 @@ type AuthSource int
 const (
 	AuthSourceClientCertificate AuthSource = iota
 	AuthSourceIDToken
+	AuthSourceAttestation
 )
 
 const (
@@ -370,6 +413,11 @@ type Caller struct {
 	Identities []string
 
 	KubernetesInfo KubernetesInfo
+
+	// OnIssued, if set, is called once a certificate was issued to the caller. Authenticators accepting single
+	// use credentials consume them there, so that a failed issuance does not consume the credential. If it fails,
+	// the certificate is not returned to the caller.
+	OnIssued func() error
 }
 
 // KubernetesInfo defines Kubernetes specific information extracted from the caller.
@@ -582,3 +630,59 @@ func SdsCertificateConfigFromResourceNameForOSCACert(resource string) (SdsCertif
 	}
 	return SdsCertificateConfig{"", "", resource}, true
 }
+
+// UserSecret is the source of a user-defined secret, served over SDS as UserSecretPrefix + name, and
+// UserSecretPrefix + name + UserSecretCaSuffix for its CA certificates.
+type UserSecret struct {
+	// CertificateChain is the path to the certificate chain file.
+	CertificateChain string `json:"certificateChain,omitempty"`
+	// PrivateKey is the path to the private key file.
+	PrivateKey string `json:"privateKey,omitempty"`
+	// CaCertificates is the path to the CA certificates file.
+	CaCertificates string `json:"caCertificates,omitempty"`
+	// Socket is the path to the Unix Domain Socket of a local secret store serving the SDS protocol.
+	// Exclusive with the file paths.
+	Socket string `json:"socket,omitempty"`
+	// ResourceName is the resource requested from Socket. Defaults to the name of the secret; the CA
+	// certificates are requested with UserSecretCaSuffix appended.
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// ParseUserSecrets parses and validates the value of the UserSecretsAnnotation.
+func ParseUserSecrets(value string) (map[string]UserSecret, error) {
+	secrets := map[string]UserSecret{}
+	if value == "" {
+		return secrets, nil
+	}
+	if err := json.Unmarshal([]byte(value), &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse user secrets: %v", err)
+	}
+	for name, s := range secrets {
+		if name == "" || strings.HasSuffix(name, UserSecretCaSuffix) {
+			return nil, fmt.Errorf("invalid user secret name %q", name)
+		}
+		hasFiles := s.CertificateChain != "" || s.PrivateKey != "" || s.CaCertificates != ""
+		switch {
+		case s.Socket != "" && hasFiles:
+			return nil, fmt.Errorf("user secret %q: socket and files are mutually exclusive", name)
+		case s.Socket == "" && !hasFiles:
+			return nil, fmt.Errorf("user secret %q: either socket or files must be set", name)
+		case (s.CertificateChain == "") != (s.PrivateKey == ""):
+			return nil, fmt.Errorf("user secret %q: certificateChain and privateKey must be set together", name)
+		}
+	}
+	return secrets, nil
+}
+
+// UserSecretFromResourceName extracts the name of the user-defined secret from the SDS resource name, and
+// whether the CA certificates are requested. If the resource name is not a user secret, false is returned.
+func UserSecretFromResourceName(resource string) (name string, root bool, ok bool) {
+	name, ok = strings.CutPrefix(resource, UserSecretPrefix)
+	if !ok || name == "" {
+		return "", false, false
+	}
+	if n, isRoot := strings.CutSuffix(name, UserSecretCaSuffix); isRoot {
+		return n, true, true
+	}
+	return name, false, true
+}
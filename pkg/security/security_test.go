@@ -86,3 +86,56 @@ func TestSdsCertificateConfigFromResourceName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseUserSecrets(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"files", `{"partner":{"certificateChain":"/c.pem","privateKey":"/k.pem","caCertificates":"/ca.pem"}}`, 1, false},
+		{"ca only", `{"partner":{"caCertificates":"/ca.pem"}}`, 1, false},
+		{"socket", `{"partner":{"socket":"/run/store.sock"},"other":{"socket":"/run/store.sock","resourceName":"x"}}`, 2, false},
+		{"invalid json", `{`, 0, true},
+		{"no source", `{"partner":{}}`, 0, true},
+		{"socket and files", `{"partner":{"socket":"/s","caCertificates":"/ca.pem"}}`, 0, true},
+		{"key without cert", `{"partner":{"privateKey":"/k.pem"}}`, 0, true},
+		{"reserved suffix", `{"partner-cacert":{"caCertificates":"/ca.pem"}}`, 0, true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserSecrets(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("expected %d secrets, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUserSecretFromResourceName(t *testing.T) {
+	cases := []struct {
+		resource string
+		name     string
+		root     bool
+		ok       bool
+	}{
+		{"user://partner", "partner", false, true},
+		{"user://partner-cacert", "partner", true, true},
+		{"user://", "", false, false},
+		{"file-cert:a~b", "", false, false},
+		{"default", "", false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.resource, func(t *testing.T) {
+			name, root, ok := UserSecretFromResourceName(tt.resource)
+			if name != tt.name || root != tt.root || ok != tt.ok {
+				t.Fatalf("got (%q, %v, %v), want (%q, %v, %v)", name, root, ok, tt.name, tt.root, tt.ok)
+			}
+		})
+	}
+}
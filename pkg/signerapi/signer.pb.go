@@ -0,0 +1,438 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: signerapi/signer.proto
+
+// External signer API. Istiod acts as a registration authority: it authenticates workloads
+// and validates their CSRs, then delegates signing to an out-of-process signer implementing
+// this service.
+
+package signerapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CallerMetadata struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Pod name of the authenticated caller, if known.
+	PodName string `protobuf:"bytes,1,opt,name=pod_name,json=podName,proto3" json:"pod_name,omitempty"`
+	// Pod namespace of the authenticated caller, if known.
+	PodNamespace string `protobuf:"bytes,2,opt,name=pod_namespace,json=podNamespace,proto3" json:"pod_namespace,omitempty"`
+	// Pod UID of the authenticated caller, if known.
+	PodUid string `protobuf:"bytes,3,opt,name=pod_uid,json=podUid,proto3" json:"pod_uid,omitempty"`
+	// Service account of the authenticated caller, if known.
+	PodServiceAccount string `protobuf:"bytes,4,opt,name=pod_service_account,json=podServiceAccount,proto3" json:"pod_service_account,omitempty"`
+	// Additional caller attributes.
+	Attributes    map[string]string `protobuf:"bytes,5,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallerMetadata) Reset() {
+	*x = CallerMetadata{}
+	mi := &file_signerapi_signer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallerMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallerMetadata) ProtoMessage() {}
+
+func (x *CallerMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_signerapi_signer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallerMetadata.ProtoReflect.Descriptor instead.
+func (*CallerMetadata) Descriptor() ([]byte, []int) {
+	return file_signerapi_signer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CallerMetadata) GetPodName() string {
+	if x != nil {
+		return x.PodName
+	}
+	return ""
+}
+
+func (x *CallerMetadata) GetPodNamespace() string {
+	if x != nil {
+		return x.PodNamespace
+	}
+	return ""
+}
+
+func (x *CallerMetadata) GetPodUid() string {
+	if x != nil {
+		return x.PodUid
+	}
+	return ""
+}
+
+func (x *CallerMetadata) GetPodServiceAccount() string {
+	if x != nil {
+		return x.PodServiceAccount
+	}
+	return ""
+}
+
+func (x *CallerMetadata) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type SignRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// PEM encoded PKCS#10 certificate signing request. Istiod has already verified the
+	// CSR signature and that its SANs match the identities.
+	Csr string `protobuf:"bytes,1,opt,name=csr,proto3" json:"csr,omitempty"`
+	// Identities (URI SANs) to be set in the issued certificate.
+	Identities []string `protobuf:"bytes,2,rep,name=identities,proto3" json:"identities,omitempty"`
+	// Requested certificate lifetime.
+	Ttl *durationpb.Duration `protobuf:"bytes,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Name of the signer requested by the workload, if any.
+	CertSigner string `protobuf:"bytes,4,opt,name=cert_signer,json=certSigner,proto3" json:"cert_signer,omitempty"`
+	// Metadata about the authenticated caller.
+	Caller        *CallerMetadata `protobuf:"bytes,5,opt,name=caller,proto3" json:"caller,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignRequest) Reset() {
+	*x = SignRequest{}
+	mi := &file_signerapi_signer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignRequest) ProtoMessage() {}
+
+func (x *SignRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signerapi_signer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignRequest.ProtoReflect.Descriptor instead.
+func (*SignRequest) Descriptor() ([]byte, []int) {
+	return file_signerapi_signer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SignRequest) GetCsr() string {
+	if x != nil {
+		return x.Csr
+	}
+	return ""
+}
+
+func (x *SignRequest) GetIdentities() []string {
+	if x != nil {
+		return x.Identities
+	}
+	return nil
+}
+
+func (x *SignRequest) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *SignRequest) GetCertSigner() string {
+	if x != nil {
+		return x.CertSigner
+	}
+	return ""
+}
+
+func (x *SignRequest) GetCaller() *CallerMetadata {
+	if x != nil {
+		return x.Caller
+	}
+	return nil
+}
+
+type SignResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// PEM encoded certificate chain, leaf first, excluding the root.
+	CertChain []string `protobuf:"bytes,1,rep,name=cert_chain,json=certChain,proto3" json:"cert_chain,omitempty"`
+	// PEM encoded root certificate of the chain. Istiod does not trust it: the chain is
+	// verified against the roots returned by GetRootCertificates or configured in istiod.
+	RootCert      string `protobuf:"bytes,2,opt,name=root_cert,json=rootCert,proto3" json:"root_cert,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignResponse) Reset() {
+	*x = SignResponse{}
+	mi := &file_signerapi_signer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignResponse) ProtoMessage() {}
+
+func (x *SignResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signerapi_signer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignResponse.ProtoReflect.Descriptor instead.
+func (*SignResponse) Descriptor() ([]byte, []int) {
+	return file_signerapi_signer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SignResponse) GetCertChain() []string {
+	if x != nil {
+		return x.CertChain
+	}
+	return nil
+}
+
+func (x *SignResponse) GetRootCert() string {
+	if x != nil {
+		return x.RootCert
+	}
+	return ""
+}
+
+type GetRootCertificatesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRootCertificatesRequest) Reset() {
+	*x = GetRootCertificatesRequest{}
+	mi := &file_signerapi_signer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRootCertificatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRootCertificatesRequest) ProtoMessage() {}
+
+func (x *GetRootCertificatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signerapi_signer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRootCertificatesRequest.ProtoReflect.Descriptor instead.
+func (*GetRootCertificatesRequest) Descriptor() ([]byte, []int) {
+	return file_signerapi_signer_proto_rawDescGZIP(), []int{3}
+}
+
+type GetRootCertificatesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// PEM encoded root certificates that the signer issues workload certificates under.
+	RootCerts     []string `protobuf:"bytes,1,rep,name=root_certs,json=rootCerts,proto3" json:"root_certs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRootCertificatesResponse) Reset() {
+	*x = GetRootCertificatesResponse{}
+	mi := &file_signerapi_signer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRootCertificatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRootCertificatesResponse) ProtoMessage() {}
+
+func (x *GetRootCertificatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signerapi_signer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRootCertificatesResponse.ProtoReflect.Descriptor instead.
+func (*GetRootCertificatesResponse) Descriptor() ([]byte, []int) {
+	return file_signerapi_signer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRootCertificatesResponse) GetRootCerts() []string {
+	if x != nil {
+		return x.RootCerts
+	}
+	return nil
+}
+
+var File_signerapi_signer_proto protoreflect.FileDescriptor
+
+const file_signerapi_signer_proto_rawDesc = "" +
+	"\n" +
+	"\x16signerapi/signer.proto\x12\x1eistio.security.signer.v1alpha1\x1a\x1egoogle/protobuf/duration.proto\"\xb8\x02\n" +
+	"\x0eCallerMetadata\x12\x19\n" +
+	"\bpod_name\x18\x01 \x01(\tR\apodName\x12#\n" +
+	"\rpod_namespace\x18\x02 \x01(\tR\fpodNamespace\x12\x17\n" +
+	"\apod_uid\x18\x03 \x01(\tR\x06podUid\x12.\n" +
+	"\x13pod_service_account\x18\x04 \x01(\tR\x11podServiceAccount\x12^\n" +
+	"\n" +
+	"attributes\x18\x05 \x03(\v2>.istio.security.signer.v1alpha1.CallerMetadata.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd5\x01\n" +
+	"\vSignRequest\x12\x10\n" +
+	"\x03csr\x18\x01 \x01(\tR\x03csr\x12\x1e\n" +
+	"\n" +
+	"identities\x18\x02 \x03(\tR\n" +
+	"identities\x12+\n" +
+	"\x03ttl\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12\x1f\n" +
+	"\vcert_signer\x18\x04 \x01(\tR\n" +
+	"certSigner\x12F\n" +
+	"\x06caller\x18\x05 \x01(\v2..istio.security.signer.v1alpha1.CallerMetadataR\x06caller\"J\n" +
+	"\fSignResponse\x12\x1d\n" +
+	"\n" +
+	"cert_chain\x18\x01 \x03(\tR\tcertChain\x12\x1b\n" +
+	"\troot_cert\x18\x02 \x01(\tR\brootCert\"\x1c\n" +
+	"\x1aGetRootCertificatesRequest\"<\n" +
+	"\x1bGetRootCertificatesResponse\x12\x1d\n" +
+	"\n" +
+	"root_certs\x18\x01 \x03(\tR\trootCerts2\x84\x02\n" +
+	"\x0eExternalSigner\x12a\n" +
+	"\x04Sign\x12+.istio.security.signer.v1alpha1.SignRequest\x1a,.istio.security.signer.v1alpha1.SignResponse\x12\x8e\x01\n" +
+	"\x13GetRootCertificates\x12:.istio.security.signer.v1alpha1.GetRootCertificatesRequest\x1a;.istio.security.signer.v1alpha1.GetRootCertificatesResponseB\x0fZ\rpkg/signerapib\x06proto3"
+
+var (
+	file_signerapi_signer_proto_rawDescOnce sync.Once
+	file_signerapi_signer_proto_rawDescData []byte
+)
+
+func file_signerapi_signer_proto_rawDescGZIP() []byte {
+	file_signerapi_signer_proto_rawDescOnce.Do(func() {
+		file_signerapi_signer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_signerapi_signer_proto_rawDesc), len(file_signerapi_signer_proto_rawDesc)))
+	})
+	return file_signerapi_signer_proto_rawDescData
+}
+
+var file_signerapi_signer_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_signerapi_signer_proto_goTypes = []any{
+	(*CallerMetadata)(nil),              // 0: istio.security.signer.v1alpha1.CallerMetadata
+	(*SignRequest)(nil),                 // 1: istio.security.signer.v1alpha1.SignRequest
+	(*SignResponse)(nil),                // 2: istio.security.signer.v1alpha1.SignResponse
+	(*GetRootCertificatesRequest)(nil),  // 3: istio.security.signer.v1alpha1.GetRootCertificatesRequest
+	(*GetRootCertificatesResponse)(nil), // 4: istio.security.signer.v1alpha1.GetRootCertificatesResponse
+	nil,                                 // 5: istio.security.signer.v1alpha1.CallerMetadata.AttributesEntry
+	(*durationpb.Duration)(nil),         // 6: google.protobuf.Duration
+}
+var file_signerapi_signer_proto_depIdxs = []int32{
+	5, // 0: istio.security.signer.v1alpha1.CallerMetadata.attributes:type_name -> istio.security.signer.v1alpha1.CallerMetadata.AttributesEntry
+	6, // 1: istio.security.signer.v1alpha1.SignRequest.ttl:type_name -> google.protobuf.Duration
+	0, // 2: istio.security.signer.v1alpha1.SignRequest.caller:type_name -> istio.security.signer.v1alpha1.CallerMetadata
+	1, // 3: istio.security.signer.v1alpha1.ExternalSigner.Sign:input_type -> istio.security.signer.v1alpha1.SignRequest
+	3, // 4: istio.security.signer.v1alpha1.ExternalSigner.GetRootCertificates:input_type -> istio.security.signer.v1alpha1.GetRootCertificatesRequest
+	2, // 5: istio.security.signer.v1alpha1.ExternalSigner.Sign:output_type -> istio.security.signer.v1alpha1.SignResponse
+	4, // 6: istio.security.signer.v1alpha1.ExternalSigner.GetRootCertificates:output_type -> istio.security.signer.v1alpha1.GetRootCertificatesResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_signerapi_signer_proto_init() }
+func file_signerapi_signer_proto_init() {
+	if File_signerapi_signer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_signerapi_signer_proto_rawDesc), len(file_signerapi_signer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_signerapi_signer_proto_goTypes,
+		DependencyIndexes: file_signerapi_signer_proto_depIdxs,
+		MessageInfos:      file_signerapi_signer_proto_msgTypes,
+	}.Build()
+	File_signerapi_signer_proto = out.File
+	file_signerapi_signer_proto_goTypes = nil
+	file_signerapi_signer_proto_depIdxs = nil
+}
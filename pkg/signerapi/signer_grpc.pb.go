@@ -0,0 +1,193 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: signerapi/signer.proto
+
+// External signer API. Istiod acts as a registration authority: it authenticates workloads
+// and validates their CSRs, then delegates signing to an out-of-process signer implementing
+// this service.
+
+package signerapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ExternalSigner_Sign_FullMethodName                = "/istio.security.signer.v1alpha1.ExternalSigner/Sign"
+	ExternalSigner_GetRootCertificates_FullMethodName = "/istio.security.signer.v1alpha1.ExternalSigner/GetRootCertificates"
+)
+
+// ExternalSignerClient is the client API for ExternalSigner service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ExternalSigner signs workload certificates on behalf of istiod.
+// Signers should also implement the standard grpc.health.v1.Health service, which istiod uses
+// to pick a healthy signer.
+type ExternalSignerClient interface {
+	// Sign issues a certificate for the given CSR.
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	// GetRootCertificates returns the roots of the certificates issued by the signer. Istiod
+	// calls it at startup when no CA certificate is configured, and distributes the roots to
+	// workloads as trust anchors.
+	GetRootCertificates(ctx context.Context, in *GetRootCertificatesRequest, opts ...grpc.CallOption) (*GetRootCertificatesResponse, error)
+}
+
+type externalSignerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewExternalSignerClient(cc grpc.ClientConnInterface) ExternalSignerClient {
+	return &externalSignerClient{cc}
+}
+
+func (c *externalSignerClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SignResponse)
+	err := c.cc.Invoke(ctx, ExternalSigner_Sign_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *externalSignerClient) GetRootCertificates(ctx context.Context, in *GetRootCertificatesRequest, opts ...grpc.CallOption) (*GetRootCertificatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRootCertificatesResponse)
+	err := c.cc.Invoke(ctx, ExternalSigner_GetRootCertificates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExternalSignerServer is the server API for ExternalSigner service.
+// All implementations must embed UnimplementedExternalSignerServer
+// for forward compatibility.
+//
+// ExternalSigner signs workload certificates on behalf of istiod.
+// Signers should also implement the standard grpc.health.v1.Health service, which istiod uses
+// to pick a healthy signer.
+type ExternalSignerServer interface {
+	// Sign issues a certificate for the given CSR.
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+	// GetRootCertificates returns the roots of the certificates issued by the signer. Istiod
+	// calls it at startup when no CA certificate is configured, and distributes the roots to
+	// workloads as trust anchors.
+	GetRootCertificates(context.Context, *GetRootCertificatesRequest) (*GetRootCertificatesResponse, error)
+	mustEmbedUnimplementedExternalSignerServer()
+}
+
+// UnimplementedExternalSignerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedExternalSignerServer struct{}
+
+func (UnimplementedExternalSignerServer) Sign(context.Context, *SignRequest) (*SignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+}
+func (UnimplementedExternalSignerServer) GetRootCertificates(context.Context, *GetRootCertificatesRequest) (*GetRootCertificatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRootCertificates not implemented")
+}
+func (UnimplementedExternalSignerServer) mustEmbedUnimplementedExternalSignerServer() {}
+func (UnimplementedExternalSignerServer) testEmbeddedByValue()                        {}
+
+// UnsafeExternalSignerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ExternalSignerServer will
+// result in compilation errors.
+type UnsafeExternalSignerServer interface {
+	mustEmbedUnimplementedExternalSignerServer()
+}
+
+func RegisterExternalSignerServer(s grpc.ServiceRegistrar, srv ExternalSignerServer) {
+	// If the following call pancis, it indicates UnimplementedExternalSignerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ExternalSigner_ServiceDesc, srv)
+}
+
+func _ExternalSigner_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalSignerServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExternalSigner_Sign_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalSignerServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExternalSigner_GetRootCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRootCertificatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExternalSignerServer).GetRootCertificates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExternalSigner_GetRootCertificates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExternalSignerServer).GetRootCertificates(ctx, req.(*GetRootCertificatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ExternalSigner_ServiceDesc is the grpc.ServiceDesc for ExternalSigner service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ExternalSigner_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "istio.security.signer.v1alpha1.ExternalSigner",
+	HandlerType: (*ExternalSignerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Sign",
+			Handler:    _ExternalSigner_Sign_Handler,
+		},
+		{
+			MethodName: "GetRootCertificates",
+			Handler:    _ExternalSigner_GetRootCertificates_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "signerapi/signer.proto",
+}
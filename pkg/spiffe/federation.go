@@ -0,0 +1,305 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// BundleEndpointProfile is the authentication profile of a SPIFFE bundle endpoint, as defined by the
+// SPIFFE Trust Domain and Bundle specification.
+type BundleEndpointProfile string
+
+const (
+	// ProfileHTTPSWeb authenticates the bundle endpoint using Web PKI.
+	ProfileHTTPSWeb BundleEndpointProfile = "https_web"
+	// ProfileHTTPSSPIFFE authenticates the bundle endpoint using an X509-SVID issued by the endpoint's trust domain.
+	ProfileHTTPSSPIFFE BundleEndpointProfile = "https_spiffe"
+
+	// BundleEndpointPath is the path istiod serves its own trust bundle on.
+	BundleEndpointPath = "/spiffe/bundle"
+
+	x509SVIDUse = "x509-svid"
+
+	maxBundleSize = 1 << 20
+)
+
+// Bundle is a parsed SPIFFE trust bundle for a single trust domain.
+type Bundle struct {
+	TrustDomain     string
+	X509Authorities []*x509.Certificate
+	// RefreshHint is the interval suggested by the bundle publisher for polling the endpoint. Zero if not set.
+	RefreshHint time.Duration
+	Sequence    uint64
+}
+
+// BundleEndpoint describes a foreign trust domain bundle endpoint.
+type BundleEndpoint struct {
+	TrustDomain string
+	URL         string
+	Profile     BundleEndpointProfile
+	// EndpointSPIFFEID is the SPIFFE ID the bundle endpoint server must present. Only used by https_spiffe.
+	EndpointSPIFFEID string
+}
+
+// Validate checks the endpoint is well formed.
+func (e BundleEndpoint) Validate() error {
+	if e.TrustDomain == "" {
+		return fmt.Errorf("trust domain must be set")
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return fmt.Errorf("invalid bundle endpoint URL %q: %v", e.URL, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("bundle endpoint URL %q must use https", e.URL)
+	}
+	switch e.Profile {
+	case ProfileHTTPSWeb:
+	case ProfileHTTPSSPIFFE:
+		if !strings.HasPrefix(e.EndpointSPIFFEID, URIPrefix) {
+			return fmt.Errorf("profile %s requires a SPIFFE ID for the bundle endpoint, got %q", e.Profile, e.EndpointSPIFFEID)
+		}
+	default:
+		return fmt.Errorf("unknown bundle endpoint profile %q", e.Profile)
+	}
+	return nil
+}
+
+// ParseBundle decodes a SPIFFE bundle document for the given trust domain.
+func ParseBundle(trustDomain string, data []byte) (*Bundle, error) {
+	doc := new(bundleDoc)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("trust domain [%s] failed to decode bundle: %v", trustDomain, err)
+	}
+	b := &Bundle{
+		TrustDomain: trustDomain,
+		RefreshHint: time.Duration(doc.RefreshHint) * time.Second,
+		Sequence:    doc.Sequence,
+	}
+	for i, key := range doc.Keys {
+		if key.Use != x509SVIDUse {
+			continue
+		}
+		if len(key.Certificates) != 1 {
+			return nil, fmt.Errorf("trust domain [%s] expected 1 certificate in x509-svid entry %d; got %d",
+				trustDomain, i, len(key.Certificates))
+		}
+		b.X509Authorities = append(b.X509Authorities, key.Certificates[0])
+	}
+	if len(b.X509Authorities) == 0 {
+		return nil, fmt.Errorf("trust domain [%s] does not provide a X509 SVID", trustDomain)
+	}
+	return b, nil
+}
+
+// MarshalBundle encodes the X.509 authorities as a SPIFFE bundle document.
+func MarshalBundle(b *Bundle) ([]byte, error) {
+	doc := bundleDoc{
+		Sequence:    b.Sequence,
+		RefreshHint: int(b.RefreshHint / time.Second),
+	}
+	for _, cert := range b.X509Authorities {
+		doc.Keys = append(doc.Keys, jose.JSONWebKey{
+			Key:          cert.PublicKey,
+			Certificates: []*x509.Certificate{cert},
+			Use:          x509SVIDUse,
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// FetchBundle retrieves the trust bundle from a foreign bundle endpoint.
+// webRoots is used to authenticate https_web endpoints; the system pool is used if nil.
+// endpointRoots are the current authorities of the endpoint's trust domain, used to authenticate https_spiffe endpoints.
+func FetchBundle(ctx context.Context, endpoint BundleEndpoint, webRoots *x509.CertPool, endpointRoots []*x509.Certificate) (*Bundle, error) {
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := bundleEndpointTLSConfig(endpoint, webRoots, endpointRoots)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout: time.Second * 10,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+			DialContext: (&net.Dialer{
+				Timeout: time.Second * 10,
+			}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s failed: %v", endpoint.URL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBundleSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle from %s failed: %v", endpoint.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calling %s failed with unexpected status: %v", endpoint.URL, resp.StatusCode)
+	}
+	return ParseBundle(endpoint.TrustDomain, body)
+}
+
+func bundleEndpointTLSConfig(endpoint BundleEndpoint, webRoots *x509.CertPool, endpointRoots []*x509.Certificate) (*tls.Config, error) {
+	if endpoint.Profile == ProfileHTTPSWeb {
+		return &tls.Config{
+			RootCAs:    webRoots,
+			MinVersion: tls.VersionTLS12,
+		}, nil
+	}
+	if len(endpointRoots) == 0 {
+		return nil, fmt.Errorf("no trust bundle available to authenticate %s endpoint for trust domain %s",
+			endpoint.Profile, endpoint.TrustDomain)
+	}
+	roots := x509.NewCertPool()
+	for _, c := range endpointRoots {
+		roots.AddCert(c)
+	}
+	// The endpoint presents an X509-SVID, which carries no DNS SANs, so hostname verification is replaced
+	// by verification of the chain against the endpoint's trust domain bundle and of the expected SPIFFE ID.
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// nolint: gosec // verification is performed in VerifyPeerCertificate
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("bundle endpoint did not present a certificate")
+			}
+			intermediates := x509.NewCertPool()
+			var leaf *x509.Certificate
+			for i, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					leaf = cert
+				} else {
+					intermediates.AddCert(cert)
+				}
+			}
+			if _, err := leaf.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}); err != nil {
+				return fmt.Errorf("bundle endpoint certificate verification failed: %v", err)
+			}
+			if len(leaf.URIs) != 1 || leaf.URIs[0].String() != endpoint.EndpointSPIFFEID {
+				return fmt.Errorf("bundle endpoint presented unexpected identity %v, expected %s", leaf.URIs, endpoint.EndpointSPIFFEID)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// BundleServer serves the local trust domain bundle, implementing the server side of SPIFFE bundle federation.
+// The sequence number is incremented whenever the set of served authorities changes.
+type BundleServer struct {
+	trustDomain string
+	refreshHint time.Duration
+	roots       func() []*x509.Certificate
+
+	mu       sync.Mutex
+	sequence uint64
+	last     [][]byte
+}
+
+// NewBundleServer returns a BundleServer publishing the certificates returned by roots.
+func NewBundleServer(trustDomain string, refreshHint time.Duration, roots func() []*x509.Certificate) *BundleServer {
+	return &BundleServer{
+		trustDomain: trustDomain,
+		refreshHint: refreshHint,
+		roots:       roots,
+	}
+}
+
+// Bundle returns the bundle currently published.
+func (s *BundleServer) Bundle() *Bundle {
+	certs := s.roots()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !sameCerts(s.last, certs) {
+		s.sequence++
+		s.last = make([][]byte, 0, len(certs))
+		for _, c := range certs {
+			s.last = append(s.last, c.Raw)
+		}
+	}
+	return &Bundle{
+		TrustDomain:     s.trustDomain,
+		X509Authorities: certs,
+		RefreshHint:     s.refreshHint,
+		Sequence:        s.sequence,
+	}
+}
+
+func sameCerts(last [][]byte, certs []*x509.Certificate) bool {
+	if len(last) != len(certs) {
+		return false
+	}
+	for i, c := range certs {
+		if !bytes.Equal(last[i], c.Raw) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *BundleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b := s.Bundle()
+	if len(b.X509Authorities) == 0 {
+		http.Error(w, "trust bundle not available", http.StatusServiceUnavailable)
+		return
+	}
+	out, err := MarshalBundle(b)
+	if err != nil {
+		spiffeLog.Errorf("failed to marshal trust bundle: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key}
+}
+
+// issueServing issues a serving certificate carrying the given SPIFFE ID and IP SAN for 127.0.0.1.
+func (ca testCA) issueServing(t *testing.T, spiffeID string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(spiffeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		URIs:         []*url.URL{u},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	ca := newTestCA(t, "td-a")
+	in := &Bundle{
+		TrustDomain:     "td-a",
+		X509Authorities: []*x509.Certificate{ca.cert},
+		RefreshHint:     5 * time.Minute,
+		Sequence:        7,
+	}
+	raw, err := MarshalBundle(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseBundle("td-a", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Sequence != 7 || out.RefreshHint != 5*time.Minute {
+		t.Fatalf("unexpected bundle metadata: sequence %d, refresh hint %v", out.Sequence, out.RefreshHint)
+	}
+	if len(out.X509Authorities) != 1 || !out.X509Authorities[0].Equal(ca.cert) {
+		t.Fatalf("unexpected authorities %v", out.X509Authorities)
+	}
+
+	if _, err := ParseBundle("td-a", []byte(`{"keys":[]}`)); err == nil {
+		t.Fatal("expected error for bundle without X509 authorities")
+	}
+}
+
+func TestBundleServerSequence(t *testing.T) {
+	ca1 := newTestCA(t, "root-1")
+	ca2 := newTestCA(t, "root-2")
+	roots := []*x509.Certificate{ca1.cert}
+	s := NewBundleServer("td-a", time.Minute, func() []*x509.Certificate { return roots })
+
+	if seq := s.Bundle().Sequence; seq != 1 {
+		t.Fatalf("expected sequence 1, got %d", seq)
+	}
+	if seq := s.Bundle().Sequence; seq != 1 {
+		t.Fatalf("expected unchanged sequence 1, got %d", seq)
+	}
+	roots = []*x509.Certificate{ca1.cert, ca2.cert}
+	if seq := s.Bundle().Sequence; seq != 2 {
+		t.Fatalf("expected sequence 2 after rotation, got %d", seq)
+	}
+}
+
+func TestFetchBundleHTTPSWeb(t *testing.T) {
+	foreign := newTestCA(t, "foreign")
+	server := httptest.NewTLSServer(NewBundleServer("foreign.td", time.Minute, func() []*x509.Certificate {
+		return []*x509.Certificate{foreign.cert}
+	}))
+	defer server.Close()
+
+	webRoots := x509.NewCertPool()
+	webRoots.AddCert(server.Certificate())
+	endpoint := BundleEndpoint{
+		TrustDomain: "foreign.td",
+		URL:         server.URL + BundleEndpointPath,
+		Profile:     ProfileHTTPSWeb,
+	}
+	b, err := FetchBundle(context.Background(), endpoint, webRoots, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.X509Authorities) != 1 || !b.X509Authorities[0].Equal(foreign.cert) {
+		t.Fatalf("unexpected authorities %v", b.X509Authorities)
+	}
+	if b.RefreshHint != time.Minute || b.Sequence != 1 {
+		t.Fatalf("unexpected bundle metadata: sequence %d, refresh hint %v", b.Sequence, b.RefreshHint)
+	}
+
+	// An untrusted server must be rejected.
+	if _, err := FetchBundle(context.Background(), endpoint, x509.NewCertPool(), nil); err == nil {
+		t.Fatal("expected error for untrusted https_web endpoint")
+	}
+}
+
+func TestFetchBundleHTTPSSPIFFE(t *testing.T) {
+	foreign := newTestCA(t, "foreign")
+	other := newTestCA(t, "other")
+	endpointID := "spiffe://foreign.td/bundle-endpoint"
+
+	server := httptest.NewUnstartedServer(NewBundleServer("foreign.td", 0, func() []*x509.Certificate {
+		return []*x509.Certificate{foreign.cert}
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{foreign.issueServing(t, endpointID)},
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		id      string
+		roots   []*x509.Certificate
+		wantErr string
+	}{
+		{
+			name:  "authenticated",
+			id:    endpointID,
+			roots: []*x509.Certificate{foreign.cert},
+		},
+		{
+			name:    "unexpected identity",
+			id:      "spiffe://foreign.td/other",
+			roots:   []*x509.Certificate{foreign.cert},
+			wantErr: "unexpected identity",
+		},
+		{
+			name:    "untrusted roots",
+			id:      endpointID,
+			roots:   []*x509.Certificate{other.cert},
+			wantErr: "verification failed",
+		},
+		{
+			name:    "no roots",
+			id:      endpointID,
+			wantErr: "no trust bundle available",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := BundleEndpoint{
+				TrustDomain:      "foreign.td",
+				URL:              server.URL + BundleEndpointPath,
+				Profile:          ProfileHTTPSSPIFFE,
+				EndpointSPIFFEID: tt.id,
+			}
+			b, err := FetchBundle(context.Background(), endpoint, nil, tt.roots)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b.X509Authorities) != 1 || !b.X509Authorities[0].Equal(foreign.cert) {
+				t.Fatalf("unexpected authorities %v", b.X509Authorities)
+			}
+		})
+	}
+}
+
+func TestBundleEndpointValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		endpoint BundleEndpoint
+		valid    bool
+	}{
+		{"web", BundleEndpoint{TrustDomain: "td", URL: "https://example.com/bundle", Profile: ProfileHTTPSWeb}, true},
+		{"spiffe", BundleEndpoint{
+			TrustDomain: "td", URL: "https://example.com/bundle", Profile: ProfileHTTPSSPIFFE,
+			EndpointSPIFFEID: "spiffe://td/endpoint",
+		}, true},
+		{"spiffe without id", BundleEndpoint{TrustDomain: "td", URL: "https://example.com/bundle", Profile: ProfileHTTPSSPIFFE}, false},
+		{"http", BundleEndpoint{TrustDomain: "td", URL: "http://example.com/bundle", Profile: ProfileHTTPSWeb}, false},
+		{"unknown profile", BundleEndpoint{TrustDomain: "td", URL: "https://example.com/bundle", Profile: "foo"}, false},
+		{"no trust domain", BundleEndpoint{URL: "https://example.com/bundle", Profile: ProfileHTTPSWeb}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.endpoint.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attestor provides the node agent plugins producing workload attestation evidence,
+// verified by the attestation authenticator of the Istio CA.
+package attestor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"cloud.google.com/go/compute/metadata"
+
+	"istio.io/istio/pkg/security"
+)
+
+const (
+	JoinToken             = "join_token"
+	AWSInstanceIdentity   = "aws_iid"
+	GCPInstanceIdentity   = "gcp_iit"
+	defaultAWSMetadataURL = "http://169.254.169.254"
+	awsTokenTTLSeconds    = "60"
+	metadataTimeout       = 10 * time.Second
+)
+
+// Options configures the attestor.
+type Options struct {
+	// JoinTokenPath is the file holding the join token, for the join_token type.
+	JoinTokenPath string
+	// Audience of the instance identity token, for the gcp_iit type.
+	Audience string
+}
+
+// New creates the attestor of the given type. Returns nil if the type is empty.
+func New(attestationType string, opts Options) (security.Attestor, error) {
+	switch attestationType {
+	case "":
+		return nil, nil
+	case JoinToken:
+		if opts.JoinTokenPath == "" {
+			return nil, fmt.Errorf("join token path is required for %s attestation", JoinToken)
+		}
+		return &joinTokenAttestor{path: opts.JoinTokenPath}, nil
+	case AWSInstanceIdentity:
+		return &awsAttestor{metadataURL: defaultAWSMetadataURL, client: &http.Client{Timeout: metadataTimeout}}, nil
+	case GCPInstanceIdentity:
+		if opts.Audience == "" {
+			return nil, fmt.Errorf("audience is required for %s attestation", GCPInstanceIdentity)
+		}
+		return &gcpAttestor{audience: opts.Audience}, nil
+	default:
+		return nil, fmt.Errorf("unsupported attestation type %q", attestationType)
+	}
+}
+
+// joinTokenAttestor presents a one-time join token read from a file.
+type joinTokenAttestor struct {
+	path string
+}
+
+func (j *joinTokenAttestor) Type() string {
+	return JoinToken
+}
+
+func (j *joinTokenAttestor) Evidence(context.Context) ([]byte, error) {
+	b, err := os.ReadFile(j.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read join token: %v", err)
+	}
+	return bytes.TrimSpace(b), nil
+}
+
+// awsEvidence matches the evidence expected by the CA for aws_iid attestation.
+type awsEvidence struct {
+	Document  string `json:"document"`
+	Signature string `json:"signature"`
+}
+
+// awsAttestor presents the EC2 instance identity document and its signature, read through IMDSv2.
+type awsAttestor struct {
+	metadataURL string
+	client      *http.Client
+}
+
+func (a *awsAttestor) Type() string {
+	return AWSInstanceIdentity
+}
+
+func (a *awsAttestor) Evidence(ctx context.Context) ([]byte, error) {
+	token, err := a.request(ctx, http.MethodPut, "/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": awsTokenTTLSeconds,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IMDS token: %v", err)
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": token}
+	doc, err := a.request(ctx, http.MethodGet, "/latest/dynamic/instance-identity/document", headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance identity document: %v", err)
+	}
+	sig, err := a.request(ctx, http.MethodGet, "/latest/dynamic/instance-identity/signature", headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance identity signature: %v", err)
+	}
+	return json.Marshal(awsEvidence{Document: doc, Signature: string(bytes.TrimSpace([]byte(sig)))})
+}
+
+func (a *awsAttestor) request(ctx context.Context, method, path string, headers map[string]string) (string, error) {
+	u, err := url.JoinPath(a.metadataURL, path)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, path)
+	}
+	return string(body), nil
+}
+
+// gcpAttestor presents a GCE instance identity token including the instance details.
+type gcpAttestor struct {
+	audience string
+}
+
+func (g *gcpAttestor) Type() string {
+	return GCPInstanceIdentity
+}
+
+func (g *gcpAttestor) Evidence(ctx context.Context) ([]byte, error) {
+	uri := fmt.Sprintf("instance/service-accounts/default/identity?audience=%s&format=full", url.QueryEscape(g.audience))
+	token, err := metadata.GetWithContext(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance identity token: %v", err)
+	}
+	return []byte(token), nil
+}
@@ -0,0 +1,98 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	if a, err := New("", Options{}); a != nil || err != nil {
+		t.Fatalf("expected no attestor, got %v %v", a, err)
+	}
+	if _, err := New(JoinToken, Options{}); err == nil {
+		t.Fatal("expected error for missing join token path")
+	}
+	if _, err := New(GCPInstanceIdentity, Options{}); err == nil {
+		t.Fatal("expected error for missing audience")
+	}
+	if _, err := New("tpm", Options{}); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
+
+func TestJoinTokenEvidence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(JoinToken, Options{JoinTokenPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := a.Evidence(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ev) != "secret-token" {
+		t.Fatalf("unexpected evidence %q", ev)
+	}
+}
+
+func TestAWSEvidence(t *testing.T) {
+	const doc = `{"accountId":"111122223333","instanceId":"i-1","region":"us-west-2"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte("imds-token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest/dynamic/instance-identity/document":
+			_, _ = w.Write([]byte(doc))
+		case "/latest/dynamic/instance-identity/signature":
+			_, _ = w.Write([]byte("c2lnbmF0dXJl\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	a := &awsAttestor{metadataURL: server.URL, client: server.Client()}
+	raw, err := a.Evidence(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := awsEvidence{}
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Document != doc || ev.Signature != "c2lnbmF0dXJl" {
+		t.Fatalf("unexpected evidence %+v", ev)
+	}
+}
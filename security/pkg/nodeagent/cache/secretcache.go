@@ -65,11 +65,14 @@ const (
 // Istiod will serve an SDS response, by selecting the appropriate cluster in the SDS configuration
 // it serves.
 //
-// SecretManagerClient supports two modes of retrieving certificate (potentially at the same time):
+// SecretManagerClient supports three modes of retrieving certificate (potentially at the same time):
 //   - File based certificates. If certs are mounted under well-known path /etc/certs/{key,cert,root-cert.pem},
 //     requests for `default` and `ROOTCA` will automatically read from these files. Additionally,
 //     certificates from Gateway/DestinationRule can also be served. This is done by parsing resource
 //     names in accordance with security.SdsCertificateConfig (file-cert: and file-root:).
+//   - User-defined secrets. These are configured per pod with the security.UserSecretsAnnotation and
+//     requested as security.UserSecretPrefix + name, and are read from files or from a local secret
+//     store socket.
 //   - On demand CSRs. This is used only for the `default` certificate. When this resource is
 //     requested, a CSR will be sent to the configured caClient.
 //
@@ -119,6 +122,10 @@ type SecretManagerClient struct {
 	stop  chan struct{}
 
 	caRootPath string
+
+	// user secrets fetched from secret store sockets with a refresh scheduled.
+	userSecretRefresh sets.String
+	userSecretMutex   sync.Mutex
 }
 
 type secretCache struct {
@@ -186,6 +193,8 @@ func NewSecretManagerClient(caClient security.Client, options *security.Options)
 		fileCerts:   make(map[FileCert]struct{}),
 		stop:        make(chan struct{}),
 		caRootPath:  options.CARootPath,
+
+		userSecretRefresh: sets.New[string](),
 	}
 
 	go ret.queue.Run(ret.stop)
@@ -270,6 +279,14 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 		}
 	}()
 
+	// User-defined secrets are never generated by the CA.
+	if isUserSecret, ns, err := sc.generateUserSecret(resourceName); isUserSecret {
+		if err != nil {
+			return nil, err
+		}
+		return ns, nil
+	}
+
 	// First try to generate secret from file.
 	if sdsFromFile, ns, err := sc.generateFileSecret(resourceName); sdsFromFile {
 		if err != nil {
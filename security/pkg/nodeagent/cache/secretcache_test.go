@@ -624,7 +624,8 @@ func verifySecret(t *testing.T, gotSecret *security.SecretItem, expectedSecret *
 			gotSecret.ResourceName)
 	}
 	cfg, ok := security.SdsCertificateConfigFromResourceName(expectedSecret.ResourceName)
-	if expectedSecret.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
+	_, userRoot, _ := security.UserSecretFromResourceName(expectedSecret.ResourceName)
+	if expectedSecret.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) || userRoot {
 		expectedRootCert := bytes.TrimSpace(expectedSecret.RootCert)
 		gotRootCert := bytes.TrimSpace(gotSecret.RootCert)
 		if !bytes.Equal(expectedRootCert, gotRootCert) {
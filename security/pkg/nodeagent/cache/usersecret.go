@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	sds "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"istio.io/istio/pkg/model"
+	"istio.io/istio/pkg/security"
+	nodeagentutil "istio.io/istio/security/pkg/nodeagent/util"
+)
+
+// userSecretRefreshInterval is how often secrets without expiry, such as CA certificates, are
+// fetched again from a secret store socket.
+var userSecretRefreshInterval = time.Hour
+
+// generateUserSecret serves the user-defined secrets configured for the pod. Secrets read from files
+// are watched for changes, like other file certificates. Secrets fetched from a secret store socket
+// are fetched again when near expiration.
+func (sc *SecretManagerClient) generateUserSecret(resourceName string) (bool, *security.SecretItem, error) {
+	name, root, ok := security.UserSecretFromResourceName(resourceName)
+	if !ok {
+		return false, nil, nil
+	}
+	src, ok := sc.configOptions.UserSecrets[name]
+	if !ok {
+		return true, nil, fmt.Errorf("user secret %q is not configured, set it with the %s annotation",
+			name, security.UserSecretsAnnotation.Name)
+	}
+
+	var sitem *security.SecretItem
+	var err error
+	switch {
+	case src.Socket != "":
+		if sitem, err = sc.fetchUserSecretFromSocket(src, name, root, resourceName); err == nil {
+			sc.scheduleUserSecretRefresh(*sitem)
+		}
+	case root && src.CaCertificates != "":
+		if sitem, err = sc.generateRootCertFromExistingFile(src.CaCertificates, resourceName, false); err == nil {
+			sc.addFileWatcher(src.CaCertificates, resourceName)
+		}
+	case !root && src.CertificateChain != "":
+		if sitem, err = sc.generateKeyCertFromExistingFiles(src.CertificateChain, src.PrivateKey, resourceName); err == nil {
+			// Adding cert is sufficient here as key can't change without changing the cert.
+			sc.addFileWatcher(src.CertificateChain, resourceName)
+		}
+	default:
+		err = fmt.Errorf("user secret %q has no source for %s", name, resourceName)
+	}
+	if err != nil {
+		cacheLog.Errorf("%s failed to generate user secret: %v", cacheLogPrefix(resourceName), err)
+		numFileSecretFailures.Increment()
+		return true, nil, err
+	}
+	resourceLog(resourceName).Info("read user secret")
+	return true, sitem, nil
+}
+
+// fetchUserSecretFromSocket fetches the secret from a local secret store serving the SDS protocol.
+func (sc *SecretManagerClient) fetchUserSecretFromSocket(src security.UserSecret, name string, root bool,
+	resourceName string,
+) (*security.SecretItem, error) {
+	remote := src.ResourceName
+	if remote == "" {
+		remote = name
+	}
+	if root {
+		remote += security.UserSecretCaSuffix
+	}
+
+	conn, err := grpc.NewClient("unix://"+src.Socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to secret store %s: %v", src.Socket, err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), totalTimeout)
+	defer cancel()
+	stream, err := sds.NewSecretDiscoveryServiceClient(conn).StreamSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream to secret store %s: %v", src.Socket, err)
+	}
+	if err := stream.Send(&discovery.DiscoveryRequest{TypeUrl: model.SecretType, ResourceNames: []string{remote}}); err != nil {
+		return nil, fmt.Errorf("failed to request %s from secret store %s: %v", remote, src.Socket, err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive %s from secret store %s: %v", remote, src.Socket, err)
+	}
+	_ = stream.CloseSend()
+
+	for _, r := range resp.GetResources() {
+		secret := &tls.Secret{}
+		if err := r.UnmarshalTo(secret); err != nil {
+			return nil, fmt.Errorf("failed to decode secret from secret store %s: %v", src.Socket, err)
+		}
+		if secret.GetName() != remote {
+			continue
+		}
+		return userSecretItem(secret, resourceName)
+	}
+	return nil, fmt.Errorf("secret store %s did not return %s", src.Socket, remote)
+}
+
+func userSecretItem(secret *tls.Secret, resourceName string) (*security.SecretItem, error) {
+	now := time.Now()
+	switch t := secret.GetType().(type) {
+	case *tls.Secret_TlsCertificate:
+		certChain := t.TlsCertificate.GetCertificateChain().GetInlineBytes()
+		key := t.TlsCertificate.GetPrivateKey().GetInlineBytes()
+		if len(certChain) == 0 || len(key) == 0 {
+			return nil, fmt.Errorf("secret %s has no inline certificate chain and private key", secret.GetName())
+		}
+		expire, err := nodeagentutil.ParseCertAndGetExpiryTimestamp(certChain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract expiration time of secret %s: %v", secret.GetName(), err)
+		}
+		return &security.SecretItem{
+			CertificateChain: certChain,
+			PrivateKey:       key,
+			ResourceName:     resourceName,
+			CreatedTime:      now,
+			ExpireTime:       expire,
+		}, nil
+	case *tls.Secret_ValidationContext:
+		rootCert := t.ValidationContext.GetTrustedCa().GetInlineBytes()
+		if len(rootCert) == 0 {
+			return nil, fmt.Errorf("secret %s has no inline trusted CA", secret.GetName())
+		}
+		return &security.SecretItem{
+			RootCert:     rootCert,
+			ResourceName: resourceName,
+			CreatedTime:  now,
+		}, nil
+	default:
+		return nil, fmt.Errorf("secret %s has unsupported type %T", secret.GetName(), t)
+	}
+}
+
+// scheduleUserSecretRefresh triggers an update of the secret when it is near expiration, or after
+// userSecretRefreshInterval for secrets without expiry. Only one refresh is scheduled per resource.
+func (sc *SecretManagerClient) scheduleUserSecretRefresh(item security.SecretItem) {
+	delay := userSecretRefreshInterval
+	if !item.ExpireTime.IsZero() {
+		delay = rotateTime(item, sc.configOptions.SecretRotationGracePeriodRatio, sc.configOptions.SecretRotationGracePeriodRatioJitter)
+	}
+	sc.userSecretMutex.Lock()
+	defer sc.userSecretMutex.Unlock()
+	if sc.userSecretRefresh.Contains(item.ResourceName) {
+		return
+	}
+	sc.userSecretRefresh.Insert(item.ResourceName)
+	resourceLog(item.ResourceName).Debugf("scheduled user secret refresh in %v", delay)
+	sc.queue.PushDelayed(func() error {
+		sc.userSecretMutex.Lock()
+		sc.userSecretRefresh.Delete(item.ResourceName)
+		sc.userSecretMutex.Unlock()
+		sc.OnSecretUpdate(item.ResourceName)
+		return nil
+	}, delay)
+}
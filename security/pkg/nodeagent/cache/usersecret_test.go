@@ -0,0 +1,176 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	sds "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pkg/file"
+	"istio.io/istio/pkg/model"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/testcerts"
+)
+
+func TestUserSecretsFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"root-cert.pem", "key.pem", "cert-chain.pem"} {
+		if err := file.AtomicCopy(filepath.Join("./testdata", f), dir, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := NewUpdateTracker(t)
+	sc := createCache(t, nil, u.Callback, security.Options{
+		UserSecrets: map[string]security.UserSecret{
+			"partner": {
+				CertificateChain: filepath.Join(dir, "cert-chain.pem"),
+				PrivateKey:       filepath.Join(dir, "key.pem"),
+				CaCertificates:   filepath.Join(dir, "root-cert.pem"),
+			},
+			"no-ca": {
+				CertificateChain: filepath.Join(dir, "cert-chain.pem"),
+				PrivateKey:       filepath.Join(dir, "key.pem"),
+			},
+		},
+	})
+	certResource := security.UserSecretPrefix + "partner"
+	rootResource := certResource + security.UserSecretCaSuffix
+
+	certChain, _ := os.ReadFile(filepath.Join(dir, "cert-chain.pem"))
+	key, _ := os.ReadFile(filepath.Join(dir, "key.pem"))
+	rootCert, _ := os.ReadFile(filepath.Join(dir, "root-cert.pem"))
+	checkSecret(t, sc, certResource, security.SecretItem{
+		ResourceName:     certResource,
+		CertificateChain: certChain,
+		PrivateKey:       key,
+	})
+	checkSecret(t, sc, rootResource, security.SecretItem{
+		ResourceName: rootResource,
+		RootCert:     rootCert,
+	})
+	u.Expect(map[string]int{})
+
+	if err := file.AtomicWrite(filepath.Join(dir, "key.pem"), testcerts.RotatedKey, os.FileMode(0o644)); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.AtomicWrite(filepath.Join(dir, "cert-chain.pem"), testcerts.RotatedCert, os.FileMode(0o644)); err != nil {
+		t.Fatal(err)
+	}
+	u.Expect(map[string]int{certResource: 1})
+	checkSecret(t, sc, certResource, security.SecretItem{
+		ResourceName:     certResource,
+		CertificateChain: testcerts.RotatedCert,
+		PrivateKey:       testcerts.RotatedKey,
+	})
+
+	for _, resource := range []string{security.UserSecretPrefix + "unknown", security.UserSecretPrefix + "no-ca" + security.UserSecretCaSuffix} {
+		if _, err := sc.GenerateSecret(resource); err == nil {
+			t.Fatalf("expected error generating %s", resource)
+		}
+	}
+}
+
+type fakeSecretStore struct {
+	sds.UnimplementedSecretDiscoveryServiceServer
+	secrets map[string]*tls.Secret
+}
+
+func (f *fakeSecretStore) StreamSecrets(stream sds.SecretDiscoveryService_StreamSecretsServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	resp := &discovery.DiscoveryResponse{TypeUrl: model.SecretType}
+	for _, name := range req.ResourceNames {
+		if s, ok := f.secrets[name]; ok {
+			a, _ := anypb.New(s)
+			resp.Resources = append(resp.Resources, a)
+		}
+	}
+	if err := stream.Send(resp); err != nil {
+		return err
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+func inline(b []byte) *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: b}}
+}
+
+func TestUserSecretsFromSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "store.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeSecretStore{secrets: map[string]*tls.Secret{
+		"vault/partner": {
+			Name: "vault/partner",
+			Type: &tls.Secret_TlsCertificate{TlsCertificate: &tls.TlsCertificate{
+				CertificateChain: inline(testcerts.RotatedCert),
+				PrivateKey:       inline(testcerts.RotatedKey),
+			}},
+		},
+		"vault/partner-cacert": {
+			Name: "vault/partner-cacert",
+			Type: &tls.Secret_ValidationContext{ValidationContext: &tls.CertificateValidationContext{
+				TrustedCa: inline(testcerts.CACert),
+			}},
+		},
+	}}
+	server := grpc.NewServer()
+	sds.RegisterSecretDiscoveryServiceServer(server, store)
+	go func() {
+		_ = server.Serve(l)
+	}()
+	t.Cleanup(server.Stop)
+
+	u := NewUpdateTracker(t)
+	sc := createCache(t, nil, u.Callback, security.Options{
+		SecretRotationGracePeriodRatio: 1,
+		UserSecrets: map[string]security.UserSecret{
+			"partner": {Socket: socket, ResourceName: "vault/partner"},
+			"missing": {Socket: socket},
+		},
+	})
+	certResource := security.UserSecretPrefix + "partner"
+	rootResource := certResource + security.UserSecretCaSuffix
+
+	checkSecret(t, sc, certResource, security.SecretItem{
+		ResourceName:     certResource,
+		CertificateChain: testcerts.RotatedCert,
+		PrivateKey:       testcerts.RotatedKey,
+	})
+	checkSecret(t, sc, rootResource, security.SecretItem{
+		ResourceName: rootResource,
+		RootCert:     testcerts.CACert,
+	})
+	// With a grace period ratio of 1, the certificate is refreshed right away.
+	u.Expect(map[string]int{certResource: 1})
+
+	if _, err := sc.GenerateSecret(security.UserSecretPrefix + "missing"); err == nil {
+		t.Fatal("expected error for secret not served by the secret store")
+	}
+}
@@ -16,8 +16,10 @@ package citadel
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"sync/atomic"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -44,6 +46,9 @@ type CitadelClient struct {
 	conn     *grpc.ClientConn
 	provider credentials.PerRPCCredentials
 	opts     *security.Options
+	// attested is set once a certificate was issued with attestation evidence. Renewals then authenticate
+	// with the issued certificate, as the evidence may be single use.
+	attested atomic.Bool
 }
 
 type TLSOptions struct {
@@ -108,6 +113,19 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) (res []s
 	for k, v := range c.opts.CAHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
+	sentEvidence := false
+	if c.opts.Attestor != nil && !c.attested.Load() {
+		// Evidence is only needed until the workload holds a certificate, so failures are not fatal:
+		// other authentication methods may still succeed.
+		if evidence, err := c.opts.Attestor.Evidence(ctx); err != nil {
+			citadelClientLog.Warnf("failed to get %s attestation evidence: %v", c.opts.Attestor.Type(), err)
+		} else {
+			ctx = metadata.AppendToOutgoingContext(ctx,
+				security.AttestationTypeHeader, c.opts.Attestor.Type(),
+				security.AttestationEvidenceHeader, base64.StdEncoding.EncodeToString(evidence))
+			sentEvidence = true
+		}
+	}
 
 	resp, err := c.client.CreateCertificate(ctx, req)
 	if err != nil {
@@ -117,6 +135,9 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) (res []s
 	if len(resp.CertChain) <= 1 {
 		return nil, errors.New("invalid empty CertChain")
 	}
+	if sentEvidence {
+		c.attested.Store(true)
+	}
 
 	return resp.CertChain, nil
 }
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -393,3 +394,54 @@ func TestCitadelClientWithDifferentTypeToken(t *testing.T) {
 		})
 	}
 }
+
+type fakeAttestor struct{}
+
+func (fakeAttestor) Type() string { return "join_token" }
+
+func (fakeAttestor) Evidence(context.Context) ([]byte, error) { return []byte("token"), nil }
+
+type mockAttestationCAServer struct {
+	pb.UnimplementedIstioCertificateServiceServer
+	mu       sync.Mutex
+	evidence []bool
+}
+
+func (ca *mockAttestationCAServer) CreateCertificate(ctx context.Context, in *pb.IstioCertificateRequest) (*pb.IstioCertificateResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	ca.evidence = append(ca.evidence, len(md.Get(security.AttestationEvidenceHeader)) > 0)
+	return &pb.IstioCertificateResponse{CertChain: fakeCert}, nil
+}
+
+// Attestation evidence is only sent until a certificate is issued, renewals do not present it again.
+func TestCitadelClientAttestation(t *testing.T) {
+	server := &mockAttestationCAServer{}
+	s := grpc.NewServer()
+	t.Cleanup(s.Stop)
+	lis, err := net.Listen("tcp", mockServerAddress)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	pb.RegisterIstioCertificateServiceServer(s, server)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	cli, err := NewCitadelClient(&security.Options{CAEndpoint: lis.Addr().String(), ClusterID: constants.DefaultClusterName, Attestor: fakeAttestor{}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cli.Close)
+	for range 2 {
+		if _, err := cli.CSRSign([]byte{0o1}, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !reflect.DeepEqual(server.evidence, []bool{true, false}) {
+		t.Fatalf("expected evidence only in the first request, got %v", server.evidence)
+	}
+}
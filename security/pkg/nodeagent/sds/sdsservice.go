@@ -300,7 +300,8 @@ func toEnvoySecret(s *security.SecretItem, caRootPath string, pkpConf *mesh.Priv
 	} else {
 		cfg, ok = security.SdsCertificateConfigFromResourceName(s.ResourceName)
 	}
-	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
+	_, userRoot, _ := security.UserSecretFromResourceName(s.ResourceName)
+	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) || userRoot {
 		secretValidationContext := &tls.Secret_ValidationContext{
 			ValidationContext: &tls.CertificateValidationContext{
 				TrustedCa: &core.DataSource{
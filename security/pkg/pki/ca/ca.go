@@ -30,6 +30,7 @@ import (
 
 	"istio.io/istio/pkg/backoff"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/security"
 	"istio.io/istio/security/pkg/cmd"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
@@ -95,6 +96,10 @@ type CertOpts struct {
 
 	// Cert Signer info
 	CertSigner string
+
+	// Caller is the authenticated caller requesting the certificate, if known. It is not used by the
+	// Istio CA, but is forwarded by registration authorities that delegate signing to external signers.
+	Caller *security.Caller
 }
 
 const (
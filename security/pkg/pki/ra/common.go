@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"google.golang.org/grpc"
 	clientset "k8s.io/client-go/kubernetes"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
@@ -62,12 +63,23 @@ type IstioRAOptions struct {
 	TrustDomain string
 	// CertSignerDomain info
 	CertSignerDomain string
+	// ExternalSignerAddresses : gRPC targets of the external signers, in order of preference
+	ExternalSignerAddresses []string
+	// ExternalSignerDialOptions : Dial options, including transport credentials, for the external signers
+	ExternalSignerDialOptions []grpc.DialOption
+	// ExternalSignerTimeout : Timeout of each request to an external signer
+	ExternalSignerTimeout time.Duration
+	// ExternalSignerHealthCheckInterval : Interval between health checks of the external signers
+	ExternalSignerHealthCheckInterval time.Duration
 }
 
 const (
 	// ExtCAK8s : Integrate with external CA using k8s CSR API
 	ExtCAK8s CaExternalType = "ISTIOD_RA_KUBERNETES_API"
 
+	// ExtCAGrpc : Integrate with external CA using the external signer gRPC API
+	ExtCAGrpc CaExternalType = "ISTIOD_RA_EXTERNAL_SIGNER"
+
 	// DefaultExtCACertDir : Location of external CA certificate
 	DefaultExtCACertDir string = "./etc/external-ca-cert"
 )
@@ -177,6 +189,13 @@ func NewIstioRA(opts *IstioRAOptions) (RegistrationAuthority, error) {
 		}
 		return istioRA, err
 	}
+	if opts.ExternalCAType == ExtCAGrpc {
+		istioRA, err := NewExternalSignerRA(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create an external signer RA: %v", err)
+		}
+		return istioRA, err
+	}
 	return nil, fmt.Errorf("invalid CA Name %s", opts.ExternalCAType)
 }
 
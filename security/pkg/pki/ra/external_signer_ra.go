@@ -0,0 +1,372 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/security"
+	pb "istio.io/istio/pkg/signerapi"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/security/pkg/pki/ca"
+	raerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	defaultSignerRequestTimeout      = 10 * time.Second
+	defaultSignerHealthCheckInterval = 10 * time.Second
+)
+
+// externalSigner is a connection to a single external signer.
+type externalSigner struct {
+	address string
+	conn    *grpc.ClientConn
+	client  pb.ExternalSignerClient
+	health  healthpb.HealthClient
+	healthy bool
+}
+
+// ExternalSignerRA delegates signing of workload certificates to one or more external signers
+// implementing the istio.security.signer.v1alpha1.ExternalSigner gRPC API.
+// Signers are tried in order, preferring the ones that passed their last health check.
+type ExternalSignerRA struct {
+	raOpts        *IstioRAOptions
+	keyCertBundle *util.KeyCertBundle
+
+	// mutex protects signers health and caCertificatesFromMeshConfig.
+	mutex                        sync.RWMutex
+	signers                      []*externalSigner
+	caCertificatesFromMeshConfig map[string]string
+}
+
+// NewExternalSignerRA creates a RA that connects to the external signers in raOpts.ExternalSignerAddresses.
+func NewExternalSignerRA(raOpts *IstioRAOptions) (*ExternalSignerRA, error) {
+	if len(raOpts.ExternalSignerAddresses) == 0 {
+		return nil, raerror.NewError(raerror.CAIllegalConfig, fmt.Errorf("no external signer addresses configured"))
+	}
+	r := &ExternalSignerRA{
+		raOpts:                       raOpts,
+		caCertificatesFromMeshConfig: make(map[string]string),
+	}
+	for _, addr := range raOpts.ExternalSignerAddresses {
+		conn, err := grpc.NewClient(addr, raOpts.ExternalSignerDialOptions...)
+		if err != nil {
+			r.Close()
+			return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("failed to create connection to external signer %s: %v", addr, err))
+		}
+		r.signers = append(r.signers, &externalSigner{
+			address: addr,
+			conn:    conn,
+			client:  pb.NewExternalSignerClient(conn),
+			health:  healthpb.NewHealthClient(conn),
+			// Assume healthy until the first check completes.
+			healthy: true,
+		})
+	}
+	if raOpts.CaCertFile != "" {
+		keyCertBundle, err := util.NewKeyCertBundleWithRootCertFromFile(raOpts.CaCertFile)
+		if err != nil {
+			r.Close()
+			return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("error processing Certificate Bundle for external signer RA: %v", err))
+		}
+		r.keyCertBundle = keyCertBundle
+		return r, nil
+	}
+	// Without a CA cert file the trust anchor is the root of the signers. It is fetched once, so that it is
+	// distributed to workloads and used to verify the chains returned by the signers.
+	root, err := r.fetchRootCerts(context.Background())
+	if err != nil {
+		r.Close()
+		return nil, raerror.NewError(raerror.CAInitFail, err)
+	}
+	if _, err := util.ParsePemEncodedCertificate(root); err != nil {
+		r.Close()
+		return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("invalid root certificates from external signer: %v", err))
+	}
+	r.keyCertBundle = util.NewKeyCertBundleFromPem(nil, nil, nil, root, nil)
+	return r, nil
+}
+
+// fetchRootCerts returns the roots of the first signer that answers GetRootCertificates.
+func (r *ExternalSignerRA) fetchRootCerts(ctx context.Context) ([]byte, error) {
+	var lastErr error
+	for _, s := range r.signers {
+		ctx, cancel := context.WithTimeout(ctx, r.requestTimeout())
+		resp, err := s.client.GetRootCertificates(ctx, &pb.GetRootCertificatesRequest{})
+		cancel()
+		if err != nil {
+			lastErr = fmt.Errorf("external signer %s: %v", s.address, err)
+			pkiRaLog.Warnf("failed to get root certificates: %v", lastErr)
+			continue
+		}
+		if len(resp.RootCerts) == 0 {
+			lastErr = fmt.Errorf("external signer %s returned no root certificates", s.address)
+			continue
+		}
+		root := []byte(strings.Join(resp.RootCerts, ""))
+		pkiRaLog.Infof("using root certificates of external signer %s", s.address)
+		return root, nil
+	}
+	return nil, fmt.Errorf("failed to get the root certificates from the external signers, last error: %v", lastErr)
+}
+
+// Run periodically checks the health of the external signers until stop is closed.
+func (r *ExternalSignerRA) Run(stop <-chan struct{}) {
+	interval := r.raOpts.ExternalSignerHealthCheckInterval
+	if interval <= 0 {
+		interval = defaultSignerHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer r.Close()
+	for {
+		r.CheckHealth(context.Background())
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// CheckHealth checks the health of every external signer using the gRPC health checking protocol.
+func (r *ExternalSignerRA) CheckHealth(ctx context.Context) {
+	for _, s := range r.signers {
+		ctx, cancel := context.WithTimeout(ctx, r.requestTimeout())
+		resp, err := s.health.Check(ctx, &healthpb.HealthCheckRequest{Service: pb.ExternalSigner_ServiceDesc.ServiceName})
+		cancel()
+		// Signers that do not implement the health service are assumed healthy.
+		healthy := status.Code(err) == codes.Unimplemented || (err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING)
+		r.mutex.Lock()
+		if healthy != s.healthy {
+			if healthy {
+				pkiRaLog.Infof("external signer %s is healthy", s.address)
+			} else {
+				pkiRaLog.Warnf("external signer %s is unhealthy: status %v, error %v", s.address, resp.GetStatus(), err)
+			}
+		}
+		s.healthy = healthy
+		r.mutex.Unlock()
+	}
+}
+
+// Close closes the connections to the external signers.
+func (r *ExternalSignerRA) Close() {
+	for _, s := range r.signers {
+		_ = s.conn.Close()
+	}
+}
+
+func (r *ExternalSignerRA) requestTimeout() time.Duration {
+	if r.raOpts.ExternalSignerTimeout > 0 {
+		return r.raOpts.ExternalSignerTimeout
+	}
+	return defaultSignerRequestTimeout
+}
+
+// orderedSigners returns the healthy signers followed by the unhealthy ones, each in configured order.
+func (r *ExternalSignerRA) orderedSigners() []*externalSigner {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	out := make([]*externalSigner, 0, len(r.signers))
+	for _, s := range r.signers {
+		if s.healthy {
+			out = append(out, s)
+		}
+	}
+	for _, s := range r.signers {
+		if !s.healthy {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// retryable returns true if the error indicates the signer could not process the request,
+// in which case the next signer is tried.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+func callerMetadata(caller *security.Caller) *pb.CallerMetadata {
+	if caller == nil {
+		return nil
+	}
+	return &pb.CallerMetadata{
+		PodName:           caller.KubernetesInfo.PodName,
+		PodNamespace:      caller.KubernetesInfo.PodNamespace,
+		PodUid:            caller.KubernetesInfo.PodUID,
+		PodServiceAccount: caller.KubernetesInfo.PodServiceAccount,
+		Attributes: map[string]string{
+			"auth_source": authSourceName(caller.AuthSource),
+			"identities":  strings.Join(caller.Identities, ","),
+		},
+	}
+}
+
+func authSourceName(s security.AuthSource) string {
+	switch s {
+	case security.AuthSourceClientCertificate:
+		return "client_certificate"
+	case security.AuthSourceIDToken:
+		return "id_token"
+	case security.AuthSourceAttestation:
+		return "attestation"
+	}
+	return "unknown"
+}
+
+func (r *ExternalSignerRA) sign(csrPEM []byte, certOpts ca.CertOpts) (*pb.SignResponse, error) {
+	lifetime, err := preSign(r.raOpts, csrPEM, certOpts.SubjectIDs, certOpts.TTL, certOpts.ForCA)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.SignRequest{
+		Csr:        string(csrPEM),
+		Identities: certOpts.SubjectIDs,
+		Ttl:        durationpb.New(lifetime),
+		CertSigner: certOpts.CertSigner,
+		Caller:     callerMetadata(certOpts.Caller),
+	}
+	var lastErr error
+	for _, s := range r.orderedSigners() {
+		ctx, cancel := context.WithTimeout(context.Background(), r.requestTimeout())
+		resp, err := s.client.Sign(ctx, req)
+		cancel()
+		if err == nil {
+			if len(resp.CertChain) == 0 {
+				return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("external signer %s returned an empty certificate chain", s.address))
+			}
+			return resp, nil
+		}
+		lastErr = fmt.Errorf("external signer %s: %v", s.address, err)
+		if !retryable(err) {
+			if status.Code(err) == codes.InvalidArgument {
+				return nil, raerror.NewError(raerror.CSRError, lastErr)
+			}
+			return nil, raerror.NewError(raerror.CertGenError, lastErr)
+		}
+		pkiRaLog.Warnf("signing failed, trying next signer: %v", lastErr)
+		r.mutex.Lock()
+		s.healthy = false
+		r.mutex.Unlock()
+	}
+	return nil, raerror.NewError(raerror.CANotReady, fmt.Errorf("all external signers failed, last error: %v", lastErr))
+}
+
+// Sign takes a PEM-encoded CSR and cert opts, and returns the certificate chain signed by an external signer.
+// The root is appended if it is not already known by the CA server.
+func (r *ExternalSignerRA) Sign(csrPEM []byte, certOpts ca.CertOpts) ([]byte, error) {
+	chain, err := r.SignWithCertChain(csrPEM, certOpts)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(chain, "")), nil
+}
+
+// SignWithCertChain is similar to Sign but returns the leaf cert and the entire cert chain.
+// The chain is verified against the trust anchors of the RA: the root from the CA cert file or fetched from the
+// signers at startup, and the root configured for the requested signer in mesh config. The root returned by the
+// signer along with the chain is never trusted. The CA server appends the root of the key cert bundle itself.
+func (r *ExternalSignerRA) SignWithCertChain(csrPEM []byte, certOpts ca.CertOpts) ([]string, error) {
+	resp, err := r.sign(csrPEM, certOpts)
+	if err != nil {
+		return nil, err
+	}
+	chain := []byte(strings.Join(resp.CertChain, ""))
+	roots := r.GetCAKeyCertBundle().GetRootCertPem()
+	if certOpts.CertSigner != "" {
+		if fromMesh, err := r.GetRootCertFromMeshConfig(certOpts.CertSigner); err == nil {
+			roots = append(roots, fromMesh...)
+		}
+	}
+	if err := util.VerifyCertificate(nil, chain, roots, nil); err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("certificate chain from external signer is invalid: %v", err))
+	}
+	if err := verifyLeaf(chain, csrPEM, certOpts.SubjectIDs); err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("certificate from external signer does not match the request: %v", err))
+	}
+	return []string{string(chain)}, nil
+}
+
+// verifyLeaf checks that the leaf of chain certifies the key of the CSR, and only the requested identities.
+func verifyLeaf(chain, csrPEM []byte, subjectIDs []string) error {
+	leaf, err := util.ParsePemEncodedCertificate(chain)
+	if err != nil {
+		return err
+	}
+	csr, err := util.ParsePemEncodedCSR(csrPEM)
+	if err != nil {
+		return err
+	}
+	pub, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(csr.PublicKey) {
+		return fmt.Errorf("public key does not match the CSR")
+	}
+	if len(subjectIDs) > 0 && len(leaf.URIs) == 0 {
+		return fmt.Errorf("no URI SAN, expected one of %v", subjectIDs)
+	}
+	for _, uri := range leaf.URIs {
+		if !slices.Contains(subjectIDs, uri.String()) {
+			return fmt.Errorf("URI SAN %s is not one of the requested identities %v", uri, subjectIDs)
+		}
+	}
+	return nil
+}
+
+// GetCAKeyCertBundle returns the KeyCertBundle for the CA.
+func (r *ExternalSignerRA) GetCAKeyCertBundle() *util.KeyCertBundle {
+	return r.keyCertBundle
+}
+
+func (r *ExternalSignerRA) SetCACertificatesFromMeshConfig(caCertificates []*meshconfig.MeshConfig_CertificateData) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, pemCert := range caCertificates {
+		cert := pemCert.GetPem()
+		if cert == "" {
+			continue
+		}
+		for _, signer := range pemCert.CertSigners {
+			r.caCertificatesFromMeshConfig[signer] = cert
+		}
+	}
+}
+
+func (r *ExternalSignerRA) GetRootCertFromMeshConfig(signerName string) ([]byte, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if cert, ok := r.caCertificatesFromMeshConfig[signerName]; ok {
+		return []byte(cert), nil
+	}
+	return nil, fmt.Errorf("failed to find root cert for signer: %v in mesh config", signerName)
+}
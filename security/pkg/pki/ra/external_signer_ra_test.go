@@ -0,0 +1,361 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pkg/security"
+	pb "istio.io/istio/pkg/signerapi"
+	"istio.io/istio/security/pkg/pki/ca"
+	raerror "istio.io/istio/security/pkg/pki/error"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+type fakeSigner struct {
+	pb.UnimplementedExternalSignerServer
+	rootPEM  []byte
+	rootCert *x509.Certificate
+	rootKey  crypto.PrivateKey
+
+	mu          sync.Mutex
+	failWith    codes.Code
+	calls       *atomic.Int32
+	lastRequest *pb.SignRequest
+	health      *health.Server
+	noRoots     bool
+	// issueKey and issueIdentities, when set, replace the key and identities of the request in issued certificates.
+	issueKey        crypto.PublicKey
+	issueIdentities []string
+}
+
+func newFakeSigner(t *testing.T) *fakeSigner {
+	rootPEM, keyPEM, err := pkiutil.GenCertKeyFromOptions(pkiutil.CertOptions{
+		Org:          "external signer",
+		IsCA:         true,
+		IsSelfSigned: true,
+		TTL:          time.Hour,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := pkiutil.ParsePemEncodedCertificate(rootPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootKey, err := pkiutil.ParsePemEncodedKey(keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus(pb.ExternalSigner_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return &fakeSigner{rootPEM: rootPEM, rootCert: rootCert, rootKey: rootKey, calls: atomic.NewInt32(0), health: hs}
+}
+
+// sameRoot makes f issue certificates under the root of other, as replicas of the same signer do.
+func (f *fakeSigner) sameRoot(other *fakeSigner) *fakeSigner {
+	f.rootPEM, f.rootCert, f.rootKey = other.rootPEM, other.rootCert, other.rootKey
+	return f
+}
+
+func (f *fakeSigner) setFailure(c codes.Code) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failWith = c
+}
+
+func (f *fakeSigner) Sign(_ context.Context, req *pb.SignRequest) (*pb.SignResponse, error) {
+	f.calls.Inc()
+	f.mu.Lock()
+	f.lastRequest = req
+	failWith := f.failWith
+	f.mu.Unlock()
+	if failWith != codes.OK {
+		return nil, status.Error(failWith, "injected failure")
+	}
+	csr, err := pkiutil.ParsePemEncodedCSR([]byte(req.Csr))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	key, identities := csr.PublicKey, req.Identities
+	if f.issueKey != nil {
+		key = f.issueKey
+	}
+	if f.issueIdentities != nil {
+		identities = f.issueIdentities
+	}
+	der, err := pkiutil.GenCertFromCSR(csr, f.rootCert, key, f.rootKey, identities, req.Ttl.AsDuration(), false)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.SignResponse{
+		CertChain: []string{string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))},
+		RootCert:  string(f.rootPEM),
+	}, nil
+}
+
+func (f *fakeSigner) GetRootCertificates(context.Context, *pb.GetRootCertificatesRequest) (*pb.GetRootCertificatesResponse, error) {
+	if f.noRoots {
+		return nil, status.Error(codes.Unimplemented, "not implemented")
+	}
+	return &pb.GetRootCertificatesResponse{RootCerts: []string{string(f.rootPEM)}}, nil
+}
+
+func (f *fakeSigner) start(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterExternalSignerServer(s, f)
+	healthpb.RegisterHealthServer(s, f.health)
+	go func() {
+		_ = s.Serve(l)
+	}()
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func testExternalSignerRAOptions(addrs ...string) *IstioRAOptions {
+	return &IstioRAOptions{
+		ExternalCAType:            ExtCAGrpc,
+		DefaultCertTTL:            time.Hour,
+		MaxCertTTL:                24 * time.Hour,
+		ExternalSignerAddresses:   addrs,
+		ExternalSignerDialOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		ExternalSignerTimeout:     5 * time.Second,
+	}
+}
+
+func newTestExternalSignerRA(t *testing.T, addrs ...string) *ExternalSignerRA {
+	r, err := NewExternalSignerRA(testExternalSignerRAOptions(addrs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestExternalSignerRASign(t *testing.T) {
+	signer := newFakeSigner(t)
+	r := newTestExternalSignerRA(t, signer.start(t))
+
+	caller := &security.Caller{
+		AuthSource:     security.AuthSourceIDToken,
+		Identities:     []string{testCsrHostName},
+		KubernetesInfo: security.KubernetesInfo{PodName: "productpage", PodNamespace: "default"},
+	}
+	chain, err := r.SignWithCertChain(createDefaultFakeCsr(t), ca.CertOpts{
+		SubjectIDs: []string{testCsrHostName},
+		TTL:        time.Hour,
+		Caller:     caller,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 1 {
+		t.Fatalf("expected the chain from the external signer without the root, got %d entries", len(chain))
+	}
+	if !bytes.Equal(r.GetCAKeyCertBundle().GetRootCertPem(), signer.rootPEM) {
+		t.Fatal("expected the root of the external signer to be the trust anchor of the RA")
+	}
+	if err := pkiutil.VerifyCertificate(nil, []byte(chain[0]), signer.rootPEM, nil); err != nil {
+		t.Fatalf("issued certificate does not verify: %v", err)
+	}
+	signer.mu.Lock()
+	req := signer.lastRequest
+	signer.mu.Unlock()
+	if req.Ttl.AsDuration() != time.Hour || req.Caller.GetPodName() != "productpage" ||
+		req.Caller.GetAttributes()["auth_source"] != "id_token" {
+		t.Fatalf("unexpected request forwarded to signer: %v", req)
+	}
+}
+
+func TestExternalSignerRALeafMismatch(t *testing.T) {
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		mutate  func(f *fakeSigner)
+		wantErr string
+	}{
+		{
+			name:    "public key",
+			mutate:  func(f *fakeSigner) { f.issueKey = otherKey.Public() },
+			wantErr: "public key does not match the CSR",
+		},
+		{
+			name:    "identities",
+			mutate:  func(f *fakeSigner) { f.issueIdentities = []string{"spiffe://cluster.local/ns/kube-system/sa/admin"} },
+			wantErr: "is not one of the requested identities",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := newFakeSigner(t)
+			tt.mutate(signer)
+			r := newTestExternalSignerRA(t, signer.start(t))
+			_, err := r.SignWithCertChain(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: time.Hour})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected a leaf not matching the request to be rejected with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExternalSignerRAValidation(t *testing.T) {
+	signer := newFakeSigner(t)
+	r := newTestExternalSignerRA(t, signer.start(t))
+
+	// Identities not matching the CSR are rejected before contacting the signer.
+	_, err := r.Sign(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{"spiffe://cluster.local/ns/other/sa/other"}, TTL: time.Hour})
+	if err == nil {
+		t.Fatal("expected CSR validation error")
+	}
+	if signer.calls.Load() != 0 {
+		t.Fatalf("expected signer not to be called, got %d calls", signer.calls.Load())
+	}
+
+	// TTLs above the maximum are rejected.
+	_, err = r.Sign(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: 48 * time.Hour})
+	if e, ok := err.(*raerror.Error); !ok || e.ErrorType() != "TTL_ERROR" {
+		t.Fatalf("expected TTL error, got %v", err)
+	}
+}
+
+func TestExternalSignerRAFailover(t *testing.T) {
+	primary := newFakeSigner(t)
+	primary.setFailure(codes.Unavailable)
+	secondary := newFakeSigner(t).sameRoot(primary)
+	r := newTestExternalSignerRA(t, primary.start(t), secondary.start(t))
+
+	opts := ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: time.Hour}
+	if _, err := r.SignWithCertChain(createDefaultFakeCsr(t), opts); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls.Load() != 1 || secondary.calls.Load() != 1 {
+		t.Fatalf("unexpected calls: primary %d, secondary %d", primary.calls.Load(), secondary.calls.Load())
+	}
+
+	// The failed primary is now deprioritized.
+	if _, err := r.SignWithCertChain(createDefaultFakeCsr(t), opts); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls.Load() != 1 || secondary.calls.Load() != 2 {
+		t.Fatalf("unexpected calls: primary %d, secondary %d", primary.calls.Load(), secondary.calls.Load())
+	}
+
+	// Once healthy again the primary is preferred.
+	primary.setFailure(codes.OK)
+	r.CheckHealth(context.Background())
+	if _, err := r.SignWithCertChain(createDefaultFakeCsr(t), opts); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls.Load() != 2 {
+		t.Fatalf("expected the primary signer to be used after recovering, got %d calls", primary.calls.Load())
+	}
+
+	// Non retryable errors are returned without failover.
+	primary.setFailure(codes.PermissionDenied)
+	_, err := r.SignWithCertChain(createDefaultFakeCsr(t), opts)
+	if err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("expected signer error, got %v", err)
+	}
+	if secondary.calls.Load() != 2 {
+		t.Fatalf("expected no failover for non retryable errors, got %d secondary calls", secondary.calls.Load())
+	}
+}
+
+func TestExternalSignerRAHealthCheck(t *testing.T) {
+	primary := newFakeSigner(t)
+	secondary := newFakeSigner(t).sameRoot(primary)
+	r := newTestExternalSignerRA(t, primary.start(t), secondary.start(t))
+
+	primary.health.SetServingStatus(pb.ExternalSigner_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	r.CheckHealth(context.Background())
+	ordered := r.orderedSigners()
+	if ordered[0].address == r.signers[0].address {
+		t.Fatal("expected the unhealthy primary signer to be deprioritized")
+	}
+
+	_, err := r.SignWithCertChain(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secondary.calls.Load() != 1 || primary.calls.Load() != 0 {
+		t.Fatal("expected certificate to be issued by the healthy signer")
+	}
+
+	// All signers failing is reported as CA not ready.
+	primary.setFailure(codes.Unavailable)
+	secondary.setFailure(codes.Unavailable)
+	_, err = r.Sign(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: time.Hour})
+	if e, ok := err.(*raerror.Error); !ok || e.ErrorType() != "CA_NOT_READY" {
+		t.Fatalf("expected CA not ready error, got %v", err)
+	}
+}
+
+func TestExternalSignerRATrustAnchor(t *testing.T) {
+	// The configured root is the trust anchor, not the root returned by the signer along with the chain.
+	trusted := newFakeSigner(t)
+	rootFile := filepath.Join(t.TempDir(), "root-cert.pem")
+	if err := os.WriteFile(rootFile, trusted.rootPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rogue := newFakeSigner(t)
+	opts := testExternalSignerRAOptions(rogue.start(t))
+	opts.CaCertFile = rootFile
+	r, err := NewExternalSignerRA(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	_, err = r.SignWithCertChain(createDefaultFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: time.Hour})
+	if err == nil || !strings.Contains(err.Error(), "certificate chain from external signer is invalid") {
+		t.Fatalf("expected the chain not issued under the trust anchor to be rejected, got %v", err)
+	}
+
+	// Without a CA cert file the roots must be fetched from the signers.
+	noRoots := newFakeSigner(t)
+	noRoots.noRoots = true
+	if _, err := NewExternalSignerRA(testExternalSignerRAOptions(noRoots.start(t))); err == nil {
+		t.Fatal("expected an error when the signers do not return their roots")
+	}
+}
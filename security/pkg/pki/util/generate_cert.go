@@ -46,9 +46,19 @@ type SupportedECSignatureAlgorithms string
 // to be used in key generation (e.g. P256, P384)
 type SupportedEllipticCurves string
 
+// SupportedMLDSAParameterSets are the ML-DSA parameter sets
+// to be used in key generation (e.g. ML-DSA-44, ML-DSA-65)
+type SupportedMLDSAParameterSets string
+
 const (
-	// only ECDSA is currently supported
 	EcdsaSigAlg SupportedECSignatureAlgorithms = "ECDSA"
+	// MLDSASigAlg selects post-quantum ML-DSA keys. It requires the ENABLE_ML_DSA feature flag.
+	MLDSASigAlg SupportedECSignatureAlgorithms = "MLDSA"
+
+	// supported parameter sets when using ML-DSA
+	MLDSA44 SupportedMLDSAParameterSets = "ML-DSA-44"
+	MLDSA65 SupportedMLDSAParameterSets = "ML-DSA-65"
+	MLDSA87 SupportedMLDSAParameterSets = "ML-DSA-87"
 
 	// supported curves when using ECC
 	P256Curve SupportedEllipticCurves = "P256"
@@ -102,8 +112,8 @@ type CertOptions struct {
 	PKCS8Key bool
 
 	// The type of Elliptical Signature algorithm to use
-	// when generating private keys. Currently only ECDSA is supported.
-	// If empty, RSA is used, otherwise ECC is used.
+	// when generating private keys. ECDSA and, behind a feature flag, ML-DSA are supported.
+	// If empty, RSA is used.
 	ECSigAlg SupportedECSignatureAlgorithms
 
 	// The type of Elliptical Signature algorithm to use
@@ -111,6 +121,10 @@ type CertOptions struct {
 	// If empty, RSA is used, otherwise ECC is used.
 	ECCCurve SupportedEllipticCurves
 
+	// The ML-DSA parameter set to use when ECSigAlg is MLDSASigAlg.
+	// If empty, ML-DSA-65 is used.
+	MLDSAParams SupportedMLDSAParameterSets
+
 	// Subjective Alternative Name values.
 	DNSNames string
 }
@@ -139,7 +153,14 @@ func GenCertKeyFromOptions(options CertOptions) (pemCert []byte, pemKey []byte,
 			if err != nil {
 				return nil, nil, fmt.Errorf("cert generation fails at EC key generation (%v)", err)
 			}
-
+		case MLDSASigAlg:
+			priv, pub, err := generateMLDSAKey(options.MLDSAParams)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cert generation fails at ML-DSA key generation (%v)", err)
+			}
+			// ML-DSA private keys can only be encoded as PKCS#8.
+			options.PKCS8Key = true
+			return genCert(options, priv, pub)
 		default:
 			return nil, nil, errors.New("cert generation fails due to unsupported EC signature algorithm")
 		}
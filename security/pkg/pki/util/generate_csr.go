@@ -56,6 +56,13 @@ func GenCSR(options CertOptions) ([]byte, []byte, error) {
 			if err != nil {
 				return nil, nil, fmt.Errorf("EC key generation failed (%v)", err)
 			}
+		case MLDSASigAlg:
+			priv, _, err = generateMLDSAKey(options.MLDSAParams)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ML-DSA key generation failed (%v)", err)
+			}
+			// ML-DSA private keys can only be encoded as PKCS#8.
+			options.PKCS8Key = true
 		default:
 			return nil, nil, errors.New("csr cert generation fails due to unsupported EC signature algorithm")
 		}
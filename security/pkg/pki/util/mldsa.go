@@ -0,0 +1,49 @@
+//go:build go1.27
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/mldsa"
+	"errors"
+	"fmt"
+
+	"istio.io/istio/pkg/features"
+)
+
+// generateMLDSAKey generates an ML-DSA private key with the given parameter set,
+// returning the private and public keys.
+func generateMLDSAKey(params SupportedMLDSAParameterSets) (any, any, error) {
+	if !features.EnableMLDSA {
+		return nil, nil, errors.New("ML-DSA key generation is disabled, set ENABLE_ML_DSA to enable it")
+	}
+	var p mldsa.Parameters
+	switch params {
+	case MLDSA44:
+		p = mldsa.MLDSA44()
+	case MLDSA65, "":
+		p = mldsa.MLDSA65()
+	case MLDSA87:
+		p = mldsa.MLDSA87()
+	default:
+		return nil, nil, fmt.Errorf("unsupported ML-DSA parameter set %q", params)
+	}
+	priv, err := mldsa.GenerateKey(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, priv.PublicKey(), nil
+}
@@ -0,0 +1,87 @@
+//go:build go1.27
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"crypto/mldsa"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/features"
+	"istio.io/istio/pkg/test"
+)
+
+func TestGenMLDSACertAndCSR(t *testing.T) {
+	if _, _, err := GenCSR(CertOptions{Host: "spiffe://cluster.local/ns/foo/sa/bar", ECSigAlg: MLDSASigAlg}); err == nil {
+		t.Fatal("expected ML-DSA to be rejected when the feature flag is disabled")
+	}
+	test.SetForTest(t, &features.EnableMLDSA, true)
+
+	rootPEM, rootKeyPEM, err := GenCertKeyFromOptions(CertOptions{
+		Org:          "MyOrg",
+		IsCA:         true,
+		IsSelfSigned: true,
+		TTL:          time.Hour,
+		ECSigAlg:     MLDSASigAlg,
+		MLDSAParams:  MLDSA87,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := ParsePemEncodedCertificate(rootPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootKey, err := ParsePemEncodedKey(rootKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rootKey.(*mldsa.PrivateKey); !ok {
+		t.Fatalf("expected an ML-DSA root key, got %T", rootKey)
+	}
+
+	csrPEM, keyPEM, err := GenCSR(CertOptions{
+		Host:     "spiffe://cluster.local/ns/foo/sa/bar",
+		ECSigAlg: MLDSASigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParsePemEncodedKey(keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	csr, err := ParsePemEncodedCSR(csrPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Fatalf("invalid CSR signature: %v", err)
+	}
+	der, err := GenCertFromCSR(csr, rootCert, csr.PublicKey, rootKey, []string{"spiffe://cluster.local/ns/foo/sa/bar"}, time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := VerifyCertificate(nil, leafPEM, rootPEM, nil); err != nil {
+		t.Fatalf("ML-DSA certificate does not verify: %v", err)
+	}
+
+	if _, _, err := GenCSR(CertOptions{Host: "spiffe://cluster.local/ns/foo/sa/bar", ECSigAlg: MLDSASigAlg, MLDSAParams: "ML-DSA-1"}); err == nil {
+		t.Fatal("expected error for unknown ML-DSA parameter set")
+	}
+}
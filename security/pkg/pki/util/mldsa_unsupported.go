@@ -0,0 +1,24 @@
+//go:build !go1.27
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import "errors"
+
+// generateMLDSAKey always fails, as ML-DSA requires crypto/mldsa from Go 1.27 or later.
+func generateMLDSAKey(SupportedMLDSAParameterSets) (any, any, error) {
+	return nil, nil, errors.New("ML-DSA is not supported by this Go version")
+}
@@ -0,0 +1,214 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attestation authenticates workloads, typically VMs, by verifying evidence about the
+// platform they run on. A successful attestation maps the workload to a WorkloadGroup, whose
+// service account identity is then issued through the regular CreateCertificate path.
+package attestation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	AuthenticatorType = "AttestationAuthenticator"
+
+	defaultServiceAccount = "default"
+)
+
+var attestationLog = log.RegisterScope("attestation", "workload attestation debugging")
+
+// Result is the outcome of a successful attestation.
+type Result struct {
+	// WorkloadGroup the attested workload belongs to.
+	WorkloadGroup types.NamespacedName
+	// Selectors describe the attested properties of the workload, such as aws:account:1234.
+	Selectors []string
+	// Consume, if set, consumes single use evidence. It is only called once a certificate was issued to the
+	// workload, so that a failed issuance does not burn the evidence.
+	Consume func() error
+}
+
+// Verifier verifies one type of attestation evidence.
+type Verifier interface {
+	// Type returns the attestation type, matching security.Attestor.Type on the agent side.
+	Type() string
+	// Attest verifies the evidence and returns the WorkloadGroup of the workload. Single use evidence is
+	// checked but not consumed, see Result.Consume.
+	Attest(ctx context.Context, evidence []byte) (*Result, error)
+}
+
+// WorkloadGroupLookup returns the service account of a WorkloadGroup, and whether the WorkloadGroup exists.
+type WorkloadGroupLookup func(name types.NamespacedName) (serviceAccount string, found bool)
+
+// Authenticator authenticates callers presenting attestation evidence in the
+// security.AttestationTypeHeader and security.AttestationEvidenceHeader headers.
+type Authenticator struct {
+	meshHolder mesh.Holder
+	lookup     WorkloadGroupLookup
+	verifiers  map[string]Verifier
+}
+
+var _ security.Authenticator = &Authenticator{}
+
+// NewAuthenticator creates an Authenticator using the given verifiers.
+func NewAuthenticator(meshHolder mesh.Holder, lookup WorkloadGroupLookup, verifiers ...Verifier) *Authenticator {
+	a := &Authenticator{
+		meshHolder: meshHolder,
+		lookup:     lookup,
+		verifiers:  make(map[string]Verifier, len(verifiers)),
+	}
+	for _, v := range verifiers {
+		a.verifiers[v.Type()] = v
+	}
+	return a
+}
+
+func (a *Authenticator) AuthenticatorType() string {
+	return AuthenticatorType
+}
+
+// Authenticate verifies the attestation evidence of the caller and returns the identity of its WorkloadGroup.
+func (a *Authenticator) Authenticate(authCtx security.AuthContext) (*security.Caller, error) {
+	attestationType := authCtx.Header(security.AttestationTypeHeader)
+	encoded := authCtx.Header(security.AttestationEvidenceHeader)
+	if len(attestationType) == 0 || len(encoded) == 0 {
+		return nil, fmt.Errorf("no attestation evidence")
+	}
+	v, ok := a.verifiers[attestationType[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported attestation type %q", attestationType[0])
+	}
+	evidence, err := base64.StdEncoding.DecodeString(encoded[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode attestation evidence: %v", err)
+	}
+	ctx := context.Background()
+	if authCtx.GrpcContext != nil {
+		ctx = authCtx.GrpcContext
+	} else if authCtx.Request != nil {
+		ctx = authCtx.Request.Context()
+	}
+	res, err := v.Attest(ctx, evidence)
+	if err != nil {
+		return nil, fmt.Errorf("%s attestation failed: %v", v.Type(), err)
+	}
+	sa, found := a.lookup(res.WorkloadGroup)
+	if !found {
+		return nil, fmt.Errorf("workload group %s not found", res.WorkloadGroup)
+	}
+	if sa == "" {
+		sa = defaultServiceAccount
+	}
+	attestationLog.Infof("attested workload from %s as %s/%s via %s: %v",
+		authCtx.RemoteAddress(), res.WorkloadGroup.Namespace, sa, v.Type(), res.Selectors)
+	return &security.Caller{
+		AuthSource: security.AuthSourceAttestation,
+		Identities: []string{spiffe.MustGenSpiffeURI(a.meshHolder.Mesh(), res.WorkloadGroup.Namespace, sa)},
+		KubernetesInfo: security.KubernetesInfo{
+			PodNamespace:      res.WorkloadGroup.Namespace,
+			PodServiceAccount: sa,
+		},
+		OnIssued: res.Consume,
+	}, nil
+}
+
+// Config configures the attestation verifiers.
+type Config struct {
+	JoinTokens []JoinTokenConfig `json:"joinTokens,omitempty"`
+	AWS        *AWSConfig        `json:"aws,omitempty"`
+	GCP        *GCPConfig        `json:"gcp,omitempty"`
+}
+
+// ReadConfig reads a JSON Config from a file.
+func ReadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestation config: %v", err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse attestation config: %v", err)
+	}
+	return cfg, nil
+}
+
+// NewVerifiers creates the verifiers enabled in the config, recording the consumed evidence in store.
+func NewVerifiers(cfg *Config, store UsageStore) ([]Verifier, error) {
+	var out []Verifier
+	if len(cfg.JoinTokens) > 0 {
+		jt := NewJoinTokenVerifier(store)
+		for _, t := range cfg.JoinTokens {
+			if err := jt.Add(t); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, jt)
+	}
+	if cfg.AWS != nil {
+		v, err := NewAWSVerifier(*cfg.AWS, store)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if cfg.GCP != nil {
+		v, err := NewGCPVerifier(*cfg.GCP, store)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// ParseWorkloadGroup parses a WorkloadGroup reference in the namespace/name format.
+func ParseWorkloadGroup(s string) (types.NamespacedName, error) {
+	ns, name, ok := strings.Cut(s, "/")
+	if !ok || ns == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("invalid workload group %q, expected namespace/name", s)
+	}
+	return types.NamespacedName{Namespace: ns, Name: name}, nil
+}
+
+// consumeOnce checks that the evidence identified by key was not used yet, and returns the function consuming it.
+// The use is recorded until expiry, after which the evidence is either no longer accepted or may be used again.
+func consumeOnce(ctx context.Context, store UsageStore, key, desc string, expiry time.Time) (func() error, error) {
+	used, err := store.Used(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the use of %s: %v", desc, err)
+	}
+	if used {
+		return nil, fmt.Errorf("%s %w", desc, ErrAlreadyUsed)
+	}
+	return func() error {
+		if err := store.Consume(ctx, key, expiry); err != nil {
+			return fmt.Errorf("failed to consume %s: %w", desc, err)
+		}
+		return nil
+	}, nil
+}
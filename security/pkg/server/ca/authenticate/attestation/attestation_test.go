@@ -0,0 +1,418 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	oidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"google.golang.org/grpc/metadata"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh/meshwatcher"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/util/sets"
+)
+
+var testWorkloadGroup = types.NamespacedName{Namespace: "vm", Name: "app"}
+
+func testLookup(name types.NamespacedName) (string, bool) {
+	if name == testWorkloadGroup {
+		return "app-sa", true
+	}
+	if name.Name == "no-sa" {
+		return "", true
+	}
+	return "", false
+}
+
+func authContext(attestationType string, evidence []byte) security.AuthContext {
+	md := metadata.Pairs(
+		security.AttestationTypeHeader, attestationType,
+		security.AttestationEvidenceHeader, base64.StdEncoding.EncodeToString(evidence))
+	return security.AuthContext{GrpcContext: metadata.NewIncomingContext(context.Background(), md)}
+}
+
+func addToken(t *testing.T, jt *JoinTokenVerifier, token, wg string) {
+	t.Helper()
+	sum := sha256.Sum256([]byte(token))
+	if err := jt.Add(JoinTokenConfig{SHA256: hex.EncodeToString(sum[:]), WorkloadGroup: wg}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	jt := NewJoinTokenVerifier(NewMemoryUsageStore())
+	a := NewAuthenticator(meshwatcher.NewTestWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"}), testLookup, jt)
+	addToken(t, jt, "token", "vm/app")
+	addToken(t, jt, "no-sa-token", "vm/no-sa")
+	addToken(t, jt, "missing-token", "vm/missing")
+
+	caller, err := a.Authenticate(authContext(JoinTokenType, []byte("token")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.AuthSource != security.AuthSourceAttestation ||
+		len(caller.Identities) != 1 || caller.Identities[0] != "spiffe://cluster.local/ns/vm/sa/app-sa" {
+		t.Fatalf("unexpected caller %+v", caller)
+	}
+
+	// The token is only consumed once a certificate is issued.
+	caller, err = a.Authenticate(authContext(JoinTokenType, []byte("token")))
+	if err != nil {
+		t.Fatalf("expected the token to be accepted until a certificate is issued: %v", err)
+	}
+	if err := caller.OnIssued(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(authContext(JoinTokenType, []byte("token"))); err == nil {
+		t.Fatal("expected reused join token to be rejected")
+	}
+
+	// WorkloadGroups without a service account use the default one.
+	caller, err = a.Authenticate(authContext(JoinTokenType, []byte("no-sa-token")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.Identities[0] != "spiffe://cluster.local/ns/vm/sa/default" {
+		t.Fatalf("unexpected identity %v", caller.Identities)
+	}
+
+	// Tokens for missing WorkloadGroups are rejected.
+	if _, err := a.Authenticate(authContext(JoinTokenType, []byte("missing-token"))); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing workload group error, got %v", err)
+	}
+
+	if _, err := a.Authenticate(authContext("tpm", []byte("x"))); err == nil {
+		t.Fatal("expected unsupported attestation type to be rejected")
+	}
+	if _, err := a.Authenticate(security.AuthContext{GrpcContext: context.Background()}); err == nil {
+		t.Fatal("expected missing evidence to be rejected")
+	}
+}
+
+func TestJoinTokenVerifier(t *testing.T) {
+	jt := NewJoinTokenVerifier(NewMemoryUsageStore())
+	now := time.Now()
+	jt.now = func() time.Time { return now }
+
+	sum := sha256.Sum256([]byte("provisioned-token"))
+	if err := jt.Add(JoinTokenConfig{SHA256: hex.EncodeToString(sum[:]), WorkloadGroup: "vm/app", Expiry: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := jt.Add(JoinTokenConfig{SHA256: "abcd", WorkloadGroup: "vm/app"}); err == nil {
+		t.Fatal("expected invalid hash to be rejected")
+	}
+	if err := jt.Add(JoinTokenConfig{SHA256: hex.EncodeToString(sum[:]), WorkloadGroup: "app"}); err == nil {
+		t.Fatal("expected invalid workload group to be rejected")
+	}
+	res, err := jt.Attest(context.Background(), []byte("provisioned-token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WorkloadGroup != testWorkloadGroup {
+		t.Fatalf("unexpected workload group %v", res.WorkloadGroup)
+	}
+	if _, err := jt.Attest(context.Background(), []byte("unknown-token")); err == nil {
+		t.Fatal("expected unknown token to be rejected")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := jt.Attest(context.Background(), []byte("provisioned-token")); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected expired token error, got %v", err)
+	}
+}
+
+// The consumed tokens are shared by the istiod replicas through the Secret.
+func TestSecretUsageStore(t *testing.T) {
+	client := kube.NewFakeClient().Kube()
+	replica1 := NewJoinTokenVerifier(NewSecretUsageStore(client, "istio-system"))
+	replica2 := NewJoinTokenVerifier(NewSecretUsageStore(client, "istio-system"))
+	addToken(t, replica1, "token", "vm/app")
+	addToken(t, replica2, "token", "vm/app")
+
+	first, err := replica1.Attest(context.Background(), []byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	concurrent, err := replica2.Attest(context.Background(), []byte("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Consume(); err != nil {
+		t.Fatal(err)
+	}
+	// The token was consumed by the other replica in the meantime.
+	if err := concurrent.Consume(); !errors.Is(err, ErrAlreadyUsed) {
+		t.Fatalf("expected the token to be consumed once, got %v", err)
+	}
+	if _, err := replica2.Attest(context.Background(), []byte("token")); !errors.Is(err, ErrAlreadyUsed) {
+		t.Fatalf("expected used token to be rejected, got %v", err)
+	}
+	secret, err := client.CoreV1().Secrets("istio-system").Get(context.Background(), UsageSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, f := secret.Data[usageKey(JoinTokenType, hashToken([]byte("token")))]; !f || len(secret.Data) != 1 {
+		t.Fatalf("unexpected usage secret data %v", secret.Data)
+	}
+}
+
+// Expired records are pruned from the Secret when evidence is consumed, so it does not grow without bound.
+func TestSecretUsageStorePruning(t *testing.T) {
+	client := kube.NewFakeClient().Kube()
+	store := NewSecretUsageStore(client, "istio-system")
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := store.Consume(ctx, "forever", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Consume(ctx, "short", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Consume(ctx, "long", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Consume(ctx, "short", now.Add(time.Minute)); !errors.Is(err, ErrAlreadyUsed) {
+		t.Fatalf("expected the record to be kept until its expiry, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if used, err := store.Used(ctx, "short"); err != nil || used {
+		t.Fatalf("expected an expired record not to count as used, got %v %v", used, err)
+	}
+	if err := store.Consume(ctx, "new", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.CoreV1().Secrets("istio-system").Get(ctx, UsageSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sets.New(maps.Keys(secret.Data)...)
+	if want := sets.New("forever", "long", "new"); !got.Equals(want) {
+		t.Fatalf("expected expired records to be pruned, got keys %v", sets.SortedList(got))
+	}
+
+	// Instances are remembered for the configured usage TTL.
+	mem := NewMemoryUsageStore()
+	mem.now = func() time.Time { return now }
+	key, certPEM := newSigningCert(t)
+	v, err := NewAWSVerifier(AWSConfig{
+		Certificates: certPEM,
+		Bindings:     []PlatformBinding{{Account: "123", WorkloadGroup: "vm/app"}},
+		UsageTTL:     &metav1.Duration{Duration: time.Hour},
+	}, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.now = mem.now
+	ev := awsEvidence(t, key, map[string]string{"accountId": "123", "instanceId": "i-1", "region": "us-east-1"})
+	res, err := v.Attest(ctx, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Consume(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Attest(ctx, ev); !errors.Is(err, ErrAlreadyUsed) {
+		t.Fatalf("expected the instance to be rejected within the usage TTL, got %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := v.Attest(ctx, ev); err != nil {
+		t.Fatalf("expected the instance to attest again after the usage TTL, got %v", err)
+	}
+}
+
+func newSigningCert(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Amazon Web Services LLC"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func awsEvidence(t *testing.T, key *rsa.PrivateKey, doc map[string]string) []byte {
+	t.Helper()
+	raw, _ := json.Marshal(doc)
+	digest := sha256.Sum256(raw)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, _ := json.Marshal(AWSEvidence{Document: string(raw), Signature: base64.StdEncoding.EncodeToString(sig)})
+	return ev
+}
+
+func TestAWSVerifier(t *testing.T) {
+	key, certPEM := newSigningCert(t)
+	otherKey, _ := newSigningCert(t)
+	v, err := NewAWSVerifier(AWSConfig{
+		Certificates: certPEM,
+		Bindings: []PlatformBinding{
+			{Account: "111122223333", Location: "us-west-2", WorkloadGroup: "vm/app"},
+			{Account: "444455556666", WorkloadGroup: "vm/other"},
+		},
+	}, NewMemoryUsageStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := map[string]string{"accountId": "111122223333", "region": "us-west-2", "instanceId": "i-1"}
+
+	res, err := v.Attest(context.Background(), awsEvidence(t, key, doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WorkloadGroup != testWorkloadGroup {
+		t.Fatalf("unexpected workload group %v", res.WorkloadGroup)
+	}
+	if err := res.Consume(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		evidence []byte
+		wantErr  string
+	}{
+		{"replayed document", awsEvidence(t, key, doc), "already used"},
+		{"untrusted signer", awsEvidence(t, otherKey, map[string]string{"accountId": "111122223333", "region": "us-west-2", "instanceId": "i-2"}), "signature"},
+		{"unbound region", awsEvidence(t, key, map[string]string{"accountId": "111122223333", "region": "eu-west-1", "instanceId": "i-3"}), "no workload group"},
+		{"malformed evidence", []byte("{"), "failed to parse"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Attest(context.Background(), tt.evidence); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Any region is accepted for bindings without a location.
+	res, err = v.Attest(context.Background(), awsEvidence(t, key, map[string]string{"accountId": "444455556666", "region": "eu-west-1", "instanceId": "i-4"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WorkloadGroup.Name != "other" {
+		t.Fatalf("unexpected workload group %v", res.WorkloadGroup)
+	}
+}
+
+func gcpToken(t *testing.T, key *rsa.PrivateKey, aud, project, zone, instance string) []byte {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]any{
+		"iss": GCPIssuer,
+		"aud": aud,
+		"sub": "1234",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"google": map[string]any{
+			"compute_engine": map[string]string{
+				"project_id":    project,
+				"zone":          zone,
+				"instance_id":   instance,
+				"instance_name": "vm-" + instance,
+			},
+		},
+	}
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(token)
+}
+
+func TestGCPVerifier(t *testing.T) {
+	key, _ := newSigningCert(t)
+	otherKey, _ := newSigningCert(t)
+	v, err := newGCPVerifier(GCPConfig{
+		Audience: "istiod.istio-system.svc",
+		Bindings: []PlatformBinding{{Account: "my-project", Location: "us-central1-a", WorkloadGroup: "vm/app"}},
+	}, &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{&key.PublicKey}}, NewMemoryUsageStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := v.Attest(context.Background(), gcpToken(t, key, "istiod.istio-system.svc", "my-project", "us-central1-a", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WorkloadGroup != testWorkloadGroup {
+		t.Fatalf("unexpected workload group %v", res.WorkloadGroup)
+	}
+	if err := res.Consume(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		evidence []byte
+		wantErr  string
+	}{
+		{"replayed token", gcpToken(t, key, "istiod.istio-system.svc", "my-project", "us-central1-a", "1"), "already used"},
+		{"untrusted signer", gcpToken(t, otherKey, "istiod.istio-system.svc", "my-project", "us-central1-a", "2"), "failed to verify"},
+		{"wrong audience", gcpToken(t, key, "other", "my-project", "us-central1-a", "3"), "failed to verify"},
+		{"unbound project", gcpToken(t, key, "istiod.istio-system.svc", "other-project", "us-central1-a", "4"), "no workload group"},
+		{"no instance", gcpToken(t, key, "istiod.istio-system.svc", "my-project", "us-central1-a", ""), "no instance ID"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Attest(context.Background(), tt.evidence); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewGCPVerifier(GCPConfig{Bindings: []PlatformBinding{{Account: "p", WorkloadGroup: "vm/app"}}}, NewMemoryUsageStore()); err == nil {
+		t.Fatal("expected error for missing audience")
+	}
+}
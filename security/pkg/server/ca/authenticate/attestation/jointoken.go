@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	JoinTokenType = "join_token"
+)
+
+// JoinTokenConfig pre-provisions a join token. Only the SHA-256 of the token is configured,
+// so the configuration does not hold usable credentials.
+type JoinTokenConfig struct {
+	// SHA256 is the hex encoded SHA-256 of the token.
+	SHA256 string `json:"sha256"`
+	// WorkloadGroup is the namespace/name of the WorkloadGroup the token joins.
+	WorkloadGroup string `json:"workloadGroup"`
+	// Expiry is the time after which the token is no longer accepted. If unset, the token does not expire.
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+type joinToken struct {
+	workloadGroup types.NamespacedName
+	expiry        time.Time
+}
+
+// JoinTokenVerifier verifies pre-provisioned one-time join tokens. A token is consumed once a certificate
+// was issued with it; afterwards the workload renews its certificate using the issued certificate.
+type JoinTokenVerifier struct {
+	tokens map[string]joinToken
+	store  UsageStore
+	now    func() time.Time
+}
+
+var _ Verifier = &JoinTokenVerifier{}
+
+func NewJoinTokenVerifier(store UsageStore) *JoinTokenVerifier {
+	return &JoinTokenVerifier{
+		tokens: map[string]joinToken{},
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+func (j *JoinTokenVerifier) Type() string {
+	return JoinTokenType
+}
+
+// Add registers a pre-provisioned token.
+func (j *JoinTokenVerifier) Add(cfg JoinTokenConfig) error {
+	wg, err := ParseWorkloadGroup(cfg.WorkloadGroup)
+	if err != nil {
+		return err
+	}
+	hash, err := hex.DecodeString(cfg.SHA256)
+	if err != nil || len(hash) != sha256.Size {
+		return fmt.Errorf("invalid join token hash for workload group %s", cfg.WorkloadGroup)
+	}
+	j.tokens[hex.EncodeToString(hash)] = joinToken{workloadGroup: wg, expiry: cfg.Expiry}
+	return nil
+}
+
+// Attest checks the token and returns the WorkloadGroup it was provisioned for.
+func (j *JoinTokenVerifier) Attest(ctx context.Context, evidence []byte) (*Result, error) {
+	h := hashToken(evidence)
+	t, ok := j.tokens[h]
+	if !ok {
+		return nil, fmt.Errorf("unknown join token")
+	}
+	if !t.expiry.IsZero() && j.now().After(t.expiry) {
+		return nil, fmt.Errorf("join token expired at %v", t.expiry)
+	}
+	// The token is rejected once expired, so its use only needs to be recorded until then.
+	consume, err := consumeOnce(ctx, j.store, usageKey(JoinTokenType, h), "join token", t.expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		WorkloadGroup: t.workloadGroup,
+		Selectors:     []string{"join_token:" + h[:16]},
+		Consume:       consume,
+	}, nil
+}
+
+func hashToken(token []byte) string {
+	h := sha256.Sum256(token)
+	return hex.EncodeToString(h[:])
+}
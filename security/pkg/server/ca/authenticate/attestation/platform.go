@@ -0,0 +1,312 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	oidc "github.com/coreos/go-oidc/v3/oidc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	AWSInstanceIdentityType = "aws_iid"
+	GCPInstanceIdentityType = "gcp_iit"
+
+	// GCPIssuer is the issuer of GCE instance identity tokens.
+	GCPIssuer = "https://accounts.google.com"
+	// GCPJwksURI serves the keys signing GCE instance identity tokens.
+	GCPJwksURI = "https://www.googleapis.com/oauth2/v3/certs"
+
+	// defaultInstanceUsageTTL is how long the attestation of an instance is remembered when it is not configured.
+	defaultInstanceUsageTTL = 30 * 24 * time.Hour
+)
+
+// PlatformBinding maps platform instances to a WorkloadGroup.
+type PlatformBinding struct {
+	// Account is the AWS account ID or the GCP project ID.
+	Account string `json:"account"`
+	// Location optionally restricts the binding to an AWS region or a GCP zone.
+	Location string `json:"location,omitempty"`
+	// WorkloadGroup is the namespace/name of the WorkloadGroup the instances belong to.
+	WorkloadGroup string `json:"workloadGroup"`
+}
+
+type platformBinding struct {
+	account       string
+	location      string
+	workloadGroup types.NamespacedName
+}
+
+func parseBindings(bindings []PlatformBinding) ([]platformBinding, error) {
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("at least one binding is required")
+	}
+	out := make([]platformBinding, 0, len(bindings))
+	for _, b := range bindings {
+		if b.Account == "" {
+			return nil, fmt.Errorf("binding for workload group %s has no account", b.WorkloadGroup)
+		}
+		wg, err := ParseWorkloadGroup(b.WorkloadGroup)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, platformBinding{account: b.Account, location: b.Location, workloadGroup: wg})
+	}
+	return out, nil
+}
+
+// instanceUsageTTL returns the configured usage TTL, or the default if unset.
+func instanceUsageTTL(ttl *metav1.Duration) time.Duration {
+	if ttl == nil || ttl.Duration <= 0 {
+		return defaultInstanceUsageTTL
+	}
+	return ttl.Duration
+}
+
+func matchBinding(bindings []platformBinding, account, location string) (types.NamespacedName, bool) {
+	for _, b := range bindings {
+		if b.account == account && (b.location == "" || b.location == location) {
+			return b.workloadGroup, true
+		}
+	}
+	return types.NamespacedName{}, false
+}
+
+// AWSConfig configures verification of AWS EC2 instance identity documents.
+type AWSConfig struct {
+	// Certificates are the PEM encoded AWS public certificates for the regions in use, which verify
+	// the RSA-SHA256 signature of instance identity documents.
+	Certificates string            `json:"certificates"`
+	Bindings     []PlatformBinding `json:"bindings"`
+	// AllowReattestation allows an instance to attest more than once. By default an instance
+	// identity document is only accepted the first time, as it is readable by anything on the instance.
+	AllowReattestation bool `json:"allowReattestation,omitempty"`
+	// UsageTTL is how long the attestation of an instance is remembered, after which the instance can attest
+	// again. Defaults to 30 days.
+	UsageTTL *metav1.Duration `json:"usageTTL,omitempty"`
+}
+
+// AWSEvidence is the evidence sent by AWS instances.
+type AWSEvidence struct {
+	// Document is the raw instance identity document.
+	Document string `json:"document"`
+	// Signature is the base64 encoded signature of the document.
+	Signature string `json:"signature"`
+}
+
+type awsDocument struct {
+	AccountID  string `json:"accountId"`
+	InstanceID string `json:"instanceId"`
+	Region     string `json:"region"`
+}
+
+// AWSVerifier verifies AWS instance identity documents.
+type AWSVerifier struct {
+	certs              []*x509.Certificate
+	bindings           []platformBinding
+	allowReattestation bool
+	usageTTL           time.Duration
+	store              UsageStore
+	now                func() time.Time
+}
+
+var _ Verifier = &AWSVerifier{}
+
+func NewAWSVerifier(cfg AWSConfig, store UsageStore) (*AWSVerifier, error) {
+	certs, _, err := util.ParsePemEncodedCertificateChain([]byte(cfg.Certificates))
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("invalid AWS certificates: %v", err)
+	}
+	bindings, err := parseBindings(cfg.Bindings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AWS attestation config: %v", err)
+	}
+	return &AWSVerifier{
+		certs:              certs,
+		bindings:           bindings,
+		allowReattestation: cfg.AllowReattestation,
+		usageTTL:           instanceUsageTTL(cfg.UsageTTL),
+		store:              store,
+		now:                time.Now,
+	}, nil
+}
+
+func (a *AWSVerifier) Type() string {
+	return AWSInstanceIdentityType
+}
+
+func (a *AWSVerifier) Attest(ctx context.Context, evidence []byte) (*Result, error) {
+	ev := AWSEvidence{}
+	if err := json.Unmarshal(evidence, &ev); err != nil {
+		return nil, fmt.Errorf("failed to parse evidence: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(ev.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %v", err)
+	}
+	verified := false
+	for _, c := range a.certs {
+		if c.CheckSignature(x509.SHA256WithRSA, []byte(ev.Document), sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("instance identity document signature verification failed")
+	}
+	doc := awsDocument{}
+	if err := json.Unmarshal([]byte(ev.Document), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse instance identity document: %v", err)
+	}
+	if doc.InstanceID == "" {
+		return nil, fmt.Errorf("instance identity document has no instance ID")
+	}
+	wg, ok := matchBinding(a.bindings, doc.AccountID, doc.Region)
+	if !ok {
+		return nil, fmt.Errorf("no workload group bound to account %s in region %s", doc.AccountID, doc.Region)
+	}
+	var consume func() error
+	if !a.allowReattestation {
+		if consume, err = consumeOnce(ctx, a.store, usageKey("aws", doc.AccountID, doc.InstanceID), "instance "+doc.InstanceID,
+			a.now().Add(a.usageTTL)); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{
+		WorkloadGroup: wg,
+		Consume:       consume,
+		Selectors: []string{
+			"aws:account:" + doc.AccountID,
+			"aws:region:" + doc.Region,
+			"aws:instance:" + doc.InstanceID,
+		},
+	}, nil
+}
+
+// GCPConfig configures verification of GCE instance identity tokens.
+type GCPConfig struct {
+	// Audience is the audience the instances request their identity token for.
+	Audience string `json:"audience"`
+	// JwksURI overrides the location of the keys signing identity tokens.
+	JwksURI  string            `json:"jwksURI,omitempty"`
+	Bindings []PlatformBinding `json:"bindings"`
+	// AllowReattestation allows an instance to attest more than once.
+	AllowReattestation bool `json:"allowReattestation,omitempty"`
+	// UsageTTL is how long the attestation of an instance is remembered, after which the instance can attest
+	// again. Defaults to 30 days.
+	UsageTTL *metav1.Duration `json:"usageTTL,omitempty"`
+}
+
+// gcpClaims are the claims of a GCE instance identity token requested with format=full.
+type gcpClaims struct {
+	Google struct {
+		ComputeEngine struct {
+			ProjectID    string `json:"project_id"`
+			Zone         string `json:"zone"`
+			InstanceID   string `json:"instance_id"`
+			InstanceName string `json:"instance_name"`
+		} `json:"compute_engine"`
+	} `json:"google"`
+}
+
+// GCPVerifier verifies GCE instance identity tokens.
+type GCPVerifier struct {
+	verifier           *oidc.IDTokenVerifier
+	bindings           []platformBinding
+	allowReattestation bool
+	usageTTL           time.Duration
+	store              UsageStore
+	now                func() time.Time
+}
+
+var _ Verifier = &GCPVerifier{}
+
+func NewGCPVerifier(cfg GCPConfig, store UsageStore) (*GCPVerifier, error) {
+	jwksURI := cfg.JwksURI
+	if jwksURI == "" {
+		jwksURI = GCPJwksURI
+	}
+	return newGCPVerifier(cfg, oidc.NewRemoteKeySet(context.Background(), jwksURI), store)
+}
+
+func newGCPVerifier(cfg GCPConfig, keySet oidc.KeySet, store UsageStore) (*GCPVerifier, error) {
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("invalid GCP attestation config: audience is required")
+	}
+	bindings, err := parseBindings(cfg.Bindings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GCP attestation config: %v", err)
+	}
+	return &GCPVerifier{
+		verifier: oidc.NewVerifier(GCPIssuer, keySet, &oidc.Config{
+			ClientID:             cfg.Audience,
+			SupportedSigningAlgs: []string{oidc.RS256},
+		}),
+		bindings:           bindings,
+		allowReattestation: cfg.AllowReattestation,
+		usageTTL:           instanceUsageTTL(cfg.UsageTTL),
+		store:              store,
+		now:                time.Now,
+	}, nil
+}
+
+func (g *GCPVerifier) Type() string {
+	return GCPInstanceIdentityType
+}
+
+func (g *GCPVerifier) Attest(ctx context.Context, evidence []byte) (*Result, error) {
+	token, err := g.verifier.Verify(ctx, string(evidence))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify identity token: %v", err)
+	}
+	claims := gcpClaims{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to extract claims from identity token: %v", err)
+	}
+	ce := claims.Google.ComputeEngine
+	if ce.InstanceID == "" {
+		return nil, fmt.Errorf("identity token has no instance ID, request it with format=full")
+	}
+	wg, ok := matchBinding(g.bindings, ce.ProjectID, ce.Zone)
+	if !ok {
+		return nil, fmt.Errorf("no workload group bound to project %s in zone %s", ce.ProjectID, ce.Zone)
+	}
+	var consume func() error
+	if !g.allowReattestation {
+		if consume, err = consumeOnce(ctx, g.store, usageKey("gcp", ce.ProjectID, ce.InstanceID), "instance "+ce.InstanceID,
+			g.now().Add(g.usageTTL)); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{
+		WorkloadGroup: wg,
+		Consume:       consume,
+		Selectors: []string{
+			"gcp:project:" + ce.ProjectID,
+			"gcp:zone:" + ce.Zone,
+			"gcp:instance:" + ce.InstanceID,
+			"gcp:instance-name:" + ce.InstanceName,
+		},
+	}, nil
+}
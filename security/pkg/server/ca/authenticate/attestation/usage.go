@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// UsageSecretName is the name of the Secret recording the single use evidence consumed by workloads.
+const UsageSecretName = "istio-workload-attestation"
+
+// ErrAlreadyUsed is returned when single use evidence was already consumed.
+var ErrAlreadyUsed = errors.New("already used")
+
+// UsageStore records the single use evidence, such as join tokens and instance identities, consumed by
+// workloads. It is shared by the istiod replicas, so evidence cannot be used once per replica.
+type UsageStore interface {
+	// Used returns true if the evidence identified by key was consumed and its record has not expired.
+	Used(ctx context.Context, key string) (bool, error)
+	// Consume records the use of the evidence identified by key until expiry, or forever if expiry is zero.
+	// Records past their expiry are pruned. It returns ErrAlreadyUsed if the evidence was consumed before.
+	Consume(ctx context.Context, key string, expiry time.Time) error
+}
+
+// usageKey returns the key of a piece of evidence, made of characters allowed in Secret keys.
+func usageKey(kind string, id ...string) string {
+	return kind + "." + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, strings.Join(id, "."))
+}
+
+// expired returns true if a record expiring at expiry is no longer needed at now.
+func expired(expiry, now time.Time) bool {
+	return !expiry.IsZero() && now.After(expiry)
+}
+
+// MemoryUsageStore records the consumed evidence in memory. It is only suitable for a single istiod replica.
+type MemoryUsageStore struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+	now  func() time.Time
+}
+
+var _ UsageStore = &MemoryUsageStore{}
+
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{used: map[string]time.Time{}, now: time.Now}
+}
+
+func (m *MemoryUsageStore) Used(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiry, f := m.used[key]
+	return f && !expired(expiry, m.now()), nil
+}
+
+func (m *MemoryUsageStore) Consume(_ context.Context, key string, expiry time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for k, e := range m.used {
+		if expired(e, now) {
+			delete(m.used, k)
+		}
+	}
+	if _, f := m.used[key]; f {
+		return ErrAlreadyUsed
+	}
+	m.used[key] = expiry
+	return nil
+}
+
+// SecretUsageStore records the consumed evidence as the keys of a Secret, with the expiry of the record as value,
+// or an empty value if it does not expire. Expired records are pruned whenever evidence is consumed, so the Secret
+// only grows with the evidence still in use.
+// Concurrent uses by several istiod replicas are serialized by the optimistic concurrency of the API server.
+// Deleting a key allows the evidence to be used again.
+type SecretUsageStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	now       func() time.Time
+}
+
+var _ UsageStore = &SecretUsageStore{}
+
+func NewSecretUsageStore(client kubernetes.Interface, namespace string) *SecretUsageStore {
+	return &SecretUsageStore{client: client, namespace: namespace, name: UsageSecretName, now: time.Now}
+}
+
+// parseExpiry returns the expiry recorded in a Secret value. Values that cannot be parsed are kept forever.
+func parseExpiry(v []byte) time.Time {
+	t, _ := time.Parse(time.RFC3339, string(v))
+	return t
+}
+
+func formatExpiry(expiry time.Time) []byte {
+	if expiry.IsZero() {
+		return []byte{}
+	}
+	return []byte(expiry.UTC().Format(time.RFC3339))
+}
+
+func (s *SecretUsageStore) Used(ctx context.Context, key string) (bool, error) {
+	secret, err := s.client.CoreV1().Secrets(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	v, f := secret.Data[key]
+	return f && !expired(parseExpiry(v), s.now()), nil
+}
+
+func (s *SecretUsageStore) Consume(ctx context.Context, key string, expiry time.Time) error {
+	value := formatExpiry(expiry)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secrets := s.client.CoreV1().Secrets(s.namespace)
+		secret, err := secrets.Get(ctx, s.name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			_, err = secrets.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+				Data:       map[string][]byte{key: value},
+			}, metav1.CreateOptions{})
+			if kerrors.IsAlreadyExists(err) {
+				// Created concurrently by another replica, retry with an update.
+				return kerrors.NewConflict(corev1.Resource("secrets"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		for k, v := range secret.Data {
+			if expired(parseExpiry(v), now) {
+				delete(secret.Data, k)
+			}
+		}
+		if _, f := secret.Data[key]; f {
+			return ErrAlreadyUsed
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = value
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
@@ -115,6 +115,7 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		TTL:        time.Duration(request.ValidityDuration) * time.Second,
 		ForCA:      false,
 		CertSigner: certSigner,
+		Caller:     caller,
 	}
 	var signErr error
 	var cert []byte
@@ -154,6 +155,14 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		response.CertChain = append(response.CertChain, string(rootCertBytes))
 	}
 
+	if caller.OnIssued != nil {
+		if err := caller.OnIssued(); err != nil {
+			s.monitoring.AuthnError.Increment()
+			serverCaLog.Warnf("certificate issued for %v discarded: %v", sans, err)
+			return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
+		}
+	}
+
 	serverCaLog.Debugf("Responding with cert chain, %q", response.CertChain)
 	s.monitoring.Success.Increment()
 	serverCaLog.Debugf("CSR successfully signed, sans %v.", sans)
@@ -80,6 +80,7 @@ type mockAuthenticator struct {
 	identities     []string
 	kubernetesInfo security.KubernetesInfo
 	errMsg         string
+	onIssued       func() error
 }
 
 func (authn *mockAuthenticator) AuthenticatorType() string {
@@ -95,6 +96,7 @@ func (authn *mockAuthenticator) Authenticate(_ security.AuthContext) (*security.
 		AuthSource:     authn.authSource,
 		Identities:     authn.identities,
 		KubernetesInfo: authn.kubernetesInfo,
+		OnIssued:       authn.onIssued,
 	}, nil
 }
 
@@ -269,6 +271,17 @@ func TestCreateCertificate(t *testing.T) {
 			certChain: []string{testCert, testCertChain, testRootCert},
 			code:      codes.OK,
 		},
+		"Single use credential already consumed": {
+			authenticators: []security.Authenticator{&mockAuthenticator{
+				identities: []string{"test-identity"},
+				onIssued:   func() error { return fmt.Errorf("already used") },
+			}},
+			ca: &mockca.FakeCA{
+				SignedCert:    []byte(testCert),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte(testCertChain), []byte(testRootCert), nil),
+			},
+			code: codes.Unauthenticated,
+		},
 		"Successful signing w/ multi-cert chain": {
 			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{"test-identity"}}},
 			ca: &mockca.FakeCA{
@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package equivalence checks that the rules rendered by istio-iptables and istio-nftables for the same configuration
+// are semantically equivalent. Both rule sets are run through a model of the netfilter packet classification, for
+// sample flows built from the ports, addresses, owners and marks found in the rules, and the flows whose verdicts
+// differ are reported.
+//
+// The model covers the matches and targets used by Istio only: it is not a general purpose netfilter simulator.
+package equivalence
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/knftables"
+
+	"istio.io/istio/tools/common/config"
+	iptablescapture "istio.io/istio/tools/istio-iptables/pkg/capture"
+	dep "istio.io/istio/tools/istio-iptables/pkg/dependencies"
+	nftablesbuilder "istio.io/istio/tools/istio-nftables/pkg/builder"
+	nftablescapture "istio.io/istio/tools/istio-nftables/pkg/capture"
+)
+
+// Difference is a flow classified differently by the two backends.
+type Difference struct {
+	Flow     Flow
+	Iptables Verdict
+	Nftables Verdict
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%v: iptables: %v, nftables: %v", d.Flow, d.Iptables, d.Nftables)
+}
+
+// Compare classifies the flows with both rule sets, and returns the flows whose verdicts differ.
+func Compare(iptables, nftables *Rules, flows []Flow) []Difference {
+	var diffs []Difference
+	for _, f := range flows {
+		ipt, nft := iptables.Classify(f), nftables.Classify(f)
+		if ipt != nft {
+			diffs = append(diffs, Difference{Flow: f, Iptables: ipt, Nftables: nft})
+		}
+	}
+	return diffs
+}
+
+// Check renders the rules of both backends for the configuration, without applying them, and returns the sample
+// flows whose verdicts differ.
+func Check(cfg *config.Config) ([]Difference, error) {
+	iptables, nftables, err := Render(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ipt, err := ParseIptables(iptables)
+	if err != nil {
+		return nil, fmt.Errorf("parse iptables rules: %v", err)
+	}
+	nft, err := ParseNftables(nftables)
+	if err != nil {
+		return nil, fmt.Errorf("parse nftables rules: %v", err)
+	}
+	return Compare(ipt, nft, Flows(ipt, nft)), nil
+}
+
+// Render returns the rules of both backends for the configuration, in the iptables-restore and nft formats, without
+// applying them.
+func Render(cfg *config.Config) (iptables, nftables string, err error) {
+	iptCfg := *cfg
+	ext := &dep.DependenciesStub{}
+	ipt, err := iptablescapture.NewIptablesConfigurator(&iptCfg, ext)
+	if err != nil {
+		return "", "", err
+	}
+	if err := ipt.Run(); err != nil {
+		return "", "", fmt.Errorf("render iptables rules: %v", err)
+	}
+
+	nftCfg := *cfg
+	mock := nftablesbuilder.NewMockNftables("", "")
+	nft, err := nftablescapture.NewNftablesConfigurator(&nftCfg, func(knftables.Family, string) (nftablesbuilder.NftablesAPI, error) {
+		return mock, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	tx, err := nft.Run()
+	if err != nil {
+		return "", "", fmt.Errorf("render nftables rules: %v", err)
+	}
+	return strings.Join(ext.ExecutedStdin, "\n"), mock.Dump(tx), nil
+}
@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equivalence
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/tools/common/config"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+var (
+	iptablesTestdata = filepath.Join("..", "..", "istio-iptables", "pkg", "capture", "testdata")
+	nftablesTestdata = filepath.Join("..", "..", "istio-nftables", "pkg", "capture", "testdata")
+)
+
+// knownDifferences are the differences between the golden rules of both backends that are understood. A new
+// difference fails the test: either a backend is fixed, or the difference is added here with its reason.
+var knownDifferences = map[string]func(d Difference) bool{}
+
+func TestGoldenEquivalence(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(iptablesTestdata, "*.golden"))
+	assert.NoError(t, err)
+	if len(files) == 0 {
+		t.Fatal("no iptables golden files found")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".golden")
+		t.Run(name, func(t *testing.T) {
+			nftFile := filepath.Join(nftablesTestdata, name+".golden")
+			if _, err := os.Stat(nftFile); err != nil {
+				t.Skipf("no nftables golden file: %v", err)
+			}
+			ipt := parseGolden(t, file, ParseIptables)
+			nft := parseGolden(t, nftFile, ParseNftables)
+			known := knownDifferences[name]
+			for _, d := range Compare(ipt, nft, Flows(ipt, nft)) {
+				if known == nil || !known(d) {
+					t.Errorf("unexpected difference: %v", d)
+				}
+			}
+		})
+	}
+}
+
+func parseGolden(t *testing.T, file string, parse func(string) (*Rules, error)) *Rules {
+	t.Helper()
+	b, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	rules, err := parse(string(b))
+	assert.NoError(t, err)
+	return rules
+}
+
+func TestCompare(t *testing.T) {
+	ipt, err := ParseIptables(`
+* nat
+-N ISTIO_REDIRECT
+-N ISTIO_OUTPUT
+-A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001
+-A OUTPUT -p tcp -j ISTIO_OUTPUT
+-A ISTIO_OUTPUT -m owner --uid-owner 1337 -j RETURN
+-A ISTIO_OUTPUT -d 127.0.0.1/32 -j RETURN
+-A ISTIO_OUTPUT -j ISTIO_REDIRECT
+COMMIT
+`)
+	assert.NoError(t, err)
+	nft, err := ParseNftables(`
+add table inet istio-proxy-nat
+add chain inet istio-proxy-nat output { type nat hook output priority -100 ; }
+add chain inet istio-proxy-nat istio-output
+add chain inet istio-proxy-nat istio-redirect
+add rule inet istio-proxy-nat istio-redirect meta l4proto tcp counter redirect to :15001
+add rule inet istio-proxy-nat output meta l4proto tcp counter jump istio-output
+add rule inet istio-proxy-nat istio-output ip daddr 127.0.0.1/32 counter return
+add rule inet istio-proxy-nat istio-output counter jump istio-redirect
+`)
+	assert.NoError(t, err)
+
+	flows := Flows(ipt, nft)
+	diffs := Compare(ipt, nft, flows)
+	if len(diffs) == 0 {
+		t.Fatal("expected differences for the traffic of the proxy")
+	}
+	for _, d := range diffs {
+		if d.Flow.UID != "1337" || d.Iptables.RedirectPort != 0 || d.Nftables.RedirectPort != 15001 {
+			t.Errorf("unexpected difference: %v", d)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	nft, err := ParseNftables(`
+add table inet istio-proxy-mangle
+add chain inet istio-proxy-mangle prerouting { type filter hook prerouting priority -150 ; }
+add chain inet istio-proxy-mangle istio-divert
+add chain inet istio-proxy-mangle istio-tproxy
+add rule inet istio-proxy-mangle istio-divert meta mark set 1337 counter accept
+add rule inet istio-proxy-mangle istio-tproxy ip daddr != 127.0.0.1/32 meta l4proto tcp tproxy ip to :15006 meta mark set 1337 counter accept
+add rule inet istio-proxy-mangle prerouting meta l4proto tcp ct state related,established counter jump istio-divert
+add rule inet istio-proxy-mangle prerouting tcp dport { 15008, 15020, } counter return
+add rule inet istio-proxy-mangle prerouting meta l4proto tcp counter jump istio-tproxy
+`)
+	assert.NoError(t, err)
+	flow := Flow{
+		Hook:     Prerouting,
+		Protocol: "tcp",
+		Src:      netip.MustParseAddr("10.0.0.1"),
+		Dst:      netip.MustParseAddr("10.0.0.2"),
+		SrcPort:  40000,
+		DstPort:  8080,
+		CtState:  CtNew,
+	}
+	cases := []struct {
+		name string
+		flow func(f Flow) Flow
+		want Verdict
+	}{
+		{"new", func(f Flow) Flow { return f }, Verdict{TProxyPort: 15006, Mark: 1337}},
+		{"established", func(f Flow) Flow { f.CtState = CtEstablished; return f }, Verdict{Mark: 1337}},
+		{"excluded port", func(f Flow) Flow { f.DstPort = 15020; return f }, Verdict{}},
+		{"loopback", func(f Flow) Flow { f.Dst = netip.MustParseAddr("127.0.0.1"); return f }, Verdict{}},
+		{"udp", func(f Flow) Flow { f.Protocol = "udp"; return f }, Verdict{}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, nft.Classify(tt.flow(flow)), tt.want)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := &config.Config{
+		ProxyPort:               "15001",
+		InboundCapturePort:      "15006",
+		InboundTunnelPort:       "15008",
+		ProxyUID:                constants.DefaultProxyUID,
+		ProxyGID:                constants.DefaultProxyUID,
+		InboundTProxyMark:       "1337",
+		InboundTProxyRouteTable: "133",
+		OwnerGroupsInclude:      constants.OwnerGroupsInclude.DefaultValue,
+		HostIPv4LoopbackCidr:    constants.HostIPv4LoopbackCidr.DefaultValue,
+		InboundInterceptionMode: "TPROXY",
+		InboundPortsInclude:     "*",
+		OutboundIPRangesInclude: "*",
+		EnableIPv6:              true,
+		RedirectDNS:             true,
+		DNSServersV4:            []string{"127.0.0.53"},
+		DNSServersV6:            []string{"::127.0.0.53"},
+	}
+	diffs, err := Check(cfg)
+	assert.NoError(t, err)
+	for _, d := range diffs {
+		t.Errorf("unexpected difference: %v", d)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equivalence
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pkg/maps"
+)
+
+// MaxFlows bounds the number of flows per hook and IP family returned by Flows. When the combinations of the values
+// found in the rules exceed it, a deterministic sample of them is returned.
+var MaxFlows = 100000
+
+// Values used by sample flows, in addition to the ones found in the rules, so that flows matching none of the rules
+// are classified as well.
+var (
+	defaultAddrV4 = netip.MustParseAddr("203.0.113.10")
+	defaultAddrV6 = netip.MustParseAddr("fd00:203:113::10")
+	podAddrV4     = netip.MustParseAddr("10.244.0.10")
+	podAddrV6     = netip.MustParseAddr("fd00:10:244::10")
+	defaultPort   = uint16(8080)
+	sourcePort    = uint16(40000)
+	defaultIface  = "eth0"
+	defaultOwner  = "1000"
+)
+
+// Flows returns sample flows, built from the combinations of the values (ports, addresses, interfaces, owners,
+// marks and conntrack states) found in the rules. IPv6 flows are only built if some of the rules have IPv6 rules.
+func Flows(rules ...*Rules) []Flow {
+	v := newValues()
+	for _, r := range rules {
+		v.merge(r.values)
+	}
+	families := []bool{false}
+	if v.ipv6 {
+		families = append(families, true)
+	}
+	var flows []Flow
+	for _, ipv6 := range families {
+		for _, hook := range []Hook{Prerouting, Output} {
+			flows = append(flows, v.flows(hook, ipv6)...)
+		}
+	}
+	return flows
+}
+
+func (v *values) flows(hook Hook, ipv6 bool) []Flow {
+	family := func(addrs map[netip.Addr]bool, extra ...netip.Addr) []netip.Addr {
+		res := slices.Clone(extra)
+		for a := range addrs {
+			if a.Is6() == ipv6 {
+				res = append(res, a)
+			}
+		}
+		slices.SortFunc(res, func(a, b netip.Addr) int { return a.Compare(b) })
+		return slices.Compact(res)
+	}
+	defaultAddr, podAddr := defaultAddrV4, podAddrV4
+	if ipv6 {
+		defaultAddr, podAddr = defaultAddrV6, podAddrV6
+	}
+	protocols := withDefault(v.protocols, "tcp", "udp")
+	ports := withDefault(v.ports, defaultPort)
+	srcPorts := withDefault(v.srcPorts, sourcePort)
+	dsts := family(v.addrs, defaultAddr, podAddr)
+	srcs := family(v.srcAddrs, podAddr)
+	marks := withDefault(v.marks, 0)
+	ctStates := withDefault(v.ctStates, CtNew, CtEstablished)
+	ifaces, uids, gids := []string{""}, []string{""}, []string{""}
+	if hook == Prerouting {
+		ifaces = withDefault(v.inIfaces, defaultIface)
+	} else {
+		ifaces = withDefault(v.outIfaces, defaultIface)
+		uids = withDefault(v.uids, defaultOwner)
+		gids = withDefault(v.gids, defaultOwner)
+	}
+
+	dims := []int{
+		len(protocols), len(ports), len(srcPorts), len(dsts), len(srcs), len(ifaces),
+		len(uids), len(gids), len(marks), len(marks), len(ctStates),
+	}
+	total := 1
+	for _, d := range dims {
+		total *= d
+	}
+	build := func(n int) Flow {
+		idx := make([]int, len(dims))
+		for i := len(dims) - 1; i >= 0; i-- {
+			idx[i] = n % dims[i]
+			n /= dims[i]
+		}
+		f := Flow{
+			Hook:     hook,
+			Protocol: protocols[idx[0]],
+			DstPort:  ports[idx[1]],
+			SrcPort:  srcPorts[idx[2]],
+			Dst:      dsts[idx[3]],
+			Src:      srcs[idx[4]],
+			UID:      uids[idx[6]],
+			GID:      gids[idx[7]],
+			Mark:     marks[idx[8]],
+			ConnMark: marks[idx[9]],
+			CtState:  ctStates[idx[10]],
+		}
+		if hook == Prerouting {
+			f.InInterface = ifaces[idx[5]]
+		} else {
+			f.OutInterface = ifaces[idx[5]]
+		}
+		return f
+	}
+
+	if total <= MaxFlows {
+		flows := make([]Flow, 0, total)
+		for n := 0; n < total; n++ {
+			flows = append(flows, build(n))
+		}
+		return flows
+	}
+	// A fixed seed keeps the sample, and so the reported differences, stable.
+	rnd := rand.New(rand.NewSource(int64(total)))
+	flows := make([]Flow, 0, MaxFlows)
+	for range MaxFlows {
+		flows = append(flows, build(rnd.Intn(total)))
+	}
+	return flows
+}
+
+// withDefault returns the sorted values of the set, and the defaults.
+func withDefault[T cmp.Ordered](set map[T]bool, defaults ...T) []T {
+	res := append(maps.Keys(set), defaults...)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+func (v *values) merge(o *values) {
+	mergeSet(v.protocols, o.protocols)
+	mergeSet(v.ports, o.ports)
+	mergeSet(v.srcPorts, o.srcPorts)
+	mergeSet(v.addrs, o.addrs)
+	mergeSet(v.srcAddrs, o.srcAddrs)
+	mergeSet(v.inIfaces, o.inIfaces)
+	mergeSet(v.outIfaces, o.outIfaces)
+	mergeSet(v.uids, o.uids)
+	mergeSet(v.gids, o.gids)
+	mergeSet(v.marks, o.marks)
+	mergeSet(v.ctStates, o.ctStates)
+	v.ipv6 = v.ipv6 || o.ipv6
+}
+
+func mergeSet[T comparable](dst, src map[T]bool) {
+	for k := range src {
+		dst[k] = true
+	}
+}
+
+func (v *values) addAddr(addr netip.Addr, src bool) {
+	if addr.Is6() {
+		v.ipv6 = true
+	}
+	if src {
+		v.srcAddrs[addr] = true
+	} else {
+		v.addrs[addr] = true
+	}
+}
+
+func (v *values) addInterface(name string, in bool) {
+	if in {
+		v.inIfaces[name] = true
+	} else {
+		v.outIfaces[name] = true
+	}
+}
+
+func (v *values) addPorts(ports []uint16, src bool) {
+	for _, p := range ports {
+		if src {
+			v.srcPorts[p] = true
+		} else {
+			v.ports[p] = true
+		}
+	}
+}
+
+func (v *values) addOwner(id string, group bool) {
+	if group {
+		v.gids[id] = true
+	} else {
+		v.uids[id] = true
+	}
+}
+
+func (v *values) addCtStates(states []string) {
+	for _, s := range states {
+		v.ctStates[s] = true
+	}
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func parsePort(s string) (uint16, error) {
+	p, err := strconv.ParseUint(strings.TrimPrefix(s, ":"), 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return uint16(p), nil
+}
+
+func parsePorts(values []string) ([]uint16, error) {
+	ports := make([]uint16, 0, len(values))
+	for _, s := range values {
+		p, err := parsePort(s)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// parseMark parses a mark, with an optional mask.
+func parseMark(s string) (value, mask uint32, err error) {
+	v, m, found := strings.Cut(s, "/")
+	value64, err := strconv.ParseUint(v, 0, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid mark %q", s)
+	}
+	mask = 0xffffffff
+	if found {
+		mask64, err := strconv.ParseUint(m, 0, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid mark mask %q", s)
+		}
+		mask = uint32(mask64)
+	}
+	return uint32(value64), mask, nil
+}
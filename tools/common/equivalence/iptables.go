@@ -0,0 +1,264 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equivalence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var iptablesBaseChains = map[string]Hook{"PREROUTING": Prerouting, "OUTPUT": Output}
+
+// ParseIptables parses the rules written by istio-iptables, in the iptables-restore format. The IPv4 rules are
+// followed by the IPv6 rules, which start with the first table restored again. Other commands are ignored.
+func ParseIptables(dump string) (*Rules, error) {
+	rules := &Rules{v4: &ruleset{}, values: newValues(), socketless: true}
+	current := rules.v4
+	seen := map[string]bool{}
+	var t *table
+	for i, line := range strings.Split(dump, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "*"):
+			name := strings.TrimSpace(strings.TrimPrefix(line, "*"))
+			if _, ok := tablePriority[name]; !ok {
+				return nil, fmt.Errorf("line %d: unsupported iptables table %q", i+1, name)
+			}
+			if seen[name] {
+				if rules.v6 != nil {
+					return nil, fmt.Errorf("line %d: table %q restored more than twice", i+1, name)
+				}
+				rules.v6 = &ruleset{}
+				rules.values.ipv6 = true
+				current = rules.v6
+				seen = map[string]bool{}
+			}
+			seen[name] = true
+			t = current.table(name, name)
+			for chain, hook := range iptablesBaseChains {
+				t.base[hook] = chain
+			}
+		case line == "COMMIT":
+			t = nil
+		case strings.HasPrefix(line, "-N "):
+			if t == nil {
+				return nil, fmt.Errorf("line %d: chain outside of a table", i+1)
+			}
+			t.chains[strings.TrimSpace(strings.TrimPrefix(line, "-N "))] = nil
+		case strings.HasPrefix(line, "-A ") || strings.HasPrefix(line, "-I "):
+			if t == nil {
+				return nil, fmt.Errorf("line %d: rule outside of a table", i+1)
+			}
+			if err := parseIptablesRule(t, line, rules.values); err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func parseIptablesRule(t *table, line string, v *values) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid rule %q", line)
+	}
+	chain := fields[1]
+	fields = fields[2:]
+	// Position to insert the rule at, starting from 1
+	position := 0
+	if line[1] == 'I' {
+		position = 1
+		if len(fields) > 0 {
+			if n, err := strconv.Atoi(fields[0]); err == nil {
+				position = n
+				fields = fields[1:]
+			}
+		}
+	}
+
+	r := &rule{text: line}
+	var protocol, module, target string
+	negate := false
+	next := func(i *int) (string, error) {
+		*i++
+		if *i >= len(fields) {
+			return "", fmt.Errorf("missing value of %s in %q", fields[*i-1], line)
+		}
+		return fields[*i], nil
+	}
+	for i := 0; i < len(fields); i++ {
+		opt := fields[i]
+		if opt == "!" {
+			negate = true
+			continue
+		}
+		value, err := next(&i)
+		if err != nil {
+			return err
+		}
+		switch opt {
+		case "-p":
+			protocol = value
+			v.protocols[value] = true
+			r.matches = append(r.matches, matchProtocol(value, negate))
+		case "-s", "-d":
+			prefix, err := parsePrefix(value)
+			if err != nil {
+				return err
+			}
+			v.addAddr(prefix.Addr(), opt == "-s")
+			r.matches = append(r.matches, matchPrefix(prefix, opt == "-s", negate))
+		case "-i", "-o":
+			v.addInterface(value, opt == "-i")
+			r.matches = append(r.matches, matchInterface(value, opt == "-i", negate))
+		case "-m":
+			module = value
+		case "--dport", "--sport", "--dports", "--sports":
+			ports, err := parsePorts(strings.Split(value, ","))
+			if err != nil {
+				return err
+			}
+			src := strings.HasPrefix(opt, "--s")
+			v.addPorts(ports, src)
+			r.matches = append(r.matches, matchPort(protocol, src, ports, negate))
+		case "--uid-owner", "--gid-owner":
+			v.addOwner(value, opt == "--gid-owner")
+			r.matches = append(r.matches, matchOwner(value, opt == "--gid-owner", negate))
+		case "--mark":
+			value, mask, err := parseMark(value)
+			if err != nil {
+				return err
+			}
+			v.marks[value] = true
+			r.matches = append(r.matches, matchMark(value, mask, module == "connmark", negate))
+		case "--ctstate":
+			states := strings.Split(strings.ToLower(value), ",")
+			v.addCtStates(states)
+			r.matches = append(r.matches, matchCtState(states, negate))
+		case "-j":
+			target = value
+			if err := parseIptablesTarget(t, r, value, fields[i+1:], v); err != nil {
+				return fmt.Errorf("%v in %q", err, line)
+			}
+			i = len(fields)
+		default:
+			return fmt.Errorf("unsupported iptables option %q in %q", opt, line)
+		}
+		negate = false
+	}
+	if target == "" {
+		return fmt.Errorf("missing target in %q", line)
+	}
+
+	rules := t.chains[chain]
+	if position == 0 || position > len(rules) {
+		t.chains[chain] = append(rules, r)
+	} else {
+		rules = append(rules[:position-1], append([]*rule{r}, rules[position-1:]...)...)
+		t.chains[chain] = rules
+	}
+	return nil
+}
+
+// parseIptablesTarget adds the actions of the target, with its options, to the rule.
+func parseIptablesTarget(t *table, r *rule, target string, opts []string, v *values) error {
+	options := map[string]string{}
+	for i := 0; i < len(opts); i++ {
+		if i+1 < len(opts) && !strings.HasPrefix(opts[i+1], "--") {
+			options[opts[i]] = opts[i+1]
+			i++
+		} else {
+			options[opts[i]] = ""
+		}
+	}
+	port := func(names ...string) (uint16, error) {
+		for _, name := range names {
+			if p, ok := options[name]; ok {
+				return parsePort(p)
+			}
+		}
+		return 0, fmt.Errorf("missing port of %s target", target)
+	}
+	switch target {
+	case "RETURN":
+		r.actions = append(r.actions, action{kind: actReturn})
+	case "ACCEPT":
+		r.actions = append(r.actions, action{kind: actAccept})
+	case "DROP":
+		r.actions = append(r.actions, action{kind: actDrop})
+	case "REDIRECT":
+		p, err := port("--to-ports", "--to-port")
+		if err != nil {
+			return err
+		}
+		r.actions = append(r.actions, action{kind: actRedirect, port: p})
+	case "TPROXY":
+		p, err := port("--on-port")
+		if err != nil {
+			return err
+		}
+		r.actions = append(r.actions, action{kind: actTProxy, port: p})
+		if m, ok := options["--tproxy-mark"]; ok {
+			value, mask, err := parseMark(m)
+			if err != nil {
+				return err
+			}
+			v.marks[value] = true
+			r.actions = append(r.actions, action{kind: actSetMark, value: value, mask: mask})
+		}
+		r.actions = append(r.actions, action{kind: actAccept})
+	case "MARK":
+		m, ok := options["--set-mark"]
+		if !ok {
+			m, ok = options["--set-xmark"]
+		}
+		if !ok {
+			return fmt.Errorf("unsupported MARK target")
+		}
+		value, mask, err := parseMark(m)
+		if err != nil {
+			return err
+		}
+		v.marks[value] = true
+		r.actions = append(r.actions, action{kind: actSetMark, value: value, mask: mask})
+	case "CONNMARK":
+		switch {
+		case hasKey(options, "--save-mark"):
+			r.actions = append(r.actions, action{kind: actSaveMark})
+		case hasKey(options, "--restore-mark"):
+			r.actions = append(r.actions, action{kind: actRestoreMark})
+		default:
+			return fmt.Errorf("unsupported CONNMARK target")
+		}
+	case "CT":
+		zone, err := strconv.ParseUint(options["--zone"], 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid zone of CT target: %v", err)
+		}
+		r.actions = append(r.actions, action{kind: actSetZone, value: uint32(zone)})
+	default:
+		if _, ok := t.chains[target]; !ok {
+			return fmt.Errorf("unsupported target %q", target)
+		}
+		r.actions = append(r.actions, action{kind: actJump, chain: target})
+	}
+	return nil
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
@@ -0,0 +1,407 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equivalence
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Hook is the netfilter hook a flow traverses.
+type Hook string
+
+const (
+	// Prerouting is traversed by the packets received by the pod.
+	Prerouting Hook = "prerouting"
+	// Output is traversed by the packets sent by the processes of the pod.
+	Output Hook = "output"
+)
+
+// Conntrack states of a flow.
+const (
+	CtNew         = "new"
+	CtEstablished = "established"
+	CtRelated     = "related"
+	CtInvalid     = "invalid"
+)
+
+// Flow is a packet classified by the rules.
+type Flow struct {
+	Hook     Hook
+	Protocol string
+	Src, Dst netip.Addr
+	SrcPort  uint16
+	DstPort  uint16
+	// InInterface is set for Prerouting flows, OutInterface for Output flows.
+	InInterface  string
+	OutInterface string
+	// UID and GID own the socket of Output flows. Prerouting flows have no socket.
+	UID, GID string
+	Mark     uint32
+	ConnMark uint32
+	CtState  string
+}
+
+func (f Flow) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s -> %s", f.Hook, f.Protocol,
+		netip.AddrPortFrom(f.Src, f.SrcPort), netip.AddrPortFrom(f.Dst, f.DstPort))
+	if f.InInterface != "" {
+		fmt.Fprintf(&b, " iif=%s", f.InInterface)
+	}
+	if f.OutInterface != "" {
+		fmt.Fprintf(&b, " oif=%s", f.OutInterface)
+	}
+	if f.UID != "" || f.GID != "" {
+		fmt.Fprintf(&b, " uid=%s gid=%s", f.UID, f.GID)
+	}
+	fmt.Fprintf(&b, " mark=%d connmark=%d ct=%s", f.Mark, f.ConnMark, f.CtState)
+	return b.String()
+}
+
+// Verdict is the outcome of the classification of a flow.
+type Verdict struct {
+	Drop         bool
+	RedirectPort uint16
+	TProxyPort   uint16
+	Mark         uint32
+	ConnMark     uint32
+	CtZone       uint16
+}
+
+func (v Verdict) String() string {
+	if v.Drop {
+		return "drop"
+	}
+	parts := []string{"accept"}
+	if v.RedirectPort != 0 {
+		parts = append(parts, fmt.Sprintf("redirect :%d", v.RedirectPort))
+	}
+	if v.TProxyPort != 0 {
+		parts = append(parts, fmt.Sprintf("tproxy :%d", v.TProxyPort))
+	}
+	parts = append(parts, fmt.Sprintf("mark=%d connmark=%d", v.Mark, v.ConnMark))
+	if v.CtZone != 0 {
+		parts = append(parts, fmt.Sprintf("zone=%d", v.CtZone))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// packet is a flow being classified.
+type packet struct {
+	Flow
+	verdict Verdict
+	// socketless reports whether inverted owner matches match packets without a socket.
+	socketless bool
+}
+
+func (p *packet) isIPv6() bool {
+	return p.Dst.Is6()
+}
+
+// matcher reports whether a packet matches a condition of a rule.
+type matcher func(p *packet) bool
+
+type actionKind int
+
+const (
+	actReturn actionKind = iota
+	actAccept
+	actDrop
+	actJump
+	actGoto
+	actRedirect
+	actTProxy
+	actSetMark
+	actSaveMark
+	actRestoreMark
+	actSetZone
+)
+
+type action struct {
+	kind  actionKind
+	chain string
+	port  uint16
+	value uint32
+	mask  uint32
+}
+
+type rule struct {
+	text    string
+	matches []matcher
+	actions []action
+}
+
+// outcome of the traversal of a chain.
+type outcome int
+
+const (
+	// continue with the next rule of the calling chain.
+	outcomeContinue outcome = iota
+	outcomeAccept
+	outcomeDrop
+)
+
+// Kinds of tables, in the order they are traversed.
+const (
+	tableRaw    = "raw"
+	tableMangle = "mangle"
+	tableNat    = "nat"
+)
+
+var tablePriority = map[string]int{tableRaw: -300, tableMangle: -150, tableNat: -100}
+
+type table struct {
+	name string
+	kind string
+	// base chains attached to each hook
+	base   map[Hook]string
+	chains map[string][]*rule
+}
+
+// ruleset is the tables applying to packets of an IP family.
+type ruleset struct {
+	tables []*table
+}
+
+func (rs *ruleset) table(name, kind string) *table {
+	for _, t := range rs.tables {
+		if t.name == name {
+			return t
+		}
+	}
+	t := &table{name: name, kind: kind, base: map[Hook]string{}, chains: map[string][]*rule{}}
+	rs.tables = append(rs.tables, t)
+	sort.SliceStable(rs.tables, func(i, j int) bool {
+		return tablePriority[rs.tables[i].kind] < tablePriority[rs.tables[j].kind]
+	})
+	return t
+}
+
+// maxDepth bounds the jumps between chains, in case of loops.
+const maxDepth = 32
+
+func (rs *ruleset) classify(p *packet) Verdict {
+	for _, t := range rs.tables {
+		// NAT rules are only consulted for the first packet of a connection
+		if t.kind == tableNat && p.CtState != CtNew {
+			continue
+		}
+		chain, ok := t.base[p.Hook]
+		if !ok {
+			continue
+		}
+		if t.traverse(p, chain, 0) == outcomeDrop {
+			p.verdict.Drop = true
+			break
+		}
+	}
+	p.verdict.Mark = p.Mark
+	p.verdict.ConnMark = p.ConnMark
+	return p.verdict
+}
+
+func (t *table) traverse(p *packet, chain string, depth int) outcome {
+	if depth > maxDepth {
+		return outcomeContinue
+	}
+	for _, r := range t.chains[chain] {
+		if !r.matchesPacket(p) {
+			continue
+		}
+		for _, a := range r.actions {
+			switch a.kind {
+			case actReturn:
+				return outcomeContinue
+			case actAccept:
+				return outcomeAccept
+			case actDrop:
+				return outcomeDrop
+			case actJump:
+				if o := t.traverse(p, a.chain, depth+1); o != outcomeContinue {
+					return o
+				}
+			case actGoto:
+				return t.traverse(p, a.chain, depth+1)
+			case actRedirect:
+				p.verdict.RedirectPort = a.port
+				return outcomeAccept
+			case actTProxy:
+				p.verdict.TProxyPort = a.port
+			case actSetMark:
+				p.Mark = p.Mark&^a.mask | a.value
+			case actSaveMark:
+				p.ConnMark = p.Mark
+			case actRestoreMark:
+				p.Mark = p.ConnMark
+			case actSetZone:
+				p.verdict.CtZone = uint16(a.value)
+			}
+		}
+	}
+	return outcomeContinue
+}
+
+func (r *rule) matchesPacket(p *packet) bool {
+	for _, m := range r.matches {
+		if !m(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rules are the rules of a backend, for both IP families.
+type Rules struct {
+	v4, v6 *ruleset
+	values *values
+	// socketless reports whether inverted owner matches match packets without a socket, as with iptables.
+	socketless bool
+}
+
+// Classify returns the verdict of the rules for the flow.
+func (r *Rules) Classify(f Flow) Verdict {
+	rs := r.v4
+	if f.Dst.Is6() {
+		rs = r.v6
+	}
+	p := &packet{Flow: f, socketless: r.socketless}
+	if rs == nil {
+		return Verdict{Mark: f.Mark, ConnMark: f.ConnMark}
+	}
+	return rs.classify(p)
+}
+
+// values are the values found in the rules, used to build sample flows.
+type values struct {
+	protocols map[string]bool
+	ports     map[uint16]bool
+	srcPorts  map[uint16]bool
+	addrs     map[netip.Addr]bool
+	srcAddrs  map[netip.Addr]bool
+	inIfaces  map[string]bool
+	outIfaces map[string]bool
+	uids      map[string]bool
+	gids      map[string]bool
+	marks     map[uint32]bool
+	ctStates  map[string]bool
+	ipv6      bool
+}
+
+func newValues() *values {
+	return &values{
+		protocols: map[string]bool{},
+		ports:     map[uint16]bool{},
+		srcPorts:  map[uint16]bool{},
+		addrs:     map[netip.Addr]bool{},
+		srcAddrs:  map[netip.Addr]bool{},
+		inIfaces:  map[string]bool{},
+		outIfaces: map[string]bool{},
+		uids:      map[string]bool{},
+		gids:      map[string]bool{},
+		marks:     map[uint32]bool{},
+		ctStates:  map[string]bool{},
+	}
+}
+
+// Common matchers of both backends.
+
+func matchProtocol(proto string, negate bool) matcher {
+	return func(p *packet) bool { return (p.Protocol == proto) != negate }
+}
+
+func matchPort(proto string, src bool, ports []uint16, negate bool) matcher {
+	return func(p *packet) bool {
+		if p.Protocol != proto {
+			return false
+		}
+		port := p.DstPort
+		if src {
+			port = p.SrcPort
+		}
+		found := false
+		for _, want := range ports {
+			if port == want {
+				found = true
+				break
+			}
+		}
+		return found != negate
+	}
+}
+
+// matchPrefix matches the source or destination address. Packets of the other IP family never match, as with the ip
+// and ip6 matches of nftables.
+func matchPrefix(prefix netip.Prefix, src, negate bool) matcher {
+	return func(p *packet) bool {
+		if p.isIPv6() != prefix.Addr().Is6() {
+			return false
+		}
+		addr := p.Dst
+		if src {
+			addr = p.Src
+		}
+		return prefix.Contains(addr) != negate
+	}
+}
+
+func matchInterface(name string, in, negate bool) matcher {
+	return func(p *packet) bool {
+		iface := p.OutInterface
+		if in {
+			iface = p.InInterface
+		}
+		return (iface == name) != negate
+	}
+}
+
+func matchOwner(id string, group, negate bool) matcher {
+	return func(p *packet) bool {
+		owner := p.UID
+		if group {
+			owner = p.GID
+		}
+		if owner == "" {
+			// Packets without a socket
+			return p.socketless && negate
+		}
+		return (owner == id) != negate
+	}
+}
+
+func matchMark(value, mask uint32, conn, negate bool) matcher {
+	return func(p *packet) bool {
+		mark := p.Mark
+		if conn {
+			mark = p.ConnMark
+		}
+		return (mark&mask == value) != negate
+	}
+}
+
+func matchCtState(states []string, negate bool) matcher {
+	return func(p *packet) bool {
+		found := false
+		for _, s := range states {
+			if s == p.CtState {
+				found = true
+				break
+			}
+		}
+		return found != negate
+	}
+}
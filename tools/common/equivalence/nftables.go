@@ -0,0 +1,318 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package equivalence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseNftables parses the rules written by istio-nftables, in the nft syntax. Rules of inet tables apply to both IP
+// families. Other lines are ignored.
+func ParseNftables(dump string) (*Rules, error) {
+	rs := &ruleset{}
+	rules := &Rules{v4: rs, v6: rs, values: newValues()}
+	for i, line := range strings.Split(dump, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "inet" {
+			continue
+		}
+		var err error
+		switch fields[0] + " " + fields[1] {
+		case "add chain":
+			err = parseNftablesChain(rs, fields[3:])
+		case "add rule", "insert rule":
+			err = parseNftablesRule(rs, fields[0] == "insert", fields[3:], rules.values)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v in %q", i+1, err, line)
+		}
+	}
+	return rules, nil
+}
+
+// nftablesTable returns the table, whose kind is given by its name, as istio-nftables names them.
+func nftablesTable(rs *ruleset, name string) (*table, error) {
+	for kind := range tablePriority {
+		if strings.HasSuffix(name, "-"+kind) {
+			return rs.table(name, kind), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported nftables table %q", name)
+}
+
+// parseNftablesChain parses `<table> <chain> [{ type <type> hook <hook> priority <priority> ; }]`.
+func parseNftablesChain(rs *ruleset, fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid chain")
+	}
+	t, err := nftablesTable(rs, fields[0])
+	if err != nil {
+		return err
+	}
+	chain := fields[1]
+	if _, ok := t.chains[chain]; !ok {
+		t.chains[chain] = nil
+	}
+	for i := 2; i+1 < len(fields); i++ {
+		if fields[i] == "hook" {
+			t.base[Hook(fields[i+1])] = chain
+		}
+	}
+	return nil
+}
+
+// parseNftablesRule parses `<table> <chain> [index <n>] <statements>`.
+func parseNftablesRule(rs *ruleset, insert bool, fields []string, v *values) error {
+	if len(fields) < 3 {
+		return fmt.Errorf("invalid rule")
+	}
+	t, err := nftablesTable(rs, fields[0])
+	if err != nil {
+		return err
+	}
+	chain := fields[1]
+	fields = fields[2:]
+	index := -1
+	if fields[0] == "index" && len(fields) > 1 {
+		index, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid index %q", fields[1])
+		}
+		fields = fields[2:]
+	}
+	r := &rule{text: strings.Join(fields, " ")}
+	if err := parseNftablesStatements(r, fields, v); err != nil {
+		return err
+	}
+
+	rules := t.chains[chain]
+	switch {
+	case insert && index < 0:
+		t.chains[chain] = append([]*rule{r}, rules...)
+	case index < 0 || index >= len(rules):
+		t.chains[chain] = append(rules, r)
+	case insert:
+		// Inserted before the rule at the index
+		t.chains[chain] = append(rules[:index], append([]*rule{r}, rules[index:]...)...)
+	default:
+		// Added after the rule at the index
+		t.chains[chain] = append(rules[:index+1], append([]*rule{r}, rules[index+1:]...)...)
+	}
+	return nil
+}
+
+func parseNftablesStatements(r *rule, fields []string, v *values) error {
+	i := 0
+	peek := func() string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	take := func() string {
+		s := peek()
+		i++
+		return s
+	}
+	// operator consumes an optional != operator.
+	operator := func() bool {
+		if peek() == "!=" {
+			i++
+			return true
+		}
+		if peek() == "==" {
+			i++
+		}
+		return false
+	}
+	// list consumes a comma separated list of values, or an anonymous set of values.
+	list := func() []string {
+		braces := peek() == "{"
+		if braces {
+			i++
+		}
+		var res []string
+		for i < len(fields) {
+			tok := take()
+			for _, s := range strings.Split(tok, ",") {
+				if s != "" {
+					res = append(res, s)
+				}
+			}
+			if !braces || peek() == "}" {
+				break
+			}
+		}
+		if braces {
+			i++
+		}
+		return res
+	}
+
+	for i < len(fields) {
+		switch tok := take(); tok {
+		case "counter":
+		case "meta":
+			// The meta keyword is optional before the keys used by istio-nftables
+		case "l4proto":
+			negate := operator()
+			proto := take()
+			v.protocols[proto] = true
+			r.matches = append(r.matches, matchProtocol(proto, negate))
+		case "tcp", "udp":
+			field := take()
+			if field != "dport" && field != "sport" {
+				return fmt.Errorf("unsupported %s match %q", tok, field)
+			}
+			negate := operator()
+			ports, err := parsePorts(list())
+			if err != nil {
+				return err
+			}
+			v.addPorts(ports, field == "sport")
+			r.matches = append(r.matches, matchPort(tok, field == "sport", ports, negate))
+		case "ip", "ip6":
+			field := take()
+			if field != "daddr" && field != "saddr" {
+				return fmt.Errorf("unsupported %s match %q", tok, field)
+			}
+			negate := operator()
+			prefix, err := parsePrefix(take())
+			if err != nil {
+				return err
+			}
+			if prefix.Addr().Is6() != (tok == "ip6") {
+				return fmt.Errorf("address %v does not match %s", prefix, tok)
+			}
+			v.addAddr(prefix.Addr(), field == "saddr")
+			r.matches = append(r.matches, matchPrefix(prefix, field == "saddr", negate))
+		case "iifname", "oifname":
+			negate := operator()
+			name := strings.Trim(take(), `"`)
+			v.addInterface(name, tok == "iifname")
+			r.matches = append(r.matches, matchInterface(name, tok == "iifname", negate))
+		case "skuid", "skgid":
+			negate := operator()
+			id := take()
+			v.addOwner(id, tok == "skgid")
+			r.matches = append(r.matches, matchOwner(id, tok == "skgid", negate))
+		case "mark":
+			if peek() == "set" {
+				i++
+				if err := parseNftablesMarkSet(r, take, peek, v); err != nil {
+					return err
+				}
+				continue
+			}
+			negate := operator()
+			value, mask, err := parseMark(take())
+			if err != nil {
+				return err
+			}
+			v.marks[value] = true
+			r.matches = append(r.matches, matchMark(value, mask, false, negate))
+		case "ct":
+			switch key := take(); key {
+			case "state":
+				negate := operator()
+				states := list()
+				v.addCtStates(states)
+				r.matches = append(r.matches, matchCtState(states, negate))
+			case "mark":
+				if peek() == "set" {
+					i++
+					if take() != "mark" {
+						return fmt.Errorf("unsupported ct mark statement")
+					}
+					r.actions = append(r.actions, action{kind: actSaveMark})
+					continue
+				}
+				negate := operator()
+				value, mask, err := parseMark(take())
+				if err != nil {
+					return err
+				}
+				v.marks[value] = true
+				r.matches = append(r.matches, matchMark(value, mask, true, negate))
+			case "zone":
+				if take() != "set" {
+					return fmt.Errorf("unsupported ct zone match")
+				}
+				zone, err := strconv.ParseUint(take(), 10, 16)
+				if err != nil {
+					return fmt.Errorf("invalid zone: %v", err)
+				}
+				r.actions = append(r.actions, action{kind: actSetZone, value: uint32(zone)})
+			default:
+				return fmt.Errorf("unsupported ct key %q", key)
+			}
+		case "return":
+			r.actions = append(r.actions, action{kind: actReturn})
+		case "accept":
+			r.actions = append(r.actions, action{kind: actAccept})
+		case "drop":
+			r.actions = append(r.actions, action{kind: actDrop})
+		case "jump", "goto":
+			kind := actJump
+			if tok == "goto" {
+				kind = actGoto
+			}
+			r.actions = append(r.actions, action{kind: kind, chain: take()})
+		case "redirect", "tproxy":
+			if peek() == "ip" || peek() == "ip6" {
+				i++
+			}
+			if take() != "to" {
+				return fmt.Errorf("unsupported %s statement", tok)
+			}
+			port, err := parsePort(take())
+			if err != nil {
+				return err
+			}
+			kind := actRedirect
+			if tok == "tproxy" {
+				kind = actTProxy
+			}
+			r.actions = append(r.actions, action{kind: kind, port: port})
+		default:
+			return fmt.Errorf("unsupported nftables expression %q", tok)
+		}
+	}
+	if len(r.actions) == 0 {
+		return fmt.Errorf("rule without statement")
+	}
+	return nil
+}
+
+// parseNftablesMarkSet parses the value of `meta mark set`, a mark or the conntrack mark.
+func parseNftablesMarkSet(r *rule, take, peek func() string, v *values) error {
+	if peek() == "ct" {
+		take()
+		if take() != "mark" {
+			return fmt.Errorf("unsupported mark statement")
+		}
+		r.actions = append(r.actions, action{kind: actRestoreMark})
+		return nil
+	}
+	value, mask, err := parseMark(take())
+	if err != nil {
+		return err
+	}
+	v.marks[value] = true
+	r.actions = append(r.actions, action{kind: actSetMark, value: value, mask: mask})
+	return nil
+}
@@ -29,6 +29,10 @@ type NftablesAPI interface {
 	Dump(tx *knftables.Transaction) string
 	// ListElements returns a list of the elements in a set or map. (objectType should be "set" or "map".)
 	ListElements(ctx context.Context, objectType, name string) ([]*knftables.Element, error)
+	// List returns a list of the names of the objects of objectType ("chain", "set", ...) in the table.
+	List(ctx context.Context, objectType string) ([]string, error)
+	// ListRules returns a list of the rules in a chain, in order. Only the handles and comments of the rules are set.
+	ListRules(ctx context.Context, chain string) ([]*knftables.Rule, error)
 }
 
 // NftImpl is the real implementation of NftablesAPI using the actual knftables backend.
@@ -66,6 +70,16 @@ func (r *NftImpl) ListElements(ctx context.Context, objectType, name string) ([]
 	return r.nft.ListElements(ctx, objectType, name)
 }
 
+// List returns a list of the names of the objects of objectType using the real knftables interface.
+func (r *NftImpl) List(ctx context.Context, objectType string) ([]string, error) {
+	return r.nft.List(ctx, objectType)
+}
+
+// ListRules returns a list of the rules in a chain using the real knftables interface.
+func (r *NftImpl) ListRules(ctx context.Context, chain string) ([]*knftables.Rule, error) {
+	return r.nft.ListRules(ctx, chain)
+}
+
 // MockNftables is a mock implementation of NftablesAPI for use in unit tests.
 // It uses knftables.Fake to simulate nftables behavior without making changes to the system.
 type MockNftables struct {
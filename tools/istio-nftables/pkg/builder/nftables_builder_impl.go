@@ -15,8 +15,12 @@
 package builder
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+
 	"sigs.k8s.io/knftables"
 
+	"istio.io/istio/pkg/ptr"
 	"istio.io/istio/tools/common/config"
 	"istio.io/istio/tools/istio-nftables/pkg/constants"
 )
@@ -88,3 +92,29 @@ func (rb *NftablesRuleBuilder) AppendV6RuleIfSupported(chain string, table strin
 
 	return nil
 }
+
+// AddFingerprints tags the rules with a comment identifying their content. nft does not list rules the way they were
+// added, so the comments are what is compared to detect rules that differ from the expected ones.
+func (rb *NftablesRuleBuilder) AddFingerprints() *NftablesRuleBuilder {
+	for _, rules := range rb.Rules {
+		for i := range rules {
+			rules[i].Comment = ptr.Of(RuleFingerprint(rules[i]))
+		}
+	}
+	return rb
+}
+
+// RuleFingerprint returns the comment identifying the content of a rule.
+func RuleFingerprint(rule knftables.Rule) string {
+	sum := sha256.Sum256([]byte(rule.Table + " " + rule.Chain + " " + rule.Rule))
+	return "istio:" + hex.EncodeToString(sum[:8])
+}
+
+// RuleComments returns the comments of the rules, in order.
+func RuleComments(rules []*knftables.Rule) []string {
+	res := make([]string, 0, len(rules))
+	for _, r := range rules {
+		res = append(res, ptr.OrEmpty(r.Comment))
+	}
+	return res
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"sigs.k8s.io/knftables"
@@ -39,6 +40,7 @@ type NftablesConfigurator struct {
 	NetworkNamespace string
 	ruleBuilder      *builder.NftablesRuleBuilder // Helper to construct nftables rules
 	nftProvider      NftProviderFunc
+	fingerprints     bool
 }
 
 // NewNftablesConfigurator initializes a new configurator instance.
@@ -299,7 +301,6 @@ func (cfg *NftablesConfigurator) Run() (*knftables.Transaction, error) {
 	dropInvalid := cfg.cfg.DropInvalid
 	if dropInvalid {
 		cfg.ruleBuilder.AppendRule(constants.PreroutingChain, constants.IstioProxyMangleTable,
-			"meta l4proto tcp",
 			"ct state", "invalid", constants.Counter,
 			"jump", constants.IstioDropChain)
 		cfg.ruleBuilder.AppendRule(constants.IstioDropChain, constants.IstioProxyMangleTable, constants.Counter, "drop")
@@ -601,9 +602,17 @@ func (cfg *NftablesConfigurator) Run() (*knftables.Transaction, error) {
 			"return")
 	}
 
+	if cfg.fingerprints {
+		cfg.ruleBuilder.AddFingerprints()
+	}
 	return cfg.executeCommands()
 }
 
+// EnableRuleFingerprints tags the rules added by Run with a fingerprint of their content, which Verify compares.
+func (cfg *NftablesConfigurator) EnableRuleFingerprints() {
+	cfg.fingerprints = true
+}
+
 // SetupDNSRedir is a helper function for supporting DNS redirection use-cases.
 func (cfg *NftablesConfigurator) SetupDNSRedir(nft *builder.NftablesRuleBuilder, proxyUID, proxyGID string,
 	dnsServersV4 []string, dnsServersV6 []string, captureAllDNS bool, ownerGroupsFilter config.InterceptFilter,
@@ -1022,6 +1031,54 @@ func (cfg *NftablesConfigurator) executeCommands() (*knftables.Transaction, erro
 	return tx, nil
 }
 
+// Verify reports whether the rules in the Istio nftables tables differ from the rules applied by Run, which
+// must be called first with rule fingerprints enabled.
+func (cfg *NftablesConfigurator) Verify() (bool, error) {
+	if !cfg.fingerprints {
+		return false, fmt.Errorf("rule fingerprints are required to verify the nftables rules")
+	}
+	// Apply the rules to a fake to know the order they end up in once the inserts are applied.
+	fake := knftables.NewFake("", "")
+	tx := fake.NewTransaction()
+	tx = cfg.addIstioNatTableRules(tx)
+	tx = cfg.addIstioMangleTableRules(tx)
+	tx = cfg.addIstioRawTableRules(tx)
+	if err := fake.Run(context.TODO(), tx); err != nil {
+		return false, fmt.Errorf("failed to build the expected rules: %w", err)
+	}
+
+	for table, expected := range fake.Tables[knftables.InetFamily] {
+		nft, err := cfg.nftProvider(knftables.InetFamily, table)
+		if err != nil {
+			return false, err
+		}
+		chains, err := nft.List(context.TODO(), "chains")
+		if knftables.IsNotFound(err) {
+			log.Debugf("nftables table %s not found", table)
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to list chains of table %s: %w", table, err)
+		}
+		for chain, c := range expected.Chains {
+			if !slices.Contains(chains, chain) {
+				log.Debugf("nftables chain %s not found in table %s", chain, table)
+				return true, nil
+			}
+			rules, err := nft.ListRules(context.TODO(), chain)
+			if err != nil {
+				return false, fmt.Errorf("failed to list rules of chain %s in table %s: %w", chain, table, err)
+			}
+			want := builder.RuleComments(c.Rules)
+			if got := builder.RuleComments(rules); !slices.Equal(got, want) {
+				log.Debugf("nftables chain %s in table %s has rules %v, expected %v", chain, table, got, want)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // CombineMatchers takes a list of values and a matcher function.
 // For each value, it calls the matcher function to get a list of conditions,
 // then it combines all those lists into one big list using Flatten.
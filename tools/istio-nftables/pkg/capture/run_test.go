@@ -14,6 +14,7 @@
 package capture
 
 import (
+	"context"
 	"path/filepath"
 	"testing"
 
@@ -304,3 +305,88 @@ func TestNftables(t *testing.T) {
 		})
 	}
 }
+
+func TestVerify(t *testing.T) {
+	for _, tt := range getCommonTestCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := constructTestConfig()
+			tt.config(cfg)
+			mock := builder.NewMockNftables("", "")
+			nftProvider := func(family knftables.Family, table string) (builder.NftablesAPI, error) {
+				if table == "" {
+					return mock, nil
+				}
+				// Listing requires a default table, so return a view of the tables of the shared mock.
+				view := builder.NewMockNftables(family, table)
+				mock.RLock()
+				defer mock.RUnlock()
+				view.Tables = mock.Tables
+				view.Table = mock.Tables[family][table]
+				return view, nil
+			}
+			assertDrift := func(c *NftablesConfigurator, want bool) {
+				t.Helper()
+				drift, err := c.Verify()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if drift != want {
+					t.Fatalf("expected drift %v, got %v", want, drift)
+				}
+			}
+
+			nftConfigurator, _ := NewNftablesConfigurator(cfg, nftProvider)
+			nftConfigurator.EnableRuleFingerprints()
+			if _, err := nftConfigurator.Run(); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(nftConfigurator, false)
+
+			// Another tool replacing one of our rules, keeping the number of rules, is detected.
+			mock.RLock()
+			rules := mock.Tables[knftables.InetFamily][constants.IstioProxyNatTable].Chains[constants.IstioOutputChain].Rules
+			mock.RUnlock()
+			if len(rules) == 0 {
+				t.Fatalf("no rule found in chain %s", constants.IstioOutputChain)
+			}
+			tx := mock.NewTransaction()
+			tx.Replace(&knftables.Rule{
+				Chain:  constants.IstioOutputChain,
+				Table:  constants.IstioProxyNatTable,
+				Family: knftables.InetFamily,
+				Handle: rules[0].Handle,
+				Rule:   "counter accept",
+			})
+			if err := mock.Run(context.Background(), tx); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(nftConfigurator, true)
+
+			// Another tool flushing our rules is detected.
+			tx = mock.NewTransaction()
+			tx.Flush(&knftables.Chain{Name: constants.IstioOutputChain, Table: constants.IstioProxyNatTable, Family: knftables.InetFamily})
+			if err := mock.Run(context.Background(), tx); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(nftConfigurator, true)
+
+			// Once the tables are removed, the rules are missing.
+			cleanupCfg := *cfg
+			cleanupCfg.CleanupOnly = true
+			cleanup, _ := NewNftablesConfigurator(&cleanupCfg, nftProvider)
+			if _, err := cleanup.Run(); err != nil {
+				t.Fatal(err)
+			}
+			assertDrift(nftConfigurator, true)
+		})
+	}
+}
+
+func TestVerifyRequiresFingerprints(t *testing.T) {
+	nftConfigurator, _ := NewNftablesConfigurator(constructTestConfig(), func(knftables.Family, string) (builder.NftablesAPI, error) {
+		return builder.NewMockNftables("", ""), nil
+	})
+	if _, err := nftConfigurator.Verify(); err == nil {
+		t.Fatal("expected an error verifying rules without fingerprints")
+	}
+}